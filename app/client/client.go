@@ -18,21 +18,30 @@ import (
 	ipld "github.com/ipfs/go-ipld-format"
 	"github.com/libp2p/go-libp2p-core/metrics"
 	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
 	ma "github.com/multiformats/go-multiaddr"
 
 	chainApiTypes "github.com/filecoin-project/venus/app/submodule/chain"
 	"github.com/filecoin-project/venus/app/submodule/chain/cst"
 	mineApiTypes "github.com/filecoin-project/venus/app/submodule/mining"
+	netApiTypes "github.com/filecoin-project/venus/app/submodule/network"
 	syncApiTypes "github.com/filecoin-project/venus/app/submodule/syncer"
+	"github.com/filecoin-project/venus/pkg/beacon"
 	"github.com/filecoin-project/venus/pkg/block"
 	"github.com/filecoin-project/venus/pkg/chain"
+	"github.com/filecoin-project/venus/pkg/config"
 	"github.com/filecoin-project/venus/pkg/crypto"
+	"github.com/filecoin-project/venus/pkg/fork"
+	"github.com/filecoin-project/venus/pkg/invariants"
+	"github.com/filecoin-project/venus/pkg/journal"
 	"github.com/filecoin-project/venus/pkg/messagepool"
 	"github.com/filecoin-project/venus/pkg/net"
+	"github.com/filecoin-project/venus/pkg/slashing"
 	"github.com/filecoin-project/venus/pkg/specactors/builtin/miner"
 	"github.com/filecoin-project/venus/pkg/specactors/builtin/power"
 	"github.com/filecoin-project/venus/pkg/types"
 	"github.com/filecoin-project/venus/pkg/vm"
+	"github.com/filecoin-project/venus/pkg/vm/gas"
 	"github.com/filecoin-project/venus/pkg/wallet"
 )
 
@@ -97,14 +106,21 @@ type FullNode struct {
 	StateMinerSectorCount              func(context.Context, address.Address, block.TipSetKey) (chainApiTypes.MinerSectors, error)
 	StateMarketBalance                 func(context.Context, address.Address, block.TipSetKey) (chainApiTypes.MarketBalance, error)
 
-	ConfigSet func(string, string) error
-	ConfigGet func(string) (interface{}, error)
+	ConfigSet    func(string, string) error
+	ConfigGet    func(string) (interface{}, error)
+	ConfigSchema func() []*config.Field
 
 	SyncerTracker            func() *syncTypes.TargetTracker
 	ChainTipSetWeight        func(context.Context, block.TipSetKey) (big.Int, error)
 	ChainSyncHandleNewTipSet func(*block.ChainInfo) error
 	SyncSubmitBlock          func(context.Context, *block.BlockMsg) error
 	StateCall                func(context.Context, *types.UnsignedMessage, block.TipSetKey) (*syncApiTypes.InvocResult, error)
+	StateCallWithOverrides   func(context.Context, *types.UnsignedMessage, block.TipSetKey, *types.StateOverrides) (*syncApiTypes.InvocResult, error)
+	StateSimulate            func(context.Context, block.TipSetKey, []types.SimulatedMessage) (*syncApiTypes.SimulationResult, error)
+	StateReadState           func(context.Context, address.Address, cid.Cid) (*syncApiTypes.ActorState, error)
+	StateMigrate             func(context.Context, cid.Cid, network.Version) (*fork.MigrationDryRun, error)
+	ChainGasProfile          func(context.Context, abi.ChainEpoch, abi.ChainEpoch) ([]gas.ProfileEntry, error)
+	ChainValidate            func(context.Context, abi.ChainEpoch, abi.ChainEpoch, bool, int) (*syncApiTypes.ChainValidation, error)
 	SyncState                func(context.Context) (*syncApiTypes.SyncState, error)
 
 	DeleteByAdress          func(context.Context, address.Address) error
@@ -126,18 +142,26 @@ type FullNode struct {
 	GasEstimateMessageGas   func(context.Context, *types.UnsignedMessage, *types.MessageSendSpec, block.TipSetKey) (*types.UnsignedMessage, error)
 	GasEstimateFeeCap       func(context.Context, *types.UnsignedMessage, int64, block.TipSetKey) (big.Int, error)
 	GasEstimateGasPremium   func(context.Context, uint64, address.Address, int64, block.TipSetKey) (big.Int, error)
+	ChainGasStats           func(context.Context, abi.ChainEpoch, abi.ChainEpoch) ([]*messagepool.GasStats, error)
+	GasFeeRecommendation    func(context.Context, messagepool.FeeUrgency) (*messagepool.FeeRecommendation, error)
 	WalletSign              func(context.Context, address.Address, []byte) (*crypto.Signature, error)
 
-	NetworkGetBandwidthStats  func() metrics.Stats
-	NetworkGetPeerAddresses   func() []ma.Multiaddr
-	NetworkGetPeerID          func() peer.ID
-	NetworkFindProvidersAsync func(context.Context, cid.Cid, int) chan peer.AddrInfo
-	NetworkGetClosestPeers    func(context.Context, string) (chan peer.ID, error)
-	NetworkFindPeer           func(context.Context, peer.ID) (peer.AddrInfo, error)
-	NetworkConnect            func(context.Context, []string) (chan net.ConnectionResult, error)
-	NetworkPeers              func(context.Context, bool) (*net.SwarmConnInfos, error)
-	Version                   func(context.Context) (network.Version, error)
-	NetAddrsListen            func(context.Context) (peer.AddrInfo, error)
+	NetworkGetBandwidthStats    func() metrics.Stats
+	NetBandwidthStatsByPeer     func(context.Context) (map[string]metrics.Stats, error)
+	NetBandwidthStatsByProtocol func(context.Context) (map[protocol.ID]metrics.Stats, error)
+	NetworkGetPeerAddresses     func() []ma.Multiaddr
+	NetworkGetPeerID            func() peer.ID
+	NetworkFindProvidersAsync   func(context.Context, cid.Cid, int) chan peer.AddrInfo
+	NetworkGetClosestPeers      func(context.Context, string) (chan peer.ID, error)
+	NetworkFindPeer             func(context.Context, peer.ID) (peer.AddrInfo, error)
+	NetworkConnect              func(context.Context, []string) (chan net.ConnectionResult, error)
+	NetworkPeers                func(context.Context, bool) (*net.SwarmConnInfos, error)
+	Version                     func(context.Context) (network.Version, error)
+	NetAddrsListen              func(context.Context) (peer.AddrInfo, error)
+	NetBlockAdd                 func(context.Context, net.NetBlockList) error
+	NetBlockRemove              func(context.Context, net.NetBlockList) error
+	NetBlockList                func(context.Context) (net.NetBlockList, error)
+	NetPubsubScores             func(context.Context) ([]netApiTypes.PubsubScore, error)
 
 	WalletBalance        func(context.Context, address.Address) (abi.TokenAmount, error)
 	WalletHas            func(context.Context, address.Address) (bool, error)
@@ -159,10 +183,26 @@ type FullNode struct {
 	ActorGetSignature func(context.Context, address.Address, abi.MethodNum) (vm.ActorMethodSignature, error)
 	ListActor         func(context.Context) (map[address.Address]*types.Actor, error)
 
+	StateCheckInvariants func(context.Context, block.TipSetKey) (*invariants.Report, error)
+
 	BeaconGetEntry func(context.Context, abi.ChainEpoch) (*block.BeaconEntry, error)
+	BeaconStatus   func(context.Context) ([]beacon.DrandStatus, error)
 
 	MinerGetBaseInfo func(context.Context, address.Address, abi.ChainEpoch, block.TipSetKey) (*block.MiningBaseInfo, error)
 	MinerCreateBlock func(context.Context, *mineApiTypes.BlockTemplate) (*block.BlockMsg, error)
+	MiningOnce       func(context.Context) (*block.BlockMsg, error)
+	MiningStart      func(context.Context) error
+	MiningStop       func(context.Context) error
+
+	MinerCheckEligibility func(context.Context, address.Address, abi.ChainEpoch) (*mineApiTypes.EligibilityReport, error)
+
+	SlasherListReported func(context.Context) ([]*slashing.ReportedFault, error)
+	SlasherEnabled      func(context.Context) (bool, error)
+
+	JournalQuery func(context.Context, journal.EventFilter) ([]*journal.Event, error)
+	JournalTail  func(context.Context, journal.EventFilter) (chan *journal.Event, error)
+
+	Backup func(context.Context, string) error
 }
 
 type AccountAPI struct {
@@ -175,13 +215,23 @@ type ActorAPI struct {
 	ListActor         func(context.Context) (map[address.Address]*types.Actor, error)
 }
 
+type InvariantsAPI struct {
+	StateCheckInvariants func(context.Context, block.TipSetKey) (*invariants.Report, error)
+}
+
 type BeaconAPI struct {
 	BeaconGetEntry func(context.Context, abi.ChainEpoch) (*block.BeaconEntry, error)
+	BeaconStatus   func(context.Context) ([]beacon.DrandStatus, error)
 }
 
 type MiningAPI struct {
 	MinerGetBaseInfo func(context.Context, address.Address, abi.ChainEpoch, block.TipSetKey) (*block.MiningBaseInfo, error)
 	MinerCreateBlock func(context.Context, *mineApiTypes.BlockTemplate) (*block.BlockMsg, error)
+	MiningOnce       func(context.Context) (*block.BlockMsg, error)
+	MiningStart      func(context.Context) error
+	MiningStop       func(context.Context) error
+
+	MinerCheckEligibility func(context.Context, address.Address, abi.ChainEpoch) (*mineApiTypes.EligibilityReport, error)
 }
 
 type DbAPI struct {
@@ -221,9 +271,24 @@ type MinerStateAPI struct {
 	StateMarketBalance                 func(context.Context, address.Address, block.TipSetKey) (chainApiTypes.MarketBalance, error)
 }
 
+type SlasherAPI struct {
+	SlasherListReported func(context.Context) ([]*slashing.ReportedFault, error)
+	SlasherEnabled      func(context.Context) (bool, error)
+}
+
+type JournalAPI struct {
+	JournalQuery func(context.Context, journal.EventFilter) ([]*journal.Event, error)
+	JournalTail  func(context.Context, journal.EventFilter) (chan *journal.Event, error)
+}
+
+type BackupAPI struct {
+	Backup func(context.Context, string) error
+}
+
 type ConfigAPI struct {
-	ConfigSet func(string, string) error
-	ConfigGet func(string) (interface{}, error)
+	ConfigSet    func(string, string) error
+	ConfigGet    func(string) (interface{}, error)
+	ConfigSchema func() []*config.Field
 }
 
 type SyncerAPI struct {
@@ -232,6 +297,12 @@ type SyncerAPI struct {
 	ChainSyncHandleNewTipSet func(*block.ChainInfo) error
 	SyncSubmitBlock          func(context.Context, *block.BlockMsg) error
 	StateCall                func(context.Context, *types.UnsignedMessage, block.TipSetKey) (*syncApiTypes.InvocResult, error)
+	StateCallWithOverrides   func(context.Context, *types.UnsignedMessage, block.TipSetKey, *types.StateOverrides) (*syncApiTypes.InvocResult, error)
+	StateSimulate            func(context.Context, block.TipSetKey, []types.SimulatedMessage) (*syncApiTypes.SimulationResult, error)
+	StateReadState           func(context.Context, address.Address, cid.Cid) (*syncApiTypes.ActorState, error)
+	StateMigrate             func(context.Context, cid.Cid, network.Version) (*fork.MigrationDryRun, error)
+	ChainGasProfile          func(context.Context, abi.ChainEpoch, abi.ChainEpoch) ([]gas.ProfileEntry, error)
+	ChainValidate            func(context.Context, abi.ChainEpoch, abi.ChainEpoch, bool, int) (*syncApiTypes.ChainValidation, error)
 	SyncState                func(context.Context) (*syncApiTypes.SyncState, error)
 }
 
@@ -255,20 +326,28 @@ type MessagePoolAPI struct {
 	GasEstimateMessageGas   func(context.Context, *types.UnsignedMessage, *types.MessageSendSpec, block.TipSetKey) (*types.UnsignedMessage, error)
 	GasEstimateFeeCap       func(context.Context, *types.UnsignedMessage, int64, block.TipSetKey) (big.Int, error)
 	GasEstimateGasPremium   func(context.Context, uint64, address.Address, int64, block.TipSetKey) (big.Int, error)
+	ChainGasStats           func(context.Context, abi.ChainEpoch, abi.ChainEpoch) ([]*messagepool.GasStats, error)
+	GasFeeRecommendation    func(context.Context, messagepool.FeeUrgency) (*messagepool.FeeRecommendation, error)
 	WalletSign              func(context.Context, address.Address, []byte) (*crypto.Signature, error)
 }
 
 type NetworkAPI struct {
-	NetworkGetBandwidthStats  func() metrics.Stats
-	NetworkGetPeerAddresses   func() []ma.Multiaddr
-	NetworkGetPeerID          func() peer.ID
-	NetworkFindProvidersAsync func(context.Context, cid.Cid, int) chan peer.AddrInfo
-	NetworkGetClosestPeers    func(context.Context, string) (chan peer.ID, error)
-	NetworkFindPeer           func(context.Context, peer.ID) (peer.AddrInfo, error)
-	NetworkConnect            func(context.Context, []string) (chan net.ConnectionResult, error)
-	NetworkPeers              func(context.Context, bool) (*net.SwarmConnInfos, error)
-	Version                   func(context.Context) (network.Version, error)
-	NetAddrsListen            func(context.Context) (peer.AddrInfo, error)
+	NetworkGetBandwidthStats    func() metrics.Stats
+	NetBandwidthStatsByPeer     func(context.Context) (map[string]metrics.Stats, error)
+	NetBandwidthStatsByProtocol func(context.Context) (map[protocol.ID]metrics.Stats, error)
+	NetworkGetPeerAddresses     func() []ma.Multiaddr
+	NetworkGetPeerID            func() peer.ID
+	NetworkFindProvidersAsync   func(context.Context, cid.Cid, int) chan peer.AddrInfo
+	NetworkGetClosestPeers      func(context.Context, string) (chan peer.ID, error)
+	NetworkFindPeer             func(context.Context, peer.ID) (peer.AddrInfo, error)
+	NetworkConnect              func(context.Context, []string) (chan net.ConnectionResult, error)
+	NetworkPeers                func(context.Context, bool) (*net.SwarmConnInfos, error)
+	Version                     func(context.Context) (network.Version, error)
+	NetAddrsListen              func(context.Context) (peer.AddrInfo, error)
+	NetBlockAdd                 func(context.Context, net.NetBlockList) error
+	NetBlockRemove              func(context.Context, net.NetBlockList) error
+	NetBlockList                func(context.Context) (net.NetBlockList, error)
+	NetPubsubScores             func(context.Context) ([]netApiTypes.PubsubScore, error)
 }
 
 type WalletAPI struct {
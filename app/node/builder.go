@@ -14,14 +14,17 @@ import (
 	"github.com/libp2p/go-libp2p"
 	"github.com/pkg/errors"
 
+	"github.com/filecoin-project/venus/app/submodule/backup"
 	"github.com/filecoin-project/venus/app/submodule/blockservice"
 	"github.com/filecoin-project/venus/app/submodule/blockstore"
 	"github.com/filecoin-project/venus/app/submodule/chain"
 	config2 "github.com/filecoin-project/venus/app/submodule/config"
 	"github.com/filecoin-project/venus/app/submodule/discovery"
+	journal2 "github.com/filecoin-project/venus/app/submodule/journal"
 	"github.com/filecoin-project/venus/app/submodule/mining"
 	"github.com/filecoin-project/venus/app/submodule/mpool"
 	"github.com/filecoin-project/venus/app/submodule/network"
+	"github.com/filecoin-project/venus/app/submodule/slasher"
 	"github.com/filecoin-project/venus/app/submodule/storagenetworking"
 	"github.com/filecoin-project/venus/app/submodule/syncer"
 	"github.com/filecoin-project/venus/app/submodule/wallet"
@@ -189,7 +192,7 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 
 	var err error
 	if b.journal == nil {
-		b.journal = journal.NewNoopJournal()
+		b.journal = journal.NilJournal()
 	}
 
 	// fetch genesis block id
@@ -204,6 +207,8 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 		repo:        b.repo,
 	}
 	nd.configModule = config2.NewConfigModule(b.repo)
+	nd.journal = journal2.NewJournalSubmodule(b.journal)
+	nd.backup = backup.NewBackupSubmodule(b.repo)
 	nd.blockstore, err = blockstore.NewBlockstoreSubmodule(ctx, b.repo)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build node.blockstore")
@@ -224,7 +229,7 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 		return nil, errors.Wrap(err, "failed to build node.blockservice")
 	}
 
-	nd.chain, err = chain.NewChainSubmodule((*builder)(b), b.repo, nd.blockstore, b.verifier)
+	nd.chain, err = chain.NewChainSubmodule((*builder)(b), b.repo, nd.blockstore, nd.network, b.verifier)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build node.Chain")
 	}
@@ -261,11 +266,16 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 		return nil, errors.Wrap(err, "failed to build node.mpool")
 	}
 
+	nd.slasher, err = slasher.NewSlasherSubmodule(b.repo, nd.chain, nd.syncer, nd.mpool)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to build node.slasher")
+	}
+
 	nd.storageNetworking, err = storagenetworking.NewStorgeNetworkingSubmodule(ctx, nd.network)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to build node.storageNetworking")
 	}
-	nd.mining = mining.NewMiningModule(b.repo, nd.chain, nd.blockstore, nd.network, nd.syncer, *nd.wallet, b.verifier)
+	nd.mining = mining.NewMiningModule(b.repo, nd.chain, nd.blockstore, nd.network, nd.syncer, nd.mpool, *nd.wallet, b.verifier)
 	nd.jwtAuth, err = jwtauth.NewJwtAuth(b.repo)
 	if err != nil {
 		return nil, xerrors.Errorf("read or generate jwt secrect error %s", err)
@@ -284,12 +294,16 @@ func (b *Builder) build(ctx context.Context) (*Node, error) {
 		nd.storageNetworking,
 		nd.mining,
 		nd.mpool,
+		nd.slasher,
+		nd.journal,
+		nd.backup,
 		nd.jwtAuth,
 	)
 	if err != nil {
 		return nil, errors.Wrap(err, "add service failed ")
 	}
 	nd.jsonRPCService = apiBuilder.Build()
+	nd.registerConfigHandlers()
 	return nd, nil
 }
 
@@ -0,0 +1,75 @@
+package node
+
+import (
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/pkg/config"
+	"github.com/filecoin-project/venus/pkg/metrics"
+	"github.com/filecoin-project/venus/pkg/net"
+)
+
+// registerConfigHandlers applies the config fields that do not need a restart
+// when they are changed through the config api.
+func (node *Node) registerConfigHandlers() {
+	cm := node.configModule
+
+	cm.OnChange("bootstrap.addresses", func(cfg *config.Config) error {
+		peers, err := net.PeerAddrsToAddrInfo(cfg.Bootstrap.Addresses)
+		if err != nil {
+			return errors.Wrapf(err, "couldn't parse bootstrap addresses [%s]", cfg.Bootstrap.Addresses)
+		}
+		node.discovery.Bootstrapper.SetBootstrapPeers(peers)
+		if pmgr, ok := node.network.PeerMgr.(*net.PeerMgr); ok {
+			pmgr.SetBootstrappers(peers)
+		}
+		return nil
+	})
+
+	cm.OnChange("mpool", func(cfg *config.Config) error {
+		return node.mpool.ApplyConfig(cfg.Mpool)
+	})
+
+	cm.OnChange("api", func(cfg *config.Config) error {
+		if node.apiServerConfig == nil {
+			return nil
+		}
+		node.apiServerConfig.SetAllowedOrigins(cfg.API.AccessControlAllowOrigin...)
+		node.apiServerConfig.SetAllowedMethods(cfg.API.AccessControlAllowMethods...)
+		node.apiServerConfig.SetAllowCredentials(cfg.API.AccessControlAllowCredentials)
+		return nil
+	})
+
+	cm.OnChange("log", func(cfg *config.Config) error {
+		return applyLogConfig(cfg.Log)
+	})
+
+	cm.OnChange("observability.metrics", func(cfg *config.Config) error {
+		return metrics.RegisterPrometheusEndpoint(cfg.Observability.Metrics)
+	})
+
+	cm.OnChange("observability.tracing", func(cfg *config.Config) error {
+		return metrics.RegisterJaeger(node.network.Host.ID().Pretty(), cfg.Observability.Tracing)
+	})
+}
+
+// applyLogConfig sets the level of all loggers, then the levels of the
+// subsystems that override it.
+func applyLogConfig(cfg *config.LogConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	if cfg.Level != "" {
+		lvl, err := logging.LevelFromString(cfg.Level)
+		if err != nil {
+			return err
+		}
+		logging.SetAllLoggers(lvl)
+	}
+	for subsystem, level := range cfg.Subsystems {
+		if err := logging.SetLogLevel(subsystem, level); err != nil {
+			return errors.Wrapf(err, "subsystem %s", subsystem)
+		}
+	}
+	return nil
+}
@@ -5,14 +5,17 @@ import (
 
 	cmds "github.com/ipfs/go-ipfs-cmds"
 
+	"github.com/filecoin-project/venus/app/submodule/backup"
 	"github.com/filecoin-project/venus/app/submodule/blockservice"
 	"github.com/filecoin-project/venus/app/submodule/blockstore"
 	"github.com/filecoin-project/venus/app/submodule/chain"
 	"github.com/filecoin-project/venus/app/submodule/config"
 	"github.com/filecoin-project/venus/app/submodule/discovery"
+	"github.com/filecoin-project/venus/app/submodule/journal"
 	"github.com/filecoin-project/venus/app/submodule/mining"
 	"github.com/filecoin-project/venus/app/submodule/mpool"
 	"github.com/filecoin-project/venus/app/submodule/network"
+	"github.com/filecoin-project/venus/app/submodule/slasher"
 	"github.com/filecoin-project/venus/app/submodule/storagenetworking"
 	"github.com/filecoin-project/venus/app/submodule/syncer"
 	"github.com/filecoin-project/venus/app/submodule/wallet"
@@ -33,6 +36,9 @@ type Env struct {
 	WalletAPI            *wallet.WalletAPI
 	MingingAPI           *mining.MiningAPI
 	MessagePoolAPI       *mpool.MessagePoolAPI
+	SlasherAPI           *slasher.SlasherAPI
+	JournalAPI           *journal.JournalAPI
+	BackupAPI            *backup.BackupAPI
 }
 
 var _ cmds.Environment = (*Env)(nil)
@@ -18,14 +18,17 @@ import (
 
 	"github.com/filecoin-project/go-jsonrpc"
 	"github.com/filecoin-project/go-jsonrpc/auth"
+	"github.com/filecoin-project/venus/app/submodule/backup"
 	"github.com/filecoin-project/venus/app/submodule/blockservice"
 	"github.com/filecoin-project/venus/app/submodule/blockstore"
 	chain2 "github.com/filecoin-project/venus/app/submodule/chain"
 	configModule "github.com/filecoin-project/venus/app/submodule/config"
 	"github.com/filecoin-project/venus/app/submodule/discovery"
+	journal2 "github.com/filecoin-project/venus/app/submodule/journal"
 	"github.com/filecoin-project/venus/app/submodule/mining"
 	"github.com/filecoin-project/venus/app/submodule/mpool"
 	network2 "github.com/filecoin-project/venus/app/submodule/network"
+	"github.com/filecoin-project/venus/app/submodule/slasher"
 	"github.com/filecoin-project/venus/app/submodule/storagenetworking"
 	syncer2 "github.com/filecoin-project/venus/app/submodule/syncer"
 	"github.com/filecoin-project/venus/app/submodule/wallet"
@@ -78,6 +81,9 @@ type Node struct {
 	//
 	wallet            *wallet.WalletSubmodule
 	mpool             *mpool.MessagePoolSubmodule
+	slasher           *slasher.SlasherSubmodule
+	journal           *journal2.JournalSubmodule
+	backup            *backup.BackupSubmodule
 	storageNetworking *storagenetworking.StorageNetworkingSubmodule
 
 	//
@@ -91,12 +97,20 @@ type Node struct {
 	// Jsonrpc
 	//
 	jsonRPCService *jsonrpc.RPCServer
+
+	// apiServerConfig holds the CORS settings of the http api, kept to apply
+	// config changes without a restart.
+	apiServerConfig *cmdhttp.ServerConfig
 }
 
 func (node *Node) Chain() *chain2.ChainSubmodule {
 	return node.chain
 }
 
+func (node *Node) Syncer() *syncer2.SyncerSubmodule {
+	return node.syncer
+}
+
 func (node *Node) StorageNetworking() *storagenetworking.StorageNetworkingSubmodule {
 	return node.storageNetworking
 }
@@ -143,6 +157,10 @@ func (node *Node) OfflineMode() bool {
 
 // Start boots up the node.
 func (node *Node) Start(ctx context.Context) error {
+	if err := applyLogConfig(node.repo.Config().Log); err != nil {
+		return errors.Wrap(err, "failed to apply log config")
+	}
+
 	if err := metrics.RegisterPrometheusEndpoint(node.repo.Config().Observability.Metrics); err != nil {
 		return errors.Wrap(err, "failed to setup metrics")
 	}
@@ -151,6 +169,10 @@ func (node *Node) Start(ctx context.Context) error {
 		return errors.Wrap(err, "failed to setup tracing")
 	}
 
+	if err := node.chain.Start(ctx); err != nil {
+		return errors.Wrap(err, "failed to start chain")
+	}
+
 	var syncCtx context.Context
 	syncCtx, node.syncer.CancelChainSync = context.WithCancel(context.Background())
 
@@ -178,6 +200,9 @@ func (node *Node) Start(ctx context.Context) error {
 
 // Stop initiates the shutdown of the node.
 func (node *Node) Stop(ctx context.Context) {
+	// stop devnet mining
+	node.mining.Stop(ctx)
+
 	// stop mpool submodule
 	node.mpool.Stop(ctx)
 
@@ -193,6 +218,9 @@ func (node *Node) Stop(ctx context.Context) {
 	//Stop chain submodule
 	node.chain.Stop(ctx)
 
+	// close the journal once the submodules recording to it are stopped
+	node.journal.Stop(ctx)
+
 	if err := node.repo.Close(); err != nil {
 		fmt.Printf("error closing repo: %s\n", err)
 	}
@@ -273,6 +301,7 @@ func (node *Node) runRustfulAPI(ctx context.Context, handler *http.ServeMux, roo
 	cfg.SetAllowedOrigins(apiConfig.AccessControlAllowOrigin...)
 	cfg.SetAllowedMethods(apiConfig.AccessControlAllowMethods...)
 	cfg.SetAllowCredentials(apiConfig.AccessControlAllowCredentials)
+	node.apiServerConfig = cfg
 
 	handler.Handle(APIPrefix+"/", cmdhttp.NewHandler(servenv, rootCmdDaemon, cfg))
 	return nil
@@ -304,6 +333,9 @@ func (node *Node) createServerEnv(ctx context.Context) *Env {
 		WalletAPI:            node.wallet.API(),
 		MingingAPI:           node.mining.API(),
 		MessagePoolAPI:       node.mpool.API(),
+		SlasherAPI:           node.slasher.API(),
+		JournalAPI:           node.journal.API(),
+		BackupAPI:            node.backup.API(),
 	}
 
 	return &env
@@ -0,0 +1,24 @@
+package backup
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/pkg/repo"
+)
+
+type BackupAPI struct { //nolint
+	backup *BackupSubmodule
+}
+
+// Backup writes a backup archive of the config, the keystore, the api token,
+// the wallet, the local messages and the slash filter records to `path` on
+// the machine of the node. The chain is not included.
+func (backupAPI *BackupAPI) Backup(ctx context.Context, path string) error {
+	if !filepath.IsAbs(path) {
+		return errors.Errorf("backup path %s must be absolute", path)
+	}
+	return repo.BackupToFile(backupAPI.backup.repo, path)
+}
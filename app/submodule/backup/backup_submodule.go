@@ -0,0 +1,20 @@
+package backup
+
+import (
+	"github.com/filecoin-project/venus/pkg/repo"
+)
+
+// BackupSubmodule backs up the irreplaceable state of the node repo.
+type BackupSubmodule struct { //nolint
+	repo repo.Repo
+}
+
+// NewBackupSubmodule creates a new backup submodule.
+func NewBackupSubmodule(r repo.Repo) *BackupSubmodule {
+	return &BackupSubmodule{repo: r}
+}
+
+// API create a new backup api implement
+func (backupSubmodule *BackupSubmodule) API() *BackupAPI {
+	return &BackupAPI{backup: backupSubmodule}
+}
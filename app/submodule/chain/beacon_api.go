@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/venus/pkg/beacon"
 	"github.com/filecoin-project/venus/pkg/block"
 )
 
@@ -33,3 +34,9 @@ func (beaconAPI *BeaconAPI) BeaconGetEntry(ctx context.Context, epoch abi.ChainE
 		return nil, ctx.Err()
 	}
 }
+
+// BeaconStatus returns the source, latency and last verified round of the
+// drand beacons of the schedule.
+func (beaconAPI *BeaconAPI) BeaconStatus(ctx context.Context) ([]beacon.DrandStatus, error) {
+	return beaconAPI.chain.Drand.Status(), nil
+}
@@ -7,4 +7,5 @@ type ChainAPI struct { // nolint: golint
 	ChainInfoAPI
 	DbAPI
 	MinerStateAPI
+	InvariantsAPI
 }
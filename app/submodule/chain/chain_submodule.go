@@ -7,15 +7,20 @@ import (
 
 	"github.com/filecoin-project/go-address"
 	"github.com/ipfs/go-cid"
+	logging "github.com/ipfs/go-log/v2"
+	libp2pps "github.com/libp2p/go-libp2p-pubsub"
+	xerrors "github.com/pkg/errors"
 
 	"github.com/filecoin-project/venus/app/submodule/blockstore"
 	"github.com/filecoin-project/venus/app/submodule/chain/cst"
+	"github.com/filecoin-project/venus/app/submodule/network"
 	"github.com/filecoin-project/venus/pkg/beacon"
 	"github.com/filecoin-project/venus/pkg/block"
 	"github.com/filecoin-project/venus/pkg/chain"
 	"github.com/filecoin-project/venus/pkg/config"
 	"github.com/filecoin-project/venus/pkg/consensus"
 	"github.com/filecoin-project/venus/pkg/fork"
+	"github.com/filecoin-project/venus/pkg/journal"
 	"github.com/filecoin-project/venus/pkg/repo"
 	"github.com/filecoin-project/venus/pkg/slashing"
 	appstate "github.com/filecoin-project/venus/pkg/state"
@@ -25,6 +30,8 @@ import (
 	"github.com/filecoin-project/venus/pkg/vmsupport"
 )
 
+var log = logging.Logger("chain")
+
 // ChainSubmodule enhances the `Node` with chain capabilities.
 type ChainSubmodule struct { //nolint
 	ChainReader  *chain.Store
@@ -46,11 +53,15 @@ type ChainSubmodule struct { //nolint
 
 	// Wait for confirm message
 	Waiter *cst.Waiter
+
+	// stopInvariants stops the periodic invariant checks of the head state.
+	stopInvariants context.CancelFunc
 }
 
 // xxx go back to using an interface here
 type chainRepo interface {
 	ChainDatastore() repo.Datastore
+	MetaDatastore() repo.Datastore
 	Config() *config.Config
 }
 
@@ -58,6 +69,7 @@ type chainConfig interface {
 	GenesisCid() cid.Cid
 	BlockTime() time.Duration
 	Repo() repo.Repo
+	Journal() journal.Journal
 }
 
 type chainReader interface {
@@ -78,18 +90,24 @@ type stateReader interface {
 func NewChainSubmodule(config chainConfig,
 	repo chainRepo,
 	blockstore *blockstore.BlockstoreSubmodule,
+	network *network.NetworkSubmodule,
 	verifier ffiwrapper.Verifier,
 ) (*ChainSubmodule, error) {
 	// initialize chain store
 	chainStatusReporter := chain.NewStatusReporter()
-	chainStore := chain.NewStore(repo.ChainDatastore(), blockstore.CborStore, blockstore.Blockstore, chainStatusReporter, repo.Config().NetworkParams.ForkUpgradeParam, config.GenesisCid())
+	chainStore := chain.NewStore(repo.ChainDatastore(), blockstore.CborStore, blockstore.Blockstore, chainStatusReporter, repo.Config().NetworkParams.ForkUpgradeParam, config.GenesisCid(), config.Journal())
 	//drand
 	genBlk, err := chainStore.GetGenesisBlock(context.TODO())
 	if err != nil {
 		return nil, err
 	}
 
-	drand, err := beacon.DrandConfigSchedule(genBlk.Timestamp, repo.Config().NetworkParams.BlockDelay, repo.Config().NetworkParams.DrandSchedule)
+	// receive drand rounds over gossipsub as well when enabled
+	var drandPubsub *libp2pps.PubSub
+	if repo.Config().Pubsub.DrandRelay {
+		drandPubsub = network.Pubsub
+	}
+	drand, err := beacon.DrandConfigSchedule(genBlk.Timestamp, repo.Config().NetworkParams, drandPubsub, repo.MetaDatastore())
 	if err != nil {
 		return nil, err
 	}
@@ -135,12 +153,26 @@ func NewChainSubmodule(config chainConfig,
 	return store, nil
 }
 
-// Start loads the chain from disk.
+// Start runs the pre-migrations of the upcoming network upgrades and, when
+// configured, the periodic invariant checks of the head state.
 func (chain *ChainSubmodule) Start(ctx context.Context) error {
-	return nil
+	if cfg := chain.config.Repo().Config().Invariants; cfg != nil && cfg.Period != "" {
+		period, err := time.ParseDuration(cfg.Period)
+		if err != nil {
+			return xerrors.Wrap(err, "invalid invariants.period")
+		}
+		var checkCtx context.Context
+		checkCtx, chain.stopInvariants = context.WithCancel(context.Background())
+		go chain.checkInvariantsPeriodically(checkCtx, period)
+	}
+	return chain.Fork.Start(ctx)
 }
 
 func (chain *ChainSubmodule) Stop(ctx context.Context) {
+	if chain.stopInvariants != nil {
+		chain.stopInvariants()
+	}
+	chain.Fork.Stop(ctx)
 	chain.ChainReader.Stop()
 }
 
@@ -152,5 +184,6 @@ func (chain *ChainSubmodule) API() *ChainAPI {
 		ChainInfoAPI:  NewChainInfoAPI(chain),
 		DbAPI:         NewDbAPI(chain),
 		MinerStateAPI: NewMinerStateAPI(chain),
+		InvariantsAPI: NewInvariantsAPI(chain),
 	}
 }
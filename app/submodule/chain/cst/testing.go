@@ -45,7 +45,7 @@ func requireCommonDepsWithGifAndBlockstore(t *testing.T, gif genesis.InitFunc, r
 	wallet := wallet.New(backend)
 	genBlk, err := chainStore.GetGenesisBlock(context.TODO())
 	require.NoError(t, err)
-	drand, err := beacon.DrandConfigSchedule(genBlk.Timestamp, r.Config().NetworkParams.BlockDelay, r.Config().NetworkParams.DrandSchedule)
+	drand, err := beacon.DrandConfigSchedule(genBlk.Timestamp, r.Config().NetworkParams, nil, nil)
 	require.NoError(t, err)
 	chainState := NewChainStateReadWriter(chainStore, messageStore, bs, register.DefaultActors, drand)
 
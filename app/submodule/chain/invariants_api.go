@@ -0,0 +1,76 @@
+package chain
+
+import (
+	"context"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	xerrors "github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/invariants"
+)
+
+type InvariantsAPI struct {
+	chain *ChainSubmodule
+}
+
+func NewInvariantsAPI(chain *ChainSubmodule) InvariantsAPI {
+	return InvariantsAPI{chain: chain}
+}
+
+// StateCheckInvariants checks the invariants of the builtin actors on the
+// state computed by the tipset `tsk`, the head when empty.
+func (invariantsAPI *InvariantsAPI) StateCheckInvariants(ctx context.Context, tsk block.TipSetKey) (*invariants.Report, error) {
+	ts, err := invariantsAPI.chain.ChainReader.GetTipSet(tsk)
+	if err != nil {
+		return nil, xerrors.Wrapf(err, "loading tipset %s", tsk)
+	}
+	return invariantsAPI.chain.checkInvariants(ctx, ts)
+}
+
+func (chain *ChainSubmodule) checkInvariants(ctx context.Context, ts *block.TipSet) (*invariants.Report, error) {
+	root, err := chain.ChainReader.GetTipSetStateRoot(ts)
+	if err != nil {
+		return nil, xerrors.Wrapf(err, "no state computed for tipset %s", ts.Key())
+	}
+	height, err := ts.Height()
+	if err != nil {
+		return nil, err
+	}
+	store := cbor.NewCborStore(chain.ChainReader.Blockstore())
+	return invariants.Check(ctx, store, root, height, chain.Fork.GetNtwkVersion(ctx, height))
+}
+
+// checkInvariantsPeriodically checks the head state every `period` until
+// `ctx` is done, the violations are logged and counted in the metrics.
+func (chain *ChainSubmodule) checkInvariantsPeriodically(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	last := cid.Undef
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+		head := chain.ChainReader.GetHead()
+		root, err := chain.ChainReader.GetTipSetStateRoot(head)
+		if err != nil || root == last {
+			continue
+		}
+		report, err := chain.checkInvariants(ctx, head)
+		if err != nil {
+			log.Warnf("failed to check the invariants of head %s: %s", head.Key(), err)
+			continue
+		}
+		last = root
+		for _, v := range report.Violations {
+			for _, msg := range v.Messages {
+				log.Errorf("invariant violated at %d by %s: %s", report.Height, v.Actor, msg)
+			}
+		}
+	}
+}
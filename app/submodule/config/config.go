@@ -1,14 +1,33 @@
 package config
 
 import (
-	repo2 "github.com/filecoin-project/venus/pkg/repo"
+	"reflect"
 	"sync"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/pkg/config"
+	repo2 "github.com/filecoin-project/venus/pkg/repo"
 )
 
+var log = logging.Logger("config.module")
+
+// ChangeHandler applies the config after the value of the key it watches
+// changed.
+type ChangeHandler func(cfg *config.Config) error
+
+type changeWatcher struct {
+	key     string
+	handler ChangeHandler
+}
+
 // configModule is plumbing implementation for setting and retrieving values from local config.
 type ConfigModule struct { //nolint
 	repo repo2.Repo
 	lock sync.Mutex
+
+	watchers []changeWatcher
 }
 
 // NewConfig returns a new configModule.
@@ -16,17 +35,67 @@ func NewConfigModule(repo repo2.Repo) *ConfigModule {
 	return &ConfigModule{repo: repo}
 }
 
-// Set sets a value in config
+// OnChange calls `handler` with the new config each time a Set changes the
+// value at the dotted key `key`.
+func (s *ConfigModule) OnChange(key string, handler ChangeHandler) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.watchers = append(s.watchers, changeWatcher{key: key, handler: handler})
+}
+
+// Set sets a value in config. The handlers of the keys it changes run before
+// the config is saved, a value a handler refuses is not saved.
 func (s *ConfigModule) Set(dottedKey string, jsonString string) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
 	cfg := s.repo.Config()
-	if err := cfg.Set(dottedKey, jsonString); err != nil {
+	old, err := cfg.Clone()
+	if err != nil {
+		return err
+	}
+	updated, err := cfg.Clone()
+	if err != nil {
+		return err
+	}
+	if err := updated.Set(dottedKey, jsonString); err != nil {
 		return err
 	}
 
-	return s.repo.ReplaceConfig(cfg)
+	var applied []changeWatcher
+	for _, w := range s.watchers {
+		before, err := old.Get(w.key)
+		if err != nil {
+			return err
+		}
+		after, err := updated.Get(w.key)
+		if err != nil {
+			return err
+		}
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+		if err := w.handler(updated); err != nil {
+			// put back what the previous handlers applied
+			for _, a := range applied {
+				if rerr := a.handler(old); rerr != nil {
+					log.Warnf("failed to restore %s: %s", a.key, rerr)
+				}
+			}
+			return errors.Wrapf(err, "applying %s failed, the config is not saved", w.key)
+		}
+		applied = append(applied, w)
+	}
+
+	*cfg = *updated
+	if err := s.repo.ReplaceConfig(cfg); err != nil {
+		return err
+	}
+	if keys := config.RestartRequired(old, cfg); len(keys) > 0 {
+		log.Warnf("the changes of %v apply after a restart", keys)
+	}
+	return nil
 }
 
 // Get gets a value from config
@@ -1,5 +1,9 @@
 package config
 
+import (
+	"github.com/filecoin-project/venus/pkg/config"
+)
+
 type ConfigAPI struct { //nolint
 	config *ConfigModule
 }
@@ -19,3 +23,9 @@ func (configAPI *ConfigAPI) ConfigSet(dottedPath string, paramJSON string) error
 func (configAPI *ConfigAPI) ConfigGet(dottedPath string) (interface{}, error) {
 	return configAPI.config.Get(dottedPath)
 }
+
+// ConfigSchema describes the fields of the config, with their units and
+// whether changing them needs a restart of the daemon.
+func (configAPI *ConfigAPI) ConfigSchema() []*config.Field {
+	return config.Schema
+}
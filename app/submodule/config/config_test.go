@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/filecoin-project/go-address"
@@ -51,7 +52,9 @@ func TestConfigSet(t *testing.T) {
 		repo := repo2.NewInMemoryRepo()
 		cfgAPI := NewConfigModule(repo)
 
-		jsonBlob := `{"addresses": ["bootup1", "bootup2"]}`
+		bootup1 := "/ip4/127.0.0.1/tcp/1/p2p/12D3KooWCVe8MmsEMes2FzgTpt9fXtmCY7wrq91GRiaC8PHSCCBj"
+		bootup2 := "/ip4/127.0.0.1/tcp/2/p2p/12D3KooWCVe8MmsEMes2FzgTpt9fXtmCY7wrq91GRiaC8PHSCCBj"
+		jsonBlob := `{"addresses": ["` + bootup1 + `", "` + bootup2 + `"]}`
 
 		err := cfgAPI.Set("bootstrap", jsonBlob)
 		require.NoError(t, err)
@@ -60,7 +63,7 @@ func TestConfigSet(t *testing.T) {
 
 		// validate output
 		expected := config.NewDefaultConfig().Bootstrap
-		expected.Addresses = []string{"bootup1", "bootup2"}
+		expected.Addresses = []string{bootup1, bootup2}
 		assert.Equal(t, expected, out)
 
 		// validate config write
@@ -68,9 +71,9 @@ func TestConfigSet(t *testing.T) {
 		assert.Equal(t, expected, cfg.Bootstrap)
 		assert.Equal(t, defaultCfg.Datastore, cfg.Datastore)
 
-		err = cfgAPI.Set("api.apiAddress", ":1234")
+		err = cfgAPI.Set("api.apiAddress", "/ip4/127.0.0.1/tcp/1234")
 		require.NoError(t, err)
-		assert.Equal(t, ":1234", cfg.API.APIAddress)
+		assert.Equal(t, "/ip4/127.0.0.1/tcp/1234", cfg.API.APIAddress)
 
 		testAddr := types.RequireIDAddress(t, 100).String()
 		err = cfgAPI.Set("walletModule.defaultAddress", testAddr)
@@ -115,3 +118,31 @@ func TestConfigSet(t *testing.T) {
 	})
 
 }
+
+func TestConfigSetAppliesBeforeSaving(t *testing.T) {
+	tf.UnitTest(t)
+
+	repo := repo2.NewInMemoryRepo()
+	cfgAPI := NewConfigModule(repo)
+	var applied []uint
+	cfgAPI.OnChange("mpool", func(cfg *config.Config) error {
+		applied = append(applied, cfg.Mpool.MaxPoolSize)
+		return nil
+	})
+	cfgAPI.OnChange("mpool.maxNonceGap", func(cfg *config.Config) error {
+		if cfg.Mpool.MaxNonceGap > 10 {
+			return errors.New("gap too large")
+		}
+		return nil
+	})
+
+	require.NoError(t, cfgAPI.Set("mpool.maxPoolSize", "10000"))
+	assert.Equal(t, uint(10000), repo.Config().Mpool.MaxPoolSize)
+
+	err := cfgAPI.Set("mpool", `{"maxPoolSize": 500, "maxNonceGap": 20}`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the config is not saved")
+	assert.Equal(t, uint(10000), repo.Config().Mpool.MaxPoolSize)
+	// the handler applied before the refusal is restored
+	assert.Equal(t, []uint{10000, 500, 10000}, applied)
+}
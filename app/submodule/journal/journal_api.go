@@ -0,0 +1,55 @@
+package journal
+
+import (
+	"context"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/pkg/journal"
+)
+
+var log = logging.Logger("journal.module")
+
+// ErrJournalNotReadable is returned when the journal of the node does not
+// store events, as in the in-memory repos of tests.
+var ErrJournalNotReadable = errors.New("the journal of this node cannot be read")
+
+type JournalAPI struct { //nolint
+	journal *JournalSubmodule
+}
+
+// JournalQuery returns the journaled events matching `filter`, oldest first.
+func (journalAPI *JournalAPI) JournalQuery(ctx context.Context, filter journal.EventFilter) ([]*journal.Event, error) {
+	reader, ok := journalAPI.journal.Journal.(journal.Reader)
+	if !ok {
+		return nil, ErrJournalNotReadable
+	}
+	return reader.Query(ctx, filter)
+}
+
+// JournalTail streams the events matching `filter` as they are journaled
+// until `ctx` is done.
+func (journalAPI *JournalAPI) JournalTail(ctx context.Context, filter journal.EventFilter) (chan *journal.Event, error) {
+	reader, ok := journalAPI.journal.Journal.(journal.Reader)
+	if !ok {
+		return nil, ErrJournalNotReadable
+	}
+	events, err := reader.Subscribe(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan *journal.Event)
+	go func() {
+		defer close(out)
+		for evt := range events {
+			select {
+			case out <- evt:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,29 @@
+package journal
+
+import (
+	"context"
+
+	"github.com/filecoin-project/venus/pkg/journal"
+)
+
+// JournalSubmodule owns the event journal the other submodules record to.
+type JournalSubmodule struct { //nolint
+	Journal journal.Journal
+}
+
+// NewJournalSubmodule creates a new journal submodule.
+func NewJournalSubmodule(j journal.Journal) *JournalSubmodule {
+	return &JournalSubmodule{Journal: j}
+}
+
+// API create a new journal api implement
+func (journalSubmodule *JournalSubmodule) API() *JournalAPI {
+	return &JournalAPI{journal: journalSubmodule}
+}
+
+// Stop closes the journal.
+func (journalSubmodule *JournalSubmodule) Stop(ctx context.Context) {
+	if err := journalSubmodule.Journal.Close(); err != nil {
+		log.Warnf("failed to close journal: %s", err)
+	}
+}
@@ -0,0 +1,356 @@
+package mining
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	acrypto "github.com/filecoin-project/go-state-types/crypto"
+	logging "github.com/ipfs/go-log/v2"
+	xerrors "github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/chain"
+	"github.com/filecoin-project/venus/pkg/consensus"
+	"github.com/filecoin-project/venus/pkg/constants"
+	"github.com/filecoin-project/venus/pkg/util/ffiwrapper"
+)
+
+var log = logging.Logger("mining")
+
+// DevnetMaxNullRounds is the number of consecutive rounds MiningOnce tries
+// before giving up when none of the local miners wins an election.
+const DevnetMaxNullRounds = 10
+
+// mockWinningPoStProof is the proof produced by devnet miners, it is
+// accepted by ffiwrapper.FakeVerifier and by insecure PoSt validation.
+var mockWinningPoStProof = []byte("valid proof")
+
+// ErrMockProofsRequired is returned when devnet mining is requested on a node
+// that verifies real proofs, its blocks would never validate.
+var ErrMockProofsRequired = xerrors.New("devnet mining requires a node started with mock proofs")
+
+// devnetMiner mines blocks for the genesis miners whose worker keys are in the
+// local wallet, so that devnets advance without an external miner.
+type devnetMiner struct {
+	lk     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// start runs `mine` in a loop until stop is called. After each block it runs
+// `wait`, so that the next block is mined on top of the previous one.
+func (dm *devnetMiner) start(mine func(context.Context) (*block.BlockMsg, error), wait func(context.Context, *block.BlockMsg) error, blockDelay time.Duration) error {
+	dm.lk.Lock()
+	defer dm.lk.Unlock()
+	if dm.cancel != nil {
+		return xerrors.New("mining is already running")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	dm.cancel = cancel
+	dm.done = make(chan struct{})
+	go func() {
+		defer close(dm.done)
+		for {
+			blk, err := mine(ctx)
+			if err == nil {
+				err = wait(ctx, blk)
+			}
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Errorf("devnet mining failed: %s", err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(blockDelay):
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+		}
+	}()
+	return nil
+}
+
+// stop stops the mining loop and waits for it to exit.
+func (dm *devnetMiner) stop() error {
+	dm.lk.Lock()
+	defer dm.lk.Unlock()
+	if dm.cancel == nil {
+		return xerrors.New("mining is not running")
+	}
+
+	dm.cancel()
+	<-dm.done
+	dm.cancel = nil
+	dm.done = nil
+	return nil
+}
+
+func (dm *devnetMiner) running() bool {
+	dm.lk.Lock()
+	defer dm.lk.Unlock()
+	return dm.cancel != nil
+}
+
+// mineOnce mines on top of the current head. It tries consecutive rounds
+// until at least one local miner wins, waits for the start of that round and
+// submits the winning blocks. The first block is returned.
+func (miningModule *MiningModule) mineOnce(ctx context.Context) (*block.BlockMsg, error) {
+	if _, ok := miningModule.proofVerifier.(*ffiwrapper.FakeVerifier); !ok {
+		return nil, ErrMockProofsRequired
+	}
+
+	base := miningModule.ChainModule.ChainReader.GetHead()
+	miners, err := miningModule.localMiners(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+	if len(miners) == 0 {
+		return nil, xerrors.New("no miner has its worker key in the local wallet")
+	}
+
+	tickets, err := miningModule.ticketMachine(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// skip the rounds that are already over when the chain has been idle.
+	blockDelay := miningModule.Config.Repo().Config().NetworkParams.BlockDelay
+	firstNull := 0
+	if now := uint64(time.Now().Unix()); now > base.MinTimestamp() {
+		if elapsed := (now - base.MinTimestamp()) / blockDelay; elapsed > 1 {
+			firstNull = int(elapsed) - 1
+		}
+	}
+
+	for nulls := firstNull; nulls <= firstNull+DevnetMaxNullRounds; nulls++ {
+		round := base.EnsureHeight() + abi.ChainEpoch(nulls) + 1
+		timestamp := base.MinTimestamp() + blockDelay*uint64(nulls+1)
+
+		var blks []*block.BlockMsg
+		for _, maddr := range miners {
+			blk, err := miningModule.mineRound(ctx, tickets, maddr, base, round, timestamp)
+			if err != nil {
+				return nil, xerrors.Wrapf(err, "mining round %d for %s", round, maddr)
+			}
+			if blk != nil {
+				blks = append(blks, blk)
+			}
+		}
+		if len(blks) == 0 {
+			log.Debugf("no local miner won round %d", round)
+			continue
+		}
+
+		// blocks from the future are rejected by the validator.
+		if wait := time.Until(time.Unix(int64(timestamp), 0)); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		for _, blk := range blks {
+			if err := miningModule.SyncModule.API().SyncSubmitBlock(ctx, blk); err != nil {
+				return nil, xerrors.Wrapf(err, "failed to submit block %s", blk.Header.Cid())
+			}
+			log.Infof("mined block %s at height %d for %s", blk.Header.Cid(), round, blk.Header.Miner)
+		}
+		return blks[0], nil
+	}
+
+	return nil, xerrors.Errorf("no local miner won in %d rounds", DevnetMaxNullRounds+1)
+}
+
+// waitMined waits until the head reaches the height of `mined`, or until the
+// round after it starts when the block does not make it to the head.
+func (miningModule *MiningModule) waitMined(ctx context.Context, mined *block.BlockMsg) error {
+	return waitHead(ctx, miningModule.ChainModule.ChainReader, mined, miningModule.blockDelay())
+}
+
+func waitHead(ctx context.Context, chainReader *chain.Store, mined *block.BlockMsg, blockDelay time.Duration) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	nextRound := time.NewTimer(time.Until(time.Unix(int64(mined.Header.Timestamp), 0).Add(blockDelay)))
+	defer nextRound.Stop()
+	changes := chainReader.SubHeadChanges(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-nextRound.C:
+			return nil
+		case hcs, ok := <-changes:
+			if !ok {
+				return xerrors.New("head change subscription closed")
+			}
+			for _, hc := range hcs {
+				if hc.Type != chain.HCRevert && hc.Val.EnsureHeight() >= mined.Header.Height {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// mineRound creates the block of `maddr` for `round` on top of `base`, it
+// returns nil when the miner is not eligible or does not win the election.
+func (miningModule *MiningModule) mineRound(ctx context.Context,
+	tickets *consensus.TicketMachine,
+	maddr address.Address,
+	base *block.TipSet,
+	round abi.ChainEpoch,
+	timestamp uint64,
+) (*block.BlockMsg, error) {
+	api := miningModule.API()
+	mbi, err := api.MinerGetBaseInfo(ctx, maddr, round, base.Key())
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to get mining base info")
+	}
+	if mbi == nil || !mbi.EligibleForMining {
+		return nil, nil
+	}
+
+	rbase := mbi.PrevBeaconEntry
+	if len(mbi.BeaconEntries) > 0 {
+		rbase = mbi.BeaconEntries[len(mbi.BeaconEntries)-1]
+	}
+
+	eproof, err := miningModule.computeElectionProof(ctx, maddr, mbi, &rbase, round)
+	if err != nil {
+		return nil, err
+	}
+	if eproof.WinCount < 1 {
+		return nil, nil
+	}
+
+	ticket, err := miningModule.computeTicket(ctx, tickets, maddr, mbi, &rbase, base, round)
+	if err != nil {
+		return nil, err
+	}
+
+	var winningPoSt []block.PoStProof
+	if len(mbi.Sectors) > 0 {
+		proofType, err := mbi.Sectors[0].SealProof.RegisteredWinningPoStProof()
+		if err != nil {
+			return nil, xerrors.Wrap(err, "failed to determine winning post proof type")
+		}
+		winningPoSt = []block.PoStProof{{PoStProof: proofType, ProofBytes: mockWinningPoStProof}}
+	}
+
+	msgs, err := miningModule.MpoolModule.API().MpoolSelect(ctx, base.Key(), ticket.Quality())
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to select messages for block")
+	}
+
+	beaconValues := make([]*block.BeaconEntry, len(mbi.BeaconEntries))
+	for i := range mbi.BeaconEntries {
+		beaconValues[i] = &mbi.BeaconEntries[i]
+	}
+
+	return api.MinerCreateBlock(ctx, &BlockTemplate{
+		Miner:            maddr,
+		Parents:          base.Key(),
+		Ticket:           ticket,
+		Eproof:           eproof,
+		BeaconValues:     beaconValues,
+		Messages:         msgs,
+		Epoch:            round,
+		Timestamp:        timestamp,
+		WinningPoStProof: winningPoSt,
+	})
+}
+
+// ticketMachine returns a ticket machine reading the local chain.
+func (miningModule *MiningModule) ticketMachine(ctx context.Context) (*consensus.TicketMachine, error) {
+	chainReader := miningModule.ChainModule.ChainReader
+	genesis, err := chainReader.GetGenesisBlock(ctx)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to load genesis block")
+	}
+	return consensus.NewTicketMachine(chain.NewSampler(chainReader, genesis.Ticket), chainReader), nil
+}
+
+// computeTicket computes the ticket of `maddr` for a block at `round` on top
+// of `base`, signing with the worker key of `mbi`.
+func (miningModule *MiningModule) computeTicket(ctx context.Context,
+	tickets *consensus.TicketMachine,
+	maddr address.Address,
+	mbi *block.MiningBaseInfo,
+	rbase *block.BeaconEntry,
+	base *block.TipSet,
+	round abi.ChainEpoch,
+) (block.Ticket, error) {
+	smokeHeight := miningModule.Config.Repo().Config().NetworkParams.ForkUpgradeParam.UpgradeSmokeHeight
+	ticket, err := tickets.MakeTicket(ctx, base.Key(), round-constants.TicketRandomnessLookback, maddr, rbase, round > smokeHeight, mbi.WorkerKey, miningModule.Wallet.Signer)
+	if err != nil {
+		return block.Ticket{}, xerrors.Wrap(err, "failed to compute ticket")
+	}
+	return ticket, nil
+}
+
+// computeElectionProof runs the election of `maddr` for `round` and returns
+// its proof, the miner won when the win count is at least one.
+func (miningModule *MiningModule) computeElectionProof(ctx context.Context, maddr address.Address, mbi *block.MiningBaseInfo, rbase *block.BeaconEntry, round abi.ChainEpoch) (*block.ElectionProof, error) {
+	buf := new(bytes.Buffer)
+	if err := maddr.MarshalCBOR(buf); err != nil {
+		return nil, xerrors.Wrap(err, "failed to marshal miner address")
+	}
+
+	vrfBase, err := chain.DrawRandomness(rbase.Data, acrypto.DomainSeparationTag_ElectionProofProduction, round, buf.Bytes())
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to draw election randomness")
+	}
+
+	vrfOut, err := miningModule.Wallet.Signer.SignBytes(ctx, vrfBase, mbi.WorkerKey)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to compute election vrf")
+	}
+
+	ep := &block.ElectionProof{VRFProof: vrfOut.Data}
+	ep.WinCount = ep.ComputeWinCount(mbi.MinerPower, mbi.NetworkPower)
+	return ep, nil
+}
+
+// localMiners returns the miners of the power table whose worker key is in
+// the local wallet.
+func (miningModule *MiningModule) localMiners(ctx context.Context, ts *block.TipSet) ([]address.Address, error) {
+	view, err := miningModule.ChainModule.State.StateView(ts)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to load state view")
+	}
+
+	miners, err := view.StateListMiners(ctx, ts.Key())
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to list miners")
+	}
+
+	var out []address.Address
+	for _, maddr := range miners {
+		worker, err := view.GetMinerWorkerRaw(ctx, maddr)
+		if err != nil {
+			return nil, xerrors.Wrapf(err, "failed to get worker of %s", maddr)
+		}
+		workerKey, err := view.ResolveToKeyAddr(ctx, worker)
+		if err != nil {
+			return nil, xerrors.Wrapf(err, "failed to resolve worker of %s", maddr)
+		}
+		if miningModule.Wallet.Wallet.HasAddress(workerKey) {
+			out = append(out, maddr)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,115 @@
+package mining
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/chain"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+func TestDevnetMinerStartStop(t *testing.T) {
+	tf.UnitTest(t)
+
+	var dm devnetMiner
+	require.Error(t, dm.stop())
+
+	mined := make(chan struct{}, 1)
+	mine := func(ctx context.Context) (*block.BlockMsg, error) {
+		select {
+		case mined <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	wait := func(context.Context, *block.BlockMsg) error {
+		return nil
+	}
+
+	require.NoError(t, dm.start(mine, wait, time.Millisecond))
+	assert.True(t, dm.running())
+	assert.Error(t, dm.start(mine, wait, time.Millisecond))
+
+	<-mined
+	require.NoError(t, dm.stop())
+	assert.False(t, dm.running())
+}
+
+func TestDevnetMinerMinesOnTheNewHead(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	builder := chain.NewBuilder(t, address.Undef)
+	store := builder.Store()
+
+	// Blocks are submitted asynchronously, they become the head a bit later.
+	var lk sync.Mutex
+	var heights []abi.ChainEpoch
+	produced := make(chan struct{}, 3)
+	mine := func(mctx context.Context) (*block.BlockMsg, error) {
+		lk.Lock()
+		defer lk.Unlock()
+		if len(heights) == 3 {
+			<-mctx.Done()
+			return nil, mctx.Err()
+		}
+		next := builder.AppendOn(store.GetHead(), 1)
+		heights = append(heights, next.EnsureHeight())
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			require.NoError(t, store.PutTipSetMetadata(ctx, &chain.TipSetMetadata{
+				TipSet:          next,
+				TipSetStateRoot: next.At(0).ParentStateRoot,
+				TipSetReceipts:  next.At(0).ParentMessageReceipts,
+			}))
+			require.NoError(t, store.SetHead(ctx, next))
+			produced <- struct{}{}
+		}()
+		// Only a head change lets the loop go on before the next round.
+		header := *next.At(0)
+		header.Timestamp = uint64(time.Now().Unix())
+		return &block.BlockMsg{Header: &header}, nil
+	}
+	wait := func(ctx context.Context, mined *block.BlockMsg) error {
+		return waitHead(ctx, store, mined, time.Hour)
+	}
+
+	var dm devnetMiner
+	require.NoError(t, dm.start(mine, wait, time.Millisecond))
+	for i := 0; i < 3; i++ {
+		<-produced
+	}
+	require.NoError(t, dm.stop())
+
+	assert.Equal(t, abi.ChainEpoch(3), store.GetHead().EnsureHeight())
+	require.Len(t, heights, 3)
+	for i, h := range heights {
+		assert.Equal(t, abi.ChainEpoch(i+1), h, "block %d", i)
+	}
+}
+
+func TestWaitHeadTimesOutAtTheNextRound(t *testing.T) {
+	tf.UnitTest(t)
+
+	builder := chain.NewBuilder(t, address.Undef)
+	next := builder.AppendOn(builder.Genesis(), 1)
+	mined := &block.BlockMsg{Header: next.At(0)}
+	mined.Header.Timestamp = uint64(time.Now().Unix())
+
+	start := time.Now()
+	require.NoError(t, waitHead(context.Background(), builder.Store(), mined, time.Second))
+	assert.True(t, time.Since(start) < 3*time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, waitHead(ctx, builder.Store(), mined, time.Hour))
+}
@@ -0,0 +1,189 @@
+package mining
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	fbig "github.com/filecoin-project/go-state-types/big"
+	xerrors "github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/constants"
+	"github.com/filecoin-project/venus/pkg/state"
+	"github.com/filecoin-project/venus/pkg/types"
+)
+
+// EligibilityReport describes whether a miner could mine a block at an epoch
+// and whether it would have won the election.
+type EligibilityReport struct {
+	Miner address.Address
+	Epoch abi.ChainEpoch
+	// Base is the tipset the block would be mined on.
+	Base block.TipSetKey
+
+	RawPower         abi.StoragePower
+	MinerPower       abi.StoragePower
+	NetworkPower     abi.StoragePower
+	HasMinPower      bool
+	FeeDebt          abi.TokenAmount
+	EligibleSectors  []abi.SectorNumber
+	EligibleToMine   bool
+	BeaconRound      uint64
+	ExpectedWinCount float64
+
+	// Ticket, ElectionProof and WinCount are only computed when the worker
+	// key of the miner is in the local wallet.
+	Ticket        *block.Ticket
+	ElectionProof *block.ElectionProof
+	WinCount      int64
+	// Note explains why parts of the report could not be computed.
+	Note string
+}
+
+// MinerCheckEligibility reports the power, eligibility and election result of
+// `maddr` for a block at `epoch`. Past epochs are checked on the tipset the
+// block would have been mined on, epochs after the head on the head.
+func (miningAPI *MiningAPI) MinerCheckEligibility(ctx context.Context, maddr address.Address, epoch abi.ChainEpoch) (*EligibilityReport, error) {
+	ming := miningAPI.Ming
+	chainStore := ming.ChainModule.ChainReader
+	chainState := ming.ChainModule.State
+
+	base := chainStore.GetHead()
+	if epoch <= base.EnsureHeight() {
+		var err error
+		base, err = chainStore.GetTipSetByHeight(ctx, base, epoch-1, true)
+		if err != nil {
+			return nil, xerrors.Wrapf(err, "failed to load base tipset of epoch %d", epoch)
+		}
+	}
+	if epoch <= base.EnsureHeight() {
+		return nil, xerrors.Errorf("epoch %d is not after its base tipset at %d", epoch, base.EnsureHeight())
+	}
+
+	report := &EligibilityReport{
+		Miner:        maddr,
+		Epoch:        epoch,
+		Base:         base.Key(),
+		RawPower:     fbig.Zero(),
+		MinerPower:   fbig.Zero(),
+		NetworkPower: fbig.Zero(),
+		FeeDebt:      fbig.Zero(),
+	}
+
+	version := ming.ChainModule.Fork.GetNtwkVersion(ctx, epoch)
+	lbts, lbst, err := chainStore.GetLookbackTipSetForRound(ctx, base, epoch, version)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "getting lookback miner actor state")
+	}
+
+	view := state.NewView(chainState, lbst)
+	if _, err := view.LoadActor(ctx, maddr); err != nil {
+		if xerrors.Is(err, types.ErrActorNotFound) {
+			report.Note = "miner does not exist at the lookback epoch"
+			return report, nil
+		}
+		return nil, xerrors.Wrap(err, "failed to load miner actor")
+	}
+
+	validator := ming.SyncModule.BlockValidator
+	report.HasMinPower, err = validator.MinerHasMinPower(ctx, maddr, lbts)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "checking minimum power")
+	}
+
+	pt, err := chainState.GetTipSetStateRoot(ctx, base)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to get tipset root for mining base")
+	}
+	report.EligibleToMine, err = validator.MinerEligibleToMine(ctx, maddr, pt, base.EnsureHeight(), lbts)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "determining miner eligibility")
+	}
+
+	pas, err := view.LoadPowerState(ctx)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to load power actor state")
+	}
+	tpow, err := pas.TotalPower()
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to get network power")
+	}
+	report.NetworkPower = tpow.QualityAdjPower
+	mpow, found, err := pas.MinerPower(maddr)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to get power")
+	}
+	if !found {
+		report.Note = "miner has no power claim at the lookback epoch"
+		return report, nil
+	}
+	report.RawPower = mpow.RawBytePower
+	report.MinerPower = mpow.QualityAdjPower
+	report.ExpectedWinCount = expectedWinCount(mpow.QualityAdjPower, tpow.QualityAdjPower)
+
+	mas, err := view.LoadMinerState(ctx, maddr)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to load miner state")
+	}
+	report.FeeDebt, err = mas.FeeDebt()
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to get fee debt")
+	}
+
+	mbi, err := miningAPI.MinerGetBaseInfo(ctx, maddr, epoch, base.Key())
+	if err != nil {
+		return nil, err
+	}
+	if mbi == nil {
+		report.Note = "miner has no sectors eligible for winning PoSt"
+		return report, nil
+	}
+
+	for _, si := range mbi.Sectors {
+		report.EligibleSectors = append(report.EligibleSectors, si.SectorNumber)
+	}
+
+	rbase := mbi.PrevBeaconEntry
+	if len(mbi.BeaconEntries) > 0 {
+		rbase = mbi.BeaconEntries[len(mbi.BeaconEntries)-1]
+	}
+	report.BeaconRound = rbase.Round
+
+	if !ming.Wallet.Wallet.HasAddress(mbi.WorkerKey) {
+		report.Note = "worker key " + mbi.WorkerKey.String() + " is not in the local wallet, ticket and election proof are not computed"
+		return report, nil
+	}
+
+	eproof, err := ming.computeElectionProof(ctx, maddr, mbi, &rbase, epoch)
+	if err != nil {
+		return nil, err
+	}
+	report.ElectionProof = eproof
+	if report.EligibleToMine {
+		report.WinCount = eproof.WinCount
+	}
+
+	tickets, err := ming.ticketMachine(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ticket, err := ming.computeTicket(ctx, tickets, maddr, mbi, &rbase, base, epoch)
+	if err != nil {
+		return nil, err
+	}
+	report.Ticket = &ticket
+
+	return report, nil
+}
+
+// expectedWinCount is the mean of the poisson distribution the election win
+// count is drawn from.
+func expectedWinCount(minerPower, networkPower abi.StoragePower) float64 {
+	if networkPower.Int == nil || networkPower.Sign() <= 0 || minerPower.Int == nil {
+		return 0
+	}
+	share, _ := new(big.Rat).SetFrac(minerPower.Int, networkPower.Int).Float64()
+	return share * float64(constants.ExpectedLeadersPerEpoch)
+}
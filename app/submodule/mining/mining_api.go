@@ -19,6 +19,7 @@ import (
 	"github.com/filecoin-project/venus/pkg/specactors/builtin/miner"
 	"github.com/filecoin-project/venus/pkg/state"
 	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/util/ffiwrapper"
 	"github.com/filecoin-project/venus/pkg/wallet"
 )
 
@@ -140,6 +141,26 @@ func (miningAPI *MiningAPI) MinerGetBaseInfo(ctx context.Context, maddr address.
 	}, nil
 }
 
+// MiningOnce mines a block on top of the current head with the local genesis
+// miners, waiting for the first round one of them wins. Devnets only.
+func (miningAPI *MiningAPI) MiningOnce(ctx context.Context) (*block.BlockMsg, error) {
+	return miningAPI.Ming.mineOnce(ctx)
+}
+
+// MiningStart starts mining blocks continuously with the local genesis
+// miners. Devnets only.
+func (miningAPI *MiningAPI) MiningStart(ctx context.Context) error {
+	if _, ok := miningAPI.Ming.proofVerifier.(*ffiwrapper.FakeVerifier); !ok {
+		return ErrMockProofsRequired
+	}
+	return miningAPI.Ming.devnet.start(miningAPI.Ming.mineOnce, miningAPI.Ming.waitMined, miningAPI.Ming.blockDelay())
+}
+
+// MiningStop stops the mining started by MiningStart.
+func (miningAPI *MiningAPI) MiningStop(ctx context.Context) error {
+	return miningAPI.Ming.devnet.stop()
+}
+
 func (miningAPI *MiningAPI) MinerCreateBlock(ctx context.Context, bt *BlockTemplate) (*block.BlockMsg, error) {
 	fblk, err := miningAPI.minerCreateBlock(ctx, bt)
 	if err != nil {
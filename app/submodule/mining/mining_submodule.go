@@ -1,8 +1,12 @@
 package mining
 
 import (
+	"context"
+	"time"
+
 	"github.com/filecoin-project/venus/app/submodule/blockstore"
 	chain2 "github.com/filecoin-project/venus/app/submodule/chain"
+	"github.com/filecoin-project/venus/app/submodule/mpool"
 	"github.com/filecoin-project/venus/app/submodule/network"
 	"github.com/filecoin-project/venus/app/submodule/syncer"
 	"github.com/filecoin-project/venus/app/submodule/wallet"
@@ -20,8 +24,10 @@ type MiningModule struct { //nolint
 	BlockStore    *blockstore.BlockstoreSubmodule
 	NetworkModule *network.NetworkSubmodule
 	SyncModule    *syncer.SyncerSubmodule
+	MpoolModule   *mpool.MessagePoolSubmodule
 	Wallet        wallet.WalletSubmodule
 	proofVerifier ffiwrapper.Verifier
+	devnet        devnetMiner
 }
 
 func (miningModule *MiningModule) API() *MiningAPI {
@@ -34,6 +40,7 @@ func NewMiningModule(
 	blockStore *blockstore.BlockstoreSubmodule,
 	networkModule *network.NetworkSubmodule,
 	syncModule *syncer.SyncerSubmodule,
+	mpoolModule *mpool.MessagePoolSubmodule,
 	wallet wallet.WalletSubmodule,
 	proofVerifier ffiwrapper.Verifier,
 ) *MiningModule {
@@ -43,7 +50,21 @@ func NewMiningModule(
 		BlockStore:    blockStore,
 		NetworkModule: networkModule,
 		SyncModule:    syncModule,
+		MpoolModule:   mpoolModule,
 		Wallet:        wallet,
 		proofVerifier: proofVerifier,
 	}
 }
+
+// Stop stops devnet mining if it is running.
+func (miningModule *MiningModule) Stop(ctx context.Context) {
+	if miningModule.devnet.running() {
+		if err := miningModule.devnet.stop(); err != nil {
+			log.Warnf("failed to stop mining: %s", err)
+		}
+	}
+}
+
+func (miningModule *MiningModule) blockDelay() time.Duration {
+	return time.Duration(miningModule.Config.Repo().Config().NetworkParams.BlockDelay) * time.Second
+}
@@ -316,6 +316,19 @@ func (a *MessagePoolAPI) GasEstimateGasPremium(ctx context.Context, nblocksincl
 	return a.mp.MPool.GasEstimateGasPremium(ctx, nblocksincl, sender, gaslimit, tsk)
 }
 
+// ChainGasStats returns the base fee, gas usage and premiums of the tipsets
+// with a height in [from, to], the head excluded, within
+// messagepool.GasStatsWindow of the head.
+func (a *MessagePoolAPI) ChainGasStats(ctx context.Context, from, to abi.ChainEpoch) ([]*messagepool.GasStats, error) {
+	return a.mp.MPool.ChainGasStats(ctx, from, to)
+}
+
+// GasFeeRecommendation returns the fee cap and premium for a message to be
+// included with `urgency`.
+func (a *MessagePoolAPI) GasFeeRecommendation(ctx context.Context, urgency messagepool.FeeUrgency) (*messagepool.FeeRecommendation, error) {
+	return a.mp.MPool.GasFeeRecommendation(ctx, urgency)
+}
+
 func (a *MessagePoolAPI) WalletSign(ctx context.Context, k address.Address, msg []byte) (*crypto.Signature, error) {
 	head := a.mp.chain.ChainReader.GetHead()
 	view, err := a.mp.chain.State.StateView(head)
@@ -24,8 +24,8 @@ import (
 	"github.com/filecoin-project/venus/pkg/consensus"
 	"github.com/filecoin-project/venus/pkg/constants"
 	"github.com/filecoin-project/venus/pkg/crypto"
+	"github.com/filecoin-project/venus/pkg/journal"
 	"github.com/filecoin-project/venus/pkg/messagepool"
-	"github.com/filecoin-project/venus/pkg/messagepool/journal"
 	"github.com/filecoin-project/venus/pkg/net/msgsub"
 	"github.com/filecoin-project/venus/pkg/net/pubsub"
 	"github.com/filecoin-project/venus/pkg/repo"
@@ -49,6 +49,7 @@ var log = logging.Logger("mpool")
 
 type messagepoolConfig interface {
 	Repo() repo.Repo
+	Journal() journal.Journal
 }
 
 // MessagingSubmodule enhances the `Node` with internal messaging capabilities.
@@ -64,15 +65,6 @@ type MessagePoolSubmodule struct { //nolint
 	networkCfg *config.NetworkParamsConfig
 }
 
-func OpenFilesystemJournal(lr repo.Repo) (journal.Journal, error) {
-	jrnl, err := journal.OpenFSJournal(lr, journal.EnvDisabledEvents())
-	if err != nil {
-		return nil, err
-	}
-
-	return jrnl, err
-}
-
 func NewMpoolSubmodule(cfg messagepoolConfig,
 	network *network.NetworkSubmodule,
 	chain *chain.ChainSubmodule,
@@ -82,32 +74,50 @@ func NewMpoolSubmodule(cfg messagepoolConfig,
 ) (*MessagePoolSubmodule, error) {
 	mpp := messagepool.NewProvider(chain.ChainReader, chain.MessageStore, cfg.Repo().Config().NetworkParams, network.Pubsub)
 
-	j, err := OpenFilesystemJournal(cfg.Repo())
-	if err != nil {
-		return nil, err
-	}
-	mp, err := messagepool.New(mpp, cfg.Repo().MetaDatastore(), cfg.Repo().Config().NetworkParams.ForkUpgradeParam, network.NetworkName, syncer.Consensus, chain.State, j)
+	mp, err := messagepool.New(mpp, cfg.Repo().MetaDatastore(), cfg.Repo().Config().NetworkParams.ForkUpgradeParam, network.NetworkName, syncer.Consensus, chain.State, cfg.Journal())
 	if err != nil {
 		return nil, xerrors.Errorf("constructing mpool: %s", err)
 	}
 
+	submodule := &MessagePoolSubmodule{
+		MPool:      mp,
+		chain:      chain,
+		walletAPI:  wallet.API(),
+		network:    network,
+		networkCfg: networkCfg,
+	}
+	if err := submodule.ApplyConfig(cfg.Repo().Config().Mpool); err != nil {
+		return nil, xerrors.Errorf("applying mpool config: %s", err)
+	}
+
 	// setup messaging topic.
 	// register block validation on pubsub
 	msgSyntaxValidator := consensus.NewMessageSyntaxValidator()
 	msgSignatureValidator := consensus.NewMessageSignatureValidator(chain.State)
 
-	mtv := msgsub.NewMessageTopicValidator(msgSyntaxValidator, msgSignatureValidator)
+	mtv := msgsub.NewMessageTopicValidator(msgSyntaxValidator, msgSignatureValidator).WithPeerBanner(network)
 	if err := network.Pubsub.RegisterTopicValidator(mtv.Topic(network.NetworkName), mtv.Validator(), mtv.Opts()...); err != nil {
 		return nil, xerrors.Errorf("failed to register message validator: %s", err)
 	}
 
-	return &MessagePoolSubmodule{
-		MPool:      mp,
-		chain:      chain,
-		walletAPI:  wallet.API(),
-		network:    network,
-		networkCfg: networkCfg,
-	}, nil
+	return submodule, nil
+}
+
+// ApplyConfig sets the size limits and the nonce gap of the pool from the
+// mpool section of the node config, the other pool settings are kept. The
+// pool is pruned down to a share of the max pool size, so every size the
+// config schema accepts applies.
+func (mp *MessagePoolSubmodule) ApplyConfig(cfg *config.MessagePoolConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	mpCfg := mp.MPool.GetConfig()
+	mpCfg.SizeLimitHigh, mpCfg.SizeLimitLow = messagepool.SizeLimits(int(cfg.MaxPoolSize))
+	if err := mp.MPool.SetConfig(mpCfg); err != nil {
+		return err
+	}
+	mp.MPool.SetMaxNonceGap(cfg.MaxNonceGap)
+	return nil
 }
 
 func (mp *MessagePoolSubmodule) handleIncomingMessage(ctx context.Context, pubSubMsg pubsub.Message) (err error) {
@@ -0,0 +1,41 @@
+package network
+
+import (
+	"github.com/libp2p/go-libp2p-core/host"
+	p2pnet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+
+	"github.com/filecoin-project/venus/pkg/journal"
+)
+
+// PeerEvt is the journal entry of a peer connecting or disconnecting.
+type PeerEvt struct {
+	Peer      peer.ID
+	Addr      string
+	Direction string
+}
+
+// journalPeers records the connections and disconnections of the peers of
+// `h` to `j`.
+func journalPeers(h host.Host, j journal.Journal) {
+	connected := j.RegisterEventType("net", "peer_connected")
+	disconnected := j.RegisterEventType("net", "peer_disconnected")
+
+	record := func(evtType journal.EventType, c p2pnet.Conn) {
+		j.RecordEvent(evtType, func() interface{} {
+			return PeerEvt{
+				Peer:      c.RemotePeer(),
+				Addr:      c.RemoteMultiaddr().String(),
+				Direction: c.Stat().Direction.String(),
+			}
+		})
+	}
+	h.Network().Notify(&p2pnet.NotifyBundle{
+		ConnectedF: func(_ p2pnet.Network, c p2pnet.Conn) {
+			record(connected, c)
+		},
+		DisconnectedF: func(_ p2pnet.Network, c p2pnet.Conn) {
+			record(disconnected, c)
+		},
+	})
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/libp2p/go-libp2p-core/metrics"
 	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
 	ma "github.com/multiformats/go-multiaddr"
 )
 
@@ -20,6 +21,20 @@ func (networkAPI *NetworkAPI) NetworkGetBandwidthStats() metrics.Stats {
 	return networkAPI.network.Network.GetBandwidthStats()
 }
 
+// NetBandwidthStatsByPeer gets stats on the current bandwidth usage of the network by peer
+func (networkAPI *NetworkAPI) NetBandwidthStatsByPeer(context.Context) (map[string]metrics.Stats, error) {
+	out := make(map[string]metrics.Stats)
+	for p, s := range networkAPI.network.Network.GetBandwidthStatsByPeer() {
+		out[p.String()] = s
+	}
+	return out, nil
+}
+
+// NetBandwidthStatsByProtocol gets stats on the current bandwidth usage of the network by protocol
+func (networkAPI *NetworkAPI) NetBandwidthStatsByProtocol(context.Context) (map[protocol.ID]metrics.Stats, error) {
+	return networkAPI.network.Network.GetBandwidthStatsByProtocol(), nil
+}
+
 // NetworkGetPeerAddresses gets the current addresses of the node
 func (networkAPI *NetworkAPI) NetworkGetPeerAddresses() []ma.Multiaddr {
 	return networkAPI.network.Network.GetPeerAddresses()
@@ -78,6 +93,27 @@ func (networkAPI *NetworkAPI) Version(context.Context) (Version, error) {
 	}, nil
 }
 
+// NetBlockAdd adds peers, IP addresses and subnets to the persistent blocklist
+// and disconnects them
+func (networkAPI *NetworkAPI) NetBlockAdd(ctx context.Context, acl net.NetBlockList) error {
+	return networkAPI.network.ConnGater.Block(networkAPI.network.Host, acl)
+}
+
+// NetBlockRemove removes peers, IP addresses and subnets from the persistent blocklist
+func (networkAPI *NetworkAPI) NetBlockRemove(ctx context.Context, acl net.NetBlockList) error {
+	return networkAPI.network.ConnGater.Unblock(acl)
+}
+
+// NetBlockList returns the persistent blocklist
+func (networkAPI *NetworkAPI) NetBlockList(ctx context.Context) (net.NetBlockList, error) {
+	return networkAPI.network.ConnGater.List(), nil
+}
+
+// NetPubsubScores returns the latest gossipsub score snapshot of every peer
+func (networkAPI *NetworkAPI) NetPubsubScores(context.Context) ([]PubsubScore, error) {
+	return networkAPI.network.scoreKeeper.get(), nil
+}
+
 func (networkAPI *NetworkAPI) NetAddrsListen(context.Context) (peer.AddrInfo, error) {
 	return peer.AddrInfo{
 		ID:    networkAPI.network.Host.ID(),
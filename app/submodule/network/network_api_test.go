@@ -0,0 +1,51 @@
+package network_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/app/node/test"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+func TestNetBandwidthStats(t *testing.T) {
+	tf.IntegrationTest(t)
+	ctx := context.Background()
+
+	builder := test.NewNodeBuilder(t)
+	n1 := builder.BuildAndStart(ctx)
+	defer n1.Stop(ctx)
+	n2 := builder.BuildAndStart(ctx)
+	defer n2.Stop(ctx)
+	api := n1.Network().API()
+
+	test.ConnectNodes(t, n1, n2)
+
+	// the hello and identify streams are metered once the nodes connect
+	peerID := n2.Network().API().NetworkGetPeerID().String()
+	require.Eventually(t, func() bool {
+		byPeer, err := api.NetBandwidthStatsByPeer(ctx)
+		require.NoError(t, err)
+		s, ok := byPeer[peerID]
+		return ok && s.TotalIn > 0 && s.TotalOut > 0
+	}, 10*time.Second, 100*time.Millisecond)
+
+	byProtocol, err := api.NetBandwidthStatsByProtocol(ctx)
+	require.NoError(t, err)
+	require.NotEmpty(t, byProtocol)
+	var in, out int64
+	for _, s := range byProtocol {
+		in += s.TotalIn
+		out += s.TotalOut
+	}
+	assert.True(t, in > 0)
+	assert.True(t, out > 0)
+
+	total := api.NetworkGetBandwidthStats()
+	assert.True(t, total.TotalIn > 0)
+	assert.True(t, total.TotalOut > 0)
+}
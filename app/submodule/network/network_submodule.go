@@ -28,6 +28,7 @@ import (
 	"github.com/filecoin-project/venus/pkg/block"
 	"github.com/filecoin-project/venus/pkg/config"
 	"github.com/filecoin-project/venus/pkg/discovery"
+	"github.com/filecoin-project/venus/pkg/journal"
 	"github.com/filecoin-project/venus/pkg/net"
 	appstate "github.com/filecoin-project/venus/pkg/state"
 	"github.com/ipfs/go-bitswap"
@@ -45,6 +46,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/host"
 	p2pmetrics "github.com/libp2p/go-libp2p-core/metrics"
 	smux "github.com/libp2p/go-libp2p-core/mux"
+	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/routing"
 	dht "github.com/libp2p/go-libp2p-kad-dht"
 	mplex "github.com/libp2p/go-libp2p-mplex"
@@ -77,9 +79,15 @@ type NetworkSubmodule struct { //nolint
 
 	blockstore blockstoreutil.Blockstore
 	PeerMgr    net.IPeerMgr
+	// ConnGater refuses connections to blocked or temporarily banned peers
+	ConnGater *net.ConnGater
 	//data transfer
 	DataTransfer     datatransfer.Manager
 	DataTransferHost dtnet.DataTransferNetwork
+
+	scoreKeeper *scoreKeeper
+	// pubsubTracer writes the pubsub trace when enabled.
+	pubsubTracer *libp2pps.JSONTracer
 }
 
 func (networkSubmodule *NetworkSubmodule) API() *NetworkAPI {
@@ -90,6 +98,9 @@ func (networkSubmodule *NetworkSubmodule) Stop(ctx context.Context) {
 	if err := networkSubmodule.Host.Close(); err != nil {
 		fmt.Printf("error closing host: %s\n", err)
 	}
+	if networkSubmodule.pubsubTracer != nil {
+		networkSubmodule.pubsubTracer.Close()
+	}
 }
 
 type blankValidator struct{}
@@ -102,18 +113,24 @@ type networkConfig interface {
 	OfflineMode() bool
 	IsRelay() bool
 	Libp2pOpts() []libp2p.Option
+	Journal() journal.Journal
 }
 
 type networkRepo interface {
 	Config() *config.Config
 	ChainDatastore() repo.Datastore
+	MetaDatastore() repo.Datastore
 	Path() (string, error)
 }
 
 // NewNetworkSubmodule creates a new network submodule.
 func NewNetworkSubmodule(ctx context.Context, config networkConfig, repo networkRepo, blockstore *blockstore.BlockstoreSubmodule) (*NetworkSubmodule, error) {
 	bandwidthTracker := p2pmetrics.NewBandwidthCounter()
-	libP2pOpts := append(config.Libp2pOpts(), libp2p.BandwidthReporter(bandwidthTracker), makeSmuxTransportOption(true))
+	connGater, err := net.NewConnGater(repo.MetaDatastore())
+	if err != nil {
+		return nil, err
+	}
+	libP2pOpts := append(config.Libp2pOpts(), libp2p.BandwidthReporter(bandwidthTracker), libp2p.ConnectionGater(connGater), makeSmuxTransportOption(true))
 
 	networkName, err := retrieveNetworkName(ctx, config.GenesisCid(), blockstore.CborStore)
 	if err != nil {
@@ -126,6 +143,7 @@ func NewNetworkSubmodule(ctx context.Context, config networkConfig, repo network
 	validator := blankValidator{}
 	var pubsubMessageSigning bool
 	var peerMgr net.IPeerMgr
+	var bootNodes []peer.AddrInfo
 	if !config.OfflineMode() {
 		makeDHT := func(h host.Host) (routing.Routing, error) {
 			mode := dht.ModeServer
@@ -157,7 +175,7 @@ func NewNetworkSubmodule(ctx context.Context, config networkConfig, repo network
 		pubsubMessageSigning = true
 
 		//peer manager
-		bootNodes, err := net.ParseAddresses(ctx, repo.Config().Bootstrap.Addresses)
+		bootNodes, err = net.ParseAddresses(ctx, repo.Config().Bootstrap.Addresses)
 		if err != nil {
 			return nil, err
 		}
@@ -198,8 +216,22 @@ func NewNetworkSubmodule(ctx context.Context, config networkConfig, repo network
 		libp2pps.WithMessageSigning(pubsubMessageSigning),
 		libp2pps.WithDiscovery(&discovery.NoopDiscovery{}),
 	}
-	gsub, err := libp2pps.NewGossipSub(ctx, peerHost, options...)
+	var drandTopics []string
+	var drandRelays []peer.AddrInfo
+	if repo.Config().Pubsub.DrandRelay {
+		drandTopics, drandRelays = drandPubsub(ctx, repo.Config().NetworkParams, !config.OfflineMode())
+	}
+	sk := &scoreKeeper{}
+	scoreOptions, tracer, err := pubsubOptions(networkName, repo, bootNodes, drandTopics, drandRelays, sk)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set up pubsub scoring")
+	}
+	options = append(options, scoreOptions...)
+	gsub, err := newGossipSub(ctx, peerHost, repo.Config().Pubsub.Bootstrapper, options...)
 	if err != nil {
+		if tracer != nil {
+			tracer.Close()
+		}
 		return nil, errors.Wrap(err, "failed to set up network")
 	}
 
@@ -234,6 +266,8 @@ func NewNetworkSubmodule(ctx context.Context, config networkConfig, repo network
 	// build network
 	network := net.New(peerHost, net.NewRouter(router), bandwidthTracker)
 
+	journalPeers(peerHost, config.Journal())
+
 	// build the network submdule
 	return &NetworkSubmodule{
 		NetworkName:      networkName,
@@ -246,10 +280,26 @@ func NewNetworkSubmodule(ctx context.Context, config networkConfig, repo network
 		DataTransfer:     dt,
 		DataTransferHost: dtNet,
 		PeerMgr:          peerMgr,
+		ConnGater:        connGater,
 		blockstore:       blockstore.Blockstore,
+		scoreKeeper:      sk,
+		pubsubTracer:     tracer,
 	}, nil
 }
 
+// BanPeer refuses connections to `p` for `duration` and drops the current
+// ones. It implements net.PeerBanner for the validators and the syncer.
+func (networkSubmodule *NetworkSubmodule) BanPeer(p peer.ID, duration time.Duration, reason string) {
+	if p == networkSubmodule.Host.ID() {
+		return
+	}
+	networkLogger.Warnf("banning peer %s for %s: %s", p, duration, reason)
+	networkSubmodule.ConnGater.BanPeerFor(p, duration)
+	if err := networkSubmodule.Host.Network().ClosePeer(p); err != nil {
+		networkLogger.Warnf("failed to close connection to banned peer %s: %s", p, err)
+	}
+}
+
 func (networkSubmodule *NetworkSubmodule) FetchMessagesByCids(
 	ctx context.Context,
 	cids []cid.Cid,
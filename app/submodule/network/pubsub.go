@@ -0,0 +1,305 @@
+package network
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/peer"
+	libp2pps "github.com/libp2p/go-libp2p-pubsub"
+
+	"github.com/filecoin-project/venus/pkg/beacon"
+	"github.com/filecoin-project/venus/pkg/config"
+	"github.com/filecoin-project/venus/pkg/net"
+	"github.com/filecoin-project/venus/pkg/net/blocksub"
+	"github.com/filecoin-project/venus/pkg/net/msgsub"
+)
+
+const (
+	// Peer score thresholds, see the gossipsub v1.1 spec for their meaning.
+	GossipScoreThreshold             = -500
+	PublishScoreThreshold            = -1000
+	GraylistScoreThreshold           = -2500
+	AcceptPXScoreThreshold           = 1000
+	OpportunisticGraftScoreThreshold = 3.5
+
+	// bootstrapperScore is the application score given to bootstrap peers so
+	// that they are never pruned and their peer exchange is accepted.
+	bootstrapperScore = 2500
+	// drandRelayScore is the application score given to drand relays.
+	drandRelayScore = 1500
+
+	// pubsubTraceFile is the name of the JSON pubsub trace in the repo.
+	pubsubTraceFile = "pubsub-trace.json"
+
+	scoreInspectPeriod = 10 * time.Second
+)
+
+// PubsubScore is the score snapshot of a single peer.
+type PubsubScore struct {
+	ID    peer.ID
+	Score *libp2pps.PeerScoreSnapshot
+}
+
+// scoreKeeper keeps the latest peer score snapshots reported by gossipsub.
+type scoreKeeper struct {
+	lk     sync.Mutex
+	scores map[peer.ID]*libp2pps.PeerScoreSnapshot
+}
+
+func (sk *scoreKeeper) update(scores map[peer.ID]*libp2pps.PeerScoreSnapshot) {
+	sk.lk.Lock()
+	sk.scores = scores
+	sk.lk.Unlock()
+}
+
+func (sk *scoreKeeper) get() []PubsubScore {
+	sk.lk.Lock()
+	defer sk.lk.Unlock()
+	out := make([]PubsubScore, 0, len(sk.scores))
+	for p, score := range sk.scores {
+		out = append(out, PubsubScore{ID: p, Score: score})
+	}
+	return out
+}
+
+// topicScoreParams returns the score parameters of the block and message
+// topics of `networkName`.
+func topicScoreParams(networkName string) map[string]*libp2pps.TopicScoreParams {
+	return map[string]*libp2pps.TopicScoreParams{
+		blocksub.Topic(networkName): {
+			// expected 10 blocks/min
+			TopicWeight: 0.1, // max cap is 50, max mesh penalty is -10, single invalid message is -100
+
+			// 1 tick per second, maxes at 1 after 1 hour
+			TimeInMeshWeight:  0.00027, // ~1/3600
+			TimeInMeshQuantum: time.Second,
+			TimeInMeshCap:     1,
+
+			// deliveries decay after 1 hour, cap at 100 blocks
+			FirstMessageDeliveriesWeight: 5, // max value is 500
+			FirstMessageDeliveriesDecay:  libp2pps.ScoreParameterDecay(time.Hour),
+			FirstMessageDeliveriesCap:    100, // 100 blocks in an hour
+
+			// mesh delivery failures are not penalized for blocks, block
+			// production is too irregular to set a sane threshold
+			MeshMessageDeliveriesWeight:     0,
+			MeshMessageDeliveriesDecay:      libp2pps.ScoreParameterDecay(time.Hour),
+			MeshMessageDeliveriesCap:        100,
+			MeshMessageDeliveriesThreshold:  5,
+			MeshMessageDeliveriesWindow:     10 * time.Millisecond,
+			MeshMessageDeliveriesActivation: time.Hour,
+			MeshFailurePenaltyWeight:        0,
+			MeshFailurePenaltyDecay:         libp2pps.ScoreParameterDecay(time.Hour),
+
+			// invalid messages decay after 1 hour
+			InvalidMessageDeliveriesWeight: -1000,
+			InvalidMessageDeliveriesDecay:  libp2pps.ScoreParameterDecay(time.Hour),
+		},
+		msgsub.Topic(networkName): {
+			// expected > 1 tx/second
+			TopicWeight: 0.1, // max cap is 5, single invalid message is -100
+
+			// 1 tick per second, maxes at 1 hour
+			TimeInMeshWeight:  0.0002778, // ~1/3600
+			TimeInMeshQuantum: time.Second,
+			TimeInMeshCap:     1,
+
+			// deliveries decay after 10min, cap at 100 tx
+			FirstMessageDeliveriesWeight: 0.5, // max value is 50
+			FirstMessageDeliveriesDecay:  libp2pps.ScoreParameterDecay(10 * time.Minute),
+			FirstMessageDeliveriesCap:    100, // 100 messages in 10 minutes
+
+			// mesh delivery failures are not penalized for messages either
+			MeshMessageDeliveriesWeight:     0,
+			MeshMessageDeliveriesDecay:      libp2pps.ScoreParameterDecay(time.Hour),
+			MeshMessageDeliveriesCap:        20,
+			MeshMessageDeliveriesThreshold:  1,
+			MeshMessageDeliveriesWindow:     10 * time.Millisecond,
+			MeshMessageDeliveriesActivation: time.Hour,
+			MeshFailurePenaltyWeight:        0,
+			MeshFailurePenaltyDecay:         libp2pps.ScoreParameterDecay(time.Hour),
+
+			// invalid messages decay after 1 hour
+			InvalidMessageDeliveriesWeight: -1000,
+			InvalidMessageDeliveriesDecay:  libp2pps.ScoreParameterDecay(time.Hour),
+		},
+	}
+}
+
+// drandTopicScoreParams returns the score parameters of a drand relay topic.
+func drandTopicScoreParams() *libp2pps.TopicScoreParams {
+	return &libp2pps.TopicScoreParams{
+		// expected 2 beacons/min
+		TopicWeight: 0.5, // 5x block topic; max cap is 62.5
+
+		// 1 tick per second, maxes at 1 after 1 hour
+		TimeInMeshWeight:  0.00027, // ~1/3600
+		TimeInMeshQuantum: time.Second,
+		TimeInMeshCap:     1,
+
+		// deliveries decay after 1 hour, cap at 25 beacons
+		FirstMessageDeliveriesWeight: 5, // max value is 125
+		FirstMessageDeliveriesDecay:  libp2pps.ScoreParameterDecay(time.Hour),
+		FirstMessageDeliveriesCap:    25, // the maximum expected in an hour is ~26, including the decay
+
+		// mesh delivery failures are not penalized, the traffic is too low
+		// to tell a bad edge from a quiet one
+
+		// invalid messages decay after 1 hour
+		InvalidMessageDeliveriesWeight: -1000,
+		InvalidMessageDeliveriesDecay:  libp2pps.ScoreParameterDecay(time.Hour),
+	}
+}
+
+// drandPubsub returns the relay topics and the relay peers of the drand
+// chains of the drand schedule of `params`. Relays that cannot be resolved
+// are skipped.
+func drandPubsub(ctx context.Context, params *config.NetworkParamsConfig, online bool) ([]string, []peer.AddrInfo) {
+	var topics []string
+	var relays []peer.AddrInfo
+	for _, drandEnum := range params.DrandSchedule {
+		drandConf, err := params.DrandConf(drandEnum)
+		if err != nil {
+			networkLogger.Warnf("failed to get drand chain: %s", err)
+			continue
+		}
+		topic, err := beacon.DrandTopic(drandConf)
+		if err != nil {
+			networkLogger.Warnf("failed to get drand topic: %s", err)
+			continue
+		}
+		topics = append(topics, topic)
+
+		if !online {
+			continue
+		}
+		infos, err := net.ParseAddresses(ctx, drandConf.Relays)
+		if err != nil {
+			networkLogger.Warnf("failed to resolve drand relays: %s", err)
+			continue
+		}
+		relays = append(relays, infos...)
+	}
+	return topics, relays
+}
+
+// peerScoreParams returns the peer score parameters of the block, message and
+// drand topics, the bootstrappers and drand relays get an application score.
+func peerScoreParams(networkName string, bootstrappers []peer.AddrInfo, drandTopics []string, drandRelays []peer.AddrInfo) *libp2pps.PeerScoreParams {
+	isBootstrapper := make(map[peer.ID]struct{}, len(bootstrappers))
+	for _, info := range bootstrappers {
+		isBootstrapper[info.ID] = struct{}{}
+	}
+	isDrandRelay := make(map[peer.ID]struct{}, len(drandRelays))
+	for _, info := range drandRelays {
+		isDrandRelay[info.ID] = struct{}{}
+	}
+
+	topics := topicScoreParams(networkName)
+	for _, topic := range drandTopics {
+		topics[topic] = drandTopicScoreParams()
+	}
+
+	return &libp2pps.PeerScoreParams{
+		AppSpecificScore: func(p peer.ID) float64 {
+			// bootstrappers get a heavy positive score so that we
+			// don't unilaterally prune them and accept their PX.
+			if _, ok := isBootstrapper[p]; ok {
+				return bootstrapperScore
+			}
+			// drand relays are boosted so that rounds keep flowing
+			if _, ok := isDrandRelay[p]; ok {
+				return drandRelayScore
+			}
+			return 0
+		},
+		AppSpecificWeight: 1,
+
+		// This sets the IP colocation threshold to 5 peers before we apply penalties
+		IPColocationFactorThreshold: 5,
+		IPColocationFactorWeight:    -100,
+
+		// P7: behavioural penalties, decay after 1hr
+		BehaviourPenaltyThreshold: 6,
+		BehaviourPenaltyWeight:    -10,
+		BehaviourPenaltyDecay:     libp2pps.ScoreParameterDecay(time.Hour),
+
+		DecayInterval: libp2pps.DefaultDecayInterval,
+		DecayToZero:   libp2pps.DefaultDecayToZero,
+
+		// this retains non-positive scores for 6 hours
+		RetainScore: 6 * time.Hour,
+
+		Topics: topics,
+	}
+}
+
+// pubsubOptions returns the gossipsub options for peer scoring, bootstrapper
+// mode and tracing, and the tracer to close on shutdown when tracing.
+func pubsubOptions(networkName string, repo networkRepo, bootstrappers []peer.AddrInfo, drandTopics []string, drandRelays []peer.AddrInfo, sk *scoreKeeper) ([]libp2pps.Option, *libp2pps.JSONTracer, error) {
+	options := []libp2pps.Option{
+		libp2pps.WithPeerScore(
+			peerScoreParams(networkName, bootstrappers, drandTopics, drandRelays),
+			&libp2pps.PeerScoreThresholds{
+				GossipThreshold:             GossipScoreThreshold,
+				PublishThreshold:            PublishScoreThreshold,
+				GraylistThreshold:           GraylistScoreThreshold,
+				AcceptPXThreshold:           AcceptPXScoreThreshold,
+				OpportunisticGraftThreshold: OpportunisticGraftScoreThreshold,
+			},
+		),
+		libp2pps.WithPeerScoreInspect(sk.update, scoreInspectPeriod),
+	}
+	if len(drandRelays) > 0 {
+		options = append(options, libp2pps.WithDirectPeers(drandRelays))
+	}
+
+	cfg := repo.Config().Pubsub
+	if cfg.Bootstrapper {
+		// bootstrappers only serve peer exchange and gate peers that flood
+		// them with unwanted traffic, see newGossipSub for their mesh.
+		options = append(options,
+			libp2pps.WithPeerExchange(true),
+			libp2pps.WithPeerGater(libp2pps.NewPeerGaterParams(
+				0.33,
+				libp2pps.ScoreParameterDecay(2*time.Minute),
+				libp2pps.ScoreParameterDecay(10*time.Minute),
+			)),
+		)
+	}
+
+	var tracer *libp2pps.JSONTracer
+	if cfg.Trace {
+		repoPath, err := repo.Path()
+		if err != nil {
+			return nil, nil, err
+		}
+		tracer, err = libp2pps.NewJSONTracer(filepath.Join(repoPath, pubsubTraceFile))
+		if err != nil {
+			return nil, nil, err
+		}
+		options = append(options, libp2pps.WithEventTracer(tracer))
+	}
+
+	return options, tracer, nil
+}
+
+// bootstrapperDegrees sets the mesh degrees of a bootstrapper only once.
+var bootstrapperDegrees sync.Once
+
+// newGossipSub returns a gossipsub router, a bootstrapper keeps no mesh and
+// gossips to more peers. The gossipsub in use reads its mesh degrees from the
+// package variables whenever it runs, so those of a bootstrapper are set for
+// the whole process before its router is created and are never restored.
+func newGossipSub(ctx context.Context, h host.Host, bootstrapper bool, options ...libp2pps.Option) (*libp2pps.PubSub, error) {
+	if bootstrapper {
+		bootstrapperDegrees.Do(func() {
+			libp2pps.GossipSubD, libp2pps.GossipSubDscore, libp2pps.GossipSubDlo = 0, 0, 0
+			libp2pps.GossipSubDhi, libp2pps.GossipSubDout, libp2pps.GossipSubDlazy = 0, 0, 64
+		})
+	}
+	return libp2pps.NewGossipSub(ctx, h, options...)
+}
@@ -0,0 +1,140 @@
+package network
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	libp2pps "github.com/libp2p/go-libp2p-pubsub"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/config"
+	"github.com/filecoin-project/venus/pkg/net/blocksub"
+	"github.com/filecoin-project/venus/pkg/net/msgsub"
+	"github.com/filecoin-project/venus/pkg/repo"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+// dirRepo is an in memory repo whose path is a temporary directory.
+type dirRepo struct {
+	*repo.MemRepo
+	dir string
+}
+
+func (r *dirRepo) Path() (string, error) {
+	return r.dir, nil
+}
+
+func newDirRepo(t *testing.T, pubsub *config.PubsubConfig) *dirRepo {
+	dir, err := ioutil.TempDir("", "pubsub")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	r := &dirRepo{MemRepo: repo.NewInMemoryRepo(), dir: dir}
+	r.Config().Pubsub = pubsub
+	return r
+}
+
+func TestPeerScoreParams(t *testing.T) {
+	tf.UnitTest(t)
+
+	bootstrappers := []peer.AddrInfo{{ID: peer.ID("bootstrapper")}}
+	relays := []peer.AddrInfo{{ID: peer.ID("relay")}}
+	params := peerScoreParams("testnet", bootstrappers, []string{"/drand/pubsub/v0.0.0/00"}, relays)
+
+	assert.Equal(t, float64(bootstrapperScore), params.AppSpecificScore("bootstrapper"))
+	assert.Equal(t, float64(drandRelayScore), params.AppSpecificScore("relay"))
+	assert.Equal(t, float64(0), params.AppSpecificScore("other"))
+
+	require.Len(t, params.Topics, 3)
+	assert.Equal(t, 0.1, params.Topics[blocksub.Topic("testnet")].TopicWeight)
+	assert.Equal(t, 0.1, params.Topics[msgsub.Topic("testnet")].TopicWeight)
+	drand := params.Topics["/drand/pubsub/v0.0.0/00"]
+	require.NotNil(t, drand)
+	assert.Equal(t, drandTopicScoreParams(), drand)
+
+	// without bootstrappers nor relays no peer is boosted
+	params = peerScoreParams("testnet", nil, nil, nil)
+	assert.Equal(t, float64(0), params.AppSpecificScore("bootstrapper"))
+	assert.Len(t, params.Topics, 2)
+}
+
+func TestPubsubOptions(t *testing.T) {
+	tf.UnitTest(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mn := mocknet.New(ctx)
+
+	// the options are validated when the router is created
+	newRouter := func(t *testing.T, options []libp2pps.Option) {
+		h, err := mn.GenPeer()
+		require.NoError(t, err)
+		_, err = libp2pps.NewGossipSub(ctx, h, options...)
+		require.NoError(t, err)
+	}
+	relays := []peer.AddrInfo{{ID: peer.ID("relay")}}
+
+	t.Run("a node scores its peers without tracing", func(t *testing.T) {
+		r := newDirRepo(t, &config.PubsubConfig{})
+		options, tracer, err := pubsubOptions("testnet", r, nil, nil, nil, &scoreKeeper{})
+		require.NoError(t, err)
+		assert.Nil(t, tracer)
+		newRouter(t, options)
+
+		withRelays, _, err := pubsubOptions("testnet", r, nil, []string{"/drand/pubsub/v0.0.0/00"}, relays, &scoreKeeper{})
+		require.NoError(t, err)
+		// the drand relays are direct peers
+		assert.Len(t, withRelays, len(options)+1)
+		newRouter(t, withRelays)
+	})
+
+	t.Run("a bootstrapper serves peer exchange and gates peers", func(t *testing.T) {
+		r := newDirRepo(t, &config.PubsubConfig{Bootstrapper: true})
+		base, _, err := pubsubOptions("testnet", newDirRepo(t, &config.PubsubConfig{}), nil, nil, nil, &scoreKeeper{})
+		require.NoError(t, err)
+		options, _, err := pubsubOptions("testnet", r, nil, nil, nil, &scoreKeeper{})
+		require.NoError(t, err)
+		assert.Len(t, options, len(base)+2)
+		newRouter(t, options)
+	})
+
+	t.Run("the trace is written to the repo", func(t *testing.T) {
+		r := newDirRepo(t, &config.PubsubConfig{Trace: true})
+		options, tracer, err := pubsubOptions("testnet", r, nil, nil, nil, &scoreKeeper{})
+		require.NoError(t, err)
+		require.NotNil(t, tracer)
+		newRouter(t, options)
+		require.NoError(t, tracer.Close())
+
+		_, err = os.Stat(filepath.Join(r.dir, pubsubTraceFile))
+		assert.NoError(t, err)
+	})
+}
+
+func TestScoreKeeper(t *testing.T) {
+	tf.UnitTest(t)
+
+	sk := &scoreKeeper{}
+	assert.Empty(t, sk.get())
+
+	snapshot := &libp2pps.PeerScoreSnapshot{Score: -10}
+	sk.update(map[peer.ID]*libp2pps.PeerScoreSnapshot{"peer": snapshot})
+	assert.Equal(t, []PubsubScore{{ID: "peer", Score: snapshot}}, sk.get())
+
+	// each inspection replaces the previous snapshot
+	sk.update(map[peer.ID]*libp2pps.PeerScoreSnapshot{})
+	assert.Empty(t, sk.get())
+}
+
+func TestDrandPubsubOffline(t *testing.T) {
+	tf.UnitTest(t)
+
+	params := config.NewDefaultConfig().NetworkParams
+	topics, relays := drandPubsub(context.Background(), params, false)
+	assert.Len(t, topics, len(params.DrandSchedule))
+	assert.Empty(t, relays)
+}
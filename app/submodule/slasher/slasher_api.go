@@ -0,0 +1,21 @@
+package slasher
+
+import (
+	"context"
+
+	"github.com/filecoin-project/venus/pkg/slashing"
+)
+
+type SlasherAPI struct { //nolint
+	slasher *SlasherSubmodule
+}
+
+// SlasherListReported returns the consensus faults reported by this node.
+func (slasherAPI *SlasherAPI) SlasherListReported(ctx context.Context) ([]*slashing.ReportedFault, error) {
+	return slasherAPI.slasher.Reporter.List(ctx)
+}
+
+// SlasherEnabled returns whether detected consensus faults are reported.
+func (slasherAPI *SlasherAPI) SlasherEnabled(ctx context.Context) (bool, error) {
+	return slasherAPI.slasher.Enabled, nil
+}
@@ -0,0 +1,72 @@
+package slasher
+
+import (
+	"context"
+
+	"github.com/filecoin-project/go-address"
+	runtime2 "github.com/filecoin-project/specs-actors/v2/actors/runtime"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/app/submodule/chain"
+	"github.com/filecoin-project/venus/app/submodule/mpool"
+	"github.com/filecoin-project/venus/app/submodule/syncer"
+	"github.com/filecoin-project/venus/pkg/config"
+	"github.com/filecoin-project/venus/pkg/repo"
+	"github.com/filecoin-project/venus/pkg/slashing"
+)
+
+// SlasherSubmodule reports the consensus faults detected while syncing when
+// enabled in the config.
+type SlasherSubmodule struct { //nolint
+	Reporter *slashing.ConsensusFaultReporter
+	Enabled  bool
+}
+
+type slasherRepo interface {
+	Config() *config.Config
+	MetaDatastore() repo.Datastore
+}
+
+// NewSlasherSubmodule creates a new slasher submodule.
+func NewSlasherSubmodule(repo slasherRepo,
+	chn *chain.ChainSubmodule,
+	syncer *syncer.SyncerSubmodule,
+	mp *mpool.MessagePoolSubmodule,
+) (*SlasherSubmodule, error) {
+	cfg := repo.Config()
+	enabled := cfg.Slasher != nil && cfg.Slasher.Enabled
+
+	from := address.Undef
+	if cfg.Slasher != nil {
+		from = cfg.Slasher.From
+	}
+	if from.Empty() && cfg.Wallet != nil {
+		from = cfg.Wallet.DefaultAddress
+	}
+	if enabled && from.Empty() {
+		return nil, errors.New("slasher is enabled but neither slasher.from nor a default wallet address is configured")
+	}
+
+	checker := slashing.NewFaultChecker(chn.State, chn.Fork)
+	verify := func(ctx context.Context, h1, h2, extra []byte) (*runtime2.ConsensusFault, error) {
+		view, err := chn.State.StateView(chn.ChainReader.GetHead())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load head state")
+		}
+		return checker.VerifyConsensusFault(ctx, h1, h2, extra, view)
+	}
+
+	reporter := slashing.NewConsensusFaultReporter(from, mp.API(), verify, repo.MetaDatastore())
+	if enabled {
+		syncer.OnConsensusFault(reporter.HandleFault)
+	}
+
+	return &SlasherSubmodule{
+		Reporter: reporter,
+		Enabled:  enabled,
+	}, nil
+}
+
+func (slasher *SlasherSubmodule) API() *SlasherAPI {
+	return &SlasherAPI{slasher: slasher}
+}
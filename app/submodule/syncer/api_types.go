@@ -30,6 +30,24 @@ type InvocResult struct {
 	Duration       time.Duration
 }
 
+// SimulationResult is the outcome of a StateSimulate, Root is the simulated
+// state, readable with StateReadState while the node keeps it.
+type SimulationResult struct {
+	Root cid.Cid
+	// Epoch is the epoch the last message was applied at.
+	Epoch    abi.ChainEpoch
+	Results  []*InvocResult
+	Duration time.Duration
+}
+
+// ActorState is an actor of a state with its decoded head.
+type ActorState struct {
+	Balance abi.TokenAmount
+	Code    cid.Cid
+	Nonce   uint64
+	State   interface{}
+}
+
 type SyncState struct {
 	ActiveSyncs []ActiveSync
 
@@ -0,0 +1,124 @@
+package syncer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	account2 "github.com/filecoin-project/specs-actors/v2/actors/builtin/account"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/constants"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/venus/pkg/types"
+)
+
+func TestStateCallWithOverrides(t *testing.T) {
+	tf.IntegrationTest(t)
+	ctx := context.Background()
+
+	n, cs := newNetworkV8Node(ctx, t)
+	defer n.Stop(ctx)
+	api := n.Syncer().API()
+	head := n.Chain().ChainReader.GetHead()
+	root := head.At(0).ParentStateRoot
+
+	from, to := cs.Addr(t, 0), cs.Addr(t, 1)
+	send := func(value abi.TokenAmount) *types.UnsignedMessage {
+		return &types.UnsignedMessage{
+			From:       from,
+			To:         to,
+			Value:      value,
+			GasFeeCap:  big.Zero(),
+			GasPremium: big.Zero(),
+		}
+	}
+	sender, err := api.StateReadState(ctx, from, root)
+	require.NoError(t, err)
+
+	t.Run("the message of the caller is left untouched", func(t *testing.T) {
+		msg := send(abi.NewTokenAmount(1))
+		msg.Nonce = 99
+		res, err := api.StateCallWithOverrides(ctx, msg, head.Key(), &types.StateOverrides{})
+		require.NoError(t, err)
+		assert.Equal(t, exitcode.Ok, res.MsgRct.ExitCode)
+		assert.Equal(t, uint64(99), msg.Nonce)
+		assert.Equal(t, int64(0), msg.GasLimit)
+
+		// the result holds the message executed
+		assert.Equal(t, sender.Nonce, res.Msg.Nonce)
+		assert.Equal(t, int64(constants.BlockGasLimit), res.Msg.GasLimit)
+	})
+
+	t.Run("a balance override funds the sender", func(t *testing.T) {
+		value := big.Add(sender.Balance, abi.NewTokenAmount(1))
+		res, err := api.StateCallWithOverrides(ctx, send(value), head.Key(), &types.StateOverrides{})
+		require.NoError(t, err)
+		assert.Equal(t, exitcode.SysErrInsufficientFunds, res.MsgRct.ExitCode)
+
+		balance := big.Mul(value, big.NewInt(2))
+		res, err = api.StateCallWithOverrides(ctx, send(value), head.Key(), &types.StateOverrides{
+			Actors: map[address.Address]types.ActorOverride{from: {Balance: &balance}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, exitcode.Ok, res.MsgRct.ExitCode)
+
+		// the override is not persisted
+		unchanged, err := api.StateReadState(ctx, from, root)
+		require.NoError(t, err)
+		assert.Equal(t, sender.Balance, unchanged.Balance)
+	})
+
+	t.Run("a nonce override is the nonce of the message executed", func(t *testing.T) {
+		nonce := sender.Nonce + 7
+		res, err := api.StateCallWithOverrides(ctx, send(abi.NewTokenAmount(1)), head.Key(), &types.StateOverrides{
+			Actors: map[address.Address]types.ActorOverride{from: {Nonce: &nonce}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, exitcode.Ok, res.MsgRct.ExitCode)
+		assert.Equal(t, nonce, res.Msg.Nonce)
+
+		unchanged, err := api.StateReadState(ctx, from, root)
+		require.NoError(t, err)
+		assert.Equal(t, sender.Nonce, unchanged.Nonce)
+	})
+
+	t.Run("a state override replaces the head read by the actor", func(t *testing.T) {
+		other := cs.Addr(t, 2)
+		buf := new(bytes.Buffer)
+		require.NoError(t, (&account2.State{Address: other}).MarshalCBOR(buf))
+
+		pubkey := func(overrides *types.StateOverrides) address.Address {
+			msg := send(big.Zero())
+			msg.Method = builtin2.MethodsAccount.PubkeyAddress
+			res, err := api.StateCallWithOverrides(ctx, msg, head.Key(), overrides)
+			require.NoError(t, err)
+			require.Equal(t, exitcode.Ok, res.MsgRct.ExitCode)
+			var addr address.Address
+			require.NoError(t, addr.UnmarshalCBOR(bytes.NewReader(res.MsgRct.ReturnValue)))
+			return addr
+		}
+		assert.Equal(t, to, pubkey(&types.StateOverrides{}))
+		assert.Equal(t, other, pubkey(&types.StateOverrides{
+			Actors: map[address.Address]types.ActorOverride{to: {Head: buf.Bytes()}},
+		}))
+		assert.Equal(t, to, pubkey(&types.StateOverrides{}))
+	})
+
+	t.Run("an override of a missing actor is refused", func(t *testing.T) {
+		unknown, err := address.NewIDAddress(999999)
+		require.NoError(t, err)
+		balance := abi.NewTokenAmount(1)
+		_, err = api.StateCallWithOverrides(ctx, send(abi.NewTokenAmount(1)), head.Key(), &types.StateOverrides{
+			Actors: map[address.Address]types.ActorOverride{unknown: {Balance: &balance}},
+		})
+		assert.True(t, errors.Is(err, types.ErrActorNotFound), err)
+	})
+}
@@ -0,0 +1,146 @@
+package syncer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/app/node"
+	"github.com/filecoin-project/venus/app/node/test"
+	"github.com/filecoin-project/venus/pkg/config"
+	"github.com/filecoin-project/venus/pkg/constants"
+	"github.com/filecoin-project/venus/pkg/specactors/builtin/reward"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/venus/pkg/types"
+)
+
+// newNetworkV8Node starts a node on the fixture genesis with every upgrade
+// disabled, the network stays on the version of the v2 actors of the genesis,
+// without migrations.
+func newNetworkV8Node(ctx context.Context, t *testing.T) (*node.Node, *test.ChainSeed) {
+	cs := test.FixtureChainSeed(t)
+	n := test.NewNodeBuilder(t).
+		WithGenesisInit(cs.GenesisInitFunc).
+		WithConfig(func(cfg *config.Config) {
+			cfg.NetworkParams.ForkUpgradeParam = config.NoForkUpgrades()
+		}).
+		BuildAndStart(ctx)
+	return n, cs
+}
+
+func TestStateSimulate(t *testing.T) {
+	tf.IntegrationTest(t)
+	ctx := context.Background()
+
+	n, cs := newNetworkV8Node(ctx, t)
+	defer n.Stop(ctx)
+	api := n.Syncer().API()
+	head := n.Chain().ChainReader.GetHead()
+	root := head.At(0).ParentStateRoot
+
+	from, to := cs.Addr(t, 0), cs.Addr(t, 1)
+	send := func(value int64) *types.UnsignedMessage {
+		return &types.UnsignedMessage{
+			From:       from,
+			To:         to,
+			Value:      abi.NewTokenAmount(value),
+			GasFeeCap:  big.Zero(),
+			GasPremium: big.Zero(),
+		}
+	}
+	before, err := api.StateReadState(ctx, to, root)
+	require.NoError(t, err)
+
+	t.Run("the messages are applied in order between the epochs advanced", func(t *testing.T) {
+		res, err := api.StateSimulate(ctx, head.Key(), []types.SimulatedMessage{
+			{Message: send(100)},
+			{Message: send(200), AdvanceEpochs: 2, Cron: true},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, head.EnsureHeight()+2, res.Epoch)
+		require.Len(t, res.Results, 2)
+		for i, ret := range res.Results {
+			assert.Equal(t, exitcode.Ok, ret.MsgRct.ExitCode)
+			assert.Empty(t, ret.Error)
+			assert.True(t, ret.MsgRct.GasUsed > 0)
+			assert.NotNil(t, ret.ExecutionTrace)
+			assert.NotEqual(t, res.Results[1-i].MsgCid, ret.MsgCid)
+		}
+
+		// the simulated state is read from the scratch store
+		after, err := api.StateReadState(ctx, to, res.Root)
+		require.NoError(t, err)
+		assert.Equal(t, big.Add(before.Balance, abi.NewTokenAmount(300)), after.Balance)
+		sender, err := api.StateReadState(ctx, from, res.Root)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), sender.Nonce)
+
+		// and never written to the chain store
+		has, err := n.Blockstore().Blockstore.Has(res.Root)
+		require.NoError(t, err)
+		assert.False(t, has)
+		unchanged, err := api.StateReadState(ctx, to, root)
+		require.NoError(t, err)
+		assert.Equal(t, before.Balance, unchanged.Balance)
+	})
+
+	t.Run("the messages of the caller are left untouched", func(t *testing.T) {
+		msg := send(1)
+		msg.Nonce = 99
+		res, err := api.StateSimulate(ctx, head.Key(), []types.SimulatedMessage{{Message: msg, AdvanceEpochs: 1}})
+		require.NoError(t, err)
+		assert.Equal(t, uint64(99), msg.Nonce)
+		assert.Equal(t, int64(0), msg.GasLimit)
+
+		// the results hold the messages executed
+		sender, err := api.StateReadState(ctx, from, root)
+		require.NoError(t, err)
+		require.Len(t, res.Results, 1)
+		assert.Equal(t, sender.Nonce, res.Results[0].Msg.Nonce)
+		assert.Equal(t, int64(constants.BlockGasLimit), res.Results[0].Msg.GasLimit)
+	})
+
+	t.Run("the cron ticks of the epochs advanced update the reward actor", func(t *testing.T) {
+		rewardAfter := func(cron bool) interface{} {
+			res, err := api.StateSimulate(ctx, head.Key(), []types.SimulatedMessage{
+				{Message: send(1), AdvanceEpochs: 3, Cron: cron},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, head.EnsureHeight()+3, res.Epoch)
+			rewardAct, err := api.StateReadState(ctx, reward.Address, res.Root)
+			require.NoError(t, err)
+			return rewardAct.State
+		}
+		before, err := api.StateReadState(ctx, reward.Address, root)
+		require.NoError(t, err)
+		assert.Equal(t, before.State, rewardAfter(false))
+		assert.NotEqual(t, before.State, rewardAfter(true))
+	})
+
+	t.Run("invalid simulations are refused", func(t *testing.T) {
+		_, err := api.StateSimulate(ctx, head.Key(), []types.SimulatedMessage{{}})
+		assert.Error(t, err)
+		_, err = api.StateSimulate(ctx, head.Key(), []types.SimulatedMessage{{Message: send(1), AdvanceEpochs: -1}})
+		assert.Error(t, err)
+
+		unknown, err := address.NewIDAddress(999999)
+		require.NoError(t, err)
+		msg := send(1)
+		msg.From = unknown
+		_, err = api.StateSimulate(ctx, head.Key(), []types.SimulatedMessage{{Message: msg}})
+		assert.Error(t, err)
+	})
+
+	t.Run("an unknown actor is not found", func(t *testing.T) {
+		unknown, err := address.NewIDAddress(999999)
+		require.NoError(t, err)
+		_, err = api.StateReadState(ctx, unknown, root)
+		assert.Equal(t, types.ErrActorNotFound, err)
+	})
+}
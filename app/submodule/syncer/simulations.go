@@ -0,0 +1,121 @@
+package syncer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
+)
+
+const (
+	// simulationCacheBytes bounds the size of the states kept for the last
+	// simulations.
+	simulationCacheBytes = 256 << 20
+	// simulationTTL is how long the state of a simulation stays readable.
+	simulationTTL = 10 * time.Minute
+)
+
+// simulation is the store of a simulated state, `size` is the size of the
+// blocks the simulation wrote, the rest is read through to the chain store.
+type simulation struct {
+	root  cid.Cid
+	bs    blockstoreutil.Blockstore
+	size  int
+	added time.Time
+}
+
+// simulationCache keeps the stores of the last simulations by their state
+// root, until they expire or the newer ones need the room.
+type simulationCache struct {
+	lk       sync.Mutex
+	maxBytes int
+	ttl      time.Duration
+	now      func() time.Time
+
+	size   int
+	order  []*simulation // oldest first
+	byRoot map[cid.Cid]*simulation
+}
+
+func newSimulationCache(maxBytes int, ttl time.Duration) *simulationCache {
+	return &simulationCache{
+		maxBytes: maxBytes,
+		ttl:      ttl,
+		now:      time.Now,
+		byRoot:   make(map[cid.Cid]*simulation),
+	}
+}
+
+// add keeps the store `bs` of the state `root`, `size` being what the
+// simulation wrote to it. A state larger than the cache is not kept.
+func (c *simulationCache) add(root cid.Cid, bs blockstoreutil.Blockstore, size int) bool {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	c.expire()
+	if size > c.maxBytes {
+		return false
+	}
+	if old, ok := c.byRoot[root]; ok {
+		c.remove(old)
+	}
+	sim := &simulation{root: root, bs: bs, size: size, added: c.now()}
+	c.order = append(c.order, sim)
+	c.byRoot[root] = sim
+	c.size += size
+	for c.size > c.maxBytes {
+		c.remove(c.order[0])
+	}
+	return true
+}
+
+// get returns the store of the simulated state `root`.
+func (c *simulationCache) get(root cid.Cid) (blockstoreutil.Blockstore, bool) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+
+	c.expire()
+	sim, ok := c.byRoot[root]
+	if !ok {
+		return nil, false
+	}
+	return sim.bs, true
+}
+
+func (c *simulationCache) expire() {
+	now := c.now()
+	for len(c.order) > 0 && now.Sub(c.order[0].added) >= c.ttl {
+		c.remove(c.order[0])
+	}
+}
+
+func (c *simulationCache) remove(sim *simulation) {
+	for i, s := range c.order {
+		if s == sim {
+			c.order = append(c.order[:i], c.order[i+1:]...)
+			break
+		}
+	}
+	delete(c.byRoot, sim.root)
+	c.size -= sim.size
+}
+
+// storeSize returns the size of the blocks in `bs`.
+func storeSize(ctx context.Context, bs blockstoreutil.Blockstore) (int, error) {
+	keys, err := bs.AllKeysChan(ctx)
+	if err != nil {
+		return 0, err
+	}
+	size := 0
+	for k := range keys {
+		n, err := bs.GetSize(k)
+		if err != nil {
+			return 0, err
+		}
+		size += n
+	}
+	return size, nil
+}
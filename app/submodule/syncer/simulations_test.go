@@ -0,0 +1,68 @@
+package syncer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
+)
+
+func TestSimulationCacheIsBounded(t *testing.T) {
+	tf.UnitTest(t)
+
+	now := time.Unix(0, 0)
+	cache := newSimulationCache(100, time.Minute)
+	cache.now = func() time.Time { return now }
+	a, b, c := types.CidFromString(t, "a"), types.CidFromString(t, "b"), types.CidFromString(t, "c")
+	bs := blockstoreutil.NewTemporarySync()
+
+	t.Run("the oldest states make room for the new ones", func(t *testing.T) {
+		assert.True(t, cache.add(a, bs, 40))
+		assert.True(t, cache.add(b, bs, 40))
+		assert.True(t, cache.add(c, bs, 40))
+		_, ok := cache.get(a)
+		assert.False(t, ok)
+		for _, root := range []cid.Cid{b, c} {
+			_, ok := cache.get(root)
+			assert.True(t, ok)
+		}
+		assert.Equal(t, 80, cache.size)
+	})
+
+	t.Run("a state larger than the cache is not kept", func(t *testing.T) {
+		assert.False(t, cache.add(a, bs, 101))
+		_, ok := cache.get(a)
+		assert.False(t, ok)
+		assert.Equal(t, 80, cache.size)
+	})
+
+	t.Run("the states expire", func(t *testing.T) {
+		now = now.Add(30 * time.Second)
+		assert.True(t, cache.add(a, bs, 10))
+		now = now.Add(30 * time.Second)
+		_, ok := cache.get(b)
+		assert.False(t, ok)
+		_, ok = cache.get(a)
+		assert.True(t, ok)
+		assert.Equal(t, 10, cache.size)
+	})
+}
+
+func TestStoreSize(t *testing.T) {
+	tf.UnitTest(t)
+
+	bs := blockstoreutil.NewTemporarySync()
+	require.NoError(t, bs.Put(blocks.NewBlock([]byte("simulated"))))
+	require.NoError(t, bs.Put(blocks.NewBlock([]byte("state"))))
+	size, err := storeSize(context.Background(), bs)
+	require.NoError(t, err)
+	assert.Equal(t, len("simulated")+len("state"), size)
+}
@@ -0,0 +1,57 @@
+package syncer_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/network"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/app/node/test"
+	"github.com/filecoin-project/venus/pkg/config"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+func TestStateMigrate(t *testing.T) {
+	tf.IntegrationTest(t)
+	ctx := context.Background()
+
+	// the v3 actors upgrade is scheduled far above the genesis
+	cs := test.FixtureChainSeed(t)
+	n := test.NewNodeBuilder(t).
+		WithGenesisInit(cs.GenesisInitFunc).
+		WithConfig(func(cfg *config.Config) {
+			cfg.NetworkParams.ForkUpgradeParam = config.NoForkUpgrades()
+			cfg.NetworkParams.ForkUpgradeParam.UpgradeActorsV3Height = 1000
+		}).
+		BuildAndStart(ctx)
+	defer n.Stop(ctx)
+	api := n.Syncer().API()
+	root := n.Chain().ChainReader.GetHead().At(0).ParentStateRoot
+
+	t.Run("a root is migrated to a scratch store", func(t *testing.T) {
+		res, err := api.StateMigrate(ctx, root, network.Version10)
+		require.NoError(t, err)
+		assert.Equal(t, root, res.FromRoot)
+		assert.NotEqual(t, root, res.NewRoot)
+		assert.NotEmpty(t, res.Actors)
+		// the chain is not past the upgrade
+		assert.False(t, res.Checked)
+
+		has, err := n.Chain().ChainReader.Blockstore().Has(res.NewRoot)
+		require.NoError(t, err)
+		assert.False(t, has)
+	})
+
+	t.Run("the chain state is needed without a root", func(t *testing.T) {
+		_, err := api.StateMigrate(ctx, cid.Undef, network.Version10)
+		assert.Error(t, err)
+	})
+
+	t.Run("a version without migration is refused", func(t *testing.T) {
+		_, err := api.StateMigrate(ctx, root, network.Version9)
+		assert.Error(t, err)
+	})
+}
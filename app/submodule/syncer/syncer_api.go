@@ -5,9 +5,20 @@ import (
 	syncTypes "github.com/filecoin-project/venus/pkg/chainsync/types"
 	"time"
 
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/network"
 	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/consensus"
+	"github.com/filecoin-project/venus/pkg/fork"
 	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
+	"github.com/filecoin-project/venus/pkg/vm"
+	"github.com/filecoin-project/venus/pkg/vm/gas"
+	vmstate "github.com/filecoin-project/venus/pkg/vm/state"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
 	logging "github.com/ipfs/go-log/v2"
 	xerrors "github.com/pkg/errors"
 )
@@ -41,6 +52,18 @@ func (syncerAPI *SyncerAPI) ChainSyncHandleNewTipSet(ci *block.ChainInfo) error
 	return syncerAPI.syncer.SyncProvider.HandleNewTipSet(ci)
 }
 
+// BlockMinedEvt is the journal entry of a block produced by a local miner.
+type BlockMinedEvt struct {
+	Miner     address.Address
+	Cid       cid.Cid
+	Height    abi.ChainEpoch
+	Parents   block.TipSetKey
+	Timestamp uint64
+	Messages  int
+}
+
+// SyncSubmitBlock validates a block produced by a local miner, syncs to it and
+// publishes it.
 func (syncerAPI *SyncerAPI) SyncSubmitBlock(ctx context.Context, blk *block.BlockMsg) error {
 	//todo many dot. how to get directly
 	chainModule := syncerAPI.syncer.ChainModule
@@ -87,6 +110,16 @@ func (syncerAPI *SyncerAPI) SyncSubmitBlock(ctx context.Context, blk *block.Bloc
 	if err := syncerAPI.syncer.SyncProvider.HandleNewTipSet(ci); err != nil {
 		return xerrors.Errorf("sync to submitted block failed: %v", err)
 	}
+	syncerAPI.syncer.journal.RecordEvent(syncerAPI.syncer.evtTypeBlockMined, func() interface{} {
+		return BlockMinedEvt{
+			Miner:     blk.Header.Miner,
+			Cid:       blk.Header.Cid(),
+			Height:    blk.Header.Height,
+			Parents:   blk.Header.Parents,
+			Timestamp: blk.Header.Timestamp,
+			Messages:  len(blk.BlsMessages) + len(blk.SecpkMessages),
+		}
+	})
 
 	b, err := blk.Serialize()
 	if err != nil {
@@ -123,6 +156,191 @@ func (syncerAPI *SyncerAPI) StateCall(ctx context.Context, msg *types.UnsignedMe
 	}, nil
 }
 
+// StateCallWithOverrides executes `msg` against the state of the tipset
+// `tsk` changed by `overrides` and returns the result with its traces, the
+// overrides are applied to a copy and never persisted. The result holds the
+// message executed, with the nonce of its sender and a gas limit filled.
+func (syncerAPI *SyncerAPI) StateCallWithOverrides(ctx context.Context, msg *types.UnsignedMessage, tsk block.TipSetKey, overrides *types.StateOverrides) (*InvocResult, error) {
+	start := time.Now()
+	ts, err := syncerAPI.syncer.ChainModule.ChainReader.GetTipSet(tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("loading tipset %s: %v", tsk, err)
+	}
+	ret, err := syncerAPI.syncer.Consensus.CallWithOverrides(ctx, msg, ts, overrides)
+	if err != nil {
+		return nil, err
+	}
+	if ret.GasTracker != nil && ret.GasTracker.ExecutionTrace.Msg != nil {
+		msg = ret.GasTracker.ExecutionTrace.Msg
+	}
+
+	return newInvocResult(msg, ret, time.Since(start)), nil
+}
+
+// StateSimulate applies `msgs` in order on a throwaway vm started from the
+// state of the tipset `tsk`, advancing fake epochs between them as asked, and
+// returns the result of each message with the simulated state root. The
+// state is kept apart from the chain and can be read with StateReadState
+// until it expires or newer simulations need the room. The messages of the
+// caller are left untouched, the results hold the messages executed.
+func (syncerAPI *SyncerAPI) StateSimulate(ctx context.Context, tsk block.TipSetKey, msgs []types.SimulatedMessage) (*SimulationResult, error) {
+	ts, err := syncerAPI.syncer.ChainModule.ChainReader.GetTipSet(tsk)
+	if err != nil {
+		return nil, xerrors.Errorf("loading tipset %s: %v", tsk, err)
+	}
+	for i, m := range msgs {
+		if m.Message == nil {
+			return nil, xerrors.Errorf("message %d is missing", i)
+		}
+		if m.AdvanceEpochs < 0 {
+			return nil, xerrors.Errorf("message %d advances %d epochs", i, m.AdvanceEpochs)
+		}
+	}
+
+	start := time.Now()
+	scratch := blockstoreutil.NewTemporarySync()
+	bs := blockstoreutil.NewTieredBstore(syncerAPI.syncer.BlockstoreModule.Blockstore, scratch)
+	root, rets, err := syncerAPI.syncer.Consensus.Simulate(ctx, ts, msgs, bs)
+	if err != nil {
+		return nil, err
+	}
+	size, err := storeSize(ctx, scratch)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "measuring simulated state")
+	}
+	if !syncerAPI.syncer.simulations.add(root, bs, size) {
+		syncAPILog.Warnf("simulated state %s of %d bytes is too large to be kept", root, size)
+	}
+
+	res := &SimulationResult{
+		Root:     root,
+		Epoch:    ts.EnsureHeight(),
+		Results:  make([]*InvocResult, len(rets)),
+		Duration: time.Since(start),
+	}
+	// The messages run on one vm, they are not timed one by one.
+	for i, ret := range rets {
+		res.Epoch += msgs[i].AdvanceEpochs
+		msg := msgs[i].Message
+		if ret.GasTracker != nil && ret.GasTracker.ExecutionTrace.Msg != nil {
+			msg = ret.GasTracker.ExecutionTrace.Msg
+		}
+		res.Results[i] = newInvocResult(msg, ret, 0)
+	}
+	return res, nil
+}
+
+// StateMigrate rehearses the upgrade to network version `nv` on the state
+// `root`, or on the state the chain had at the upgrade when `root` is
+// undefined, and checks the latter against the chain. It runs on the
+// blockstore of the node through a scratch store, the migrated state is
+// discarded. The migrations of mainnet take minutes and gigabytes of memory,
+// `venus state migrate` runs them on a stopped node instead.
+func (syncerAPI *SyncerAPI) StateMigrate(ctx context.Context, root cid.Cid, nv network.Version) (*fork.MigrationDryRun, error) {
+	chn := syncerAPI.syncer.ChainModule
+	return fork.DryRunMigration(ctx,
+		chn.State,
+		syncerAPI.syncer.BlockstoreModule.Blockstore,
+		chn.Fork.GetForkUpgrade(),
+		root,
+		nv,
+		syncerAPI.syncer.Consensus.ExecuteMigrated,
+	)
+}
+
+// StateReadState returns the actor `addr` in the state `root`, either the
+// state of a recent simulation or one of the chain.
+func (syncerAPI *SyncerAPI) StateReadState(ctx context.Context, addr address.Address, root cid.Cid) (*ActorState, error) {
+	bs, ok := syncerAPI.syncer.SimulationStore(root)
+	if !ok {
+		bs = syncerAPI.syncer.BlockstoreModule.Blockstore
+	}
+	cst := cbor.NewCborStore(bs)
+	st, err := vmstate.LoadState(ctx, cst, root)
+	if err != nil {
+		return nil, xerrors.Wrapf(err, "loading state %s", root)
+	}
+	act, found, err := st.GetActor(ctx, addr)
+	if err != nil {
+		return nil, xerrors.Wrapf(err, "loading actor %s", addr)
+	}
+	if !found {
+		return nil, types.ErrActorNotFound
+	}
+
+	blk, err := bs.Get(act.Head)
+	if err != nil {
+		return nil, xerrors.Wrapf(err, "loading head of actor %s", addr)
+	}
+	var state interface{}
+	if err := cbor.DecodeInto(blk.RawData(), &state); err != nil {
+		return nil, xerrors.Wrapf(err, "decoding head of actor %s", addr)
+	}
+	return &ActorState{
+		Balance: act.Balance,
+		Code:    act.Code,
+		Nonce:   act.Nonce,
+		State:   state,
+	}, nil
+}
+
+// ChainGasProfile re-executes the tipsets with a height in [from, to] with
+// the gas profiling of the vm enabled and returns the charges aggregated by
+// actor code, method and charge name, the most expensive first. The tipsets
+// executing a state migration are skipped.
+func (syncerAPI *SyncerAPI) ChainGasProfile(ctx context.Context, from, to abi.ChainEpoch) ([]gas.ProfileEntry, error) {
+	chainReader := syncerAPI.syncer.ChainModule.ChainReader
+	if from < 1 || from > to {
+		return nil, xerrors.Errorf("invalid height range [%d, %d]", from, to)
+	}
+	ts, err := chainReader.GetTipSetByHeight(ctx, nil, to, true)
+	if err != nil {
+		return nil, xerrors.Wrapf(err, "loading tipset at %d", to)
+	}
+
+	profiler := gas.NewProfiler()
+	for ts.EnsureHeight() >= from {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		err := syncerAPI.syncer.Consensus.ProfileTipSet(ctx, ts, profiler)
+		if xerrors.Is(err, consensus.ErrProfileMigration) {
+			syncAPILog.Warnf("tipset %s at %d not profiled: %v", ts.Key(), ts.EnsureHeight(), err)
+		} else if err != nil {
+			return nil, xerrors.Wrapf(err, "executing tipset %s at %d", ts.Key(), ts.EnsureHeight())
+		}
+		if ts, err = chainReader.GetTipSet(ts.EnsureParents()); err != nil {
+			return nil, err
+		}
+	}
+	return profiler.Entries(), nil
+}
+
+func newInvocResult(msg *types.UnsignedMessage, ret *vm.Ret, duration time.Duration) *InvocResult {
+	mcid, _ := msg.Cid()
+	res := &InvocResult{
+		MsgCid: mcid,
+		Msg:    msg,
+		MsgRct: &ret.Receipt,
+		GasCost: MsgGasCost{
+			Message:            mcid,
+			GasUsed:            big.NewInt(ret.Receipt.GasUsed),
+			BaseFeeBurn:        ret.OutPuts.BaseFeeBurn,
+			OverEstimationBurn: ret.OutPuts.OverEstimationBurn,
+			MinerPenalty:       ret.OutPuts.MinerPenalty,
+			MinerTip:           ret.OutPuts.MinerTip,
+			Refund:             ret.OutPuts.Refund,
+			TotalCost:          big.Sub(msg.RequiredFunds(), ret.OutPuts.Refund),
+		},
+		Duration: duration,
+	}
+	if ret.GasTracker != nil {
+		res.ExecutionTrace = ret.GasTracker.ExecutionTrace
+		res.Error = res.ExecutionTrace.Error
+	}
+	return res
+}
+
 //SyncState just compatible code lotus
 func (syncerAPI *SyncerAPI) SyncState(ctx context.Context) (*SyncState, error) {
 	tracker := syncerAPI.syncer.ChainSyncManager.BlockProposer().SyncTracker()
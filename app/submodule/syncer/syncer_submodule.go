@@ -3,6 +3,7 @@ package syncer
 import (
 	"bytes"
 	"context"
+	"go.opencensus.io/stats"
 	"go.opencensus.io/trace"
 	"reflect"
 	"runtime"
@@ -28,15 +29,23 @@ import (
 	"github.com/filecoin-project/venus/pkg/chainsync"
 	"github.com/filecoin-project/venus/pkg/clock"
 	"github.com/filecoin-project/venus/pkg/consensus"
+	"github.com/filecoin-project/venus/pkg/journal"
+	"github.com/filecoin-project/venus/pkg/metrics"
 	"github.com/filecoin-project/venus/pkg/net/blocksub"
 	"github.com/filecoin-project/venus/pkg/net/pubsub"
 	"github.com/filecoin-project/venus/pkg/slashing"
 	"github.com/filecoin-project/venus/pkg/state"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
 	logging "github.com/ipfs/go-log/v2"
 )
 
 var log = logging.Logger("sync.module") // nolint: deadcode
 
+var blockArrivalDelay = metrics.NewTimerWithBuckets("chain/block_arrival_delay",
+	"Delay between the start of the epoch of a block and its arrival over pubsub in milliseconds",
+	stats.UnitMilliseconds,
+	[]float64{100, 250, 500, 1000, 2000, 4000, 6000, 8000, 10000, 15000, 20000, 30000})
+
 // SyncerSubmodule enhances the node with chain syncing capabilities
 type SyncerSubmodule struct { //nolint
 	BlockstoreModule   *blockstore.BlockstoreSubmodule
@@ -58,6 +67,15 @@ type SyncerSubmodule struct { //nolint
 	CancelChainSync context.CancelFunc
 	// faultCh receives detected consensus faults
 	faultCh chan slashing.ConsensusFault
+	// faultHandlers are called with each detected consensus fault
+	faultHandlers []func(context.Context, slashing.ConsensusFault)
+
+	journal           journal.Journal
+	evtTypeBlockMined journal.EventType
+
+	// simulations keeps the scratch blockstores of the last simulations by
+	// their final state root.
+	simulations *simulationCache
 }
 
 type syncerConfig interface {
@@ -65,6 +83,7 @@ type syncerConfig interface {
 	BlockTime() time.Duration
 	ChainClock() clock.ChainEpochClock
 	Repo() repo.Repo
+	Journal() journal.Journal
 }
 
 type nodeChainSelector interface {
@@ -109,7 +128,7 @@ func NewSyncerSubmodule(ctx context.Context,
 		gasPriceSchedule,
 	)
 	// register block validation on pubsub
-	btv := blocksub.NewBlockTopicValidator(blkValid)
+	btv := blocksub.NewBlockTopicValidator(blkValid).WithPeerBanner(network)
 	if err := network.Pubsub.RegisterTopicValidator(btv.Topic(network.NetworkName), btv.Validator(), btv.Opts()...); err != nil {
 		return nil, errors.Wrap(err, "failed to register block validator")
 	}
@@ -129,7 +148,7 @@ func NewSyncerSubmodule(ctx context.Context,
 	faultCh := make(chan slashing.ConsensusFault)
 	faultDetector := slashing.NewConsensusFaultDetector(faultCh)
 
-	chainSyncManager, err := chainsync.NewManager(nodeConsensus, blkValid, nodeChainSelector, chn.ChainReader, chn.MessageStore, blockstore.Blockstore, discovery.ExchangeClient, config.ChainClock(), faultDetector, chn.Fork)
+	chainSyncManager, err := chainsync.NewManager(nodeConsensus, blkValid, nodeChainSelector, chn.ChainReader, chn.MessageStore, blockstore.Blockstore, discovery.ExchangeClient, config.ChainClock(), faultDetector, chn.Fork, network, config.Journal())
 	if err != nil {
 		return nil, err
 	}
@@ -155,9 +174,18 @@ func NewSyncerSubmodule(ctx context.Context,
 		SyncProvider:       *NewChainSyncProvider(&chainSyncManager),
 		faultCh:            faultCh,
 		BlockValidator:     blkValid,
+		journal:            config.Journal(),
+		evtTypeBlockMined:  config.Journal().RegisterEventType("miner", "block_mined"),
+		simulations:        newSimulationCache(simulationCacheBytes, simulationTTL),
 	}, nil
 }
 
+// SimulationStore returns the blockstore holding the state `root` computed by
+// a recent simulation, it reads through to the chain store.
+func (syncer *SyncerSubmodule) SimulationStore(root cid.Cid) (blockstoreutil.Blockstore, bool) {
+	return syncer.simulations.get(root)
+}
+
 func (syncer *SyncerSubmodule) handleIncommingBlocks(ctx context.Context, msg pubsub.Message) error {
 	sender := msg.GetSender()
 	source := msg.GetSource()
@@ -175,6 +203,7 @@ func (syncer *SyncerSubmodule) handleIncommingBlocks(ctx context.Context, msg pu
 	}
 
 	header := bm.Header
+	blockArrivalDelay.Record(ctx, time.Since(time.Unix(int64(header.Timestamp), 0)))
 	span.AddAttributes(trace.StringAttribute("block", header.Cid().String()))
 	log.Infof("Received new block %s height %d from peer %s", header.Cid(), header.Height, sender)
 	_, err = syncer.ChainModule.ChainReader.PutObject(ctx, bm.Header)
@@ -269,9 +298,10 @@ func (syncer *SyncerSubmodule) Start(ctx context.Context) error {
 			select {
 			case <-ctx.Done():
 				return
-			case <-syncer.faultCh:
-				// TODO #3690 connect this up to a slasher that sends messages
-				// to outbound queue to carry out penalization
+			case fault := <-syncer.faultCh:
+				for _, handler := range syncer.faultHandlers {
+					handler(ctx, fault)
+				}
 			}
 		}
 	}()
@@ -279,6 +309,12 @@ func (syncer *SyncerSubmodule) Start(ctx context.Context) error {
 	return syncer.ChainSyncManager.Start(ctx)
 }
 
+// OnConsensusFault registers a handler called with each consensus fault
+// detected while syncing. It must be called before Start.
+func (syncer *SyncerSubmodule) OnConsensusFault(handler func(context.Context, slashing.ConsensusFault)) {
+	syncer.faultHandlers = append(syncer.faultHandlers, handler)
+}
+
 func (syncer *SyncerSubmodule) Stop(ctx context.Context) {
 	if syncer.CancelChainSync != nil {
 		syncer.CancelChainSync()
@@ -0,0 +1,320 @@
+package syncer
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/ipfs/go-cid"
+	xerrors "github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/chain"
+	"github.com/filecoin-project/venus/pkg/types"
+)
+
+// ChainValidation is the outcome of the re-execution of a range of tipsets.
+type ChainValidation struct {
+	From        abi.ChainEpoch
+	To          abi.ChainEpoch
+	Checked     int
+	Fixed       int
+	Divergences []*TipSetDivergence
+}
+
+// TipSetDivergence is a tipset whose re-execution disagrees with the stored
+// metadata or with the roots in the header of its child on chain. The roots
+// that could not be read are undefined.
+type TipSetDivergence struct {
+	Height abi.ChainEpoch
+	TipSet block.TipSetKey
+
+	StateRoot    cid.Cid
+	ReceiptsRoot cid.Cid
+
+	StoredStateRoot    cid.Cid
+	StoredReceiptsRoot cid.Cid
+
+	ChainStateRoot    cid.Cid
+	ChainReceiptsRoot cid.Cid
+
+	// FirstMismatch is the first message whose receipt differs from the
+	// receipts on chain, nil when they are the same or unavailable.
+	FirstMismatch *ReceiptMismatch
+	// Fixed is set when the stored metadata was rewritten.
+	Fixed bool
+}
+
+// ReceiptMismatch is a message whose re-executed receipt is not on chain.
+type ReceiptMismatch struct {
+	Index        int
+	Message      cid.Cid
+	Receipt      *types.MessageReceipt
+	ChainReceipt *types.MessageReceipt
+}
+
+type validationTask struct {
+	ts    *block.TipSet
+	child *block.TipSet
+}
+
+// ChainValidate re-executes the tipsets with a height in [from, to] and
+// compares the state and receipts roots computed with the stored metadata and
+// with the parent roots of the next tipset on chain. With `fix`, the stored
+// metadata that disagrees is rewritten when the next tipset confirms the
+// computed roots, the metadata of the head is never rewritten.
+//
+// The tipsets whose pre-state is in the store are executed in `parallel`
+// chunks, the others after the tipset before them, from the computed state.
+// Only the root block of a pre-state is looked up, a pre-state missing deeper
+// nodes fails the execution instead of falling back on the computed state.
+// The divergences are sorted by height.
+func (syncerAPI *SyncerAPI) ChainValidate(ctx context.Context, from, to abi.ChainEpoch, fix bool, parallel int) (*ChainValidation, error) {
+	if from < 1 || from > to {
+		return nil, xerrors.Errorf("invalid height range [%d, %d]", from, to)
+	}
+	if parallel < 1 {
+		parallel = 1
+	}
+	tasks, err := syncerAPI.validationTasks(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ChainValidation{From: from, To: to, Checked: len(tasks)}
+	var lk sync.Mutex
+	report := func(d *TipSetDivergence) {
+		lk.Lock()
+		defer lk.Unlock()
+		res.Divergences = append(res.Divergences, d)
+		if d.Fixed {
+			res.Fixed++
+		}
+	}
+
+	chunks, err := syncerAPI.validationChunks(tasks, parallel)
+	if err != nil {
+		return nil, err
+	}
+	group, gctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, parallel)
+	for _, chunk := range chunks {
+		chunk := chunk
+		sem <- struct{}{}
+		group.Go(func() error {
+			defer func() { <-sem }()
+			prev := cid.Undef
+			for _, task := range chunk {
+				if err := gctx.Err(); err != nil {
+					return err
+				}
+				pre := task.ts.At(0).ParentStateRoot
+				if has, _ := syncerAPI.syncer.ChainModule.ChainReader.Blockstore().Has(pre); !has {
+					pre = prev
+				}
+				d, root, err := syncerAPI.validateTipSet(gctx, task, pre, fix)
+				if err != nil {
+					return xerrors.Wrapf(err, "executing tipset %s at %d", task.ts.Key(), task.ts.EnsureHeight())
+				}
+				if d != nil {
+					report(d)
+				}
+				prev = root
+			}
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	sort.Slice(res.Divergences, func(i, j int) bool {
+		return res.Divergences[i].Height < res.Divergences[j].Height
+	})
+	return res, nil
+}
+
+// validationTasks lists the tipsets of the range in ascending height with the
+// tipset after them on chain, nil for the head.
+func (syncerAPI *SyncerAPI) validationTasks(ctx context.Context, from, to abi.ChainEpoch) ([]validationTask, error) {
+	chainReader := syncerAPI.syncer.ChainModule.ChainReader
+	head := chainReader.GetHead()
+	if to > head.EnsureHeight() {
+		to = head.EnsureHeight()
+	}
+	ts, err := chainReader.GetTipSetByHeight(ctx, head, to, true)
+	if err != nil {
+		return nil, xerrors.Wrapf(err, "loading tipset at %d", to)
+	}
+
+	var child *block.TipSet
+	if ts.EnsureHeight() < head.EnsureHeight() {
+		next, err := chainReader.GetTipSetByHeight(ctx, head, ts.EnsureHeight()+1, false)
+		if err != nil {
+			return nil, xerrors.Wrapf(err, "loading tipset after %d", ts.EnsureHeight())
+		}
+		if next.EnsureParents().Equals(ts.Key()) {
+			child = next
+		}
+	}
+
+	var tasks []validationTask
+	for ts.EnsureHeight() >= from {
+		tasks = append(tasks, validationTask{ts: ts, child: child})
+		child = ts
+		if ts, err = chainReader.GetTipSet(ts.EnsureParents()); err != nil {
+			return nil, err
+		}
+	}
+	for i, j := 0, len(tasks)-1; i < j; i, j = i+1, j-1 {
+		tasks[i], tasks[j] = tasks[j], tasks[i]
+	}
+	return tasks, nil
+}
+
+// validationChunks splits the tasks in about 4 chunks per worker, a chunk
+// starting on a tipset whose pre-state is missing is merged in the previous
+// one so it executes from the computed state.
+func (syncerAPI *SyncerAPI) validationChunks(tasks []validationTask, parallel int) ([][]validationTask, error) {
+	bs := syncerAPI.syncer.ChainModule.ChainReader.Blockstore()
+	size := len(tasks) / (parallel * 4)
+	if size < 1 {
+		size = 1
+	}
+	var chunks [][]validationTask
+	chunkStart := 0
+	for start := 0; start < len(tasks); start += size {
+		end := start + size
+		if end > len(tasks) {
+			end = len(tasks)
+		}
+		has, err := bs.Has(tasks[start].ts.At(0).ParentStateRoot)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case has:
+			chunkStart = start
+			chunks = append(chunks, tasks[start:end])
+		case len(chunks) == 0:
+			return nil, xerrors.Errorf("the pre-state of height %d is not in the store", tasks[start].ts.EnsureHeight())
+		default:
+			chunks[len(chunks)-1] = tasks[chunkStart:end]
+		}
+	}
+	return chunks, nil
+}
+
+// validateTipSet executes a tipset on `pre` and returns the divergence found,
+// nil when there is none, and the state root computed.
+func (syncerAPI *SyncerAPI) validateTipSet(ctx context.Context, task validationTask, pre cid.Cid, fix bool) (*TipSetDivergence, cid.Cid, error) {
+	chainReader := syncerAPI.syncer.ChainModule.ChainReader
+	messageStore := syncerAPI.syncer.ChainModule.MessageStore
+	ts := task.ts
+
+	root, receipts, err := syncerAPI.syncer.Consensus.RunStateTransition(ctx, ts, pre)
+	if err != nil {
+		return nil, cid.Undef, err
+	}
+	if !root.Defined() {
+		return nil, cid.Undef, xerrors.New("the state transition computed no state root")
+	}
+	receiptsRoot, err := messageStore.StoreReceipts(ctx, receipts)
+	if err != nil {
+		return nil, cid.Undef, err
+	}
+
+	d := &TipSetDivergence{
+		Height:       ts.EnsureHeight(),
+		TipSet:       ts.Key(),
+		StateRoot:    root,
+		ReceiptsRoot: receiptsRoot,
+	}
+	// Errors mean the metadata is missing, which is a divergence too.
+	d.StoredStateRoot, _ = chainReader.GetTipSetStateRoot(ts)
+	d.StoredReceiptsRoot, _ = chainReader.GetTipSetReceiptsRoot(ts)
+	storedOk := d.StoredStateRoot == root && d.StoredReceiptsRoot == receiptsRoot
+
+	chainOk := true
+	if task.child != nil {
+		d.ChainStateRoot = task.child.At(0).ParentStateRoot
+		d.ChainReceiptsRoot = task.child.At(0).ParentMessageReceipts
+		chainOk = d.ChainStateRoot == root && d.ChainReceiptsRoot == receiptsRoot
+		if d.ChainReceiptsRoot != receiptsRoot {
+			if d.FirstMismatch, err = syncerAPI.firstReceiptMismatch(ctx, ts, receipts, d.ChainReceiptsRoot); err != nil {
+				log.Warnf("failed to compare the receipts of %s: %s", ts.Key(), err)
+			}
+		}
+	}
+	if storedOk && chainOk {
+		return nil, root, nil
+	}
+
+	// Without a child the computed roots are not confirmed by the chain.
+	if fix && !storedOk && task.child != nil && chainOk {
+		err := chainReader.PutTipSetMetadata(ctx, &chain.TipSetMetadata{
+			TipSet:          ts,
+			TipSetStateRoot: root,
+			TipSetReceipts:  receiptsRoot,
+		})
+		if err != nil {
+			return nil, cid.Undef, xerrors.Wrapf(err, "rewriting the metadata of %s", ts.Key())
+		}
+		d.Fixed = true
+	}
+	return d, root, nil
+}
+
+// firstReceiptMismatch compares `receipts` with the receipts at `chainRoot`
+// in the order the messages of `ts` are applied.
+func (syncerAPI *SyncerAPI) firstReceiptMismatch(ctx context.Context, ts *block.TipSet, receipts []types.MessageReceipt, chainRoot cid.Cid) (*ReceiptMismatch, error) {
+	messageStore := syncerAPI.syncer.ChainModule.MessageStore
+	chainReceipts, err := messageStore.LoadReceipts(ctx, chainRoot)
+	if err != nil {
+		return nil, err
+	}
+	blkMsgs, err := messageStore.LoadTipSetMessage(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+	// The vm skips the messages already included by an earlier block.
+	var msgs []cid.Cid
+	seen := make(map[cid.Cid]struct{})
+	for _, blkMsg := range blkMsgs {
+		for _, group := range [][]types.ChainMsg{blkMsg.BlsMessages, blkMsg.SecpkMessages} {
+			for _, m := range group {
+				c, err := m.VMMessage().Cid()
+				if err != nil {
+					return nil, err
+				}
+				if _, ok := seen[c]; ok {
+					continue
+				}
+				seen[c] = struct{}{}
+				msgs = append(msgs, c)
+			}
+		}
+	}
+
+	for i := 0; i < len(receipts) || i < len(chainReceipts); i++ {
+		mismatch := &ReceiptMismatch{Index: i}
+		if i < len(msgs) {
+			mismatch.Message = msgs[i]
+		}
+		if i < len(receipts) {
+			mismatch.Receipt = &receipts[i]
+		}
+		if i < len(chainReceipts) {
+			mismatch.ChainReceipt = &chainReceipts[i]
+		}
+		if mismatch.Receipt == nil || mismatch.ChainReceipt == nil || !receiptEqual(mismatch.Receipt, mismatch.ChainReceipt) {
+			return mismatch, nil
+		}
+	}
+	return nil, nil
+}
+
+func receiptEqual(a, b *types.MessageReceipt) bool {
+	return a.ExitCode == b.ExitCode && a.GasUsed == b.GasUsed && string(a.ReturnValue) == string(b.ReturnValue)
+}
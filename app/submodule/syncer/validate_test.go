@@ -0,0 +1,211 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chain2 "github.com/filecoin-project/venus/app/submodule/chain"
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/chain"
+	"github.com/filecoin-project/venus/pkg/consensus"
+	"github.com/filecoin-project/venus/pkg/constants"
+	emptycid "github.com/filecoin-project/venus/pkg/testhelpers/empty_cid"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/venus/pkg/types"
+)
+
+// heightStateBuilder gives every tipset a distinct state root.
+type heightStateBuilder struct {
+	chain.FakeStateBuilder
+}
+
+func (heightStateBuilder) ComputeState(prev cid.Cid, blockmsg []block.BlockMessagesInfo) (cid.Cid, []types.MessageReceipt, error) {
+	root, err := constants.DefaultCidBuilder.Sum([]byte(fmt.Sprintf("%s-%d", prev, blockmsg[0].Block.Height)))
+	return root, []types.MessageReceipt{}, err
+}
+
+// replayTransition returns the state roots in `roots` and records the
+// pre-state each tipset is executed on.
+type replayTransition struct {
+	consensus.Protocol
+
+	lk    sync.Mutex
+	roots map[block.TipSetKey]cid.Cid
+	pres  map[block.TipSetKey]cid.Cid
+}
+
+func (rt *replayTransition) RunStateTransition(_ context.Context, ts *block.TipSet, pre cid.Cid) (cid.Cid, []types.MessageReceipt, error) {
+	rt.lk.Lock()
+	defer rt.lk.Unlock()
+	rt.pres[ts.Key()] = pre
+	return rt.roots[ts.Key()], []types.MessageReceipt{}, nil
+}
+
+// setupValidation builds a chain of `n` tipsets over genesis with their
+// pre-states in the store and metadata agreeing with the chain.
+func setupValidation(t *testing.T, n int) (*SyncerAPI, *chain.Builder, *replayTransition, []*block.TipSet) {
+	ctx := context.Background()
+	builder := chain.NewBuilderWithDeps(t, address.Undef, &heightStateBuilder{}, &chain.ZeroTimestamper{})
+	tipsets := []*block.TipSet{builder.Genesis()}
+	for i := 0; i < n; i++ {
+		tipsets = append(tipsets, builder.AppendOn(tipsets[len(tipsets)-1], 1))
+	}
+
+	rt := &replayTransition{
+		roots: make(map[block.TipSetKey]cid.Cid),
+		pres:  make(map[block.TipSetKey]cid.Cid),
+	}
+	store := builder.Store()
+	for i, ts := range tipsets[1:] {
+		root := types.CidFromString(t, "head")
+		if i+2 < len(tipsets) {
+			root = tipsets[i+2].At(0).ParentStateRoot
+		}
+		rt.roots[ts.Key()] = root
+		require.NoError(t, store.PutTipSetMetadata(ctx, &chain.TipSetMetadata{
+			TipSet:          ts,
+			TipSetStateRoot: root,
+			TipSetReceipts:  emptycid.EmptyReceiptsCID,
+		}))
+		putStateRoot(t, builder, ts.At(0).ParentStateRoot)
+	}
+	require.NoError(t, store.SetHead(ctx, tipsets[n]))
+
+	api := &SyncerAPI{syncer: &SyncerSubmodule{
+		ChainModule: &chain2.ChainSubmodule{
+			ChainReader:  store,
+			MessageStore: builder.Mstore(),
+		},
+		Consensus: rt,
+	}}
+	return api, builder, rt, tipsets
+}
+
+func putStateRoot(t *testing.T, builder *chain.Builder, root cid.Cid) {
+	blk, err := blocks.NewBlockWithCid([]byte(root.String()), root)
+	require.NoError(t, err)
+	require.NoError(t, builder.BlockStore().Put(blk))
+}
+
+func TestChainValidateAgreeingChain(t *testing.T) {
+	tf.UnitTest(t)
+
+	api, _, _, _ := setupValidation(t, 8)
+	res, err := api.ChainValidate(context.Background(), 1, 8, true, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 8, res.Checked)
+	assert.Empty(t, res.Divergences)
+	assert.Equal(t, 0, res.Fixed)
+
+	_, err = api.ChainValidate(context.Background(), 0, 8, false, 2)
+	assert.Error(t, err)
+	_, err = api.ChainValidate(context.Background(), 5, 4, false, 2)
+	assert.Error(t, err)
+
+	// The range is capped at the head.
+	res, err = api.ChainValidate(context.Background(), 7, 100, false, 1)
+	require.NoError(t, err)
+	assert.Equal(t, abi.ChainEpoch(100), res.To)
+	assert.Equal(t, 2, res.Checked)
+}
+
+func TestChainValidateCorruptedMetadata(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	api, builder, rt, tipsets := setupValidation(t, 8)
+	store := builder.Store()
+	corrupt := func(ts *block.TipSet) {
+		require.NoError(t, store.PutTipSetMetadata(ctx, &chain.TipSetMetadata{
+			TipSet:          ts,
+			TipSetStateRoot: types.CidFromString(t, "corrupted"),
+			TipSetReceipts:  emptycid.EmptyReceiptsCID,
+		}))
+	}
+	corrupt(tipsets[3])
+	corrupt(tipsets[8])
+
+	// Without --fix the divergences are only reported.
+	res, err := api.ChainValidate(ctx, 1, 8, false, 2)
+	require.NoError(t, err)
+	require.Len(t, res.Divergences, 2)
+	d := res.Divergences[0]
+	assert.Equal(t, abi.ChainEpoch(3), d.Height)
+	assert.Equal(t, tipsets[3].Key(), d.TipSet)
+	assert.Equal(t, rt.roots[tipsets[3].Key()], d.StateRoot)
+	assert.Equal(t, types.CidFromString(t, "corrupted"), d.StoredStateRoot)
+	assert.Equal(t, tipsets[4].At(0).ParentStateRoot, d.ChainStateRoot)
+	assert.False(t, d.Fixed)
+	assert.Equal(t, 0, res.Fixed)
+	stored, err := store.GetTipSetStateRoot(tipsets[3])
+	require.NoError(t, err)
+	assert.Equal(t, types.CidFromString(t, "corrupted"), stored)
+
+	// With --fix only the metadata confirmed by a child is rewritten, the
+	// head has none.
+	res, err = api.ChainValidate(ctx, 1, 8, true, 2)
+	require.NoError(t, err)
+	require.Len(t, res.Divergences, 2)
+	assert.True(t, res.Divergences[0].Fixed)
+	assert.Equal(t, abi.ChainEpoch(8), res.Divergences[1].Height)
+	assert.False(t, res.Divergences[1].Fixed)
+	assert.Equal(t, 1, res.Fixed)
+	stored, err = store.GetTipSetStateRoot(tipsets[3])
+	require.NoError(t, err)
+	assert.Equal(t, rt.roots[tipsets[3].Key()], stored)
+	stored, err = store.GetTipSetStateRoot(tipsets[8])
+	require.NoError(t, err)
+	assert.Equal(t, types.CidFromString(t, "corrupted"), stored)
+
+	// A state transition the chain disagrees with is never fixed.
+	rt.roots[tipsets[5].Key()] = types.CidFromString(t, "diverged")
+	res, err = api.ChainValidate(ctx, 5, 5, true, 1)
+	require.NoError(t, err)
+	require.Len(t, res.Divergences, 1)
+	assert.False(t, res.Divergences[0].Fixed)
+	stored, err = store.GetTipSetStateRoot(tipsets[5])
+	require.NoError(t, err)
+	assert.Equal(t, tipsets[6].At(0).ParentStateRoot, stored)
+}
+
+func TestChainValidateUndefinedRoot(t *testing.T) {
+	tf.UnitTest(t)
+
+	api, _, rt, tipsets := setupValidation(t, 4)
+	rt.roots[tipsets[2].Key()] = cid.Undef
+	_, err := api.ChainValidate(context.Background(), 1, 4, true, 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "computed no state root")
+}
+
+func TestChainValidateMissingPreState(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	api, builder, rt, tipsets := setupValidation(t, 8)
+	require.NoError(t, builder.BlockStore().DeleteBlock(tipsets[5].At(0).ParentStateRoot))
+
+	// One tipset per chunk, the chunk of 5 is merged in the chunk of 4 so it
+	// executes on the state computed for 4.
+	res, err := api.ChainValidate(ctx, 1, 8, false, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 8, res.Checked)
+	assert.Empty(t, res.Divergences)
+	for _, ts := range tipsets[1:] {
+		assert.Equal(t, ts.At(0).ParentStateRoot, rt.pres[ts.Key()], "height %d", ts.EnsureHeight())
+	}
+
+	// The pre-state of the first tipset is required.
+	_, err = api.ChainValidate(ctx, 5, 8, false, 4)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "pre-state of height 5 is not in the store")
+}
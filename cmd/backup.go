@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+
+	"github.com/filecoin-project/venus/app/node"
+	"github.com/filecoin-project/venus/app/paths"
+	"github.com/filecoin-project/venus/pkg/repo"
+)
+
+var backupCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Back up and restore the state of the node that cannot be synced.",
+		ShortDescription: `
+A backup holds the config, the keystore, the api token, the wallet, the local
+messages of the message pool and the slash filter records, with checksums. The
+chain is not included, import it from a snapshot after restoring.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"create":  backupCreateCmd,
+		"restore": backupRestoreCmd,
+	},
+}
+
+var backupCreateCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Write a backup of the running node to a file.",
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("path", true, false, "file to write the backup to, on the machine of the daemon"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		path, err := filepath.Abs(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		if err := env.(*node.Env).BackupAPI.Backup(req.Context, path); err != nil {
+			return err
+		}
+		return re.Emit(fmt.Sprintf("backup written to %s\n", path))
+	},
+}
+
+var backupRestoreCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Restore a backup into a new repo.",
+		ShortDescription: `
+Restores the backup into the repo directory, which must not exist or be empty.
+Start the daemon with --import-snapshot to import the chain afterwards.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("path", true, false, "backup file to restore"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		repoDir, _ := req.Options[OptionRepoDir].(string)
+		repoDir, err := paths.GetRepoPath(repoDir)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close() // nolint: errcheck
+
+		manifest, err := repo.RestoreBackup(f, repoDir)
+		if err != nil {
+			return err
+		}
+		return re.Emit(fmt.Sprintf("restored backup of %s (repo version %d) to %s\n", manifest.Created.Format("2006-01-02 15:04:05"), manifest.RepoVersion, repoDir))
+	},
+}
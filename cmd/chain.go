@@ -4,6 +4,8 @@ package cmd
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
+	"runtime"
 	"time"
 
 	"github.com/filecoin-project/go-address"
@@ -15,8 +17,11 @@ import (
 
 	"github.com/filecoin-project/venus/app/node"
 	"github.com/filecoin-project/venus/app/submodule/chain"
+	"github.com/filecoin-project/venus/cmd/tablewriter"
 	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/specactors/builtin"
 	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/vm/gas"
 )
 
 var chainCmd = &cmds.Command{
@@ -24,10 +29,12 @@ var chainCmd = &cmds.Command{
 		Tagline: "Inspect the filecoin blockchain",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"head":     chainHeadCmd,
-		"ls":       chainLsCmd,
-		"set-head": chainSetHeadCmd,
-		"getblock": chainGetBlockCmd,
+		"head":        chainHeadCmd,
+		"ls":          chainLsCmd,
+		"set-head":    chainSetHeadCmd,
+		"getblock":    chainGetBlockCmd,
+		"gas-profile": chainGasProfileCmd,
+		"validate":    chainValidateCmd,
 	},
 }
 
@@ -240,3 +247,155 @@ func apiMsgCids(in []chain.Message) []cid.Cid {
 	}
 	return out
 }
+
+var chainGasProfileCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Profile the gas charged by the vm over a range of heights",
+		ShortDescription: `
+Re-executes the tipsets from --from to --to with the gas profiling of the vm
+enabled and aggregates the charges by actor code, method number and charge
+name, with the wall-clock time spent from each charge to the next one.
+
+The report is a table sorted by gas, or with --pprof a gzipped pprof profile
+written to stdout, e.g. venus chain gas-profile --from 100 --to 200 --pprof > gas.pb.gz
+`,
+	},
+	Options: []cmds.Option{
+		cmds.Int64Option("from", "first height to execute"),
+		cmds.Int64Option("to", "last height to execute, the head height by default").WithDefault(int64(-1)),
+		cmds.BoolOption("pprof", "output a pprof profile instead of a table"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		from, ok := req.Options["from"].(int64)
+		if !ok {
+			return xerrors.New("--from is required")
+		}
+		to, _ := req.Options["to"].(int64)
+		if to < 0 {
+			head, err := env.(*node.Env).ChainAPI.ChainHead(req.Context)
+			if err != nil {
+				return err
+			}
+			to = int64(head.EnsureHeight())
+		}
+
+		entries, err := env.(*node.Env).SyncerAPI.ChainGasProfile(req.Context, abi.ChainEpoch(from), abi.ChainEpoch(to))
+		if err != nil {
+			return err
+		}
+
+		buf := new(bytes.Buffer)
+		if asPprof, _ := req.Options["pprof"].(bool); asPprof {
+			if err := gas.WritePprof(buf, entries, builtin.ActorNameByCode); err != nil {
+				return err
+			}
+			return re.Emit(buf)
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("Actor"),
+			tablewriter.Col("Method"),
+			tablewriter.Col("Charge"),
+			tablewriter.Col("Count"),
+			tablewriter.Col("TotalGas"),
+			tablewriter.Col("ComputeGas"),
+			tablewriter.Col("StorageGas"),
+			tablewriter.Col("Time"),
+			tablewriter.Col("GasPerMicrosecond"))
+		for _, e := range entries {
+			actor := "vm"
+			if e.Code.Defined() {
+				actor = builtin.ActorNameByCode(e.Code)
+			}
+			var rate interface{} = "-"
+			if us := e.Time.Microseconds(); us > 0 {
+				rate = e.TotalGas / us
+			}
+			tw.Write(map[string]interface{}{
+				"Actor":             actor,
+				"Method":            e.Method,
+				"Charge":            e.Charge,
+				"Count":             e.Count,
+				"TotalGas":          e.TotalGas,
+				"ComputeGas":        e.ComputeGas,
+				"StorageGas":        e.StorageGas,
+				"Time":              e.Time.Round(time.Microsecond),
+				"GasPerMicrosecond": rate,
+			})
+		}
+		if err := tw.Flush(buf); err != nil {
+			return err
+		}
+		return re.Emit(buf)
+	},
+}
+
+var chainValidateCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Re-execute a range of heights and check the roots",
+		ShortDescription: `
+Re-executes the tipsets from --from to --to and compares the state and receipts
+roots computed with the metadata stored for each tipset and with the parent
+roots in the header of the next tipset on chain. For each divergence the first
+message whose receipt differs from the receipts on chain is reported.
+
+With --fix, the stored metadata that disagrees is rewritten when the next
+tipset confirms the computed roots, so never for the head. The tipsets whose
+pre-state is in the store are executed in --parallel chunks.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.Int64Option("from", "first height to execute"),
+		cmds.Int64Option("to", "last height to execute, the head height by default").WithDefault(int64(-1)),
+		cmds.BoolOption("fix", "rewrite the stored metadata confirmed by the chain"),
+		cmds.IntOption("parallel", "number of chunks executed at the same time").WithDefault(runtime.NumCPU()),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		from, ok := req.Options["from"].(int64)
+		if !ok {
+			return xerrors.New("--from is required")
+		}
+		to, _ := req.Options["to"].(int64)
+		if to < 0 {
+			head, err := env.(*node.Env).ChainAPI.ChainHead(req.Context)
+			if err != nil {
+				return err
+			}
+			to = int64(head.EnsureHeight())
+		}
+		fix, _ := req.Options["fix"].(bool)
+		parallel, _ := req.Options["parallel"].(int)
+
+		res, err := env.(*node.Env).SyncerAPI.ChainValidate(req.Context, abi.ChainEpoch(from), abi.ChainEpoch(to), fix, parallel)
+		if err != nil {
+			return err
+		}
+
+		buf := new(bytes.Buffer)
+		writer := NewSilentWriter(buf)
+		writer.Printf("checked %d tipsets from %d to %d: %d divergences, %d fixed\n", res.Checked, res.From, res.To, len(res.Divergences), res.Fixed)
+		for _, d := range res.Divergences {
+			writer.Println()
+			writer.Printf("height %d, tipset %s", d.Height, d.TipSet)
+			if d.Fixed {
+				writer.Printf(" (fixed)")
+			}
+			writer.Println()
+			writer.Printf("  state root:    computed %s, stored %s, chain %s\n", d.StateRoot, d.StoredStateRoot, d.ChainStateRoot)
+			writer.Printf("  receipts root: computed %s, stored %s, chain %s\n", d.ReceiptsRoot, d.StoredReceiptsRoot, d.ChainReceiptsRoot)
+			if m := d.FirstMismatch; m != nil {
+				writer.Printf("  first mismatch: message %d %s\n", m.Index, m.Message)
+				writer.Printf("    computed: %s\n", formatReceipt(m.Receipt))
+				writer.Printf("    chain:    %s\n", formatReceipt(m.ChainReceipt))
+			}
+		}
+		return re.Emit(buf)
+	},
+}
+
+func formatReceipt(r *types.MessageReceipt) string {
+	if r == nil {
+		return "none"
+	}
+	return fmt.Sprintf("exit %d, gas used %d, return %x", r.ExitCode, r.GasUsed, r.ReturnValue)
+}
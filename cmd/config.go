@@ -1,10 +1,14 @@
 package cmd
 
 import (
-	"github.com/filecoin-project/venus/app/node"
+	"bytes"
+	"encoding/json"
 	"strings"
 
 	cmds "github.com/ipfs/go-ipfs-cmds"
+
+	"github.com/filecoin-project/venus/app/node"
+	"github.com/filecoin-project/venus/cmd/tablewriter"
 )
 
 var configCmd = &cmds.Command{
@@ -23,7 +27,11 @@ separated by a space, like so:
 venus config KEY VALUE
 
 The key should be specified as a period separated string of keys. The value may
-be either a bare string or any valid json compatible with the given key.`,
+be either a bare string or any valid json compatible with the given key.
+
+Without a key, all the fields are listed with their unit, and the fields whose
+changes only apply after a restart of the daemon are marked. Other fields apply
+right away. Values are validated, invalid values are rejected.`,
 		LongDescription: `
 venus config controls configuration variables. The configuration values
 are stored as a JSON config file in your filecoin repo. When using venus
@@ -70,11 +78,14 @@ $ venus config bootstrap
 `,
 	},
 	Arguments: []cmds.Argument{
-		cmds.StringArg("key", true, false, "The key of the config entry (e.g. \"api.address\")"),
+		cmds.StringArg("key", false, false, "The key of the config entry (e.g. \"api.address\"), all fields are listed when omitted"),
 		cmds.StringArg("value", false, false, "Optionally, a value with which to set the config entry"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		api := env.(*node.Env).ConfigAPI
+		if len(req.Arguments) == 0 {
+			return listConfig(re, env.(*node.Env))
+		}
 		key := req.Arguments[0]
 		var value string
 
@@ -100,3 +111,38 @@ $ venus config bootstrap
 		return re.Emit(res)
 	},
 }
+
+func listConfig(re cmds.ResponseEmitter, env *node.Env) error {
+	api := env.ConfigAPI
+	tw := tablewriter.New(
+		tablewriter.Col("Key"),
+		tablewriter.Col("Value"),
+		tablewriter.Col("Unit"),
+		tablewriter.Col("Applies"))
+	for _, field := range api.ConfigSchema() {
+		v, err := api.ConfigGet(field.Key)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		applies := "now"
+		if field.Restart {
+			applies = "on restart"
+		}
+		tw.Write(map[string]interface{}{
+			"Key":     field.Key,
+			"Value":   string(value),
+			"Unit":    field.Unit,
+			"Applies": applies,
+		})
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tw.Flush(buf); err != nil {
+		return err
+	}
+	return re.Emit(buf)
+}
@@ -62,15 +62,17 @@ func TestConfigDaemon(t *testing.T) {
 		n, cmdClient, done := builder.BuildAndStartAPI(ctx)
 		defer done()
 
-		cmdClient.RunSuccess(ctx, "config", "bootstrap", `{"addresses": ["fake1", "fake2"], "period": "1m", "minPeerThreshold": 0}`)
+		fake1 := "/ip4/127.0.0.1/tcp/1/p2p/12D3KooWCVe8MmsEMes2FzgTpt9fXtmCY7wrq91GRiaC8PHSCCBj"
+		fake2 := "/ip4/127.0.0.1/tcp/2/p2p/12D3KooWCVe8MmsEMes2FzgTpt9fXtmCY7wrq91GRiaC8PHSCCBj"
+		cmdClient.RunSuccess(ctx, "config", "bootstrap", `{"addresses": ["`+fake1+`", "`+fake2+`"], "period": "1m", "minPeerThreshold": 0}`)
 
 		var bootstrapConfig config.BootstrapConfig
 		cmdClient.RunMarshaledJSON(ctx, &bootstrapConfig, "config", "bootstrap")
 
 		// validate output
 		require.Len(t, bootstrapConfig.Addresses, 2)
-		assert.Equal(t, "fake1", bootstrapConfig.Addresses[0])
-		assert.Equal(t, "fake2", bootstrapConfig.Addresses[1])
+		assert.Equal(t, fake1, bootstrapConfig.Addresses[0])
+		assert.Equal(t, fake2, bootstrapConfig.Addresses[1])
 
 		// validate config write
 		nbci, err := n.ConfigModule().API().ConfigGet("bootstrap")
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strings"
 
 	blockstore "github.com/ipfs/go-ipfs-blockstore"
 	cmds "github.com/ipfs/go-ipfs-cmds"
@@ -29,6 +30,8 @@ import (
 	"github.com/filecoin-project/venus/pkg/genesis"
 	"github.com/filecoin-project/venus/pkg/journal"
 	"github.com/filecoin-project/venus/pkg/repo"
+	"github.com/filecoin-project/venus/pkg/util/ffiwrapper"
+	"github.com/filecoin-project/venus/pkg/version"
 	gengen "github.com/filecoin-project/venus/tools/gengen/util"
 )
 
@@ -51,11 +54,13 @@ var daemonCmd = &cmds.Command{
 		cmds.BoolOption(OfflineMode, "start the node without networking"),
 		cmds.BoolOption(ELStdout),
 		cmds.BoolOption(IsRelay, "advertise and allow venus network traffic to be relayed through this node"),
+		cmds.BoolOption(MockProofs, "verify proofs with a mock verifier, only for localnet devnets mined by 'venus mining'"),
 		cmds.StringOption(ImportSnapshot, "import chain state from a given chain export file or url"),
 		cmds.StringOption(GenesisFile, "path of file or HTTP(S) URL containing archive of genesis block DAG data"),
 		cmds.StringOption(PeerKeyFile, "path of file containing key to use for new node's libp2p identity"),
 		cmds.StringOption(WalletKeyFile, "path of file containing keys to import into the wallet on initialization"),
 		cmds.StringOption(Network, "when set, populates config with network specific parameters").WithDefault("testnetnet"),
+		cmds.BoolOption(MigrateRepo, "migrate an outdated repo without prompting"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
 		repoDir, _ := req.Options[OptionRepoDir].(string)
@@ -81,6 +86,11 @@ var daemonCmd = &cmds.Command{
 			if err = initRun(req); err != nil {
 				return err
 			}
+		} else {
+			autoMigrate, _ := req.Options[MigrateRepo].(bool)
+			if err := migrateRepoIfNeeded(repoDir, autoMigrate); err != nil {
+				return err
+			}
 		}
 
 		return daemonRun(req, re)
@@ -170,6 +180,12 @@ func daemonRun(req *cmds.Request, re cmds.ResponseEmitter) error {
 		config.Swarm.PublicRelayAddress = publicRelayAddress
 	}
 
+	// values written before the schema existed may not pass it, they are
+	// reported but the daemon still starts
+	if err := config.Validate(); err != nil {
+		log.Warnf("%s, correct it with 'venus config'", err)
+	}
+
 	opts, err := node.OptionsFromRepo(rep)
 	if err != nil {
 		return err
@@ -182,6 +198,10 @@ func daemonRun(req *cmds.Request, re cmds.ResponseEmitter) error {
 	if isRelay, ok := req.Options[IsRelay].(bool); ok && isRelay {
 		opts = append(opts, node.IsRelay())
 	}
+
+	if mockProofs, ok := req.Options[MockProofs].(bool); ok && mockProofs {
+		opts = append(opts, node.VerifierConfigOption(&ffiwrapper.FakeVerifier{}))
+	}
 	importPath, _ := req.Options[ImportSnapshot].(string)
 	if len(importPath) != 0 {
 		err := Import(rep, importPath)
@@ -191,11 +211,11 @@ func daemonRun(req *cmds.Request, re cmds.ResponseEmitter) error {
 		}
 	}
 
-	journal, err := journal.NewZapJournal(rep.JournalPath())
+	jrnl, err := journal.OpenFSJournal(rep.JournalPath(), journal.EnvDisabledEvents())
 	if err != nil {
 		return err
 	}
-	opts = append(opts, node.JournalConfigOption(journal))
+	opts = append(opts, node.JournalConfigOption(jrnl))
 
 	// Monkey-patch network parameters option will set package variables during node build
 	opts = append(opts, node.MonkeyPatchNetworkParamsOption(config.NetworkParams))
@@ -206,6 +226,11 @@ func daemonRun(req *cmds.Request, re cmds.ResponseEmitter) error {
 		return err
 	}
 
+	// mock proofs would let anyone forge blocks on a public network.
+	if mockProofs, _ := req.Options[MockProofs].(bool); mockProofs && !isDevnet(fcn.Network().NetworkName) {
+		return xerrors.Errorf("--%s is only allowed on a devnet, this node runs %s", MockProofs, fcn.Network().NetworkName)
+	}
+
 	if fcn.OfflineMode() {
 		_ = re.Emit("Filecoin node running in offline mode (libp2p is disabled)\n")
 	} else {
@@ -241,6 +266,12 @@ func daemonRun(req *cmds.Request, re cmds.ResponseEmitter) error {
 	return fcn.RunRPCAndWait(req.Context, RootCmdDaemon, ready)
 }
 
+// isDevnet reports whether `networkName` is a local devnet, as named by
+// genesis templates, or the network of the tests.
+func isDevnet(networkName string) bool {
+	return networkName == version.TEST || strings.HasPrefix(networkName, "localnet")
+}
+
 func getRepo(req *cmds.Request) (repo.Repo, error) {
 	repoDir, _ := req.Options[OptionRepoDir].(string)
 	repoDir, err := paths.GetRepoPath(repoDir)
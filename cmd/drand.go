@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"bytes"
+	"time"
+
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/venus/app/node"
 	cmds "github.com/ipfs/go-ipfs-cmds"
@@ -14,6 +17,7 @@ var drandCmd = &cmds.Command{
 
 	Subcommands: map[string]*cmds.Command{
 		"random": drandRandom,
+		"status": drandStatus,
 	},
 }
 
@@ -36,3 +40,44 @@ var drandRandom = &cmds.Command{
 		return re.Emit(entry)
 	},
 }
+
+var drandStatus = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Show where drand rounds come from and the last verified round",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		statuses, err := env.(*node.Env).ChainAPI.BeaconStatus(req.Context)
+		if err != nil {
+			return err
+		}
+
+		buf := new(bytes.Buffer)
+		writer := NewSilentWriter(buf)
+		for i, status := range statuses {
+			if i > 0 {
+				writer.Println()
+			}
+			writer.Printf("Chain: %s (from epoch %d)\n", status.ChainHash, status.Start)
+			writer.Printf("Servers: %v\n", status.Servers)
+			if status.PubsubTopic != "" {
+				writer.Printf("Gossipsub: %s\n", status.PubsubTopic)
+			} else {
+				writer.Println("Gossipsub: disabled")
+			}
+			writer.Printf("Persistent: %t\n", status.Persistent)
+			if status.LastSource != "" {
+				writer.Printf("Last source: %s\n", status.LastSource)
+			}
+			if status.LastLatency > 0 {
+				writer.Printf("Last fetch latency: %s\n", status.LastLatency.Truncate(time.Millisecond))
+			}
+			if status.LastVerifiedRound > 0 {
+				writer.Printf("Last verified round: %d (%s ago)\n", status.LastVerifiedRound, time.Since(status.LastVerifiedAt).Truncate(time.Second))
+			} else {
+				writer.Println("Last verified round: none")
+			}
+		}
+
+		return re.Emit(buf)
+	},
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+
+	"github.com/filecoin-project/venus/app/node"
+	"github.com/filecoin-project/venus/pkg/journal"
+)
+
+var journalCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Inspect the event journal.",
+		ShortDescription: `
+The journal records head changes and reorgs, sync target outcomes, message pool
+additions and removals, mined blocks and peer connections to rotating files in
+the journal directory of the repo. Events are named <system>:<event>, they can
+be disabled with VENUS_JOURNAL_DISABLED_EVENTS, e.g. "mpool:add,mpool:remove".
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"query": journalQueryCmd,
+		"tail":  journalTailCmd,
+	},
+}
+
+var journalFilterOptions = []cmds.Option{
+	cmds.StringOption("system", "only show the events of this system, e.g. chain"),
+	cmds.StringOption("event", "only show the events of this name, e.g. reorg"),
+}
+
+func journalFilter(req *cmds.Request) journal.EventFilter {
+	system, _ := req.Options["system"].(string)
+	event, _ := req.Options["event"].(string)
+	return journal.EventFilter{System: system, Event: event}
+}
+
+var journalQueryCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Print the journaled events.",
+	},
+	Options: append([]cmds.Option{
+		cmds.StringOption("since", "only show the events of this last duration, e.g. 2h"),
+		cmds.IntOption("limit", "only show the latest events").WithDefault(100),
+	}, journalFilterOptions...),
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		filter := journalFilter(req)
+		if since, _ := req.Options["since"].(string); since != "" {
+			d, err := time.ParseDuration(since)
+			if err != nil {
+				return fmt.Errorf("invalid since duration %s: %w", since, err)
+			}
+			filter.Since = time.Now().Add(-d)
+		}
+		filter.Limit, _ = req.Options["limit"].(int)
+
+		evts, err := env.(*node.Env).JournalAPI.JournalQuery(req.Context, filter)
+		if err != nil {
+			return err
+		}
+
+		buf := new(bytes.Buffer)
+		writer := NewSilentWriter(buf)
+		for _, evt := range evts {
+			writer.Println(formatJournalEvent(evt))
+		}
+		return re.Emit(buf)
+	},
+}
+
+var journalTailCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Print the journaled events as they are recorded.",
+	},
+	Options: append([]cmds.Option{
+		cmds.IntOption("lines", "number of past events to print first").WithDefault(10),
+	}, journalFilterOptions...),
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		api := env.(*node.Env).JournalAPI
+		filter := journalFilter(req)
+
+		// subscribe first so that no event is missed between the two calls
+		tail, err := api.JournalTail(req.Context, filter)
+		if err != nil {
+			return err
+		}
+
+		if lines, _ := req.Options["lines"].(int); lines > 0 {
+			past := filter
+			past.Limit = lines
+			evts, err := api.JournalQuery(req.Context, past)
+			if err != nil {
+				return err
+			}
+			for _, evt := range evts {
+				if err := re.Emit(formatJournalEvent(evt) + "\n"); err != nil {
+					return err
+				}
+			}
+		}
+
+		for {
+			select {
+			case evt, ok := <-tail:
+				if !ok {
+					return nil
+				}
+				if err := re.Emit(formatJournalEvent(evt) + "\n"); err != nil {
+					return err
+				}
+			case <-req.Context.Done():
+				return nil
+			}
+		}
+	},
+}
+
+func formatJournalEvent(evt *journal.Event) string {
+	data, err := json.Marshal(evt.Data)
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", evt.Data))
+	}
+	return fmt.Sprintf("%s %s %s", evt.Timestamp.Format("2006-01-02T15:04:05.000"), evt.EventType, data)
+}
@@ -7,6 +7,7 @@ import (
 	fbig "github.com/filecoin-project/go-state-types/big"
 	"io"
 	"os"
+	"strings"
 
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	"github.com/ipfs/go-ipfs-cmds/cli"
@@ -80,6 +81,10 @@ const (
 	Size = "size"
 
 	ImportSnapshot = "import-snapshot"
+
+	// MockProofs makes the daemon accept mock proofs, so that devnets can be
+	// mined with `venus mining` without sealing sectors.
+	MockProofs = "mock-proofs"
 )
 
 func init() {
@@ -102,12 +107,14 @@ START RUNNING FILECOIN
   venus config <key> [<value>] - Get and set filecoin config values
   venus daemon                 - Start a long-running daemon process
   venus wallet                 - Manage your filecoin wallets
+  venus backup                 - Back up and restore the node state
 
 VIEW DATA STRUCTURES
   venus chain                  - Inspect the filecoin blockchain
   venus sync 				   - Inspect the filecoin Sync
   venus dag                    - Interact with IPLD DAG objects
   venus show                   - Get human-readable representations of filecoin objects
+  venus journal tail           - Follow the event journal
 
 NETWORK COMMANDS
   venus swarm                  - Interact with the swarm
@@ -117,6 +124,11 @@ MESSAGE COMMANDS
   venus send                   - Send message
   venus mpool                  - Manage the message pool
 
+MINING COMMANDS
+  venus mining                 - Mine blocks on a devnet
+  venus miner check-win        - Check miner eligibility and election result
+  venus slash list             - List reported consensus faults
+
 State COMMANDS
   venus wait-msg               - Wait for a message to appear on chain
   venus search-msg             - Search to see whether a message has appeared on chain
@@ -137,6 +149,8 @@ TOOL COMMANDS
   venus leb128                 - Leb128 cli encode/decode
   venus log                    - Interact with the daemon event log output
   venus protocol               - Show protocol parameter details
+  venus repo migrate           - Upgrade the repo to the version of this binary
+  venus repo convert           - Copy the repo datastores to another backend
   venus version                - Show venus version information
 `,
 	},
@@ -161,6 +175,7 @@ var rootSubcmdsLocal = map[string]*cmds.Command{
 	"fetch":   fetchCmd,
 	"version": versionCmd,
 	"leb128":  leb128Cmd,
+	"repo":    repoCmd,
 }
 
 // all top level commands, available on daemon. set during init() to avoid configuration loops.
@@ -171,6 +186,8 @@ var rootSubcmdsDaemon = map[string]*cmds.Command{
 	"drand":    drandCmd,
 	"dag":      dagCmd,
 	"inspect":  inspectCmd,
+	"journal":  journalCmd,
+	"backup":   backupCmd,
 	"leb128":   leb128Cmd,
 	"log":      logCmd,
 	"send":     msgSendCmd,
@@ -182,6 +199,8 @@ var rootSubcmdsDaemon = map[string]*cmds.Command{
 	"version":  versionCmd,
 	"state":    stateCmd,
 	"miner":    minerCmd,
+	"mining":   miningCmd,
+	"slash":    slashCmd,
 }
 
 func init() {
@@ -287,12 +306,23 @@ func getAPIAddress(req *cmds.Request) (string, error) {
 	return host, nil
 }
 
+// localSubcmdPaths are the subcommands of daemon commands that run without the daemon.
+var localSubcmdPaths = [][]string{
+	{"backup", "restore"},
+	{"state", "migrate"},
+}
+
 func requiresDaemon(req *cmds.Request) bool {
 	for cmd := range rootSubcmdsLocal {
 		if len(req.Path) > 0 && req.Path[0] == cmd {
 			return false
 		}
 	}
+	for _, path := range localSubcmdPaths {
+		if len(req.Path) == len(path) && strings.Join(req.Path, " ") == strings.Join(path, " ") {
+			return false
+		}
+	}
 	return true
 }
 
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/network"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/venus/app/submodule/chain/cst"
+	"github.com/filecoin-project/venus/pkg/chain"
+	"github.com/filecoin-project/venus/pkg/consensus"
+	"github.com/filecoin-project/venus/pkg/fork"
+	"github.com/filecoin-project/venus/pkg/repo"
+	"github.com/filecoin-project/venus/pkg/util/ffiwrapper"
+	"github.com/filecoin-project/venus/pkg/vm/gas"
+	"github.com/filecoin-project/venus/pkg/vm/register"
+)
+
+// dryRunMigration rehearses the upgrade to `nv` on the chain of `rep`, the
+// daemon must not be running on it. The repo is only read, the migrated state
+// and the tipset executed on it are written to a scratch store.
+func dryRunMigration(ctx context.Context, rep repo.Repo, root cid.Cid, nv network.Version) (*fork.MigrationDryRun, error) {
+	raw, err := rep.ChainDatastore().Get(chain.GenesisKey)
+	if err != nil {
+		return nil, xerrors.Errorf("failed to read the genesis cid, is the repo initialized? %w", err)
+	}
+	var genesis cid.Cid
+	if err := json.Unmarshal(raw, &genesis); err != nil {
+		return nil, xerrors.Errorf("failed to decode the genesis cid: %w", err)
+	}
+
+	params := rep.Config().NetworkParams
+	bs := rep.Datastore()
+	ipldStore := cbor.NewCborStore(bs)
+	store := chain.NewStore(rep.ChainDatastore(), ipldStore, bs, chain.NewStatusReporter(), params.ForkUpgradeParam, genesis, nil)
+	if err := store.Load(ctx); err != nil {
+		return nil, xerrors.Errorf("failed to load the chain: %w", err)
+	}
+	defer store.Stop()
+	messages := chain.NewMessageStore(bs)
+	state := cst.NewChainStateReadWriter(store, messages, bs, register.DefaultActors, nil)
+	chainFork, err := fork.NewChainFork(state, ipldStore, bs, params.ForkUpgradeParam)
+	if err != nil {
+		return nil, err
+	}
+
+	// The tipset above the upgrade is executed as the node does, on the
+	// migrated root.
+	expected := consensus.NewExpected(ipldStore,
+		bs,
+		time.Duration(params.BlockDelay)*time.Second,
+		store,
+		state,
+		messages,
+		chainFork,
+		params,
+		gas.NewPricesSchedule(params.ForkUpgradeParam),
+		ffiwrapper.ProofVerifier,
+		nil,
+	)
+	return fork.DryRunMigration(ctx, state, bs, params.ForkUpgradeParam, root, nv, expected.ExecuteMigrated)
+}
@@ -36,10 +36,11 @@ var minerCmd = &cmds.Command{
 		Tagline: "Interact with actors. Actors are built-in smart contracts.",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"new":     newMinerCmd,
-		"info":    minerInfoCmd,
-		"actor":   minerActorCmd,
-		"proving": minerProvingCmd,
+		"new":       newMinerCmd,
+		"info":      minerInfoCmd,
+		"actor":     minerActorCmd,
+		"proving":   minerProvingCmd,
+		"check-win": minerCheckWinCmd,
 	},
 }
 
@@ -377,3 +378,124 @@ var minerInfoCmd = &cmds.Command{
 		return re.Emit(buf)
 	},
 }
+
+var minerCheckWinCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Check whether a miner is eligible and wins the election at an epoch.",
+		ShortDescription: `
+Prints the power, eligibility and election result of a miner for a block at
+--epoch, by default the epoch after the head. The ticket and election proof are
+only computed when the worker key of the miner is in the local wallet.
+
+With --range the last <range> epochs are checked and the expected win rate is
+compared with the blocks the miner actually produced.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("address", true, false, "Address of miner to check"),
+	},
+	Options: []cmds.Option{
+		cmds.Int64Option("epoch", "Epoch to check, defaults to the epoch after the head"),
+		cmds.Uint64Option("range", "Number of past epochs to check"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		maddr, err := address.NewFromString(req.Arguments[0])
+		if err != nil {
+			return err
+		}
+
+		ctx := req.Context
+		chainAPI := env.(*node.Env).ChainAPI
+		miningAPI := env.(*node.Env).MingingAPI
+
+		head, err := chainAPI.ChainHead(ctx)
+		if err != nil {
+			return err
+		}
+
+		buf := new(bytes.Buffer)
+		writer := NewSilentWriter(buf)
+
+		if span, ok := req.Options["range"].(uint64); ok && span > 0 {
+			from := head.EnsureHeight() - abi.ChainEpoch(span) + 1
+			if from < 1 {
+				from = 1
+			}
+
+			var expected float64
+			var computed, actual, checked, unknown int64
+			for epoch := from; epoch <= head.EnsureHeight(); epoch++ {
+				report, err := miningAPI.MinerCheckEligibility(ctx, maddr, epoch)
+				if err != nil {
+					return xerrors.Errorf("checking epoch %d: %w", epoch, err)
+				}
+				checked++
+				if report.HasMinPower {
+					expected += report.ExpectedWinCount
+				}
+				if report.ElectionProof == nil {
+					unknown++
+				} else if report.HasMinPower {
+					computed += report.WinCount
+				}
+
+				ts, err := chainAPI.ChainGetTipSetByHeight(ctx, epoch, head.Key())
+				if err != nil {
+					return xerrors.Errorf("loading tipset at %d: %w", epoch, err)
+				}
+				if ts.EnsureHeight() != epoch {
+					continue
+				}
+				for _, blk := range ts.Blocks() {
+					if blk.Miner == maddr {
+						actual++
+					}
+				}
+			}
+
+			writer.Printf("Miner: %s\n", maddr)
+			writer.Printf("Epochs: %d - %d (%d)\n", from, head.EnsureHeight(), checked)
+			writer.Printf("Expected wins: %.4f (%.4f/epoch)\n", expected, expected/float64(checked))
+			if unknown > 0 {
+				writer.Printf("Computed wins: unknown for %d epochs, worker key is not in the local wallet\n", unknown)
+			} else {
+				writer.Printf("Computed wins: %d\n", computed)
+			}
+			writer.Printf("Blocks on chain: %d (%.4f/epoch)\n", actual, float64(actual)/float64(checked))
+			return re.Emit(buf)
+		}
+
+		epoch := head.EnsureHeight() + 1
+		if e, ok := req.Options["epoch"].(int64); ok {
+			epoch = abi.ChainEpoch(e)
+		}
+
+		report, err := miningAPI.MinerCheckEligibility(ctx, maddr, epoch)
+		if err != nil {
+			return err
+		}
+
+		writer.Printf("Miner: %s\n", report.Miner)
+		writer.Printf("Epoch: %d\n", report.Epoch)
+		writer.Printf("Base: %s\n", report.Base)
+		writer.Printf("Power: %s / %s\n", crypto.DeciStr(report.MinerPower), crypto.DeciStr(report.NetworkPower))
+		writer.Printf("Raw: %s\n", crypto.SizeStr(report.RawPower))
+		writer.Printf("Has min power: %t\n", report.HasMinPower)
+		writer.Printf("Fee debt: %s\n", types.FIL(report.FeeDebt).Short())
+		writer.Printf("Eligible to mine: %t\n", report.EligibleToMine)
+		writer.Printf("Winning PoSt sectors: %v\n", report.EligibleSectors)
+		writer.Printf("Beacon round: %d\n", report.BeaconRound)
+		writer.Printf("Expected win count: %.4f\n", report.ExpectedWinCount)
+		if report.Ticket != nil {
+			writer.Printf("Ticket: %x\n", report.Ticket.VRFProof)
+		}
+		if report.ElectionProof != nil {
+			writer.Printf("Win count: %d\n", report.WinCount)
+		}
+		if report.Note != "" {
+			writer.Printf("Note: %s\n", report.Note)
+		}
+
+		return re.Emit(buf)
+	},
+}
@@ -0,0 +1,64 @@
+package cmd_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/app/node/test"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+func TestMinerCheckWin(t *testing.T) {
+	tf.IntegrationTest(t)
+	ctx := context.Background()
+
+	seed, cfg, chainClk := test.CreateBootstrapSetup(t)
+	n := test.CreateBootstrapMiner(ctx, t, seed, chainClk, cfg)
+	defer n.Stop(ctx)
+	cmdClient, apiDone := test.RunNodeAPI(ctx, n, t)
+	defer apiDone()
+
+	t.Run("an actor without a power claim has no power", func(t *testing.T) {
+		out := cmdClient.RunSuccess(ctx, "miner", "check-win", seed.Addr(t, 0).String()).ReadStdout()
+		assert.Contains(t, out, "Has min power: false")
+		assert.Contains(t, out, "Eligible to mine: false")
+		assert.Contains(t, out, "Note: miner has no power claim at the lookback epoch")
+	})
+
+	miner, _ := seed.GiveMiner(t, n, 0)
+	t.Run("the genesis miner is eligible and runs the election", func(t *testing.T) {
+		out := cmdClient.RunSuccess(ctx, "miner", "check-win", miner.String()).ReadStdout()
+		assert.Contains(t, out, "Has min power: true")
+		assert.Contains(t, out, "Eligible to mine: true")
+		assert.Contains(t, out, "Win count: ")
+		assert.NotContains(t, out, "Note: ")
+	})
+
+	t.Run("a range compares the wins with the blocks mined", func(t *testing.T) {
+		cmdClient.RunSuccess(ctx, "mining", "once")
+		require.Eventually(t, func() bool {
+			return n.Chain().ChainReader.GetHead().EnsureHeight() > 0
+		}, 30*time.Second, 100*time.Millisecond)
+		head := n.Chain().ChainReader.GetHead().EnsureHeight()
+
+		out := cmdClient.RunSuccess(ctx, "miner", "check-win", miner.String(), "--range", "1").ReadStdout()
+		assert.Contains(t, out, fmt.Sprintf("Epochs: %d - %d (1)", head, head))
+		assert.Contains(t, out, "Computed wins: ")
+		assert.NotContains(t, out, "unknown")
+		assert.Contains(t, out, "Blocks on chain: 1 (1.0000/epoch)")
+	})
+
+	t.Run("a miner created after the lookback does not exist", func(t *testing.T) {
+		unknown, err := address.NewIDAddress(999999)
+		require.NoError(t, err)
+		out := cmdClient.RunSuccess(ctx, "miner", "check-win", unknown.String()).ReadStdout()
+		assert.Contains(t, out, "Has min power: false")
+		assert.Contains(t, out, "Note: miner does not exist at the lookback epoch")
+	})
+}
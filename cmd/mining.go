@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+
+	"github.com/filecoin-project/venus/app/node"
+)
+
+var miningCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Mine blocks on a devnet.",
+		ShortDescription: `
+Mines blocks with the genesis miners whose worker keys are in the local wallet.
+Winning PoSts are mocked, so the daemon must be started with --mock-proofs.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"once":  miningOnceCmd,
+		"start": miningStartCmd,
+		"stop":  miningStopCmd,
+	},
+}
+
+var miningOnceCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Mine a single block on top of the current head.",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		blk, err := env.(*node.Env).MingingAPI.MiningOnce(req.Context)
+		if err != nil {
+			return err
+		}
+		return re.Emit(fmt.Sprintf("Mined block %s at height %d", blk.Header.Cid(), blk.Header.Height))
+	},
+}
+
+var miningStartCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Start mining blocks continuously.",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		if err := env.(*node.Env).MingingAPI.MiningStart(req.Context); err != nil {
+			return err
+		}
+		return re.Emit("Started mining")
+	},
+}
+
+var miningStopCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Stop mining blocks.",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		if err := env.(*node.Env).MingingAPI.MiningStop(req.Context); err != nil {
+			return err
+		}
+		return re.Emit("Stopped mining")
+	},
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+
+	"github.com/filecoin-project/venus/app/paths"
+	"github.com/filecoin-project/venus/pkg/repo"
+)
+
+// MigrateRepo makes the daemon migrate an outdated repo without prompting.
+const MigrateRepo = "migrate-repo"
+
+var repoCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Manage the venus repo.",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"migrate": repoMigrateCmd,
+		"convert": repoConvertCmd,
+	},
+}
+
+var repoMigrateCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Upgrade the repo to the version of this binary.",
+		ShortDescription: `
+Runs the migrations from the version of the repo to the version of this binary.
+The config, the keystore and the migrated datastores are first copied to the
+backups directory of the repo. The daemon must not be running.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("dry-run", "run the migrations without writing, and report the changes"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		repoDir, _ := req.Options[OptionRepoDir].(string)
+		repoDir, err := paths.GetRepoPath(repoDir)
+		if err != nil {
+			return err
+		}
+		dryRun, _ := req.Options["dry-run"].(bool)
+
+		res, err := repo.MigrateFSRepo(repoDir, repo.Version, repo.DefaultMigrations, dryRun)
+		if err != nil {
+			return err
+		}
+		return re.Emit(formatMigrationResult(res))
+	},
+}
+
+func formatMigrationResult(res *repo.MigrationResult) string {
+	buf := new(bytes.Buffer)
+	writer := NewSilentWriter(buf)
+	if res.From == res.To {
+		writer.Printf("repo is up to date at version %d\n", res.To)
+		return buf.String()
+	}
+	if res.DryRun {
+		writer.Printf("dry run, the repo is left at version %d\n", res.From)
+	} else {
+		writer.Printf("backup: %s\n", res.Backup)
+	}
+	for _, report := range res.Reports {
+		writer.Printf("version %d -> %d: %s\n", report.From, report.To, report.Description)
+		if report.ConfigChanged {
+			writer.Println("  config: changed")
+		}
+		stores := make([]string, 0, len(report.Changes))
+		for store := range report.Changes {
+			stores = append(stores, store)
+		}
+		sort.Strings(stores)
+		for _, store := range stores {
+			writer.Printf("  %s: %d changes\n", store, report.Changes[store])
+		}
+	}
+	return buf.String()
+}
+
+var repoConvertCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Copy the repo datastores to another backend.",
+		ShortDescription: `
+Copies the datastores to the backend given by --to, e.g. badgerds or levelds,
+and selects it in the config. The data in the previous backend is kept next to
+each datastore, suffixed with the backend name, and can be removed once the new
+backend is satisfying. The daemon must not be running.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("to", "backend to convert to").WithDefault(""),
+		cmds.StringsOption("datastore", fmt.Sprintf("datastores to convert, all by default, of %s", strings.Join(repo.ConvertibleDatastores, ", "))),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		repoDir, _ := req.Options[OptionRepoDir].(string)
+		repoDir, err := paths.GetRepoPath(repoDir)
+		if err != nil {
+			return err
+		}
+		to, _ := req.Options["to"].(string)
+		if to == "" {
+			return fmt.Errorf("--to is required")
+		}
+		names, _ := req.Options["datastore"].([]string)
+
+		res, err := repo.ConvertFSRepo(repoDir, to, names)
+		if err != nil {
+			return err
+		}
+
+		buf := new(bytes.Buffer)
+		writer := NewSilentWriter(buf)
+		if len(res.Entries) == 0 {
+			writer.Printf("datastores already use %s\n", res.To)
+		}
+		converted := make([]string, 0, len(res.Entries))
+		for name := range res.Entries {
+			converted = append(converted, name)
+		}
+		sort.Strings(converted)
+		for _, name := range converted {
+			writer.Printf("%s: %d entries copied to %s, previous data in %s\n", name, res.Entries[name], res.To, res.Previous[name])
+		}
+		return re.Emit(buf)
+	},
+}
+
+// migrateRepoIfNeeded migrates an outdated repo before the daemon opens it,
+// after asking on stdin unless `auto` is set.
+func migrateRepoIfNeeded(repoDir string, auto bool) error {
+	have, needed, err := repo.NeedsMigration(repoDir, repo.Version)
+	if err != nil || !needed {
+		return err
+	}
+
+	if !auto {
+		fmt.Printf("repo %s is at version %d, this binary needs version %d.\n", repoDir, have, repo.Version)
+		fmt.Print("Back up and migrate it now? [y/N] ")
+		answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return &repo.ErrMigrationNeeded{Have: have, Want: repo.Version}
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			return &repo.ErrMigrationNeeded{Have: have, Want: repo.Version}
+		}
+	}
+
+	res, err := repo.MigrateFSRepo(repoDir, repo.Version, repo.DefaultMigrations, false)
+	if err != nil {
+		return err
+	}
+	fmt.Print(formatMigrationResult(res))
+	return nil
+}
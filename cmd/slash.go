@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+
+	cmds "github.com/ipfs/go-ipfs-cmds"
+
+	"github.com/filecoin-project/venus/app/node"
+	"github.com/filecoin-project/venus/cmd/tablewriter"
+	"github.com/filecoin-project/venus/pkg/slashing"
+)
+
+var slashCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Inspect consensus fault reports.",
+		ShortDescription: `
+Detected consensus faults are reported to the offending miner actor when
+slasher.enabled is set in the config. Reports are sent from slasher.from, or
+from the default wallet address when it is not set.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"list": slashListCmd,
+	},
+}
+
+var slashListCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List the consensus faults reported by this node.",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		api := env.(*node.Env).SlasherAPI
+		faults, err := api.SlasherListReported(req.Context)
+		if err != nil {
+			return err
+		}
+
+		buf := new(bytes.Buffer)
+		enabled, err := api.SlasherEnabled(req.Context)
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			writer := NewSilentWriter(buf)
+			writer.Println("Consensus fault reporting is disabled, set slasher.enabled to report faults")
+		}
+
+		tw := tablewriter.New(
+			tablewriter.Col("Miner"),
+			tablewriter.Col("Epoch"),
+			tablewriter.Col("Fault"),
+			tablewriter.Col("Block1"),
+			tablewriter.Col("Block2"),
+			tablewriter.Col("Extra"),
+			tablewriter.Col("Message"),
+			tablewriter.Col("Reported"))
+		for _, fault := range faults {
+			extra := "-"
+			if fault.Extra.Defined() {
+				extra = fault.Extra.String()
+			}
+			tw.Write(map[string]interface{}{
+				"Miner":    fault.Miner,
+				"Epoch":    fault.Epoch,
+				"Fault":    slashing.ConsensusFaultTypeName(fault.Type),
+				"Block1":   fault.Block1,
+				"Block2":   fault.Block2,
+				"Extra":    extra,
+				"Message":  fault.Message,
+				"Reported": fault.ReportedAt.Format("2006-01-02 15:04:05"),
+			})
+		}
+		if err := tw.Flush(buf); err != nil {
+			return err
+		}
+
+		return re.Emit(buf)
+	},
+}
@@ -7,10 +7,13 @@ import (
 	"github.com/filecoin-project/venus/pkg/constants"
 	"io"
 	"strconv"
+	"time"
 
+	"github.com/docker/go-units"
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/network"
 	"github.com/ipfs/go-cid"
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	"github.com/multiformats/go-multiaddr"
@@ -19,6 +22,8 @@ import (
 	"github.com/filecoin-project/venus/app/node"
 	"github.com/filecoin-project/venus/app/submodule/chain"
 	"github.com/filecoin-project/venus/app/submodule/config"
+	"github.com/filecoin-project/venus/cmd/tablewriter"
+	"github.com/filecoin-project/venus/pkg/block"
 	"github.com/filecoin-project/venus/pkg/crypto"
 	"github.com/filecoin-project/venus/pkg/specactors/builtin"
 	"github.com/filecoin-project/venus/pkg/types"
@@ -38,19 +43,21 @@ var stateCmd = &cmds.Command{
 		Tagline: "Interact with and query venus chain state",
 	},
 	Subcommands: map[string]*cmds.Command{
-		"wait-msg":        stateWaitMsgCmd,
-		"search-msg":      stateSearchMsgCmd,
-		"power":           statePowerCmd,
-		"sectors":         stateSectorsCmd,
-		"active-sectors":  stateActiveSectorsCmd,
-		"sector":          stateSectorCmd,
-		"get-actor":       stateGetActorCmd,
-		"lookup":          stateLookupIDCmd,
-		"sector-size":     stateSectorSizeCmd,
-		"get-deal":        stateGetDealSetCmd,
-		"miner-info":      stateMinerInfo,
-		"network-version": stateNtwkVersionCmd,
-		"list-actor":      stateListActorCmd,
+		"wait-msg":         stateWaitMsgCmd,
+		"search-msg":       stateSearchMsgCmd,
+		"power":            statePowerCmd,
+		"sectors":          stateSectorsCmd,
+		"active-sectors":   stateActiveSectorsCmd,
+		"sector":           stateSectorCmd,
+		"get-actor":        stateGetActorCmd,
+		"lookup":           stateLookupIDCmd,
+		"sector-size":      stateSectorSizeCmd,
+		"get-deal":         stateGetDealSetCmd,
+		"miner-info":       stateMinerInfo,
+		"network-version":  stateNtwkVersionCmd,
+		"list-actor":       stateListActorCmd,
+		"migrate":          stateMigrateCmd,
+		"check-invariants": stateCheckInvariantsCmd,
 	},
 }
 
@@ -597,3 +604,131 @@ func makeActorView(act *types.Actor, addr address.Address) *ActorView {
 		Head:    act.Head,
 	}
 }
+
+var stateMigrateCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Rehearse a network upgrade on a state root",
+		ShortDescription: `
+Runs the state migrations of the upgrade to --to-version on --from-root and
+reports the duration, the heap used, the new root and the actors changed. The
+command runs on the repo without the daemon, which must be stopped. The repo is
+only read, the migrated state is written to a scratch store and discarded. A
+running daemon rehearses the upgrade with its StateMigrate API instead.
+
+Without --from-root, the state the chain had at the upgrade epoch is migrated,
+the chain must be synced past the upgrade. The tipset above the upgrade is then
+executed on the new root and the result checked against the ParentStateRoot of
+the tipset after it.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.StringOption("from-root", "state root to migrate, by default the state of the chain at the upgrade"),
+		cmds.Uint64Option("to-version", "network version to upgrade to"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		nv, ok := req.Options["to-version"].(uint64)
+		if !ok {
+			return xerrors.New("--to-version is required")
+		}
+		root := cid.Undef
+		if s, _ := req.Options["from-root"].(string); s != "" {
+			var err error
+			if root, err = cid.Decode(s); err != nil {
+				return xerrors.Errorf("invalid --from-root: %w", err)
+			}
+		}
+
+		rep, err := getRepo(req)
+		if err != nil {
+			return xerrors.Errorf("failed to open the repo, the daemon must be stopped: %w", err)
+		}
+		defer rep.Close() // nolint: errcheck
+
+		res, err := dryRunMigration(req.Context, rep, root, network.Version(nv))
+		if err != nil {
+			return err
+		}
+
+		buf := new(bytes.Buffer)
+		writer := NewSilentWriter(buf)
+		writer.Printf("network version %d, upgrade at epoch %d\n", res.Network, res.Height)
+		writer.Printf("from root: %s\n", res.FromRoot)
+		writer.Printf("new root:  %s\n", res.NewRoot)
+		writer.Printf("duration:  %s\n", res.Duration.Round(time.Millisecond))
+		writer.Printf("heap:      %s before, %s peak\n", units.BytesSize(float64(res.BaseHeap)), units.BytesSize(float64(res.PeakHeap)))
+		switch {
+		case !res.Checked:
+			writer.Println("check:     skipped, the root is not the chain state at the upgrade or the chain is not past the tipset above it")
+		case res.Matched:
+			writer.Printf("check:     executing the tipset above the upgrade gives the chain root %s\n", res.ChainRoot)
+		default:
+			writer.Printf("check:     MISMATCH, executing the tipset above the upgrade gives %s, the chain has %s\n", res.ExecutedRoot, res.ChainRoot)
+		}
+		writer.Println()
+
+		tw := tablewriter.New(
+			tablewriter.Col("Code"),
+			tablewriter.Col("Total"),
+			tablewriter.Col("CodeChanged"),
+			tablewriter.Col("HeadChanged"),
+			tablewriter.Col("BalanceChanged"),
+			tablewriter.Col("Added"),
+			tablewriter.Col("Removed"))
+		for _, a := range res.Actors {
+			tw.Write(map[string]interface{}{
+				"Code":           a.Code,
+				"Total":          a.Total,
+				"CodeChanged":    a.CodeChanged,
+				"HeadChanged":    a.HeadChanged,
+				"BalanceChanged": a.BalanceChanged,
+				"Added":          a.Added,
+				"Removed":        a.Removed,
+			})
+		}
+		if err := tw.Flush(buf); err != nil {
+			return err
+		}
+		return re.Emit(buf)
+	},
+}
+
+var stateCheckInvariantsCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Check the invariants of the builtin actors on the state of a tipset",
+		ShortDescription: `
+Runs the specs-actors invariant checks on the state computed by the tipset
+made of the given blocks, or by the head. The violations are listed per actor
+type, checks across actors such as the miners against the power table, the
+deals against the sectors and the total FIL are listed as cross-actor.
+`,
+	},
+	Arguments: []cmds.Argument{
+		cmds.StringArg("cids", false, true, "CID's of the blocks of the tipset, the head when omitted"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		blks, err := cidsFromSlice(req.Arguments)
+		if err != nil {
+			return err
+		}
+		report, err := env.(*node.Env).ChainAPI.StateCheckInvariants(req.Context, block.NewTipSetKey(blks...))
+		if err != nil {
+			return err
+		}
+
+		buf := new(bytes.Buffer)
+		writer := NewSilentWriter(buf)
+		writer.Printf("state %s at epoch %d, actors v%d\n", report.StateRoot, report.Height, report.ActorVersion)
+		if report.Ok() {
+			writer.Println("no invariant violated")
+			return re.Emit(buf)
+		}
+		writer.Printf("%d invariants violated\n", report.Count())
+		for _, v := range report.Violations {
+			writer.Printf("\n%s (%d):\n", v.Actor, len(v.Messages))
+			for _, msg := range v.Messages {
+				writer.Printf("  %s\n", msg)
+			}
+		}
+		return re.Emit(buf)
+	},
+}
@@ -1,17 +1,22 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"github.com/docker/go-units"
 	"github.com/filecoin-project/venus/app/node"
+	"github.com/filecoin-project/venus/cmd/tablewriter"
 	"github.com/ipfs/go-cid"
 	cmds "github.com/ipfs/go-ipfs-cmds"
 	"github.com/libp2p/go-libp2p-core/metrics"
 	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/libp2p/go-libp2p-core/routing"
 	ma "github.com/multiformats/go-multiaddr"
+	"io"
+	"sort"
 	"time"
 
 	"github.com/filecoin-project/venus/pkg/net"
@@ -40,6 +45,8 @@ libp2p peers on the internet.
 		"findpeer":  findPeerDhtCmd,
 		"findprovs": findProvidersDhtCmd,
 		"bandwidth": statsBandwidthCmd,
+		"block":     swarmBlockCmd,
+		"scores":    swarmScoresCmd,
 	},
 }
 
@@ -239,14 +246,241 @@ var findPeerDhtCmd = &cmds.Command{
 var statsBandwidthCmd = &cmds.Command{
 	Helptext: cmds.HelpText{
 		Tagline: "View bandwidth usage metrics",
+		ShortDescription: `
+'venus swarm bandwidth' prints the total bandwidth used by the node. With
+--by-peer or --by-protocol a table of the bandwidth used by every peer or
+protocol is printed instead, --watch refreshes that table every second.
+`,
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("by-peer", "List bandwidth usage by peer"),
+		cmds.BoolOption("by-protocol", "List bandwidth usage by protocol"),
+		cmds.BoolOption("watch", "Refresh the bandwidth table every second"),
 	},
 	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
-		bandwidthStats := env.(*node.Env).NetworkAPI.NetworkGetBandwidthStats()
-		return re.Emit(bandwidthStats)
+		api := env.(*node.Env).NetworkAPI
+		byPeer, _ := req.Options["by-peer"].(bool)
+		byProtocol, _ := req.Options["by-protocol"].(bool)
+		watch, _ := req.Options["watch"].(bool)
+
+		if byPeer && byProtocol {
+			return fmt.Errorf("cannot use --by-peer and --by-protocol at the same time")
+		}
+		if !byPeer && !byProtocol && !watch {
+			bandwidthStats := api.NetworkGetBandwidthStats()
+			return re.Emit(bandwidthStats)
+		}
+
+		writeTable := func(w io.Writer) error {
+			tw := tablewriter.New(
+				tablewriter.Col("Segment"),
+				tablewriter.Col("TotalIn"),
+				tablewriter.Col("TotalOut"),
+				tablewriter.Col("RateIn"),
+				tablewriter.Col("RateOut"))
+
+			stats := make(map[string]metrics.Stats)
+			switch {
+			case byPeer:
+				byPeerStats, err := api.NetBandwidthStatsByPeer(req.Context)
+				if err != nil {
+					return err
+				}
+				stats = byPeerStats
+			case byProtocol:
+				byProtocolStats, err := api.NetBandwidthStatsByProtocol(req.Context)
+				if err != nil {
+					return err
+				}
+				for p, s := range byProtocolStats {
+					name := string(p)
+					if name == "" {
+						name = "<unknown>"
+					}
+					stats[name] = s
+				}
+			}
+			stats["Total"] = api.NetworkGetBandwidthStats()
+
+			keys := make([]string, 0, len(stats))
+			for k := range stats {
+				if k != "Total" {
+					keys = append(keys, k)
+				}
+			}
+			sort.Slice(keys, func(i, j int) bool {
+				return stats[keys[i]].TotalOut > stats[keys[j]].TotalOut
+			})
+			keys = append([]string{"Total"}, keys...)
+
+			for _, k := range keys {
+				s := stats[k]
+				tw.Write(map[string]interface{}{
+					"Segment":  k,
+					"TotalIn":  units.HumanSize(float64(s.TotalIn)),
+					"TotalOut": units.HumanSize(float64(s.TotalOut)),
+					"RateIn":   units.HumanSize(s.RateIn) + "/s",
+					"RateOut":  units.HumanSize(s.RateOut) + "/s",
+				})
+			}
+			return tw.Flush(w)
+		}
+
+		if !watch {
+			buf := new(bytes.Buffer)
+			if err := writeTable(buf); err != nil {
+				return err
+			}
+			return re.Emit(buf)
+		}
+
+		pr, pw := io.Pipe()
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				buf := new(bytes.Buffer)
+				if err := writeTable(buf); err != nil {
+					_ = pw.CloseWithError(err)
+					return
+				}
+				buf.WriteString("\n")
+				if _, err := io.Copy(pw, buf); err != nil {
+					return
+				}
+
+				select {
+				case <-req.Context.Done():
+					_ = pw.Close()
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+		return re.Emit(pr)
 	},
 	Type: metrics.Stats{},
 }
 
+var swarmBlockCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Manage the connection blocklist",
+		ShortDescription: `
+'venus swarm block' manages the persistent list of peers, IP addresses and
+subnets this node refuses to connect to.
+`,
+	},
+	Subcommands: map[string]*cmds.Command{
+		"add":    swarmBlockAddCmd,
+		"remove": swarmBlockRemoveCmd,
+		"list":   swarmBlockListCmd,
+	},
+}
+
+var swarmBlockAddCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Add connection gating rules",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"peer":   swarmBlockRuleCmd("Block peer IDs", "peer", true),
+		"ip":     swarmBlockRuleCmd("Block IP addresses", "ip", true),
+		"subnet": swarmBlockRuleCmd("Block subnets in CIDR notation", "subnet", true),
+	},
+}
+
+var swarmBlockRemoveCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Remove connection gating rules",
+	},
+	Subcommands: map[string]*cmds.Command{
+		"peer":   swarmBlockRuleCmd("Unblock peer IDs", "peer", false),
+		"ip":     swarmBlockRuleCmd("Unblock IP addresses", "ip", false),
+		"subnet": swarmBlockRuleCmd("Unblock subnets in CIDR notation", "subnet", false),
+	},
+}
+
+func swarmBlockRuleCmd(tagline, kind string, block bool) *cmds.Command {
+	return &cmds.Command{
+		Helptext: cmds.HelpText{
+			Tagline: tagline,
+		},
+		Arguments: []cmds.Argument{
+			cmds.StringArg(kind, true, true, "rules to apply"),
+		},
+		Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+			var acl net.NetBlockList
+			switch kind {
+			case "peer":
+				for _, arg := range req.Arguments {
+					p, err := peer.Decode(arg)
+					if err != nil {
+						return err
+					}
+					acl.Peers = append(acl.Peers, p)
+				}
+			case "ip":
+				acl.IPAddrs = req.Arguments
+			case "subnet":
+				acl.IPSubnets = req.Arguments
+			}
+
+			api := env.(*node.Env).NetworkAPI
+			if block {
+				return api.NetBlockAdd(req.Context, acl)
+			}
+			return api.NetBlockRemove(req.Context, acl)
+		},
+	}
+}
+
+var swarmBlockListCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "List connection gating rules",
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		acl, err := env.(*node.Env).NetworkAPI.NetBlockList(req.Context)
+		if err != nil {
+			return err
+		}
+		return re.Emit(acl)
+	},
+	Type: net.NetBlockList{},
+}
+
+var swarmScoresCmd = &cmds.Command{
+	Helptext: cmds.HelpText{
+		Tagline: "Print gossipsub peer scores",
+	},
+	Options: []cmds.Option{
+		cmds.BoolOption("topics", "Also print the score components of every topic"),
+	},
+	Run: func(req *cmds.Request, re cmds.ResponseEmitter, env cmds.Environment) error {
+		scores, err := env.(*node.Env).NetworkAPI.NetPubsubScores(req.Context)
+		if err != nil {
+			return err
+		}
+		sort.Slice(scores, func(i, j int) bool {
+			return scores[i].Score.Score > scores[j].Score.Score
+		})
+
+		showTopics, _ := req.Options["topics"].(bool)
+		buf := new(bytes.Buffer)
+		writer := NewSilentWriter(buf)
+		for _, s := range scores {
+			writer.Printf("%s, %f, %f, %f, %f\n", s.ID, s.Score.Score, s.Score.AppSpecificScore, s.Score.IPColocationFactor, s.Score.BehaviourPenalty)
+			if !showTopics {
+				continue
+			}
+			for topic, ts := range s.Score.Topics {
+				writer.Printf("\t%s: inMesh %s, firstDeliveries %f, meshDeliveries %f, invalidDeliveries %f\n",
+					topic, ts.TimeInMesh, ts.FirstMessageDeliveries, ts.MeshMessageDeliveries, ts.InvalidMessageDeliveries)
+			}
+		}
+
+		return re.Emit(buf)
+	},
+}
+
 // IDDetails is a collection of information about a node.
 type IDDetails struct {
 	Addresses       []ma.Multiaddr
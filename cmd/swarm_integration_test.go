@@ -2,13 +2,18 @@ package cmd_test
 
 import (
 	"context"
+	"fmt"
 	th "github.com/filecoin-project/venus/pkg/testhelpers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/filecoin-project/venus/app/node/test"
+	"github.com/filecoin-project/venus/cmd"
 	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
 )
 
@@ -116,3 +121,61 @@ func TestStatsBandwidth(t *testing.T) {
 
 	assert.Equal(t, "{\n\t\"TotalIn\": 0,\n\t\"TotalOut\": 0,\n\t\"RateIn\": 0,\n\t\"RateOut\": 0\n}", stats)
 }
+
+func TestStatsBandwidthTables(t *testing.T) {
+	tf.IntegrationTest(t)
+	ctx := context.Background()
+	builder := test.NewNodeBuilder(t)
+
+	n1, cmdClient, done := builder.BuildAndStartAPI(ctx)
+	defer done()
+	n2 := builder.BuildAndStart(ctx)
+	defer n2.Stop(ctx)
+
+	test.ConnectNodes(t, n1, n2)
+	peerID := n2.Network().API().NetworkGetPeerID().String()
+	require.Eventually(t, func() bool {
+		byPeer, err := n1.Network().API().NetBandwidthStatsByPeer(ctx)
+		require.NoError(t, err)
+		_, ok := byPeer[peerID]
+		return ok
+	}, 10*time.Second, 100*time.Millisecond)
+
+	t.Run("by peer", func(t *testing.T) {
+		out := cmdClient.RunSuccess(ctx, "swarm", "bandwidth", "--by-peer").ReadStdout()
+		assert.Contains(t, out, "Segment")
+		assert.Contains(t, out, "Total")
+		assert.Contains(t, out, peerID)
+	})
+
+	t.Run("by protocol", func(t *testing.T) {
+		out := cmdClient.RunSuccess(ctx, "swarm", "bandwidth", "--by-protocol").ReadStdout()
+		assert.Contains(t, out, "Segment")
+		assert.Contains(t, out, "Total")
+		assert.NotContains(t, out, peerID)
+	})
+
+	t.Run("both at once", func(t *testing.T) {
+		cmdClient.RunFail(ctx, "cannot use --by-peer and --by-protocol at the same time",
+			"swarm", "bandwidth", "--by-peer", "--by-protocol")
+	})
+
+	t.Run("watch refreshes the table until cancelled", func(t *testing.T) {
+		rout, wout, err := os.Pipe()
+		require.NoError(t, err)
+		rerr, werr, err := os.Pipe()
+		require.NoError(t, err)
+
+		// the stream only ends with the request, its exit status is not checked
+		wctx, cancel := context.WithTimeout(ctx, 2500*time.Millisecond)
+		defer cancel()
+		args := []string{"venus", fmt.Sprintf("--cmdapiaddr=%s", cmdClient.Address()), "swarm", "bandwidth", "--by-peer", "--watch"}
+		_, _ = cmd.Run(wctx, args, nil, wout, werr)
+		require.NoError(t, wout.Close())
+		require.NoError(t, werr.Close())
+
+		out := th.ReadOutput(t, args, rout, rerr).ReadStdout()
+		assert.True(t, strings.Count(out, "Segment") >= 2, "output:\n%s", out)
+		assert.Contains(t, out, peerID)
+	})
+}
@@ -72,6 +72,9 @@ var storeStatusCmd = &cmds.Command{
 				writer.Println("\tCurrent:")
 			}
 
+			if fetched, total := t.Progress(); total > 0 {
+				writer.Println("\tFetched:", fetched, "/", total)
+			}
 			writer.Println("\tStatus:", t.State.String())
 			writer.Println("\tErr:", t.Err)
 			writer.Println()
@@ -3,6 +3,7 @@ package beacon
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"sync"
 	"time"
 
@@ -10,8 +11,12 @@ import (
 	dclient "github.com/drand/drand/client"
 	hclient "github.com/drand/drand/client/http"
 	dlog "github.com/drand/drand/log"
+	gclient "github.com/drand/drand/lp2p/client"
 	"github.com/drand/kyber"
 	kzap "github.com/go-kit/kit/log/zap"
+	lru "github.com/hashicorp/golang-lru"
+	ds "github.com/ipfs/go-datastore"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	"go.uber.org/zap/zapcore"
 	"golang.org/x/xerrors"
 
@@ -38,7 +43,8 @@ func (dp *drandPeer) IsTLS() bool {
 // randomness to the system in a way that's aligned with Filecoin rounds/epochs.
 //
 // We connect to drand peers via their public HTTP endpoints. The peers are
-// enumerated in the drandServers variable.
+// enumerated in the drandServers variable. Rounds are also received over the
+// drand gossipsub relay topic when a pubsub is given.
 //
 // The root trust for the Drand chain is configured from build.DrandChain.
 type DrandBeacon struct {
@@ -53,8 +59,56 @@ type DrandBeacon struct {
 	filGenTime   uint64
 	filRoundTime uint64
 
-	cacheLk    sync.Mutex
-	localCache map[uint64]block.BeaconEntry
+	localCache *lru.Cache
+	// store persists verified entries, it is nil without a datastore
+	store *entryStore
+
+	statusLk sync.Mutex
+	status   DrandStatus
+}
+
+// DrandStatus describes where a drand beacon gets its rounds from and the
+// last round it verified.
+type DrandStatus struct {
+	// Start is the first epoch the beacon is used for.
+	Start     abi.ChainEpoch
+	ChainHash string
+	Servers   []string
+	// PubsubTopic is the gossipsub relay topic, empty when rounds are only
+	// fetched over HTTP.
+	PubsubTopic string
+	Persistent  bool
+
+	// LastSource is where the last requested entry came from: memory,
+	// datastore or network.
+	LastSource string
+	// LastLatency is the duration of the last network fetch.
+	LastLatency       time.Duration
+	LastVerifiedRound uint64
+	LastVerifiedAt    time.Time
+}
+
+const (
+	sourceMemory    = "memory"
+	sourceDatastore = "datastore"
+	sourceNetwork   = "network"
+
+	// localCacheSize is the number of entries kept in memory.
+	localCacheSize = 1024
+)
+
+// DrandTopic returns the gossipsub topic drand relays publish the rounds of
+// the chain described by `config` on.
+func DrandTopic(config cfg.DrandConf) (string, error) {
+	drandChain, err := dchain.InfoFromJSON(bytes.NewReader([]byte(config.ChainInfoJSON)))
+	if err != nil {
+		return "", xerrors.Errorf("unable to unmarshal drand chain info: %w", err)
+	}
+	return drandTopic(drandChain), nil
+}
+
+func drandTopic(drandChain *dchain.Info) string {
+	return "/drand/pubsub/v0.0.0/" + hex.EncodeToString(drandChain.Hash())
 }
 
 // DrandHTTPClient interface overrides the user agent used by drand
@@ -62,7 +116,10 @@ type DrandHTTPClient interface {
 	SetUserAgent(string)
 }
 
-func NewDrandBeacon(genTimeStamp, interval uint64, config cfg.DrandConf) (*DrandBeacon, error) {
+// NewDrandBeacon creates a beacon for the drand chain of `config`. Rounds are
+// also received over gossipsub when `ps` is not nil, and verified entries are
+// persisted in `dstore` when it is not nil.
+func NewDrandBeacon(genTimeStamp, interval uint64, config cfg.DrandConf, ps *pubsub.PubSub, dstore ds.Batching) (*DrandBeacon, error) {
 	drandChain, err := dchain.InfoFromJSON(bytes.NewReader([]byte(config.ChainInfoJSON)))
 	if err != nil {
 		return nil, xerrors.Errorf("unable to unmarshal drand chain info: %w", err)
@@ -89,16 +146,35 @@ func NewDrandBeacon(genTimeStamp, interval uint64, config cfg.DrandConf) (*Drand
 		dclient.WithAutoWatch(),
 	}
 
-	log.Info("drand beacon without pubsub")
+	status := DrandStatus{
+		ChainHash:  hex.EncodeToString(drandChain.Hash()),
+		Servers:    config.Servers,
+		Persistent: dstore != nil,
+	}
+	if ps != nil {
+		opts = append(opts, gclient.WithPubsub(ps))
+		status.PubsubTopic = drandTopic(drandChain)
+	} else {
+		log.Info("drand beacon without pubsub")
+	}
 
 	client, err := dclient.Wrap(clients, opts...)
 	if err != nil {
 		return nil, xerrors.Errorf("creating drand client: %v", err)
 	}
 
+	localCache, err := lru.New(localCacheSize)
+	if err != nil {
+		return nil, err
+	}
+
 	db := &DrandBeacon{
 		client:     client,
-		localCache: make(map[uint64]block.BeaconEntry),
+		localCache: localCache,
+		status:     status,
+	}
+	if dstore != nil {
+		db.store = newEntryStore(dstore, hex.EncodeToString(drandChain.Hash()), BeaconEntriesKept)
 	}
 
 	db.pubkey = drandChain.PublicKey
@@ -132,28 +208,75 @@ func (db *DrandBeacon) Entry(ctx context.Context, round uint64) <-chan Response
 		} else {
 			br.Entry.Round = resp.Round()
 			br.Entry.Data = resp.Signature()
+			// the client verifies the rounds against the chain info
+			db.cacheValue(br.Entry)
+			db.recordVerified(br.Entry.Round)
 		}
-		log.Infow("done fetching randomness", "round", round, "took", time.Since(start))
+		took := time.Since(start)
+		db.recordSource(sourceNetwork, took)
+		log.Infow("done fetching randomness", "round", round, "took", took)
 		out <- br
 		close(out)
 	}()
 
 	return out
 }
+
 func (db *DrandBeacon) cacheValue(e block.BeaconEntry) {
-	db.cacheLk.Lock()
-	defer db.cacheLk.Unlock()
-	db.localCache[e.Round] = e
+	db.localCache.Add(e.Round, e)
+	if db.store != nil {
+		if err := db.store.put(e); err != nil {
+			log.Warnf("failed to persist beacon entry %d: %s", e.Round, err)
+		}
+	}
 }
 
 func (db *DrandBeacon) getCachedValue(round uint64) *block.BeaconEntry {
-	db.cacheLk.Lock()
-	defer db.cacheLk.Unlock()
-	v, ok := db.localCache[round]
-	if !ok {
+	if v, ok := db.localCache.Get(round); ok {
+		e := v.(block.BeaconEntry)
+		db.recordSource(sourceMemory, 0)
+		return &e
+	}
+	if db.store == nil {
 		return nil
 	}
-	return &v
+
+	e, err := db.store.get(round)
+	if err != nil {
+		log.Warnf("failed to load beacon entry %d: %s", round, err)
+		return nil
+	}
+	if e != nil {
+		db.localCache.Add(round, *e)
+		db.recordSource(sourceDatastore, 0)
+	}
+	return e
+}
+
+func (db *DrandBeacon) recordSource(source string, latency time.Duration) {
+	db.statusLk.Lock()
+	defer db.statusLk.Unlock()
+	db.status.LastSource = source
+	if source == sourceNetwork {
+		db.status.LastLatency = latency
+	}
+}
+
+func (db *DrandBeacon) recordVerified(round uint64) {
+	db.statusLk.Lock()
+	defer db.statusLk.Unlock()
+	if round >= db.status.LastVerifiedRound {
+		db.status.LastVerifiedRound = round
+		db.status.LastVerifiedAt = time.Now()
+	}
+}
+
+// Status returns where the beacon gets its rounds from and the last round it
+// verified.
+func (db *DrandBeacon) Status() DrandStatus {
+	db.statusLk.Lock()
+	defer db.statusLk.Unlock()
+	return db.status
 }
 
 func (db *DrandBeacon) VerifyEntry(curr block.BeaconEntry, prev block.BeaconEntry) error {
@@ -162,6 +285,9 @@ func (db *DrandBeacon) VerifyEntry(curr block.BeaconEntry, prev block.BeaconEntr
 		return nil
 	}
 	if be := db.getCachedValue(curr.Round); be != nil {
+		if !bytes.Equal(be.Data, curr.Data) {
+			return xerrors.Errorf("beacon entry %d does not match the verified entry", curr.Round)
+		}
 		// return no error if the value is in the cache already
 		return nil
 	}
@@ -173,6 +299,7 @@ func (db *DrandBeacon) VerifyEntry(curr block.BeaconEntry, prev block.BeaconEntr
 	err := dchain.VerifyBeacon(db.pubkey, b)
 	if err == nil {
 		db.cacheValue(curr)
+		db.recordVerified(curr.Round)
 	}
 	return err
 }
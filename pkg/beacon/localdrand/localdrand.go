@@ -0,0 +1,358 @@
+// Package localdrand implements a drand compatible HTTP server producing real
+// threshold BLS rounds from a local key, for devnets and integration tests
+// that must run without the public drand network.
+package localdrand
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dchain "github.com/drand/drand/chain"
+	"github.com/drand/drand/key"
+	"github.com/drand/kyber"
+	"github.com/drand/kyber/share"
+	"github.com/drand/kyber/util/random"
+	"golang.org/x/xerrors"
+
+	cfg "github.com/filecoin-project/venus/pkg/config"
+)
+
+// Key is the secret of a local drand chain, it is the polynomial the shares
+// of the chain are taken from.
+type Key struct {
+	// Coefficients are the hex encoded coefficients of the private polynomial,
+	// the threshold is their count.
+	Coefficients []string `json:"coefficients"`
+	Shares       int      `json:"shares"`
+	Period       int64    `json:"period"`
+	GenesisTime  int64    `json:"genesis_time"`
+}
+
+// NewKey generates the key of a chain with `shares` shares, `threshold` of
+// which sign each round.
+func NewKey(shares, threshold int, period time.Duration, genesis time.Time) (*Key, error) {
+	if threshold < 1 || threshold > shares {
+		return nil, xerrors.Errorf("threshold %d must be between 1 and the number of shares %d", threshold, shares)
+	}
+	if period < time.Second {
+		return nil, xerrors.Errorf("period %s must be at least a second", period)
+	}
+
+	priPoly := share.NewPriPoly(key.KeyGroup, threshold, nil, random.New())
+	k := &Key{
+		Shares:      shares,
+		Period:      int64(period / time.Second),
+		GenesisTime: genesis.Unix(),
+	}
+	for _, coeff := range priPoly.Coefficients() {
+		data, err := coeff.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		k.Coefficients = append(k.Coefficients, hex.EncodeToString(data))
+	}
+	return k, nil
+}
+
+// LoadKey reads a key written by WriteFile.
+func LoadKey(path string) (*Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var k Key
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, xerrors.Errorf("failed to decode drand key %s: %w", path, err)
+	}
+	return &k, nil
+}
+
+// WriteFile writes the key to `path`, readable by the owner only.
+func (k *Key) WriteFile(path string) error {
+	data, err := json.MarshalIndent(k, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// LocalDrand produces the rounds of a local drand chain.
+type LocalDrand struct {
+	info   *dchain.Info
+	shares []*share.PriShare
+	pubPly *share.PubPoly
+	thr    int
+
+	lk sync.Mutex
+	// rounds holds the signatures of the rounds produced so far, the one of
+	// round r at (r-1)*sigSize. Each round chains on the previous one, the
+	// file spares signing them all again on a restart.
+	rounds *os.File
+	// tip is the last round produced and tipSig its signature, the
+	// signature of round 0 is the genesis seed.
+	tip    uint64
+	tipSig []byte
+}
+
+// sigSize is the size of the signature of a round.
+var sigSize = int64(key.SigGroup.PointLen())
+
+// New returns the drand chain of `k`, the rounds produced are kept in the
+// file at `roundsPath` and read back from it on a restart.
+func New(k *Key, roundsPath string) (*LocalDrand, error) {
+	if len(k.Coefficients) == 0 {
+		return nil, xerrors.New("drand key has no coefficients")
+	}
+	if k.Period <= 0 {
+		return nil, xerrors.Errorf("invalid drand period %d", k.Period)
+	}
+
+	coeffs := make([]kyber.Scalar, len(k.Coefficients))
+	for i, c := range k.Coefficients {
+		data, err := hex.DecodeString(c)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid coefficient %d: %w", i, err)
+		}
+		coeffs[i] = key.KeyGroup.Scalar()
+		if err := coeffs[i].UnmarshalBinary(data); err != nil {
+			return nil, xerrors.Errorf("invalid coefficient %d: %w", i, err)
+		}
+	}
+	priPoly := share.CoefficientsToPriPoly(key.KeyGroup, coeffs)
+	pubPoly := priPoly.Commit(key.KeyGroup.Point().Base())
+
+	pubKey := pubPoly.Commit()
+	pubKeyBytes, err := pubKey.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	// the group hash doubles as the genesis seed like in drand
+	h := sha256.New()
+	_, _ = h.Write(pubKeyBytes)
+	_, _ = h.Write([]byte(strconv.FormatInt(k.GenesisTime, 10)))
+	groupHash := h.Sum(nil)
+
+	shares := priPoly.Shares(k.Shares)
+	ld := &LocalDrand{
+		info: &dchain.Info{
+			PublicKey:   pubKey,
+			Period:      time.Duration(k.Period) * time.Second,
+			GenesisTime: k.GenesisTime,
+			GroupHash:   groupHash,
+		},
+		shares: shares[:len(coeffs)],
+		pubPly: pubPoly,
+		thr:    len(coeffs),
+		tipSig: groupHash,
+	}
+	if err := ld.openRounds(roundsPath); err != nil {
+		return nil, err
+	}
+	return ld, nil
+}
+
+// openRounds opens the file of the rounds produced and resumes the chain
+// from its last round.
+func (ld *LocalDrand) openRounds(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	ld.rounds = f
+
+	// a round partly written when the server stopped is signed again
+	tip := uint64(fi.Size() / sigSize)
+	if err := f.Truncate(int64(tip) * sigSize); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if tip == 0 {
+		return nil
+	}
+	b, err := ld.beacon(tip)
+	if err == nil {
+		err = dchain.VerifyBeacon(ld.info.PublicKey, b)
+	}
+	if err != nil {
+		_ = f.Close()
+		return xerrors.Errorf("rounds file %s does not belong to this drand key: %w", path, err)
+	}
+	ld.tip, ld.tipSig = tip, b.Signature
+	return nil
+}
+
+// Close closes the file of the rounds produced.
+func (ld *LocalDrand) Close() error {
+	return ld.rounds.Close()
+}
+
+// Info returns the chain info of the local chain.
+func (ld *LocalDrand) Info() *dchain.Info {
+	return ld.info
+}
+
+// DrandConf returns a drand config pointing at the server of the local chain
+// at `url`, to be used as the local drand of the network parameters.
+func (ld *LocalDrand) DrandConf(url string) (cfg.DrandConf, error) {
+	buf := new(bytes.Buffer)
+	if err := ld.info.ToJSON(buf); err != nil {
+		return cfg.DrandConf{}, err
+	}
+	return cfg.DrandConf{
+		Servers:       []string{url},
+		ChainInfoJSON: strings.TrimSpace(buf.String()),
+	}, nil
+}
+
+// CurrentRound returns the latest round at `now`.
+func (ld *LocalDrand) CurrentRound(now time.Time) uint64 {
+	return dchain.CurrentRound(now.Unix(), ld.info.Period, ld.info.GenesisTime)
+}
+
+// Round returns the beacon of `round`, rounds after the current round are
+// not available yet.
+func (ld *LocalDrand) Round(round uint64) (*dchain.Beacon, error) {
+	if round == 0 {
+		return nil, xerrors.New("round 0 is the genesis of the chain")
+	}
+	if current := ld.CurrentRound(time.Now()); round > current {
+		return nil, xerrors.Errorf("round %d is in the future, current round is %d", round, current)
+	}
+
+	ld.lk.Lock()
+	defer ld.lk.Unlock()
+	for ld.tip < round {
+		r := ld.tip + 1
+		sig, err := ld.sign(r, ld.tipSig)
+		if err != nil {
+			return nil, xerrors.Errorf("failed to sign round %d: %w", r, err)
+		}
+		if _, err := ld.rounds.WriteAt(sig, int64(ld.tip)*sigSize); err != nil {
+			return nil, xerrors.Errorf("failed to write round %d: %w", r, err)
+		}
+		ld.tip, ld.tipSig = r, sig
+	}
+	return ld.beacon(round)
+}
+
+// beacon reads the beacon of `round` from the rounds produced.
+func (ld *LocalDrand) beacon(round uint64) (*dchain.Beacon, error) {
+	prevSig, err := ld.signature(round - 1)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := ld.signature(round)
+	if err != nil {
+		return nil, err
+	}
+	return &dchain.Beacon{
+		Round:       round,
+		Signature:   sig,
+		PreviousSig: prevSig,
+	}, nil
+}
+
+func (ld *LocalDrand) signature(round uint64) ([]byte, error) {
+	if round == 0 {
+		return ld.info.GroupHash, nil
+	}
+	if round == ld.tip {
+		return ld.tipSig, nil
+	}
+	sig := make([]byte, sigSize)
+	if _, err := ld.rounds.ReadAt(sig, int64(round-1)*sigSize); err != nil {
+		if err == io.EOF {
+			return nil, xerrors.Errorf("round %d was not produced", round)
+		}
+		return nil, err
+	}
+	return sig, nil
+}
+
+// sign signs `round` with a threshold of shares and recovers the group
+// signature from the partial signatures.
+func (ld *LocalDrand) sign(round uint64, prevSig []byte) ([]byte, error) {
+	msg := dchain.Message(round, prevSig)
+	partials := make([][]byte, 0, len(ld.shares))
+	for _, s := range ld.shares {
+		partial, err := key.Scheme.Sign(s, msg)
+		if err != nil {
+			return nil, err
+		}
+		partials = append(partials, partial)
+	}
+	sig, err := key.Scheme.Recover(ld.pubPly, msg, partials, ld.thr, len(ld.shares))
+	if err != nil {
+		return nil, err
+	}
+	if err := key.Scheme.VerifyRecovered(ld.info.PublicKey, msg, sig); err != nil {
+		return nil, xerrors.Errorf("recovered signature does not verify: %w", err)
+	}
+	return sig, nil
+}
+
+// randomResponse is the JSON of a round served by the drand HTTP API.
+type randomResponse struct {
+	Round             uint64 `json:"round"`
+	Randomness        string `json:"randomness"`
+	Signature         string `json:"signature"`
+	PreviousSignature string `json:"previous_signature"`
+}
+
+// ServeHTTP serves the /info, /public/latest and /public/<round> endpoints
+// of the drand HTTP API.
+func (ld *LocalDrand) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == "/info":
+		w.Header().Set("Content-Type", "application/json")
+		if err := ld.info.ToJSON(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	case strings.HasPrefix(path, "/public/"):
+		var round uint64
+		if arg := strings.TrimPrefix(path, "/public/"); arg == "latest" {
+			round = ld.CurrentRound(time.Now())
+		} else {
+			var err error
+			if round, err = strconv.ParseUint(arg, 10, 64); err != nil {
+				http.Error(w, "invalid round", http.StatusBadRequest)
+				return
+			}
+		}
+		if round == 0 {
+			http.Error(w, "chain has not started", http.StatusNotFound)
+			return
+		}
+
+		b, err := ld.Round(round)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(randomResponse{
+			Round:             b.Round,
+			Randomness:        hex.EncodeToString(dchain.RandomnessFromSignature(b.Signature)),
+			Signature:         hex.EncodeToString(b.Signature),
+			PreviousSignature: hex.EncodeToString(b.PreviousSig),
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,119 @@
+package localdrand
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	dchain "github.com/drand/drand/chain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+func TestLocalDrandRounds(t *testing.T) {
+	tf.UnitTest(t)
+
+	dir := t.TempDir()
+	k, err := NewKey(3, 2, time.Second, time.Now().Add(-10*time.Second))
+	require.NoError(t, err)
+	ld, err := New(k, filepath.Join(dir, "rounds"))
+	require.NoError(t, err)
+	defer ld.Close() // nolint: errcheck
+
+	prev, err := ld.Round(1)
+	require.NoError(t, err)
+	require.NoError(t, dchain.VerifyBeacon(ld.Info().PublicKey, prev))
+	for round := uint64(2); round <= 5; round++ {
+		b, err := ld.Round(round)
+		require.NoError(t, err)
+		require.NoError(t, dchain.VerifyBeacon(ld.Info().PublicKey, b))
+		assert.Equal(t, prev.Signature, b.PreviousSig)
+		prev = b
+	}
+
+	_, err = ld.Round(ld.CurrentRound(time.Now()) + 10)
+	assert.Error(t, err)
+
+	t.Run("a reloaded key produces the same chain", func(t *testing.T) {
+		path := filepath.Join(dir, "drand.key")
+		require.NoError(t, k.WriteFile(path))
+		loaded, err := LoadKey(path)
+		require.NoError(t, err)
+		reloaded, err := New(loaded, filepath.Join(t.TempDir(), "rounds"))
+		require.NoError(t, err)
+		defer reloaded.Close() // nolint: errcheck
+
+		assert.Equal(t, ld.Info().Hash(), reloaded.Info().Hash())
+		b, err := reloaded.Round(5)
+		require.NoError(t, err)
+		assert.Equal(t, prev.Signature, b.Signature)
+	})
+
+	t.Run("a restart resumes from the rounds produced", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "rounds")
+		first, err := New(k, path)
+		require.NoError(t, err)
+		_, err = first.Round(5)
+		require.NoError(t, err)
+		require.NoError(t, first.Close())
+
+		// a round partly written is dropped and signed again
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+		require.NoError(t, err)
+		_, err = f.Write([]byte{1, 2, 3})
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		restarted, err := New(k, path)
+		require.NoError(t, err)
+		defer restarted.Close() // nolint: errcheck
+		assert.Equal(t, uint64(5), restarted.tip)
+		assert.Equal(t, prev.Signature, restarted.tipSig)
+		for round := uint64(1); round <= 6; round++ {
+			b, err := restarted.Round(round)
+			require.NoError(t, err)
+			require.NoError(t, dchain.VerifyBeacon(ld.Info().PublicKey, b))
+		}
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, 6*sigSize, fi.Size())
+
+		// the rounds of another chain are refused
+		other, err := NewKey(3, 2, time.Second, time.Now().Add(-10*time.Second))
+		require.NoError(t, err)
+		_, err = New(other, path)
+		assert.Error(t, err)
+	})
+
+	t.Run("rounds are served over http", func(t *testing.T) {
+		srv := httptest.NewServer(ld)
+		defer srv.Close()
+
+		resp, err := http.Get(srv.URL + "/public/3")
+		require.NoError(t, err)
+		defer resp.Body.Close() // nolint: errcheck
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var rr randomResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&rr))
+		sig, err := hex.DecodeString(rr.Signature)
+		require.NoError(t, err)
+		prevSig, err := hex.DecodeString(rr.PreviousSignature)
+		require.NoError(t, err)
+		require.NoError(t, dchain.VerifyBeacon(ld.Info().PublicKey, &dchain.Beacon{Round: rr.Round, Signature: sig, PreviousSig: prevSig}))
+
+		drandConf, err := ld.DrandConf(srv.URL)
+		require.NoError(t, err)
+		info, err := dchain.InfoFromJSON(strings.NewReader(drandConf.ChainInfoJSON))
+		require.NoError(t, err)
+		assert.True(t, info.Equal(ld.Info()))
+	})
+}
@@ -4,6 +4,8 @@ import (
 	"sort"
 
 	"github.com/filecoin-project/go-state-types/abi"
+	ds "github.com/ipfs/go-datastore"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	xerrors "github.com/pkg/errors"
 
 	cfg "github.com/filecoin-project/venus/pkg/config"
@@ -21,11 +23,18 @@ func (bs Schedule) BeaconForEpoch(e abi.ChainEpoch) RandomBeacon {
 	return bs[0].Beacon
 }
 
-func DrandConfigSchedule(genTimeStamp uint64, blockDelay uint64, drandSchedule map[abi.ChainEpoch]cfg.DrandEnum) (Schedule, error) {
+// DrandConfigSchedule creates the drand beacons of the drand schedule of
+// `params`. Rounds are also received over gossipsub when `ps` is not nil, and
+// verified entries are persisted in `dstore` when it is not nil.
+func DrandConfigSchedule(genTimeStamp uint64, params *cfg.NetworkParamsConfig, ps *pubsub.PubSub, dstore ds.Batching) (Schedule, error) {
 	shd := Schedule{}
 
-	for start, config := range drandSchedule {
-		bc, err := NewDrandBeacon(genTimeStamp, blockDelay, cfg.DrandConfigs[config])
+	for start, drandEnum := range params.DrandSchedule {
+		config, err := params.DrandConf(drandEnum)
+		if err != nil {
+			return nil, xerrors.Errorf("creating drand beacon: %v", err)
+		}
+		bc, err := NewDrandBeacon(genTimeStamp, params.BlockDelay, config, ps, dstore)
 		if err != nil {
 			return nil, xerrors.Errorf("creating drand beacon: %v", err)
 		}
@@ -39,3 +48,18 @@ func DrandConfigSchedule(genTimeStamp uint64, blockDelay uint64, drandSchedule m
 	log.Infof("Schedule: %v", shd)
 	return shd, nil
 }
+
+// Status returns the status of the drand beacons of the schedule.
+func (bs Schedule) Status() []DrandStatus {
+	var out []DrandStatus
+	for _, bp := range bs {
+		db, ok := bp.Beacon.(*DrandBeacon)
+		if !ok {
+			continue
+		}
+		status := db.Status()
+		status.Start = bp.Start
+		out = append(out, status)
+	}
+	return out
+}
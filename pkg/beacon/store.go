@@ -0,0 +1,126 @@
+package beacon
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/venus/pkg/block"
+)
+
+const (
+	// BeaconEntriesKept is the number of rounds kept in the datastore behind
+	// the latest stored round, a week of 30 second rounds.
+	BeaconEntriesKept = 20160
+
+	// pruneInterval is the number of rounds stored between two prunings.
+	pruneInterval = 1000
+)
+
+// entryStore persists the verified entries of a drand chain so that they
+// survive restarts and are available while the drand servers are not.
+type entryStore struct {
+	ds   ds.Batching
+	keep uint64
+
+	lk         sync.Mutex
+	latest     uint64
+	lastPruned uint64
+}
+
+func newEntryStore(dstore ds.Batching, chainHash string, keep uint64) *entryStore {
+	return &entryStore{
+		ds:   namespace.Wrap(dstore, ds.NewKey("/beacon/drand").ChildString(chainHash)),
+		keep: keep,
+	}
+}
+
+// roundKey pads the round so that keys sort in round order.
+func roundKey(round uint64) ds.Key {
+	return ds.NewKey(fmt.Sprintf("%020d", round))
+}
+
+func (s *entryStore) get(round uint64) (*block.BeaconEntry, error) {
+	data, err := s.ds.Get(roundKey(round))
+	if err == ds.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entry block.BeaconEntry
+	if err := entry.UnmarshalCBOR(bytes.NewReader(data)); err != nil {
+		return nil, xerrors.Errorf("failed to decode beacon entry %d: %w", round, err)
+	}
+	return &entry, nil
+}
+
+func (s *entryStore) put(entry block.BeaconEntry) error {
+	buf := new(bytes.Buffer)
+	if err := entry.MarshalCBOR(buf); err != nil {
+		return err
+	}
+	if err := s.ds.Put(roundKey(entry.Round), buf.Bytes()); err != nil {
+		return err
+	}
+
+	s.lk.Lock()
+	if entry.Round > s.latest {
+		s.latest = entry.Round
+	}
+	prune := s.latest > s.keep && s.latest-s.lastPruned >= pruneInterval
+	if prune {
+		s.lastPruned = s.latest
+	}
+	cutoff := s.latest - s.keep
+	s.lk.Unlock()
+
+	if prune {
+		return s.prune(cutoff)
+	}
+	return nil
+}
+
+// prune deletes the entries before round `before`.
+func (s *entryStore) prune(before uint64) error {
+	res, err := s.ds.Query(query.Query{KeysOnly: true})
+	if err != nil {
+		return err
+	}
+	defer res.Close() // nolint: errcheck
+
+	batch, err := s.ds.Batch()
+	if err != nil {
+		return err
+	}
+	var pruned int
+	for entry := range res.Next() {
+		if entry.Error != nil {
+			return entry.Error
+		}
+		round, err := strconv.ParseUint(strings.TrimPrefix(entry.Key, "/"), 10, 64)
+		if err != nil {
+			continue
+		}
+		if round < before {
+			if err := batch.Delete(ds.NewKey(entry.Key)); err != nil {
+				return err
+			}
+			pruned++
+		}
+	}
+	if err := batch.Commit(); err != nil {
+		return err
+	}
+
+	log.Debugf("pruned %d beacon entries before round %d", pruned, before)
+	return nil
+}
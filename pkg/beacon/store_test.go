@@ -0,0 +1,46 @@
+package beacon
+
+import (
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+func TestEntryStore(t *testing.T) {
+	tf.UnitTest(t)
+
+	t.Run("entries survive a new store", func(t *testing.T) {
+		dstore := ds.NewMapDatastore()
+		store := newEntryStore(dstore, "chain", BeaconEntriesKept)
+		require.NoError(t, store.put(block.BeaconEntry{Round: 7, Data: []byte{7}}))
+
+		entry, err := newEntryStore(dstore, "chain", BeaconEntriesKept).get(7)
+		require.NoError(t, err)
+		require.NotNil(t, entry)
+		assert.Equal(t, []byte{7}, entry.Data)
+
+		entry, err = newEntryStore(dstore, "other-chain", BeaconEntriesKept).get(7)
+		require.NoError(t, err)
+		assert.Nil(t, entry)
+	})
+
+	t.Run("old entries are pruned", func(t *testing.T) {
+		store := newEntryStore(ds.NewMapDatastore(), "chain", 10)
+		for round := uint64(1); round <= pruneInterval; round++ {
+			require.NoError(t, store.put(block.BeaconEntry{Round: round, Data: []byte{1}}))
+		}
+
+		entry, err := store.get(pruneInterval - 11)
+		require.NoError(t, err)
+		assert.Nil(t, entry)
+
+		entry, err = store.get(pruneInterval - 10)
+		require.NoError(t, err)
+		assert.NotNil(t, entry)
+	})
+}
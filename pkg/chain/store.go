@@ -28,6 +28,7 @@ import (
 	"github.com/filecoin-project/venus/pkg/block"
 	"github.com/filecoin-project/venus/pkg/config"
 	"github.com/filecoin-project/venus/pkg/crypto"
+	"github.com/filecoin-project/venus/pkg/journal"
 	"github.com/filecoin-project/venus/pkg/metrics/tracing"
 	"github.com/filecoin-project/venus/pkg/repo"
 	"github.com/filecoin-project/venus/pkg/specactors/adt"
@@ -78,6 +79,24 @@ type HeadChange struct {
 	Val  *block.TipSet
 }
 
+// Journal event types.
+const (
+	evtTypeHeadChange = iota
+	evtTypeReorg
+)
+
+// HeadChangeEvt is the journal entry of a head change. Reverted is only set
+// for reorgs, that is when the new head does not extend the old one.
+type HeadChangeEvt struct {
+	From        block.TipSetKey
+	FromHeight  abi.ChainEpoch
+	To          block.TipSetKey
+	ToHeight    abi.ChainEpoch
+	RevertCount int
+	ApplyCount  int
+	Reverted    []block.TipSetKey `json:",omitempty"`
+}
+
 // CheckPoint is the key which the check-point written in the datastore.
 var CheckPoint = datastore.NewKey("/chain/checkPoint")
 
@@ -133,6 +152,9 @@ type Store struct {
 	reorgNotifeeCh chan ReorgNotifee
 
 	tsCache *lru.ARCCache
+
+	evtTypes [2]journal.EventType
+	journal  journal.Journal
 }
 
 // NewStore constructs a new default store.
@@ -142,9 +164,13 @@ func NewStore(ds repo.Datastore,
 	sr Reporter,
 	forkConfig *config.ForkUpgradeConfig,
 	genesisCid cid.Cid,
+	j journal.Journal,
 ) *Store {
 	cacheDs := NewCacheDs(ds, true)
 	tsCache, _ := lru.NewARC(10000)
+	if j == nil {
+		j = journal.NilJournal()
+	}
 	store := &Store{
 		stateAndBlockSource: cst,
 		ds:                  cacheDs,
@@ -156,6 +182,11 @@ func NewStore(ds repo.Datastore,
 		reporter:       sr,
 		reorgNotifeeCh: make(chan ReorgNotifee),
 		tsCache:        tsCache,
+		evtTypes: [...]journal.EventType{
+			evtTypeHeadChange: j.RegisterEventType("chain", "head_change"),
+			evtTypeReorg:      j.RegisterEventType("chain", "reorg"),
+		},
+		journal: j,
 	}
 	//todo cycle reference , may think a better idea
 	store.tipIndex = NewTipStateCache(store)
@@ -488,6 +519,7 @@ func (store *Store) SetHead(ctx context.Context, newTs *block.TipSet) error {
 		return nil
 	}
 
+	var oldHead *block.TipSet
 	dropped, added, update, err := func() ([]*block.TipSet, []*block.TipSet, bool, error) {
 		var dropped []*block.TipSet
 		var added []*block.TipSet
@@ -500,7 +532,7 @@ func (store *Store) SetHead(ctx context.Context, newTs *block.TipSet) error {
 				return nil, nil, false, nil
 			}
 			//reorg
-			oldHead := store.head
+			oldHead = store.head
 			dropped, added, err = CollectTipsToCommonAncestor(ctx, store, oldHead, newTs)
 			if err != nil {
 				return nil, nil, false, err
@@ -530,6 +562,7 @@ func (store *Store) SetHead(ctx context.Context, newTs *block.TipSet) error {
 		return err
 	}
 	store.reporter.UpdateStatus(validateHead(newTs.Key()), validateHeight(h))
+	store.recordHeadChange(oldHead, newTs, dropped, added)
 
 	//todo wrap by go function
 	Reverse(added)
@@ -543,6 +576,34 @@ func (store *Store) SetHead(ctx context.Context, newTs *block.TipSet) error {
 	return nil
 }
 
+// recordHeadChange journals a head change, and a reorg when tipsets of the
+// old chain are reverted.
+func (store *Store) recordHeadChange(oldHead, newHead *block.TipSet, dropped, added []*block.TipSet) {
+	evt := HeadChangeEvt{
+		To:          newHead.Key(),
+		ToHeight:    newHead.EnsureHeight(),
+		RevertCount: len(dropped),
+		ApplyCount:  len(added),
+	}
+	if oldHead != nil {
+		evt.From = oldHead.Key()
+		evt.FromHeight = oldHead.EnsureHeight()
+	}
+	store.journal.RecordEvent(store.evtTypes[evtTypeHeadChange], func() interface{} {
+		return evt
+	})
+
+	if len(dropped) > 0 {
+		store.journal.RecordEvent(store.evtTypes[evtTypeReorg], func() interface{} {
+			reorgEvt := evt
+			for _, ts := range dropped {
+				reorgEvt.Reverted = append(reorgEvt.Reverted, ts.Key())
+			}
+			return reorgEvt
+		})
+	}
+}
+
 func (store *Store) reorgWorker(ctx context.Context) chan reorg {
 	headChangeNotifee := func(rev, app []*block.TipSet) error {
 		notif := make([]*HeadChange, len(rev)+len(app))
@@ -40,7 +40,7 @@ func newChainStore(r repo.Repo, genTs *block.TipSet) *CborBlockStore {
 	tempBlock := r.Datastore()
 	cborStore := cbor.NewCborStore(tempBlock)
 	return &CborBlockStore{
-		Store:     chain.NewStore(r.ChainDatastore(), cborStore, tempBlock, chain.NewStatusReporter(), config.DefaultForkUpgradeParam, genTs.At(0).Cid()),
+		Store:     chain.NewStore(r.ChainDatastore(), cborStore, tempBlock, chain.NewStatusReporter(), config.DefaultForkUpgradeParam, genTs.At(0).Cid(), nil),
 		cborStore: cborStore,
 	}
 }
@@ -300,9 +300,9 @@ func TestHead(t *testing.T) {
 	sr := chain.NewStatusReporter()
 	bs := builder.BlockStore()
 	cborStore := builder.Cstore()
-	cs := chain.NewStore(r.ChainDatastore(), cborStore, bs, sr, config.DefaultForkUpgradeParam, genTS.At(0).Cid())
+	cs := chain.NewStore(r.ChainDatastore(), cborStore, bs, sr, config.DefaultForkUpgradeParam, genTS.At(0).Cid(), nil)
 	cboreStore := &CborBlockStore{
-		Store: chain.NewStore(r.ChainDatastore(), cborStore, bs, sr, config.DefaultForkUpgradeParam, genTS.At(0).Cid()),
+		Store: chain.NewStore(r.ChainDatastore(), cborStore, bs, sr, config.DefaultForkUpgradeParam, genTS.At(0).Cid(), nil),
 	}
 	// Construct test chain data
 	link1 := builder.AppendOn(genTS, 2)
@@ -416,7 +416,7 @@ func TestLoadAndReboot(t *testing.T) {
 	requirePutBlocksToCborStore(t, cst, link4.ToSlice()...)
 
 	cboreStore := &CborBlockStore{
-		Store:     chain.NewStore(ds, cst, bs, chain.NewStatusReporter(), config.DefaultForkUpgradeParam, genTS.At(0).Cid()),
+		Store:     chain.NewStore(ds, cst, bs, chain.NewStatusReporter(), config.DefaultForkUpgradeParam, genTS.At(0).Cid(), nil),
 		cborStore: cst,
 	}
 	requirePutTestChain(ctx, t, cboreStore, link4.Key(), builder, 5)
@@ -427,7 +427,7 @@ func TestLoadAndReboot(t *testing.T) {
 
 	// rebuild chain with same datastore and cborstore
 	sr := chain.NewStatusReporter()
-	rebootChain := chain.NewStore(ds, cst, bs, sr, config.DefaultForkUpgradeParam, genTS.At(0).Cid())
+	rebootChain := chain.NewStore(ds, cst, bs, sr, config.DefaultForkUpgradeParam, genTS.At(0).Cid(), nil)
 	rebootCbore := &CborBlockStore{
 		Store: rebootChain,
 	}
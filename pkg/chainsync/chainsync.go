@@ -14,6 +14,8 @@ import (
 	"github.com/filecoin-project/venus/pkg/chainsync/syncer"
 	"github.com/filecoin-project/venus/pkg/clock"
 	"github.com/filecoin-project/venus/pkg/fork"
+	"github.com/filecoin-project/venus/pkg/journal"
+	"github.com/filecoin-project/venus/pkg/net"
 	"github.com/filecoin-project/venus/pkg/slashing"
 )
 
@@ -42,13 +44,15 @@ func NewManager(fv syncer.StateProcessor,
 	exchangeClient exchange.Client,
 	c clock.Clock,
 	detector *slashing.ConsensusFaultDetector,
-	fork fork.IFork) (Manager, error) {
-	syncer, err := syncer.NewSyncer(fv, hv, cs, s, m, bsstore, exchangeClient, c, detector, fork)
+	fork fork.IFork,
+	peerBanner net.PeerBanner,
+	j journal.Journal) (Manager, error) {
+	syncer, err := syncer.NewSyncer(fv, hv, cs, s, m, bsstore, exchangeClient, c, detector, fork, peerBanner)
 	if err != nil {
 		return Manager{}, err
 	}
 
-	dispatcher := dispatcher.NewDispatcher(syncer)
+	dispatcher := dispatcher.NewDispatcher(syncer, j)
 
 	return Manager{
 		syncer:     syncer,
@@ -10,9 +10,12 @@ import (
 	"github.com/filecoin-project/venus/pkg/chainsync/types"
 	"github.com/streadway/handy/atomic"
 
+	"github.com/filecoin-project/go-state-types/abi"
 	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-libp2p-core/peer"
 
 	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/journal"
 )
 
 var log = logging.Logger("chainsync.dispatcher")
@@ -29,13 +32,32 @@ type dispatchSyncer interface {
 	HandleNewTipSet(context.Context, *types.Target) error
 }
 
+// Journal event types.
+const (
+	evtTypeTargetSynced = iota
+	evtTypeTargetFailed
+)
+
+// SyncTargetEvt is the journal entry of the outcome of syncing a target.
+type SyncTargetEvt struct {
+	Head     block.TipSetKey
+	Height   abi.ChainEpoch
+	Sender   peer.ID
+	Base     abi.ChainEpoch
+	Duration time.Duration
+	Error    string `json:",omitempty"`
+}
+
 // NewDispatcher creates a new syncing dispatcher with default queue sizes.
-func NewDispatcher(catchupSyncer dispatchSyncer) *Dispatcher {
-	return NewDispatcherWithSizes(catchupSyncer, DefaultWorkQueueSize, DefaultInQueueSize)
+func NewDispatcher(catchupSyncer dispatchSyncer, j journal.Journal) *Dispatcher {
+	return NewDispatcherWithSizes(catchupSyncer, DefaultWorkQueueSize, DefaultInQueueSize, j)
 }
 
 // NewDispatcherWithSizes creates a new syncing dispatcher.
-func NewDispatcherWithSizes(syncer dispatchSyncer, workQueueSize, inQueueSize int) *Dispatcher {
+func NewDispatcherWithSizes(syncer dispatchSyncer, workQueueSize, inQueueSize int, j journal.Journal) *Dispatcher {
+	if j == nil {
+		j = journal.NilJournal()
+	}
 	return &Dispatcher{
 		workTracker:     types.NewTargetTracker(workQueueSize),
 		syncer:          syncer,
@@ -44,6 +66,11 @@ func NewDispatcherWithSizes(syncer dispatchSyncer, workQueueSize, inQueueSize in
 		registeredCb:    func(t *types.Target, err error) {},
 		cancelControler: list.New(),
 		maxCount:        3,
+		evtTypes: [...]journal.EventType{
+			evtTypeTargetSynced: j.RegisterEventType("sync", "target_synced"),
+			evtTypeTargetFailed: j.RegisterEventType("sync", "target_failed"),
+		},
+		journal: j,
 	}
 }
 
@@ -84,6 +111,9 @@ type Dispatcher struct {
 	lk              sync.Mutex
 	conCurrent      atomic.Int
 	maxCount        int64
+
+	evtTypes [2]journal.EventType
+	journal  journal.Journal
 }
 
 // SendOwnBlock handles chain info from a node's own mining system
@@ -189,6 +219,7 @@ func (d *Dispatcher) syncWorker(ctx context.Context) {
 							if err != nil {
 								log.Infof("failed sync of %v at %d  %s", syncTarget.Head.Key(), syncTarget.Head.EnsureHeight(), err)
 							}
+							d.recordTarget(syncTarget, err)
 							d.registeredCb(syncTarget, err)
 							d.conCurrent.Add(-1)
 						}()
@@ -206,6 +237,29 @@ func (d *Dispatcher) syncWorker(ctx context.Context) {
 	}
 }
 
+// recordTarget journals the outcome of syncing `target`.
+func (d *Dispatcher) recordTarget(target *types.Target, err error) {
+	evtType := d.evtTypes[evtTypeTargetSynced]
+	if err != nil {
+		evtType = d.evtTypes[evtTypeTargetFailed]
+	}
+	d.journal.RecordEvent(evtType, func() interface{} {
+		evt := SyncTargetEvt{
+			Head:     target.Head.Key(),
+			Height:   target.Head.EnsureHeight(),
+			Sender:   target.Sender,
+			Duration: time.Since(target.Start),
+		}
+		if target.Base != nil {
+			evt.Base = target.Base.EnsureHeight()
+		}
+		if err != nil {
+			evt.Error = err.Error()
+		}
+		return evt
+	})
+}
+
 // RegisterCallback registers a callback on the dispatcher that
 // will fire after every successful target sync.
 func (d *Dispatcher) RegisterCallback(cb func(*types.Target, error)) {
@@ -37,7 +37,7 @@ func TestDispatchStartHappy(t *testing.T) {
 	s := &mockSyncer{
 		headsCalled: make([]*block.TipSet, 0),
 	}
-	testDispatch := dispatcher.NewDispatcher(s)
+	testDispatch := dispatcher.NewDispatcher(s, nil)
 
 	cis := []*block.ChainInfo{
 		// We need to put these in priority order to avoid a race.
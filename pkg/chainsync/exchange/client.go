@@ -15,15 +15,22 @@ import (
 	"github.com/libp2p/go-libp2p-core/network"
 	"github.com/libp2p/go-libp2p-core/peer"
 
+	"go.opencensus.io/stats"
 	"go.opencensus.io/trace"
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/metrics"
 	"github.com/filecoin-project/venus/pkg/net"
 )
 
 var exchangeClientLogger = logging.Logger("exchange.client")
 
+var requestDuration = metrics.NewTimerWithBuckets("exchange/request_duration",
+	"Duration of successful chain exchange requests in milliseconds",
+	stats.UnitMilliseconds,
+	[]float64{50, 100, 250, 500, 1000, 2500, 5000, 10000, 20000, 30000, 60000})
+
 // client implements exchange.Client, using the libp2p ChainExchange protocol
 // as the fetching mechanism.
 type client struct {
@@ -300,6 +307,10 @@ func (c *client) GetBlocks(ctx context.Context, tsk block.TipSetKey, count int)
 		)
 	}
 
+	if count > ParallelChunkSize {
+		return c.getBlocksChunked(ctx, tsk, count)
+	}
+
 	req := &Request{
 		Head:    tsk.Cids(),
 		Length:  uint64(count),
@@ -310,6 +321,7 @@ func (c *client) GetBlocks(ctx context.Context, tsk block.TipSetKey, count int)
 	if err != nil {
 		return nil, err
 	}
+	newProgressTracker(ctx, Headers, uint64(count)).add(uint64(len(validRes.tipsets)))
 
 	return validRes.tipsets, nil
 }
@@ -348,6 +360,10 @@ func (c *client) GetChainMessages(ctx context.Context, tipsets []*block.TipSet)
 	}
 	defer span.End()
 
+	if length > ParallelChunkSize {
+		return c.getChainMessagesParallel(ctx, tipsets)
+	}
+
 	req := &Request{
 		Head:    head.Key().Cids(),
 		Length:  length,
@@ -358,6 +374,7 @@ func (c *client) GetChainMessages(ctx context.Context, tipsets []*block.TipSet)
 	if err != nil {
 		return nil, err
 	}
+	newProgressTracker(ctx, Messages, length).add(uint64(len(validRes.messages)))
 
 	return validRes.messages, nil
 }
@@ -456,6 +473,7 @@ func (c *client) sendRequestToPeer(ctx context.Context, peer peer.ID, req *Reque
 		)
 	}
 
+	requestDuration.Record(ctx, time.Since(connectionStart))
 	c.peerTracker.logSuccess(peer, time.Since(connectionStart), uint64(len(res.Chain)))
 	// FIXME: We should really log a success only after we validate the response.
 	//  It might be a bit hard to do.
@@ -0,0 +1,231 @@
+package exchange
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/venus/pkg/block"
+)
+
+const (
+	// ParallelChunkSize is the number of tipsets requested from a single
+	// peer at once when a large range is split across peers.
+	ParallelChunkSize = 100
+	// MaxParallelPeers is the number of best ranked peers that serve
+	// chunks of the same range concurrently.
+	MaxParallelPeers = 4
+)
+
+// Progress reports how much of a (possibly chunked) request has already
+// been fetched and validated.
+type Progress struct {
+	// Options are the request options (`Headers` and/or `Messages`).
+	Options uint64
+	// Fetched is the number of tipsets received so far.
+	Fetched uint64
+	// Total is the number of tipsets requested.
+	Total uint64
+}
+
+// ProgressFunc is called every time a chunk of a request completes.
+// Calls for the same request are never concurrent.
+type ProgressFunc func(Progress)
+
+type progressKey struct{}
+
+// WithProgress returns a context that makes the client report the progress
+// of the requests issued with it to `fn`.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressTracker serializes progress reports of the chunks of a request.
+type progressTracker struct {
+	lk       sync.Mutex
+	report   ProgressFunc
+	progress Progress
+}
+
+func newProgressTracker(ctx context.Context, options uint64, total uint64) *progressTracker {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return &progressTracker{
+		report: fn,
+		progress: Progress{
+			Options: options,
+			Total:   total,
+		},
+	}
+}
+
+func (pt *progressTracker) add(fetched uint64) {
+	pt.lk.Lock()
+	defer pt.lk.Unlock()
+	pt.progress.Fetched += fetched
+	if pt.report != nil {
+		pt.report(pt.progress)
+	}
+}
+
+// bestPeers returns at most `MaxParallelPeers` peers ranked by the peer
+// tracker, followed by the rest of the known peers that are used as
+// fallbacks when a chunk fails.
+func (c *client) bestPeers() ([]peer.ID, []peer.ID) {
+	peers := c.peerTracker.prefSortedPeers()
+	if len(peers) <= MaxParallelPeers {
+		return peers, nil
+	}
+	return peers[:MaxParallelPeers], peers[MaxParallelPeers:]
+}
+
+// fetchChunk requests a single chunk, starting with the peer at `offset` of
+// `workers` and moving on to the rest of `workers` and then `fallbacks` when
+// a peer fails to send or the response does not validate.
+func (c *client) fetchChunk(
+	ctx context.Context,
+	req *Request,
+	tipsets []*block.TipSet,
+	workers []peer.ID,
+	fallbacks []peer.ID,
+	offset int,
+) (*validatedResponse, error) {
+	candidates := make([]peer.ID, 0, len(workers)+len(fallbacks))
+	for i := range workers {
+		candidates = append(candidates, workers[(offset+i)%len(workers)])
+	}
+	candidates = append(candidates, fallbacks...)
+
+	globalTime := time.Now()
+	for _, p := range candidates {
+		select {
+		case <-ctx.Done():
+			return nil, xerrors.Errorf("context cancelled: %w", ctx.Err())
+		default:
+		}
+
+		res, err := c.sendRequestToPeer(ctx, p, req)
+		if err != nil {
+			exchangeClientLogger.Warnf("could not send chunk request to peer %s: %s", p.String(), err)
+			continue
+		}
+
+		validRes, err := c.processResponse(req, res, tipsets)
+		if err != nil {
+			exchangeClientLogger.Warnf("processing peer %s chunk response failed: %s", p.String(), err)
+			c.peerTracker.logFailure(p, time.Since(globalTime), req.Length)
+			continue
+		}
+
+		c.peerTracker.logGlobalSuccess(time.Since(globalTime))
+		c.host.ConnManager().TagPeer(p, "bsync", SuccessPeerTagValue)
+		return validRes, nil
+	}
+
+	return nil, xerrors.Errorf("chunk of %d tipsets from %s failed for all peers", req.Length, block.NewTipSetKey(req.Head...))
+}
+
+// getChainMessagesParallel splits `tipsets` in chunks of `ParallelChunkSize`
+// and fetches their messages concurrently from the best ranked peers. Every
+// chunk is validated against its own tipsets, a chunk that fails on one peer
+// is retried on the others, a chunk that fails on every peer cancels the rest.
+func (c *client) getChainMessagesParallel(ctx context.Context, tipsets []*block.TipSet) ([]*CompactedMessages, error) {
+	workers, fallbacks := c.bestPeers()
+	if len(workers) == 0 {
+		return nil, xerrors.Errorf("no peers available")
+	}
+
+	progress := newProgressTracker(ctx, Messages, uint64(len(tipsets)))
+	messages := make([]*CompactedMessages, len(tipsets))
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, len(workers))
+	for chunkIdx, start := 0, 0; start < len(tipsets); chunkIdx, start = chunkIdx+1, start+ParallelChunkSize {
+		end := start + ParallelChunkSize
+		if end > len(tipsets) {
+			end = len(tipsets)
+		}
+		chunkIdx, start := chunkIdx, start
+		chunk := tipsets[start:end]
+
+		eg.Go(func() error {
+			select {
+			case sem <- struct{}{}:
+			case <-egCtx.Done():
+				return egCtx.Err()
+			}
+			defer func() { <-sem }()
+
+			// A peer may answer with a partial chain, keep asking for
+			// the remaining tipsets until the chunk is complete.
+			for fetched := 0; fetched < len(chunk); {
+				remain := chunk[fetched:]
+				req := &Request{
+					Head:    remain[0].Key().Cids(),
+					Length:  uint64(len(remain)),
+					Options: Messages,
+				}
+				validRes, err := c.fetchChunk(egCtx, req, remain, workers, fallbacks, chunkIdx)
+				if err != nil {
+					return err
+				}
+				copy(messages[start+fetched:], validRes.messages)
+				fetched += len(validRes.messages)
+				progress.add(uint64(len(validRes.messages)))
+			}
+			return nil
+		})
+	}
+
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// getBlocksChunked fetches `count` tipsets backwards from `tsk` in chunks of
+// `ParallelChunkSize`. Unlike messages, header chunks can not be requested
+// concurrently because the head of a chunk is only known once the previous
+// one arrived, so every chunk goes to the best ranked peer that serves it and
+// is retried on the others on failure.
+func (c *client) getBlocksChunked(ctx context.Context, tsk block.TipSetKey, count int) ([]*block.TipSet, error) {
+	progress := newProgressTracker(ctx, Headers, uint64(count))
+	out := make([]*block.TipSet, 0, count)
+
+	head := tsk
+	for chunkIdx := 0; len(out) < count; chunkIdx++ {
+		length := count - len(out)
+		if length > ParallelChunkSize {
+			length = ParallelChunkSize
+		}
+
+		workers, fallbacks := c.bestPeers()
+		if len(workers) == 0 {
+			return nil, xerrors.Errorf("no peers available")
+		}
+
+		req := &Request{
+			Head:    head.Cids(),
+			Length:  uint64(length),
+			Options: Headers,
+		}
+		validRes, err := c.fetchChunk(ctx, req, nil, workers, fallbacks, chunkIdx)
+		if err != nil {
+			return nil, err
+		}
+
+		out = append(out, validRes.tipsets...)
+		progress.add(uint64(len(validRes.tipsets)))
+
+		last := validRes.tipsets[len(validRes.tipsets)-1]
+		if last.EnsureHeight() == 0 {
+			break
+		}
+		head = last.EnsureParents()
+	}
+
+	return out, nil
+}
@@ -0,0 +1,342 @@
+package exchange
+
+import (
+	"bufio"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	cborutil "github.com/filecoin-project/go-cbor-util"
+	"github.com/filecoin-project/go-state-types/abi"
+	fbig "github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/crypto"
+	inet "github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	emptycid "github.com/filecoin-project/venus/pkg/testhelpers/empty_cid"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/venus/pkg/types"
+)
+
+// serveMode is how a test peer answers chain exchange requests.
+type serveMode int
+
+const (
+	serveAll serveMode = iota
+	// servePartial answers at most `partialLength` tipsets.
+	servePartial
+	// serveInvalid answers responses that do not validate.
+	serveInvalid
+	// serveGoAway answers with an error status.
+	serveGoAway
+)
+
+const partialLength = 30
+
+// testChain is a chain of single block tipsets, the messages of every tipset
+// hold one message whose nonce is the height.
+type testChain struct {
+	tipsets []*block.TipSet
+	heights map[string]int
+}
+
+func newTestChain(t *testing.T, length int) *testChain {
+	miner, err := address.NewIDAddress(1000)
+	require.NoError(t, err)
+	tc := &testChain{heights: make(map[string]int)}
+	parent := block.UndefTipSet
+	for h := 0; h <= length; h++ {
+		blk := &block.Block{
+			Miner:                 miner,
+			Ticket:                block.Ticket{VRFProof: []byte{byte(h), byte(h >> 8)}},
+			Parents:               parent.Key(),
+			ParentWeight:          fbig.Zero(),
+			Height:                abi.ChainEpoch(h),
+			ParentStateRoot:       emptycid.EmptyMessagesCID,
+			Messages:              emptycid.EmptyTxMetaCID,
+			ParentMessageReceipts: emptycid.EmptyReceiptsCID,
+			BLSAggregate:          &crypto.Signature{Type: crypto.SigTypeBLS, Data: []byte{}},
+			BlockSig:              &crypto.Signature{Type: crypto.SigTypeSecp256k1, Data: []byte{}},
+			ElectionProof:         &block.ElectionProof{VRFProof: []byte{0x0c, 0x0d}, WinCount: 1},
+		}
+		parent = block.RequireNewTipSet(t, blk)
+		tc.tipsets = append(tc.tipsets, parent)
+		tc.heights[parent.Key().String()] = h
+	}
+	return tc
+}
+
+// from returns `count` tipsets backwards from the height `h`.
+func (tc *testChain) from(h int, count int) []*block.TipSet {
+	var out []*block.TipSet
+	for ; h >= 0 && len(out) < count; h-- {
+		out = append(out, tc.tipsets[h])
+	}
+	return out
+}
+
+func (tc *testChain) messages(ts *block.TipSet) *CompactedMessages {
+	addr, _ := address.NewIDAddress(100)
+	return &CompactedMessages{
+		Bls: []*types.UnsignedMessage{{
+			To:         addr,
+			From:       addr,
+			Nonce:      uint64(ts.EnsureHeight()),
+			Value:      fbig.Zero(),
+			GasFeeCap:  fbig.Zero(),
+			GasPremium: fbig.Zero(),
+		}},
+		BlsIncludes:   [][]uint64{{0}},
+		SecpkIncludes: [][]uint64{{}},
+	}
+}
+
+// testPeer serves the test chain and records the requests it got.
+type testPeer struct {
+	id   peer.ID
+	mode serveMode
+
+	lk       sync.Mutex
+	requests []Request
+}
+
+func (p *testPeer) serve(t *testing.T, tc *testChain) inet.StreamHandler {
+	return func(stream inet.Stream) {
+		defer stream.Close() // nolint: errcheck
+
+		var req Request
+		if err := cborutil.ReadCborRPC(bufio.NewReader(stream), &req); err != nil {
+			t.Errorf("failed to read the request: %s", err)
+			return
+		}
+		p.lk.Lock()
+		p.requests = append(p.requests, req)
+		p.lk.Unlock()
+
+		res := &Response{Status: Ok}
+		length := int(req.Length)
+		if p.mode == servePartial && length > partialLength {
+			length = partialLength
+			res.Status = Partial
+		}
+		opts := parseOptions(req.Options)
+		for _, ts := range tc.from(tc.heights[block.NewTipSetKey(req.Head...).String()], length) {
+			bts := &BSTipSet{}
+			if opts.IncludeHeaders {
+				bts.Blocks = ts.Blocks()
+			}
+			if opts.IncludeMessages {
+				bts.Messages = tc.messages(ts)
+			}
+			res.Chain = append(res.Chain, bts)
+		}
+		if len(res.Chain) < int(req.Length) {
+			res.Status = Partial
+		}
+
+		switch p.mode {
+		case serveInvalid:
+			if opts.IncludeHeaders {
+				// the chain does not start at the head requested
+				res.Chain = res.Chain[1:]
+			}
+			for _, bts := range res.Chain {
+				if bts.Messages != nil {
+					bts.Messages.BlsIncludes = nil
+				}
+			}
+		case serveGoAway:
+			res = &Response{Status: GoAway}
+		}
+		if err := cborutil.WriteCborRPC(stream, res); err != nil {
+			t.Errorf("failed to write the response: %s", err)
+		}
+	}
+}
+
+func (p *testPeer) served() []Request {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	return append([]Request{}, p.requests...)
+}
+
+// setupPeers connects a client to a peer per mode, the peers are ranked in
+// the order of `modes` so the first `MaxParallelPeers` are the workers.
+func setupPeers(t *testing.T, tc *testChain, modes ...serveMode) (*client, []*testPeer) {
+	ctx := context.Background()
+	mn := mocknet.New(ctx)
+	h, err := mn.GenPeer()
+	require.NoError(t, err)
+
+	c := NewClient(h, nil).(*client)
+	var peers []*testPeer
+	for i, mode := range modes {
+		ph, err := mn.GenPeer()
+		require.NoError(t, err)
+		p := &testPeer{id: ph.ID(), mode: mode}
+		ph.SetStreamHandler(ChainExchangeProtocolID, p.serve(t, tc))
+		require.NoError(t, h.Peerstore().AddProtocols(p.id, ChainExchangeProtocolID))
+
+		c.AddPeer(p.id)
+		c.peerTracker.peers[p.id].successes = 1
+		c.peerTracker.peers[p.id].averageTime = time.Duration(i+1) * time.Millisecond
+		peers = append(peers, p)
+	}
+	require.NoError(t, mn.LinkAll())
+	require.NoError(t, mn.ConnectAllButSelf())
+	return c, peers
+}
+
+// recordProgress returns a context that collects the progress reports.
+func recordProgress(ctx context.Context) (context.Context, func() []Progress) {
+	var lk sync.Mutex
+	var reports []Progress
+	ctx = WithProgress(ctx, func(p Progress) {
+		lk.Lock()
+		defer lk.Unlock()
+		reports = append(reports, p)
+	})
+	return ctx, func() []Progress {
+		lk.Lock()
+		defer lk.Unlock()
+		return append([]Progress{}, reports...)
+	}
+}
+
+func assertProgress(t *testing.T, reports []Progress, options uint64, total uint64) {
+	require.NotEmpty(t, reports)
+	var last uint64
+	for _, p := range reports {
+		assert.Equal(t, options, p.Options)
+		assert.Equal(t, total, p.Total)
+		assert.True(t, p.Fetched > last, "progress goes forward")
+		last = p.Fetched
+	}
+	assert.Equal(t, total, last)
+}
+
+func TestChainMessagesChunksRetryOnFallbackPeers(t *testing.T) {
+	tf.UnitTest(t)
+
+	tc := newTestChain(t, 260)
+	// every worker fails, the first fallback refuses and the last serves
+	c, peers := setupPeers(t, tc, serveInvalid, serveInvalid, serveInvalid, serveInvalid, serveGoAway, serveAll)
+
+	ctx, progress := recordProgress(context.Background())
+	tipsets := tc.from(250, 250)
+	msgs, err := c.GetChainMessages(ctx, tipsets)
+	require.NoError(t, err)
+	require.Len(t, msgs, len(tipsets))
+	for i, ts := range tipsets {
+		require.Len(t, msgs[i].Bls, 1)
+		assert.Equal(t, uint64(ts.EnsureHeight()), msgs[i].Bls[0].Nonce)
+	}
+
+	// 3 chunks of at most ParallelChunkSize, each one tried on every peer
+	served := peers[len(peers)-1].served()
+	require.Len(t, served, 3)
+	heads := map[int]uint64{}
+	for _, req := range served {
+		assert.Equal(t, uint64(Messages), req.Options)
+		heads[tc.heights[block.NewTipSetKey(req.Head...).String()]] = req.Length
+	}
+	assert.Equal(t, map[int]uint64{250: 100, 150: 100, 50: 50}, heads)
+	for _, p := range peers[:len(peers)-1] {
+		assert.Len(t, p.served(), 3)
+	}
+
+	assertProgress(t, progress(), Messages, 250)
+}
+
+func TestChainMessagesChunksCompletePartialResponses(t *testing.T) {
+	tf.UnitTest(t)
+
+	tc := newTestChain(t, 150)
+	c, peers := setupPeers(t, tc, servePartial)
+
+	ctx, progress := recordProgress(context.Background())
+	tipsets := tc.from(150, 130)
+	msgs, err := c.GetChainMessages(ctx, tipsets)
+	require.NoError(t, err)
+	require.Len(t, msgs, len(tipsets))
+	for i, ts := range tipsets {
+		assert.Equal(t, uint64(ts.EnsureHeight()), msgs[i].Bls[0].Nonce)
+	}
+
+	// the chunk of 100 and the one of 30 are completed request by request
+	lengths := map[int]uint64{}
+	for _, req := range peers[0].served() {
+		lengths[tc.heights[block.NewTipSetKey(req.Head...).String()]] = req.Length
+	}
+	assert.Equal(t, map[int]uint64{150: 100, 120: 70, 90: 40, 60: 10, 50: 30}, lengths)
+
+	reports := progress()
+	assertProgress(t, reports, Messages, 130)
+	assert.Len(t, reports, 5)
+}
+
+func TestChainMessagesChunkFailureCancelsTheOthers(t *testing.T) {
+	tf.UnitTest(t)
+
+	tc := newTestChain(t, 600)
+	c, peers := setupPeers(t, tc, serveInvalid)
+
+	_, err := c.GetChainMessages(context.Background(), tc.from(600, 600))
+	require.Error(t, err)
+
+	// the single worker takes the chunks one at a time, the first failure
+	// stops the 6 chunks before most of them are requested
+	assert.True(t, len(peers[0].served()) <= 2, "%d chunks requested", len(peers[0].served()))
+}
+
+func TestBlocksChunksAreValidated(t *testing.T) {
+	tf.UnitTest(t)
+
+	tc := newTestChain(t, 300)
+	c, peers := setupPeers(t, tc, serveInvalid, servePartial, serveAll)
+
+	ctx, progress := recordProgress(context.Background())
+	tipsets, err := c.GetBlocks(ctx, tc.tipsets[280].Key(), 250)
+	require.NoError(t, err)
+	require.Len(t, tipsets, 250)
+	for i, ts := range tipsets {
+		assert.Equal(t, tc.tipsets[280-i].Key(), ts.Key())
+	}
+
+	// header chunks go one after the other, every one starting on the next
+	// worker, the invalid peer never gets one through
+	for _, p := range peers {
+		for _, req := range p.served() {
+			assert.Equal(t, uint64(Headers), req.Options)
+			assert.True(t, req.Length <= ParallelChunkSize)
+		}
+	}
+	assert.NotEmpty(t, peers[0].served())
+	assertProgress(t, progress(), Headers, 250)
+
+	// the chain ends at genesis before the count
+	tipsets, err = c.GetBlocks(context.Background(), tc.tipsets[120].Key(), 200)
+	require.NoError(t, err)
+	assert.Len(t, tipsets, 121)
+}
+
+func TestChunksFailWithoutPeers(t *testing.T) {
+	tf.UnitTest(t)
+
+	tc := newTestChain(t, 150)
+	c, _ := setupPeers(t, tc)
+	_, err := c.GetChainMessages(context.Background(), tc.from(150, 150))
+	assert.Error(t, err)
+	_, err = c.GetBlocks(context.Background(), tc.tipsets[150].Key(), 150)
+	assert.Error(t, err)
+
+	c, _ = setupPeers(t, tc, serveInvalid, serveGoAway)
+	_, err = c.GetChainMessages(context.Background(), tc.from(150, 150))
+	assert.Error(t, err)
+}
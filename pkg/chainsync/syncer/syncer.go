@@ -13,10 +13,12 @@ import (
 
 	"github.com/filecoin-project/go-state-types/big"
 	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
 	blockstore "github.com/ipfs/go-ipfs-blockstore"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/pkg/errors"
 	"github.com/prometheus/common/log"
+	"go.opencensus.io/stats"
 	"go.opencensus.io/trace"
 	"golang.org/x/xerrors"
 
@@ -24,10 +26,12 @@ import (
 	"github.com/filecoin-project/venus/pkg/chain"
 	"github.com/filecoin-project/venus/pkg/chainsync/exchange"
 	"github.com/filecoin-project/venus/pkg/clock"
+	"github.com/filecoin-project/venus/pkg/consensus"
 	"github.com/filecoin-project/venus/pkg/constants"
 	"github.com/filecoin-project/venus/pkg/fork"
 	"github.com/filecoin-project/venus/pkg/metrics"
 	"github.com/filecoin-project/venus/pkg/metrics/tracing"
+	"github.com/filecoin-project/venus/pkg/net"
 	"github.com/filecoin-project/venus/pkg/specactors/policy"
 	"github.com/filecoin-project/venus/pkg/types"
 	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
@@ -57,14 +61,23 @@ var (
 	// ErrUnexpectedStoreState indicates that the syncer's chain bsstore is violating expected invariants.
 	ErrUnexpectedStoreState = errors.New("the chain bsstore is in an unexpected state")
 
-	logSyncer    = logging.Logger("chainsync.syncer")
-	syncOneTimer *metrics.Float64Timer
-	reorgCnt     *metrics.Int64Counter
+	logSyncer         = logging.Logger("chainsync.syncer")
+	syncOneTimer      *metrics.Float64Timer
+	reorgCnt          *metrics.Int64Counter
+	headHeightGauge   *metrics.Int64Gauge
+	headWeightGauge   *metrics.Int64Gauge
+	headChangeLatency *metrics.Float64Timer
 )
 
 func init() {
 	syncOneTimer = metrics.NewTimerMs("syncer/sync_one", "Duration of single tipset validation in milliseconds")
 	reorgCnt = metrics.NewInt64Counter("chain/reorg_count", "The number of reorgs that have occurred.")
+	headHeightGauge = metrics.NewInt64Gauge("chain/head_height", "Height of the current chain head")
+	headWeightGauge = metrics.NewInt64Gauge("chain/head_weight", "Weight of the current chain head")
+	headChangeLatency = metrics.NewTimerWithBuckets("chain/head_change_latency",
+		"Delay between the start of the epoch of a tipset and it becoming the chain head in milliseconds",
+		stats.UnitMilliseconds,
+		[]float64{100, 250, 500, 1000, 2000, 4000, 6000, 8000, 10000, 15000, 20000, 30000})
 }
 
 // StateProcessor does semantic validation on fullblocks.
@@ -141,8 +154,14 @@ type Syncer struct {
 	checkPoint block.TipSetKey
 
 	fork fork.IFork
+
+	// peerBanner bans peers that send us chains failing validation
+	peerBanner net.PeerBanner
 }
 
+// BanDuration is how long a peer whose target chain fails validation is banned for.
+var BanDuration = time.Hour
+
 // NewSyncer constructs a Syncer ready for use.  The chain reader must have a
 // head tipset to initialize the staging field.
 func NewSyncer(fv StateProcessor,
@@ -154,7 +173,8 @@ func NewSyncer(fv StateProcessor,
 	exchangeClient exchange.Client,
 	c clock.Clock,
 	fd faultDetector,
-	fork fork.IFork) (*Syncer, error) {
+	fork fork.IFork,
+	peerBanner net.PeerBanner) (*Syncer, error) {
 	return &Syncer{
 		exchangeClient:  exchangeClient,
 		badTipSets:      syncTypes.NewBadTipSetCache(),
@@ -167,6 +187,7 @@ func NewSyncer(fv StateProcessor,
 		clock:           c,
 		faultDetector:   fd,
 		fork:            fork,
+		peerBanner:      peerBanner,
 	}, nil
 }
 
@@ -205,7 +226,11 @@ func (syncer *Syncer) syncOne(ctx context.Context, parent, next *block.TipSet) e
 		}
 		err = wg.Wait()
 		if err != nil {
-			return xerrors.Errorf("validate mining failed %w", err)
+			err = xerrors.Errorf("validate mining failed: %w", err)
+			if isPeerFault(ctx, err) {
+				return &peerFaultError{tipset: next.Key(), err: err}
+			}
+			return err
 		}
 	}
 	// Run a state transition to validate the tipset and compute
@@ -311,6 +336,9 @@ func (syncer *Syncer) HandleNewTipSet(ctx context.Context, target *syncTypes.Tar
 		tracing.AddErrorEndSpan(ctx, span, &err)
 	}()
 	logSyncer.Infof("Begin fetch and sync of chain with head %v from %s at height %v", target.Head.Key(), target.Sender.String(), target.Head.EnsureHeight())
+	ctx = exchange.WithProgress(ctx, func(progress exchange.Progress) {
+		target.SetProgress(progress.Fetched, progress.Total)
+	})
 	head := syncer.chainStore.GetHead()
 	//If the store already has this tipset then the syncer is finished.
 	if target.Head.At(0).ParentWeight.LessThan(head.At(0).ParentWeight) {
@@ -362,6 +390,12 @@ func (syncer *Syncer) syncSegement(ctx context.Context, target *syncTypes.Target
 			var processErr error
 			parent, processErr = syncer.processTipSetSegment(ctx, target, parent, segTipset)
 			if processErr != nil {
+				// the sender only vouches for the head it announced, the
+				// tipsets below it may come from other exchange peers
+				var fault *peerFaultError
+				if syncer.peerBanner != nil && target.Sender != "" && xerrors.As(processErr, &fault) && fault.tipset.Equals(target.Head.Key()) {
+					syncer.peerBanner.BanPeer(target.Sender, BanDuration, processErr.Error())
+				}
 				errProcessChan <- processErr
 				return
 			}
@@ -630,6 +664,36 @@ func (syncer *Syncer) processTipSetSegment(ctx context.Context, target *syncType
 	return parent, nil
 }
 
+// peerFaultError marks the failures showing that the peer sent an invalid
+// tipset, only those get the peer banned.
+type peerFaultError struct {
+	tipset block.TipSetKey
+	err    error
+}
+
+func (e *peerFaultError) Error() string {
+	return e.err.Error()
+}
+
+func (e *peerFaultError) Unwrap() error {
+	return e.err
+}
+
+// isPeerFault reports whether the validation error `err` comes from the
+// block itself rather than from a cancellation, missing local data or the
+// local clock.
+func isPeerFault(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	for _, local := range []error{context.Canceled, context.DeadlineExceeded, blockstore.ErrNotFound, datastore.ErrNotFound, consensus.ErrTemporal} {
+		if xerrors.Is(err, local) {
+			return false
+		}
+	}
+	return true
+}
+
 func (syncer *Syncer) Head() *block.TipSet {
 	return syncer.chainStore.GetHead()
 }
@@ -645,11 +709,25 @@ func (syncer *Syncer) SetHead(ctx context.Context, ts *block.TipSet) error {
 
 	// If it is the heaviest update the chainStore.
 	if heavier {
-		return syncer.chainStore.SetHead(ctx, ts)
+		if err := syncer.chainStore.SetHead(ctx, ts); err != nil {
+			return err
+		}
+		syncer.recordHeadMetrics(ctx, ts)
 	}
 	return nil
 }
 
+// recordHeadMetrics reports the height, weight and head change latency of
+// the new head `ts`.
+func (syncer *Syncer) recordHeadMetrics(ctx context.Context, ts *block.TipSet) {
+	headHeightGauge.Set(ctx, int64(ts.EnsureHeight()))
+	if weight, err := syncer.chainSelector.Weight(ctx, ts); err == nil {
+		headWeightGauge.Set(ctx, weight.Int64())
+	}
+	epochStart := time.Unix(int64(ts.MinTimestamp()), 0)
+	headChangeLatency.Record(ctx, syncer.clock.Now().Sub(epochStart))
+}
+
 // TODO: this function effectively accepts unchecked input from the network,
 // either validate it here, or ensure that its validated elsewhere (maybe make
 // sure the blocksync code checks it?)
@@ -35,7 +35,7 @@ func TestLoadFork(t *testing.T) {
 	// *not* as the bsstore, to which the syncer must ensure to put blocks.
 	eval := &chain.FakeStateEvaluator{MessageStore: builder.Mstore()}
 	sel := &chain.FakeChainSelector{}
-	s, err := syncer.NewSyncer(eval, eval, sel, builder.Store(), builder.Mstore(), builder.BlockStore(), builder, clock.NewFake(time.Unix(1234567890, 0)), &noopFaultDetector{}, nil)
+	s, err := syncer.NewSyncer(eval, eval, sel, builder.Store(), builder.Mstore(), builder.BlockStore(), builder, clock.NewFake(time.Unix(1234567890, 0)), &noopFaultDetector{}, nil, nil)
 	require.NoError(t, err)
 
 	base := builder.AppendManyOn(3, genesis)
@@ -77,7 +77,7 @@ func TestLoadFork(t *testing.T) {
 
 	// Load a new chain bsstore on the underlying data. It will only compute state for the
 	// left (heavy) branch. It has a fetcher that can't provide blocks.
-	newStore := chain.NewStore(builder.Repo().ChainDatastore(), builder.Cstore(), builder.BlockStore(), chain.NewStatusReporter(), config.DefaultForkUpgradeParam, genesis.At(0).Cid())
+	newStore := chain.NewStore(builder.Repo().ChainDatastore(), builder.Cstore(), builder.BlockStore(), chain.NewStatusReporter(), config.DefaultForkUpgradeParam, genesis.At(0).Cid(), nil)
 	newStore.SetCheckPoint(genesis.Key())
 	require.NoError(t, newStore.Load(ctx))
 	_, err = syncer.NewSyncer(eval,
@@ -89,7 +89,8 @@ func TestLoadFork(t *testing.T) {
 		builder,
 		clock.NewFake(time.Unix(1234567890, 0)),
 		&noopFaultDetector{},
-		fork.NewMockFork())
+		fork.NewMockFork(),
+		nil)
 	require.NoError(t, err)
 
 	assert.True(t, newStore.HasTipSetAndState(ctx, left))
@@ -12,15 +12,19 @@ import (
 	"github.com/filecoin-project/venus/pkg/chain"
 	"github.com/filecoin-project/venus/pkg/chainsync/syncer"
 	"github.com/filecoin-project/venus/pkg/clock"
+	"github.com/filecoin-project/venus/pkg/consensus"
 	"github.com/filecoin-project/venus/pkg/fork"
 	"github.com/filecoin-project/venus/pkg/specactors/policy"
 	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
 	"github.com/filecoin-project/venus/pkg/types"
 	"github.com/filecoin-project/venus/pkg/util/test"
 	"github.com/ipfs/go-cid"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	"github.com/libp2p/go-libp2p-core/peer"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/xerrors"
 )
 
 func TestOneBlock(t *testing.T) {
@@ -309,7 +313,8 @@ func TestNoUncessesaryFetch(t *testing.T) {
 		builder,
 		clock.NewFake(time.Unix(1234567890, 0)),
 		&noopFaultDetector{},
-		fork.NewMockFork())
+		fork.NewMockFork(),
+		nil)
 	require.NoError(t, err)
 
 	target2 := &syncTypes.Target{
@@ -513,6 +518,107 @@ func TestSemanticallyBadTipSetFails(t *testing.T) {
 	assert.Contains(t, err.Error(), "val semantic fails")
 }
 
+type recordingBanner struct {
+	banned []peer.ID
+}
+
+func (rb *recordingBanner) BanPeer(p peer.ID, _ time.Duration, _ string) {
+	rb.banned = append(rb.banned, p)
+}
+
+// errValidator fails the full validation of the blocks with a timestamp in
+// `errs` with its error.
+type errValidator struct {
+	*poisonValidator
+	errs map[uint64]error
+}
+
+func (ev *errValidator) ValidateFullBlock(ctx context.Context, blk *block.Block) error {
+	if err, ok := ev.errs[blk.Timestamp]; ok {
+		return err
+	}
+	return ev.poisonValidator.ValidateFullBlock(ctx, blk)
+}
+
+func TestBansPeerOnInvalidBlocksOnly(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	// the errors are wrapped the way the block validator wraps them
+	eval := &errValidator{
+		poisonValidator: newPoisonValidator(t, 98, 99),
+		errs: map[uint64]error{
+			96: xerrors.Errorf("block was from the future (now=%d, blk=%d): %w", 0, 96, consensus.ErrTemporal),
+			97: xerrors.Errorf("failed loading message list %s for block %s: %w", emptycid.EmptyTxMetaCID, emptycid.EmptyTxMetaCID, blockstore.ErrNotFound),
+		},
+	}
+	builder := chain.NewBuilder(t, address.Undef)
+	banner := &recordingBanner{}
+	s, err := syncer.NewSyncer(eval,
+		eval,
+		&chain.FakeChainSelector{},
+		builder.Store(),
+		builder.Mstore(),
+		builder.BlockStore(),
+		builder,
+		clock.NewFake(time.Unix(1234567890, 0)),
+		&noopFaultDetector{}, fork.NewMockFork(), banner)
+	require.NoError(t, err)
+	genesis := builder.Store().GetHead()
+
+	// The state transition fails locally, the sender is not at fault.
+	local := builder.BuildOneOn(genesis, func(bb *chain.BlockBuilder) {
+		bb.SetTimestamp(99)
+	})
+	err = s.HandleNewTipSet(ctx, &syncTypes.Target{ChainInfo: *block.NewChainInfo("", peer.ID("honest"), local)})
+	require.Error(t, err)
+	assert.Empty(t, banner.banned)
+
+	// The block fails validation.
+	invalid := builder.BuildOneOn(genesis, func(bb *chain.BlockBuilder) {
+		bb.SetTimestamp(98)
+	})
+	err = s.HandleNewTipSet(ctx, &syncTypes.Target{ChainInfo: *block.NewChainInfo("", peer.ID("faulty"), invalid)})
+	require.Error(t, err)
+	assert.Equal(t, []peer.ID{"faulty"}, banner.banned)
+
+	// A cancelled sync is not a fault either.
+	cancelled := builder.BuildOneOn(genesis, func(bb *chain.BlockBuilder) {
+		bb.SetTimestamp(98)
+		bb.IncHeight(1)
+	})
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	err = s.HandleNewTipSet(cctx, &syncTypes.Target{ChainInfo: *block.NewChainInfo("", peer.ID("slow"), cancelled)})
+	require.Error(t, err)
+	assert.Equal(t, []peer.ID{"faulty"}, banner.banned)
+
+	// A block ahead of the local clock is not a fault.
+	future := builder.BuildOneOn(genesis, func(bb *chain.BlockBuilder) {
+		bb.SetTimestamp(96)
+	})
+	err = s.HandleNewTipSet(ctx, &syncTypes.Target{ChainInfo: *block.NewChainInfo("", peer.ID("early"), future)})
+	require.Error(t, err)
+	assert.Equal(t, []peer.ID{"faulty"}, banner.banned)
+
+	// Nor is a block missing from the local store.
+	missing := builder.BuildOneOn(genesis, func(bb *chain.BlockBuilder) {
+		bb.SetTimestamp(97)
+	})
+	err = s.HandleNewTipSet(ctx, &syncTypes.Target{ChainInfo: *block.NewChainInfo("", peer.ID("unlucky"), missing)})
+	require.Error(t, err)
+	assert.Equal(t, []peer.ID{"faulty"}, banner.banned)
+
+	// The sender only announced the head, not the invalid tipset below it.
+	below := builder.BuildOneOn(genesis, func(bb *chain.BlockBuilder) {
+		bb.SetTimestamp(98)
+		bb.IncHeight(2)
+	})
+	above := builder.BuildOneOn(below, nil)
+	err = s.HandleNewTipSet(ctx, &syncTypes.Target{ChainInfo: *block.NewChainInfo("", peer.ID("relay"), above)})
+	require.Error(t, err)
+	assert.Equal(t, []peer.ID{"faulty"}, banner.banned)
+}
+
 func TestStoresMessageReceipts(t *testing.T) {
 	tf.UnitTest(t)
 	ctx := context.Background()
@@ -571,7 +677,7 @@ func setupWithValidator(ctx context.Context, t *testing.T, builder *chain.Builde
 		builder.BlockStore(),
 		builder,
 		clock.NewFake(time.Unix(1234567890, 0)),
-		&noopFaultDetector{}, fork.NewMockFork())
+		&noopFaultDetector{}, fork.NewMockFork(), nil)
 	require.NoError(t, err)
 
 	return builder, syncer
@@ -8,12 +8,19 @@ import (
 	"sort"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Target tracks a logical request of the syncing subsystem to run a
 // syncing job against given inputs.
 type Target struct {
+	// fetched and fetchTotal report the progress of the chain exchange
+	// request currently running for this target, they are accessed
+	// atomically and kept first for the 64-bit alignment.
+	fetched    uint64
+	fetchTotal uint64
+
 	State   SyncStateStage
 	Base    *block.TipSet
 	Current *block.TipSet
@@ -23,6 +30,19 @@ type Target struct {
 	block.ChainInfo
 }
 
+// SetProgress records the progress of the chain exchange request of the
+// target, it may be called while the target is read.
+func (target *Target) SetProgress(fetched, total uint64) {
+	atomic.StoreUint64(&target.fetched, fetched)
+	atomic.StoreUint64(&target.fetchTotal, total)
+}
+
+// Progress returns the number of tipsets fetched and requested by the chain
+// exchange request of the target.
+func (target *Target) Progress() (uint64, uint64) {
+	return atomic.LoadUint64(&target.fetched), atomic.LoadUint64(&target.fetchTotal)
+}
+
 func (target *Target) IsNeibor(t *Target) bool {
 	if target.Head.EnsureHeight() != t.Head.EnsureHeight() {
 		return false
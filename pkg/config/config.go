@@ -20,9 +20,13 @@ type Config struct {
 	API           *APIConfig           `json:"api"`
 	Bootstrap     *BootstrapConfig     `json:"bootstrap"`
 	Datastore     *DatastoreConfig     `json:"datastore"`
+	Invariants    *InvariantsConfig    `json:"invariants"`
+	Log           *LogConfig           `json:"log"`
 	Mpool         *MessagePoolConfig   `json:"mpool"`
 	NetworkParams *NetworkParamsConfig `json:"parameters"`
 	Observability *ObservabilityConfig `json:"observability"`
+	Pubsub        *PubsubConfig        `json:"pubsub"`
+	Slasher       *SlasherConfig       `json:"slasher"`
 	Swarm         *SwarmConfig         `json:"swarm"`
 	Wallet        *WalletConfig        `json:"walletModule"`
 }
@@ -48,13 +52,35 @@ func newDefaultAPIConfig() *APIConfig {
 	}
 }
 
-// DatastoreConfig holds all the configuration options for the datastore.
-// TODO: use the advanced datastore configuration from ipfs
+// DatastoreConfig holds all the configuration options for the datastores.
 type DatastoreConfig struct {
+	// Type is the backend of the block datastore, and of the chain, meta and
+	// wallet datastores unless they set their own.
 	Type string `json:"type"`
 	Path string `json:"path"`
+	// Chain, Meta and Wallet override the backend of their datastore, empty
+	// uses Type.
+	Chain  string `json:"chain"`
+	Meta   string `json:"meta"`
+	Wallet string `json:"wallet"`
+	// Badger tunes the datastores using the badgerds backend.
+	Badger *BadgerConfig `json:"badger"`
 }
 
+// BadgerConfig holds the badger options operators may tune, a zero size keeps
+// the default of the datastore.
+type BadgerConfig struct {
+	// Truncate drops corrupt or unsynced data on open instead of failing.
+	Truncate         bool  `json:"truncate"`
+	SyncWrites       bool  `json:"syncWrites"`
+	MaxTableSize     int64 `json:"maxTableSize"`
+	ValueLogFileSize int64 `json:"valueLogFileSize"`
+	NumCompactors    int   `json:"numCompactors"`
+}
+
+// DatastoreTypes are the supported datastore backends.
+var DatastoreTypes = []string{"badgerds", "levelds", "memds"}
+
 // Validators hold the list of validation functions for each configuration
 // property. Validators must take a key and json string respectively as
 // arguments, and must return either an error or nil depending on whether or not
@@ -68,6 +94,56 @@ func newDefaultDatastoreConfig() *DatastoreConfig {
 	return &DatastoreConfig{
 		Type: "badgerds",
 		Path: "badger",
+		Badger: &BadgerConfig{
+			Truncate:   true,
+			SyncWrites: true,
+		},
+	}
+}
+
+// TypeOf returns the backend of the datastore with the repo directory `name`.
+func (cfg *DatastoreConfig) TypeOf(name string) string {
+	var typ string
+	switch name {
+	case "chain":
+		typ = cfg.Chain
+	case "metadata":
+		typ = cfg.Meta
+	case "wallet":
+		typ = cfg.Wallet
+	}
+	if typ == "" {
+		return cfg.Type
+	}
+	return typ
+}
+
+// InvariantsConfig holds all configuration options related to checking the
+// invariants of the head state.
+type InvariantsConfig struct {
+	// Period is the interval of the checks of the head state, they are
+	// disabled when empty. Golang duration units are accepted.
+	Period string `json:"period"`
+}
+
+func newDefaultInvariantsConfig() *InvariantsConfig {
+	return &InvariantsConfig{
+		Period: "",
+	}
+}
+
+// LogConfig holds the log levels of the node.
+type LogConfig struct {
+	// Level is the level of all subsystems, GOLOG_LOG_LEVEL applies when empty.
+	Level string `json:"level"`
+	// Subsystems overrides the level of single subsystems.
+	Subsystems map[string]string `json:"subsystems"`
+}
+
+func newDefaultLogConfig() *LogConfig {
+	return &LogConfig{
+		Level:      "",
+		Subsystems: map[string]string{},
 	}
 }
 
@@ -83,6 +159,43 @@ func newDefaultSwarmConfig() *SwarmConfig {
 	}
 }
 
+// PubsubConfig holds all configuration options related to gossipsub.
+type PubsubConfig struct {
+	// Bootstrapper runs gossipsub without a mesh, serving peer exchange
+	// to the nodes that connect to it.
+	Bootstrapper bool `json:"bootstrapper"`
+	// Trace writes a JSON trace of pubsub events to the repo.
+	Trace bool `json:"trace"`
+	// DrandRelay receives drand rounds over the gossipsub relay topic in
+	// addition to the drand HTTP servers.
+	DrandRelay bool `json:"drandRelay"`
+}
+
+func newDefaultPubsubConfig() *PubsubConfig {
+	return &PubsubConfig{
+		Bootstrapper: false,
+		Trace:        false,
+		DrandRelay:   false,
+	}
+}
+
+// SlasherConfig holds all configuration options related to reporting
+// consensus faults.
+type SlasherConfig struct {
+	// Enabled submits a ReportConsensusFault message for each detected fault.
+	Enabled bool `json:"enabled"`
+	// From is the address paying for the reports, the default wallet
+	// address when undefined.
+	From address.Address `json:"from,omitempty"`
+}
+
+func newDefaultSlasherConfig() *SlasherConfig {
+	return &SlasherConfig{
+		Enabled: false,
+		From:    address.Undef,
+	}
+}
+
 // BootstrapConfig holds all configuration options related to bootstrap nodes
 type BootstrapConfig struct {
 	Addresses        []string `json:"addresses"`
@@ -188,10 +301,12 @@ type MessagePoolConfig struct {
 	MaxNonceGap uint64 `json:"maxNonceGap"`
 }
 
+// newDefaultMessagePoolConfig matches messagepool.MemPoolSizeLimitHiDefault
+// and messagepool.MaxNonceGap.
 func newDefaultMessagePoolConfig() *MessagePoolConfig {
 	return &MessagePoolConfig{
-		MaxPoolSize: 1000000,
-		MaxNonceGap: 100,
+		MaxPoolSize: 30000,
+		MaxNonceGap: 4,
 	}
 }
 
@@ -203,6 +318,24 @@ type NetworkParamsConfig struct {
 	DrandSchedule          map[abi.ChainEpoch]DrandEnum `json:"drandSchedule"`
 	ForkUpgradeParam       *ForkUpgradeConfig           `json:"forkUpgradeParam"`
 	AddressNetwork         address.Network              `json:"addressNetwork"`
+	// LocalDrand is the drand chain used for DrandLocalnet, usually served
+	// by tools/drand-local for devnets that run without the drand network.
+	LocalDrand *DrandConf `json:"localDrand,omitempty"`
+}
+
+// DrandConf returns the drand chain of `drandEnum`.
+func (params *NetworkParamsConfig) DrandConf(drandEnum DrandEnum) (DrandConf, error) {
+	if drandEnum == DrandLocalnet {
+		if params.LocalDrand == nil {
+			return DrandConf{}, errors.New("the local drand chain is not configured")
+		}
+		return *params.LocalDrand, nil
+	}
+	drandConf, ok := DrandConfigs[drandEnum]
+	if !ok {
+		return DrandConf{}, errors.Errorf("unknown drand chain %d", drandEnum)
+	}
+	return drandConf, nil
 }
 
 type ForkUpgradeConfig struct {
@@ -265,9 +398,13 @@ func NewDefaultConfig() *Config {
 		API:           newDefaultAPIConfig(),
 		Bootstrap:     newDefaultBootstrapConfig(),
 		Datastore:     newDefaultDatastoreConfig(),
+		Invariants:    newDefaultInvariantsConfig(),
+		Log:           newDefaultLogConfig(),
 		Mpool:         newDefaultMessagePoolConfig(),
 		NetworkParams: newDefaultNetworkParamsConfig(),
 		Observability: newDefaultObservabilityConfig(),
+		Pubsub:        newDefaultPubsubConfig(),
+		Slasher:       newDefaultSlasherConfig(),
 		Swarm:         newDefaultSwarmConfig(),
 		Wallet:        newDefaultWalletConfig(),
 	}
@@ -314,8 +451,22 @@ func ReadFile(file string) (*Config, error) {
 	return cfg, nil
 }
 
+// Clone returns a deep copy of the config.
+func (cfg *Config) Clone() (*Config, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var clone Config
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
 // Set sets the config sub-struct referenced by `key`, e.g. 'api.address'
-// or 'datastore' to the json key value pair encoded in jsonVal.
+// or 'datastore' to the json key value pair encoded in jsonVal. The config is
+// left untouched when the new values fail validation against the Schema.
 func (cfg *Config) Set(dottedKey string, jsonString string) error {
 	if !json.Valid([]byte(jsonString)) {
 		jsonBytes, _ := json.Marshal(jsonString)
@@ -331,10 +482,21 @@ func (cfg *Config) Set(dottedKey string, jsonString string) error {
 		jsonString = fmt.Sprintf(`{ "%s": %s }`, keys[i], jsonString)
 	}
 
+	updated, err := cfg.Clone()
+	if err != nil {
+		return err
+	}
 	decoder := json.NewDecoder(strings.NewReader(jsonString))
 	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&updated); err != nil {
+		return err
+	}
 
-	return decoder.Decode(&cfg)
+	if err := updated.ValidateKey(dottedKey); err != nil {
+		return err
+	}
+	*cfg = *updated
+	return nil
 }
 
 // Get gets the config sub-struct referenced by `key`, e.g. 'api.address'
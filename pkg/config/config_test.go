@@ -204,10 +204,10 @@ func TestConfigSet(t *testing.T) {
 	t.Run("set table value", func(t *testing.T) {
 		cfg := NewDefaultConfig()
 
-		jsonBlob := `{"type": "badgerbadgerbadgerds", "path": "mushroom-mushroom"}`
+		jsonBlob := `{"type": "badgerds", "path": "mushroom-mushroom"}`
 		err := cfg.Set("datastore", jsonBlob)
 		assert.NoError(t, err)
-		assert.Equal(t, cfg.Datastore.Type, "badgerbadgerbadgerds")
+		assert.Equal(t, cfg.Datastore.Type, "badgerds")
 		assert.Equal(t, cfg.Datastore.Path, "mushroom-mushroom")
 
 		cfg1path, cleaner, err := createConfigFile(fmt.Sprintf(`{"datastore": %s}`, jsonBlob))
@@ -221,7 +221,7 @@ func TestConfigSet(t *testing.T) {
 		assert.Equal(t, cfg1.Datastore, cfg.Datastore)
 
 		// inline tables
-		jsonBlob = `{"type": "badgerbadgerbadgerds", "path": "mushroom-mushroom"}`
+		jsonBlob = `{"type": "badgerds", "path": "mushroom-mushroom"}`
 		err = cfg.Set("datastore", jsonBlob)
 		assert.NoError(t, err)
 
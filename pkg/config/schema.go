@@ -0,0 +1,425 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pkg/errors"
+)
+
+// Field describes a leaf of the config: its unit, the values it accepts and
+// whether a change needs the daemon to restart.
+type Field struct {
+	// Key is the dotted path of the field, e.g. "bootstrap.period".
+	Key string `json:"key"`
+	// Unit is the unit or format of the value, e.g. "duration".
+	Unit        string `json:"unit"`
+	Description string `json:"description"`
+	// Restart is set when a change only applies after the daemon restarts,
+	// other fields apply on `venus config`.
+	Restart bool `json:"restart"`
+
+	check func(v interface{}) error
+}
+
+// Schema lists every leaf of Config, new config fields must be added here.
+var Schema = []*Field{
+	{Key: "api.apiAddress", Unit: "multiaddr", Restart: true, Description: "address the api listens on", check: isMultiaddr(false)},
+	{Key: "api.accessControlAllowOrigin", Unit: "[]url", Description: "origins allowed by CORS, or *", check: each(isOrigin)},
+	{Key: "api.accessControlAllowCredentials", Unit: "bool", Description: "allow credentials in CORS requests", check: isBool},
+	{Key: "api.accessControlAllowMethods", Unit: "[]method", Description: "methods allowed by CORS", check: each(oneOf("GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"))},
+
+	{Key: "bootstrap.addresses", Unit: "[]multiaddr", Description: "bootstrap peers, with their /p2p peer id", check: each(isPeerMultiaddr)},
+	{Key: "bootstrap.minPeerThreshold", Unit: "peers", Restart: true, Description: "number of connections the bootstrapper maintains", check: inRange(0, 1000)},
+	{Key: "bootstrap.period", Unit: "duration", Restart: true, Description: "interval of the bootstrap connection checks", check: durationAtLeast(time.Second)},
+
+	{Key: "datastore.type", Unit: "enum", Restart: true, Description: "backend of the block datastore and the default of the others", check: isDatastoreType(false)},
+	{Key: "datastore.path", Unit: "path", Restart: true, Description: "block datastore directory in the repo", check: notEmpty},
+	{Key: "datastore.chain", Unit: "enum", Restart: true, Description: "backend of the chain datastore, empty for datastore.type", check: isDatastoreType(true)},
+	{Key: "datastore.meta", Unit: "enum", Restart: true, Description: "backend of the meta datastore, empty for datastore.type", check: isDatastoreType(true)},
+	{Key: "datastore.wallet", Unit: "enum", Restart: true, Description: "backend of the wallet datastore, empty for datastore.type", check: isDatastoreType(true)},
+	{Key: "datastore.badger.truncate", Unit: "bool", Restart: true, Description: "drop corrupt or unsynced badger data on open", check: isBool},
+	{Key: "datastore.badger.syncWrites", Unit: "bool", Restart: true, Description: "sync badger writes to disk before returning", check: isBool},
+	{Key: "datastore.badger.maxTableSize", Unit: "bytes", Restart: true, Description: "size of a badger table, 0 for the default", check: inRange(0, 1<<32)},
+	{Key: "datastore.badger.valueLogFileSize", Unit: "bytes", Restart: true, Description: "size of a badger value log file, 0 for the default", check: inRange(0, 2<<30-1)},
+	{Key: "datastore.badger.numCompactors", Unit: "goroutines", Restart: true, Description: "number of badger compaction workers, 0 for the default", check: inRange(0, 64)},
+
+	{Key: "invariants.period", Unit: "duration", Restart: true, Description: "interval of the invariant checks of the head state, disabled when empty", check: orEmpty(durationAtLeast(time.Minute))},
+
+	{Key: "mpool.maxPoolSize", Unit: "messages", Description: "number of pending messages above which the pool is pruned", check: inRange(1, 1<<32)},
+	{Key: "mpool.maxNonceGap", Unit: "nonces", Description: "maximum gap between the nonce of a message and the next nonce of its sender", check: inRange(0, 10000)},
+
+	{Key: "parameters.consensusMinerMinPower", Unit: "bytes", Restart: true, Description: "minimum power of a miner to win blocks, 0 for the network default", check: orZero(atLeast(2 << 10))},
+	{Key: "parameters.minVerifiedDealSize", Unit: "bytes", Restart: true, Description: "minimum size of a verified deal", check: inRange(0, 1<<62)},
+	{Key: "parameters.replaceProofTypes", Unit: "[]proof", Restart: true, Description: "accepted seal proof types", check: each(isSealProof)},
+	{Key: "parameters.blockDelay", Unit: "seconds", Restart: true, Description: "duration of an epoch, 0 for the network default", check: inRange(0, 3600)},
+	{Key: "parameters.drandSchedule", Unit: "epoch:drand", Restart: true, Description: "drand chain used from each epoch", check: isDrandSchedule},
+	{Key: "parameters.forkUpgradeParam", Unit: "epochs", Restart: true, Description: "network upgrade heights, -1 when not scheduled", check: isForkUpgrades},
+	{Key: "parameters.addressNetwork", Unit: "enum", Restart: true, Description: "address network, 0 for mainnet, 1 for testnet", check: inRange(int64(address.Mainnet), int64(address.Testnet))},
+	{Key: "parameters.localDrand", Unit: "drand", Restart: true, Description: "drand chain of DrandLocalnet", check: isLocalDrand},
+
+	{Key: "observability.metrics.prometheusEnabled", Unit: "bool", Description: "serve prometheus metrics", check: isBool},
+	{Key: "observability.metrics.reportInterval", Unit: "duration", Description: "interval of the metrics updates", check: durationAtLeast(100 * time.Millisecond)},
+	{Key: "observability.metrics.prometheusEndpoint", Unit: "multiaddr", Description: "address the metrics are served on", check: isMultiaddr(false)},
+	{Key: "observability.tracing.jaegerTracingEnabled", Unit: "bool", Description: "export traces to jaeger", check: isBool},
+	{Key: "observability.tracing.probabilitySampler", Unit: "fraction", Description: "fraction of the traces sampled, between 0 and 1", check: fraction},
+	{Key: "observability.tracing.jaegerEndpoint", Unit: "url", Description: "jaeger collector endpoint", check: isURL},
+
+	{Key: "pubsub.bootstrapper", Unit: "bool", Restart: true, Description: "run gossipsub without a mesh, serving peer exchange", check: isBool},
+	{Key: "pubsub.trace", Unit: "bool", Restart: true, Description: "write a trace of the pubsub events to the repo", check: isBool},
+	{Key: "pubsub.drandRelay", Unit: "bool", Restart: true, Description: "receive drand rounds over gossipsub", check: isBool},
+
+	{Key: "slasher.enabled", Unit: "bool", Restart: true, Description: "report consensus faults", check: isBool},
+	{Key: "slasher.from", Unit: "address", Restart: true, Description: "address paying for the reports, the default address when empty", check: isKeyAddress},
+
+	{Key: "swarm.address", Unit: "multiaddr", Restart: true, Description: "address the libp2p host listens on", check: isMultiaddr(false)},
+	{Key: "swarm.public_relay_address", Unit: "multiaddr", Restart: true, Description: "public address of a relay node", check: isMultiaddr(true)},
+
+	{Key: "walletModule.defaultAddress", Unit: "address", Description: "default wallet address, none when empty", check: isAddress},
+
+	{Key: "log.level", Unit: "enum", Description: "level of all the log subsystems, GOLOG_LOG_LEVEL applies when empty", check: oneOf(append([]string{""}, logLevels...)...)},
+	{Key: "log.subsystems", Unit: "subsystem:level", Description: "levels of single log subsystems, e.g. {\"chainsync\": \"debug\"}", check: isSubsystemLevels},
+}
+
+var logLevels = []string{"debug", "info", "warn", "error", "dpanic", "panic", "fatal"}
+
+// SchemaField returns the schema of the field `key`.
+func SchemaField(key string) (*Field, bool) {
+	for _, f := range Schema {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+// Validate checks every field of the config against the schema.
+func (cfg *Config) Validate() error {
+	return cfg.ValidateKey("")
+}
+
+// ValidateKey checks the fields at and below the dotted key `key` against the
+// schema, all fields when `key` is empty.
+func (cfg *Config) ValidateKey(key string) error {
+	for _, f := range Schema {
+		if key != "" && f.Key != key && !strings.HasPrefix(f.Key, key+".") {
+			continue
+		}
+		v, ok := cfg.lookup(f.Key)
+		if !ok {
+			// unset section
+			continue
+		}
+		if err := f.check(v); err != nil {
+			return fmt.Errorf("invalid %s (%s): %s", f.Key, f.Unit, err)
+		}
+	}
+	return nil
+}
+
+// RestartRequired returns the fields whose change needs a restart of the
+// daemon and that differ between `a` and `b`.
+func RestartRequired(a, b *Config) []string {
+	var keys []string
+	for _, f := range Schema {
+		if !f.Restart {
+			continue
+		}
+		va, _ := a.lookup(f.Key)
+		vb, _ := b.lookup(f.Key)
+		if !reflect.DeepEqual(va, vb) {
+			keys = append(keys, f.Key)
+		}
+	}
+	return keys
+}
+
+// lookup returns the value of the field `key`, it reports false when a
+// section on the path is nil.
+func (cfg *Config) lookup(key string) (interface{}, bool) {
+	v := reflect.ValueOf(cfg)
+	for _, name := range strings.Split(key, ".") {
+		v = reflect.Indirect(v)
+		if !v.IsValid() || v.Kind() != reflect.Struct {
+			return nil, false
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			if strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0] == name {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return v.Interface(), true
+}
+
+func isBool(v interface{}) error {
+	if _, ok := v.(bool); !ok {
+		return errors.Errorf("%v is not a bool", v)
+	}
+	return nil
+}
+
+func notEmpty(v interface{}) error {
+	if s, _ := v.(string); s == "" {
+		return errors.New("must not be empty")
+	}
+	return nil
+}
+
+func orEmpty(check func(interface{}) error) func(interface{}) error {
+	return func(v interface{}) error {
+		if s, _ := v.(string); s == "" {
+			return nil
+		}
+		return check(v)
+	}
+}
+
+func orZero(check func(interface{}) error) func(interface{}) error {
+	return func(v interface{}) error {
+		if i, _ := toInt64(v); i == 0 {
+			return nil
+		}
+		return check(v)
+	}
+}
+
+func each(check func(interface{}) error) func(interface{}) error {
+	return func(v interface{}) error {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			return errors.Errorf("%v is not a list", v)
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if err := check(rv.Index(i).Interface()); err != nil {
+				return errors.Wrapf(err, "entry %d", i)
+			}
+		}
+		return nil
+	}
+}
+
+func oneOf(values ...string) func(interface{}) error {
+	return func(v interface{}) error {
+		s, _ := v.(string)
+		for _, value := range values {
+			if s == value {
+				return nil
+			}
+		}
+		return errors.Errorf("%q is not one of %s", s, strings.Join(values, ", "))
+	}
+}
+
+// isDatastoreType reads DatastoreTypes on each check, backends may be
+// registered after the schema is built.
+func isDatastoreType(allowEmpty bool) func(interface{}) error {
+	return func(v interface{}) error {
+		if s, _ := v.(string); s == "" && allowEmpty {
+			return nil
+		}
+		return oneOf(DatastoreTypes...)(v)
+	}
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if rv.Uint() > 1<<63-1 {
+			return 1<<63 - 1, true
+		}
+		return int64(rv.Uint()), true
+	}
+	return 0, false
+}
+
+func inRange(min, max int64) func(interface{}) error {
+	return func(v interface{}) error {
+		i, ok := toInt64(v)
+		if !ok {
+			return errors.Errorf("%v is not an integer", v)
+		}
+		if i < min || i > max {
+			return errors.Errorf("%d is not between %d and %d", i, min, max)
+		}
+		return nil
+	}
+}
+
+func atLeast(min int64) func(interface{}) error {
+	return func(v interface{}) error {
+		i, ok := toInt64(v)
+		if !ok {
+			return errors.Errorf("%v is not an integer", v)
+		}
+		if i < min {
+			return errors.Errorf("%d is less than %d", i, min)
+		}
+		return nil
+	}
+}
+
+func fraction(v interface{}) error {
+	f, ok := v.(float64)
+	if !ok || f < 0 || f > 1 {
+		return errors.Errorf("%v is not between 0 and 1", v)
+	}
+	return nil
+}
+
+func durationAtLeast(min time.Duration) func(interface{}) error {
+	return func(v interface{}) error {
+		s, _ := v.(string)
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errors.Errorf("%q is not a duration, e.g. 30s or 5m", s)
+		}
+		if d < min {
+			return errors.Errorf("%s is shorter than %s", d, min)
+		}
+		return nil
+	}
+}
+
+func isMultiaddr(allowEmpty bool) func(interface{}) error {
+	return func(v interface{}) error {
+		s, _ := v.(string)
+		if s == "" && allowEmpty {
+			return nil
+		}
+		if _, err := ma.NewMultiaddr(s); err != nil {
+			return errors.Errorf("%q is not a multiaddr: %s", s, err)
+		}
+		return nil
+	}
+}
+
+func isPeerMultiaddr(v interface{}) error {
+	s, _ := v.(string)
+	addr, err := ma.NewMultiaddr(s)
+	if err != nil {
+		return errors.Errorf("%q is not a multiaddr: %s", s, err)
+	}
+	if _, err := peer.AddrInfoFromP2pAddr(addr); err != nil {
+		return errors.Errorf("%q has no /p2p peer id", s)
+	}
+	return nil
+}
+
+// isAddress accepts any address.
+func isAddress(v interface{}) error {
+	if _, ok := v.(address.Address); !ok {
+		return errors.Errorf("%v is not an address", v)
+	}
+	return nil
+}
+
+// isKeyAddress accepts the empty address and the secp256k1 and bls addresses,
+// which the wallet can sign for.
+func isKeyAddress(v interface{}) error {
+	addr, ok := v.(address.Address)
+	if !ok {
+		return errors.Errorf("%v is not an address", v)
+	}
+	if addr.Empty() {
+		return nil
+	}
+	if p := addr.Protocol(); p != address.SECP256K1 && p != address.BLS {
+		return errors.Errorf("%s is not a secp256k1 or bls address", addr)
+	}
+	return nil
+}
+
+func isURL(v interface{}) error {
+	s, _ := v.(string)
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.Errorf("%q is not an http(s) url", s)
+	}
+	return nil
+}
+
+func isOrigin(v interface{}) error {
+	if s, _ := v.(string); s == "*" {
+		return nil
+	}
+	return isURL(v)
+}
+
+func isSealProof(v interface{}) error {
+	i, _ := toInt64(v)
+	if _, err := abi.RegisteredSealProof(i).SectorSize(); err != nil {
+		return errors.Errorf("%d is not a seal proof type", i)
+	}
+	return nil
+}
+
+func isDrandSchedule(v interface{}) error {
+	schedule, _ := v.(map[abi.ChainEpoch]DrandEnum)
+	if len(schedule) == 0 {
+		return errors.New("must schedule a drand chain")
+	}
+	for epoch, drandEnum := range schedule {
+		if drandEnum == DrandLocalnet {
+			continue
+		}
+		if _, ok := DrandConfigs[drandEnum]; !ok {
+			return errors.Errorf("unknown drand chain %d at epoch %d", drandEnum, epoch)
+		}
+	}
+	return nil
+}
+
+func isForkUpgrades(v interface{}) error {
+	upgrades, _ := v.(*ForkUpgradeConfig)
+	if upgrades == nil {
+		return errors.New("must be set")
+	}
+	rv := reflect.ValueOf(upgrades).Elem()
+	for i := 0; i < rv.NumField(); i++ {
+		if epoch := rv.Field(i).Int(); epoch < -1 {
+			return errors.Errorf("%s is %d, not an epoch or -1", rv.Type().Field(i).Tag.Get("json"), epoch)
+		}
+	}
+	return nil
+}
+
+func isLocalDrand(v interface{}) error {
+	drandConf, _ := v.(*DrandConf)
+	if drandConf == nil {
+		return nil
+	}
+	if len(drandConf.Servers) == 0 {
+		return errors.New("needs at least one server")
+	}
+	if drandConf.ChainInfoJSON == "" {
+		return errors.New("needs the chain info")
+	}
+	return each(isURL)(drandConf.Servers)
+}
+
+func isSubsystemLevels(v interface{}) error {
+	levels, _ := v.(map[string]string)
+	for subsystem, level := range levels {
+		if err := oneOf(logLevels...)(level); err != nil {
+			return errors.Wrapf(err, "subsystem %s", subsystem)
+		}
+	}
+	return nil
+}
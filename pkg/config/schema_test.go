@@ -0,0 +1,93 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+func TestSchemaCoversConfig(t *testing.T) {
+	tf.UnitTest(t)
+
+	var walk func(prefix string, typ reflect.Type)
+	walk = func(prefix string, typ reflect.Type) {
+		for i := 0; i < typ.NumField(); i++ {
+			key := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			if _, ok := SchemaField(key); ok {
+				continue
+			}
+			fieldType := typ.Field(i).Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() != reflect.Struct {
+				t.Errorf("config field %s is not in the schema", key)
+				continue
+			}
+			walk(key, fieldType)
+		}
+	}
+	walk("", reflect.TypeOf(Config{}))
+}
+
+func TestValidate(t *testing.T) {
+	tf.UnitTest(t)
+
+	require.NoError(t, NewDefaultConfig().Validate())
+
+	for _, tc := range []struct {
+		key   string
+		value string
+		err   string
+	}{
+		{"bootstrap.period", `"1x"`, `invalid bootstrap.period (duration): "1x" is not a duration, e.g. 30s or 5m`},
+		{"bootstrap.period", `"10ms"`, "invalid bootstrap.period (duration): 10ms is shorter than 1s"},
+		{"bootstrap.addresses", `["/ip4/127.0.0.1/tcp/1"]`, `invalid bootstrap.addresses ([]multiaddr): entry 0: "/ip4/127.0.0.1/tcp/1" has no /p2p peer id`},
+		{"api.apiAddress", `"localhost"`, `invalid api.apiAddress (multiaddr): "localhost" is not a multiaddr`},
+		{"datastore.type", `"leveldb"`, `invalid datastore.type (enum): "leveldb" is not one of badgerds, levelds, memds`},
+		{"datastore.chain", `"rocksdb"`, `invalid datastore.chain (enum): "rocksdb" is not one of badgerds, levelds, memds`},
+		{"mpool.maxPoolSize", `0`, "invalid mpool.maxPoolSize (messages): 0 is not between 1 and 4294967296"},
+		{"parameters.consensusMinerMinPower", `1024`, "invalid parameters.consensusMinerMinPower (bytes): 1024 is less than 2048"},
+		{"slasher.from", `"t01000"`, "invalid slasher.from (address): t01000 is not a secp256k1 or bls address"},
+		{"observability.tracing.probabilitySampler", `1.5`, "invalid observability.tracing.probabilitySampler (fraction): 1.5 is not between 0 and 1"},
+		{"log", `{"subsystems": {"chainsync": "loud"}}`, `invalid log.subsystems (subsystem:level): subsystem chainsync: "loud" is not one of debug, info, warn, error, dpanic, panic, fatal`},
+	} {
+		cfg := NewDefaultConfig()
+		err := cfg.Set(tc.key, tc.value)
+		require.Error(t, err, tc.key)
+		assert.Contains(t, err.Error(), tc.err, tc.key)
+		// rejected values are not applied
+		assert.Equal(t, NewDefaultConfig(), cfg, tc.key)
+	}
+
+	cfg := NewDefaultConfig()
+	require.NoError(t, cfg.Set("bootstrap.addresses", `["/ip4/127.0.0.1/tcp/1/p2p/12D3KooWCVe8MmsEMes2FzgTpt9fXtmCY7wrq91GRiaC8PHSCCBj"]`))
+	require.NoError(t, cfg.Set("log", `{"level": "warn", "subsystems": {"chainsync": "debug"}}`))
+	assert.Equal(t, "debug", cfg.Log.Subsystems["chainsync"])
+	require.NoError(t, cfg.Set("parameters.consensusMinerMinPower", "0"))
+	require.NoError(t, cfg.Set("walletModule.defaultAddress", `""`))
+	require.NoError(t, cfg.Set("walletModule.defaultAddress", `"t01000"`))
+}
+
+func TestRestartRequired(t *testing.T) {
+	tf.UnitTest(t)
+
+	a := NewDefaultConfig()
+	b, err := a.Clone()
+	require.NoError(t, err)
+	assert.Equal(t, a, b)
+
+	require.NoError(t, b.Set("mpool.maxPoolSize", "10"))
+	assert.Empty(t, RestartRequired(a, b))
+
+	require.NoError(t, b.Set("swarm.address", `"/ip4/0.0.0.0/tcp/6001"`))
+	assert.Equal(t, []string{"swarm.address"}, RestartRequired(a, b))
+}
@@ -16,3 +16,24 @@ func SanityCheck(t *testing.T, cfgJSON string) {
 	assert.True(t, strings.Contains(cfgJSON, "bootstrap"))
 	assert.True(t, strings.Contains(cfgJSON, "\"minPeerThreshold\": 3"))
 }
+
+// NoForkUpgrades returns a fork upgrade config with every upgrade disabled, a
+// network on it stays on the version of its genesis.
+func NoForkUpgrades() *ForkUpgradeConfig {
+	return &ForkUpgradeConfig{
+		UpgradeSmokeHeight:       -1,
+		UpgradeBreezeHeight:      -1,
+		UpgradeIgnitionHeight:    -1,
+		UpgradeLiftoffHeight:     -1,
+		UpgradeActorsV2Height:    -1,
+		UpgradeRefuelHeight:      -1,
+		UpgradeTapeHeight:        -1,
+		UpgradeKumquatHeight:     -1,
+		BreezeGasTampingDuration: -1,
+		UpgradeCalicoHeight:      -1,
+		UpgradePersianHeight:     -1,
+		UpgradeOrangeHeight:      -1,
+		UpgradeClausHeight:       -1,
+		UpgradeActorsV3Height:    -1,
+	}
+}
@@ -171,7 +171,7 @@ func (bv *BlockValidator) validateBlock(ctx context.Context, blk *block.Block) e
 
 	now := uint64(time.Now().Unix())
 	if blk.Timestamp > now+AllowableClockDriftSecs {
-		return xerrors.Errorf("block was from the future (now=%d, blk=%d): %v", now, blk.Timestamp, ErrTemporal)
+		return xerrors.Errorf("block was from the future (now=%d, blk=%d): %w", now, blk.Timestamp, ErrTemporal)
 	}
 	if blk.Timestamp > now {
 		logExpect.Warn("Got block from the future, but within threshold", blk.Timestamp, time.Now().Unix())
@@ -369,7 +369,7 @@ func (bv *BlockValidator) ValidateBlockWinner(ctx context.Context, waddr address
 	baseHeight, _ := baseTs.Height()
 	eligible, err := bv.MinerEligibleToMine(ctx, blk.Miner, baseRoot, baseHeight, lbTs)
 	if err != nil {
-		return xerrors.Errorf("determining if miner has min power failed: %v", err)
+		return xerrors.Errorf("determining if miner has min power failed: %w", err)
 	}
 
 	if !eligible {
@@ -418,7 +418,7 @@ func (bv *BlockValidator) ValidateBlockWinner(ctx context.Context, waddr address
 }
 
 func (bv *BlockValidator) MinerEligibleToMine(ctx context.Context, addr address.Address, parentStateRoot cid.Cid, parentHeight abi.ChainEpoch, lookbackTs *block.TipSet) (bool, error) {
-	hmp, err := bv.minerHasMinPower(ctx, addr, lookbackTs)
+	hmp, err := bv.MinerHasMinPower(ctx, addr, lookbackTs)
 
 	// TODO: We're blurring the lines between a "runtime network version" and a "Lotus upgrade epoch", is that unavoidable?
 	if bv.fork.GetNtwkVersion(ctx, parentHeight) <= network.Version3 {
@@ -437,12 +437,12 @@ func (bv *BlockValidator) MinerEligibleToMine(ctx context.Context, addr address.
 	vms := cbor.NewCborStore(bv.bstore)
 	sm, err := state.LoadState(ctx, vms, parentStateRoot)
 	if err != nil {
-		return false, xerrors.Errorf("loading state: %v", err)
+		return false, xerrors.Errorf("loading state: %w", err)
 	}
 
 	pact, find, err := sm.GetActor(ctx, power.Address)
 	if err != nil {
-		return false, xerrors.Errorf("get power actor failed: %v", err)
+		return false, xerrors.Errorf("get power actor failed: %w", err)
 	}
 
 	if !find {
@@ -456,7 +456,7 @@ func (bv *BlockValidator) MinerEligibleToMine(ctx context.Context, addr address.
 
 	mact, find, err := sm.GetActor(ctx, addr)
 	if err != nil {
-		return false, xerrors.Errorf("loading miner actor state: %v", err)
+		return false, xerrors.Errorf("loading miner actor state: %w", err)
 	}
 
 	if !find {
@@ -497,16 +497,19 @@ func (bv *BlockValidator) MinerEligibleToMine(ctx context.Context, addr address.
 	return true, nil
 }
 
-func (bv *BlockValidator) minerHasMinPower(ctx context.Context, addr address.Address, ts *block.TipSet) (bool, error) {
+// MinerHasMinPower returns whether `addr` meets the consensus minimum power
+// in the state of the lookback tipset `ts`, a miner without a power claim,
+// before it registered, does not have it.
+func (bv *BlockValidator) MinerHasMinPower(ctx context.Context, addr address.Address, ts *block.TipSet) (bool, error) {
 	vms := cbor.NewCborStore(bv.bstore)
 	sm, err := state.LoadState(ctx, vms, ts.Blocks()[0].ParentStateRoot)
 	if err != nil {
-		return false, xerrors.Errorf("loading state: %v", err)
+		return false, xerrors.Errorf("loading state: %w", err)
 	}
 
 	pact, find, err := sm.GetActor(ctx, power.Address)
 	if err != nil {
-		return false, xerrors.Errorf("get power actor failed: %v", err)
+		return false, xerrors.Errorf("get power actor failed: %w", err)
 	}
 
 	if !find {
@@ -518,6 +521,9 @@ func (bv *BlockValidator) minerHasMinPower(ctx context.Context, addr address.Add
 		return false, err
 	}
 
+	if _, found, err := ps.MinerPower(addr); err != nil || !found {
+		return false, err
+	}
 	return ps.MinerNominalPowerMeetsConsensusMinimum(addr)
 }
 
@@ -591,7 +597,7 @@ func (bv *BlockValidator) VerifyWinningPoStProof(ctx context.Context, nv network
 func (bv *BlockValidator) checkBlockMessages(ctx context.Context, sigValidator *appstate.SignatureValidator, blk *block.Block, baseTs *block.TipSet) (err error) {
 	blksecpMsgs, blkblsMsgs, err := bv.messageStore.LoadMetaMessages(ctx, blk.Messages)
 	if err != nil {
-		return xerrors.Errorf("failed loading message list %s for block %s %v", blk.Messages, blk.Cid(), err)
+		return xerrors.Errorf("failed loading message list %s for block %s: %w", blk.Messages, blk.Cid(), err)
 	}
 
 	{
@@ -612,7 +618,7 @@ func (bv *BlockValidator) checkBlockMessages(ctx context.Context, sigValidator *
 	vms := cbor.NewCborStore(bv.bstore)
 	st, err := state.LoadState(ctx, vms, blk.ParentStateRoot)
 	if err != nil {
-		return xerrors.Errorf("loading state: %v", err)
+		return xerrors.Errorf("loading state: %w", err)
 	}
 
 	baseHeight, _ := baseTs.Height()
@@ -640,7 +646,7 @@ func (bv *BlockValidator) checkBlockMessages(ctx context.Context, sigValidator *
 			// `GetActor` does not validate that this is an account actor.
 			act, find, err := st.GetActor(ctx, m.From)
 			if err != nil {
-				return xerrors.Errorf("failed to get actor: %v", err)
+				return xerrors.Errorf("failed to get actor: %w", err)
 			}
 
 			if !find {
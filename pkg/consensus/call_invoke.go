@@ -1,6 +1,7 @@
 package consensus
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 
@@ -13,12 +14,14 @@ import (
 	"github.com/ipfs/go-cid"
 	cbor "github.com/ipfs/go-ipld-cbor"
 	xerrors "github.com/pkg/errors"
+	cbg "github.com/whyrusleeping/cbor-gen"
 	"go.opencensus.io/trace"
 
 	"github.com/filecoin-project/venus/pkg/block"
 	"github.com/filecoin-project/venus/pkg/constants"
 	"github.com/filecoin-project/venus/pkg/fork"
 	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
 	"github.com/filecoin-project/venus/pkg/vm"
 	"github.com/filecoin-project/venus/pkg/vm/state"
 )
@@ -93,6 +96,7 @@ func (c *Expected) CallWithGas(ctx context.Context, msg *types.UnsignedMessage,
 		PRoot:             stateRoot,
 		Bsstore:           c.bstore,
 		SysCallsImpl:      c.syscallsImpl,
+		StateCache:        c.stateCache,
 		Fork:              c.fork,
 	}
 
@@ -206,8 +210,130 @@ func (c *Expected) Call(ctx context.Context, msg *types.UnsignedMessage, ts *blo
 		PRoot:             ts.At(0).ParentStateRoot,
 		Bsstore:           c.bstore,
 		SysCallsImpl:      c.syscallsImpl,
+		StateCache:        c.stateCache,
 	}
 
 	// TODO: maybe just use the invoker directly?
 	return c.processor.ProcessMessage(ctx, msg, vmOption)
 }
+
+// CallWithOverrides executes `msg` like Call, on a copy-on-write view of the
+// state of `ts` changed by `overrides`, the state of `ts` is left untouched.
+// The overrides only change actors in the state, the ones of missing actors
+// are refused with types.ErrActorNotFound. `msg` is executed with the nonce of
+// its sender and the block gas limit when it has none, the trace of the result
+// holds the message executed.
+func (c *Expected) CallWithOverrides(ctx context.Context, msg *types.UnsignedMessage, ts *block.TipSet, overrides *types.StateOverrides) (*vm.Ret, error) {
+	ctx, span := trace.StartSpan(ctx, "statemanager.CallWithOverrides")
+	defer span.End()
+
+	bstate := ts.At(0).ParentStateRoot
+	bheight := ts.EnsureHeight()
+	if bheight-1 > 0 && c.fork.HasExpensiveFork(ctx, bheight-1) {
+		return nil, ErrExpensiveFork
+	}
+	bstate, err := c.fork.HandleStateForks(ctx, bstate, bheight-1, ts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to handle fork: %v", err)
+	}
+
+	// Writes go to a scratch store so the overrides never reach the chain store.
+	bs := blockstoreutil.NewTieredBstore(c.bstore, blockstoreutil.NewTemporarySync())
+	st, err := state.LoadState(ctx, cbor.NewCborStore(bs), bstate)
+	if err != nil {
+		return nil, xerrors.Errorf("loading state: %v", err)
+	}
+
+	epoch := ts.At(0).Height
+	baseFee := ts.At(0).ParentBaseFee
+	if overrides != nil {
+		if err := applyActorOverrides(ctx, st, overrides.Actors); err != nil {
+			return nil, err
+		}
+		if bstate, err = st.Flush(ctx); err != nil {
+			return nil, xerrors.Errorf("flushing overridden state: %v", err)
+		}
+		if overrides.Epoch != nil {
+			epoch = *overrides.Epoch
+		}
+		if overrides.BaseFee != nil {
+			baseFee = *overrides.BaseFee
+		}
+	}
+
+	call := *msg
+	if call.GasLimit == 0 {
+		call.GasLimit = constants.BlockGasLimit
+	}
+	fromActor, found, err := st.GetActor(ctx, call.From)
+	if err != nil {
+		return nil, xerrors.Errorf("call raw get actor: %s", err)
+	}
+	if !found {
+		return nil, xerrors.Wrapf(types.ErrActorNotFound, "sender %s", call.From)
+	}
+	call.Nonce = fromActor.Nonce
+
+	rnd := HeadRandomness{
+		Chain: c.rnd,
+		Head:  ts.Key(),
+	}
+	vmOption := vm.VmOption{
+		CircSupplyCalculator: func(ctx context.Context, epoch abi.ChainEpoch, tree state.Tree) (abi.TokenAmount, error) {
+			dertail, err := c.chainState.GetCirculatingSupplyDetailed(ctx, epoch, tree)
+			if err != nil {
+				return abi.TokenAmount{}, err
+			}
+			return dertail.FilCirculating, nil
+		},
+		NtwkVersionGetter: c.fork.GetNtwkVersion,
+		Rnd:               &rnd,
+		BaseFee:           baseFee,
+		Epoch:             epoch,
+		GasPriceSchedule:  c.gasPirceSchedule,
+		Fork:              c.fork,
+		PRoot:             bstate,
+		Bsstore:           bs,
+		SysCallsImpl:      c.syscallsImpl,
+		StateCache:        c.stateCache,
+	}
+	ret, err := c.processor.ProcessMessage(ctx, &call, vmOption)
+	if err != nil {
+		return nil, err
+	}
+	if ret.GasTracker != nil {
+		ret.GasTracker.ExecutionTrace.Msg = &call
+	}
+	return ret, nil
+}
+
+func applyActorOverrides(ctx context.Context, st *state.State, overrides map[address.Address]types.ActorOverride) error {
+	for addr, o := range overrides {
+		act, found, err := st.GetActor(ctx, addr)
+		if err != nil {
+			return xerrors.Errorf("loading actor %s: %v", addr, err)
+		}
+		if !found {
+			return xerrors.Wrapf(types.ErrActorNotFound, "can not override actor %s", addr)
+		}
+		if o.Balance != nil {
+			act.Balance = *o.Balance
+		}
+		if o.Nonce != nil {
+			act.Nonce = *o.Nonce
+		}
+		if o.Head != nil {
+			var head cbg.Deferred
+			if err := head.UnmarshalCBOR(bytes.NewReader(o.Head)); err != nil {
+				return xerrors.Errorf("invalid head of actor %s: %v", addr, err)
+			}
+			if act.Head, err = st.GetStore().Put(ctx, &head); err != nil {
+				return xerrors.Errorf("storing head of actor %s: %v", addr, err)
+			}
+		}
+		if err := st.SetActor(ctx, addr, act); err != nil {
+			return xerrors.Errorf("setting actor %s: %v", addr, err)
+		}
+	}
+	return nil
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/filecoin-project/venus/pkg/fork"
 	appstate "github.com/filecoin-project/venus/pkg/state"
 	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
 	"github.com/filecoin-project/venus/pkg/vm"
 	"github.com/filecoin-project/venus/pkg/vm/gas"
 	"github.com/filecoin-project/venus/pkg/vm/state"
@@ -40,6 +41,8 @@ var (
 	ErrUnorderedTipSets = errors.New("trying to order two identical tipsets")
 	// ErrReceiptRootMismatch is returned when the block's receipt root doesn't match the receipt root computed for the parent tipset.
 	ErrReceiptRootMismatch = errors.New("blocks receipt root does not match parent tip set")
+	// ErrProfileMigration is returned when profiling a tipset whose execution migrates the state.
+	ErrProfileMigration = errors.New("refusing to profile a tipset executing a state migration")
 )
 
 var logExpect = logging.Logger("consensus")
@@ -129,6 +132,9 @@ type Expected struct {
 	syscallsImpl                vm.SyscallsImpl
 
 	blockValidator *BlockValidator
+
+	// stateCache is shared by the vms of the node.
+	stateCache *state.Cache
 }
 
 // Ensure Expected satisfies the Protocol interface at compile time.
@@ -150,6 +156,7 @@ func NewExpected(cs cbor.IpldStore,
 	faultChecker := slashing.NewFaultChecker(chainState, fork)
 	syscalls := vmsupport.NewSyscalls(faultChecker, proofVerifier)
 	processor := NewDefaultProcessor(syscalls)
+	stateCache, _ := state.NewCache(state.DefaultCacheActors, state.DefaultCacheBlocks, state.DefaultCacheRetention)
 	c := &Expected{
 		processor:                   processor,
 		syscallsImpl:                syscalls,
@@ -163,6 +170,7 @@ func NewExpected(cs cbor.IpldStore,
 		gasPirceSchedule:            gasPirceSchedule,
 		blockValidator:              blockValidator,
 		circulatingSupplyCalculator: chain.NewCirculatingSupplyCalculator(bs, chainState, config.ForkUpgradeParam),
+		stateCache:                  stateCache,
 	}
 	return c
 }
@@ -182,6 +190,69 @@ func (c *Expected) RunStateTransition(ctx context.Context,
 	ctx, span := trace.StartSpan(ctx, "Expected.RunStateTransition")
 	span.AddAttributes(trace.StringAttribute("tipset", ts.String()))
 
+	return c.runStateTransition(ctx, ts, parentStateRoot, c.bstore, nil)
+}
+
+// ProfileTipSet re-executes the messages of `ts` on its parent state with the
+// charges of the vm aggregated in `profiler`, the computed state is discarded.
+// The migrations write to the chain store, the tipsets executing one are
+// refused with ErrProfileMigration.
+func (c *Expected) ProfileTipSet(ctx context.Context, ts *block.TipSet, profiler *gas.Profiler) error {
+	ctx, span := trace.StartSpan(ctx, "Expected.ProfileTipSet")
+	span.AddAttributes(trace.StringAttribute("tipset", ts.String()))
+	defer span.End()
+
+	if ts.EnsureHeight() > 0 {
+		parent, err := c.chainState.GetTipSet(ts.EnsureParents())
+		if err != nil {
+			return err
+		}
+		for epoch := parent.EnsureHeight(); epoch < ts.EnsureHeight(); epoch++ {
+			if c.fork.HasMigration(ctx, epoch) {
+				return errors.Wrapf(ErrProfileMigration, "migration at %d", epoch)
+			}
+		}
+	}
+	bs := blockstoreutil.NewTieredBstore(c.bstore, blockstoreutil.NewTemporarySync())
+	_, _, err := c.runStateTransition(ctx, ts, ts.At(0).ParentStateRoot, bs, profiler)
+	return err
+}
+
+// ExecuteMigrated executes the messages of `ts` on `root`, a state migrated
+// already by the upgrades below `ts`, so the migrations are not run again. The
+// vm writes to `bs` only. It checks the migrations of the dry runs.
+func (c *Expected) ExecuteMigrated(ctx context.Context, bs blockstoreutil.Blockstore, ts *block.TipSet, root cid.Cid) (cid.Cid, error) {
+	ctx, span := trace.StartSpan(ctx, "Expected.ExecuteMigrated")
+	span.AddAttributes(trace.StringAttribute("tipset", ts.String()))
+	defer span.End()
+
+	migrated := *c
+	migrated.fork = migratedFork{c.fork}
+	// the epoch of the cache of the node is not moved back to the upgrade
+	stateCache, err := state.NewCache(state.DefaultCacheActors, state.DefaultCacheBlocks, state.DefaultCacheRetention)
+	if err != nil {
+		return cid.Undef, err
+	}
+	migrated.stateCache = stateCache
+	newRoot, _, err := migrated.runStateTransition(ctx, ts, root, bs, nil)
+	return newRoot, err
+}
+
+// migratedFork runs no migration, the states it is given are migrated already.
+type migratedFork struct {
+	fork.IFork
+}
+
+func (migratedFork) HandleStateForks(_ context.Context, root cid.Cid, _ abi.ChainEpoch, _ *block.TipSet) (cid.Cid, error) {
+	return root, nil
+}
+
+func (c *Expected) runStateTransition(ctx context.Context,
+	ts *block.TipSet,
+	parentStateRoot cid.Cid,
+	bs blockstoreutil.Blockstore,
+	profiler *gas.Profiler,
+) (cid.Cid, []types.MessageReceipt, error) {
 	blockMessageInfo, err := c.messageStore.LoadTipSetMessage(ctx, ts)
 	if err != nil {
 		return cid.Undef, nil, nil
@@ -206,6 +277,7 @@ func (c *Expected) RunStateTransition(ctx context.Context,
 		Head:  ts.Key(),
 	}
 
+	c.stateCache.SetEpoch(ts.At(0).Height)
 	vmOption := vm.VmOption{
 		CircSupplyCalculator: func(ctx context.Context, epoch abi.ChainEpoch, tree state.Tree) (abi.TokenAmount, error) {
 			dertail, err := c.chainState.GetCirculatingSupplyDetailed(ctx, epoch, tree)
@@ -220,9 +292,11 @@ func (c *Expected) RunStateTransition(ctx context.Context,
 		Fork:              c.fork,
 		Epoch:             ts.At(0).Height,
 		GasPriceSchedule:  c.gasPirceSchedule,
-		Bsstore:           c.bstore,
+		Bsstore:           bs,
 		PRoot:             parentStateRoot,
 		SysCallsImpl:      c.syscallsImpl,
+		StateCache:        c.stateCache,
+		GasProfiler:       profiler,
 	}
 	root, receipts, err := c.processor.ProcessTipSet(ctx, pts, ts, blockMessageInfo, vmOption)
 	if err != nil {
@@ -17,7 +17,9 @@ import (
 
 	"github.com/filecoin-project/venus/pkg/block"
 	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
 	"github.com/filecoin-project/venus/pkg/vm"
+	"github.com/filecoin-project/venus/pkg/vm/gas"
 )
 
 // Protocol is an interface defining a blockchain consensus protocol.  The
@@ -36,4 +38,14 @@ type Protocol interface {
 	Call(ctx context.Context, msg *types.UnsignedMessage, ts *block.TipSet) (*vm.Ret, error)
 
 	CallWithGas(ctx context.Context, msg *types.UnsignedMessage, priorMsgs []types.ChainMsg, ts *block.TipSet) (*vm.Ret, error)
+
+	CallWithOverrides(ctx context.Context, msg *types.UnsignedMessage, ts *block.TipSet, overrides *types.StateOverrides) (*vm.Ret, error)
+
+	Simulate(ctx context.Context, ts *block.TipSet, msgs []types.SimulatedMessage, bs blockstoreutil.Blockstore) (cid.Cid, []*vm.Ret, error)
+
+	// ProfileTipSet re-executes the messages of a tipset with the gas charges aggregated in the profiler.
+	ProfileTipSet(ctx context.Context, ts *block.TipSet, profiler *gas.Profiler) error
+
+	// ExecuteMigrated executes the messages of a tipset on a state already migrated, writing to bs only.
+	ExecuteMigrated(ctx context.Context, bs blockstoreutil.Blockstore, ts *block.TipSet, root cid.Cid) (cid.Cid, error)
 }
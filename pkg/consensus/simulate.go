@@ -0,0 +1,151 @@
+package consensus
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	acrypto "github.com/filecoin-project/go-state-types/crypto"
+	"github.com/ipfs/go-cid"
+	xerrors "github.com/pkg/errors"
+	"go.opencensus.io/trace"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/constants"
+	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
+	"github.com/filecoin-project/venus/pkg/vm"
+	"github.com/filecoin-project/venus/pkg/vm/state"
+)
+
+// Simulate applies `msgs` in order on a single vm started from the state of
+// `ts`, advancing fake epochs between them as asked. Everything the vm writes
+// goes to `bs`, which must read through to the chain store and keep the
+// writes apart, the returned root is only available in it. The messages are
+// executed on copies, with the nonce of their sender and the block gas limit
+// when they have none, the trace of each result holds the message executed.
+// The epochs advanced past `ts` draw a SimulationRandomness.
+func (c *Expected) Simulate(ctx context.Context, ts *block.TipSet, msgs []types.SimulatedMessage, bs blockstoreutil.Blockstore) (cid.Cid, []*vm.Ret, error) {
+	ctx, span := trace.StartSpan(ctx, "statemanager.Simulate")
+	defer span.End()
+
+	bstate := ts.At(0).ParentStateRoot
+	epoch := ts.EnsureHeight()
+	if epoch-1 > 0 && c.fork.HasExpensiveFork(ctx, epoch-1) {
+		return cid.Undef, nil, ErrExpensiveFork
+	}
+	bstate, err := c.fork.HandleStateForks(ctx, bstate, epoch-1, ts)
+	if err != nil {
+		return cid.Undef, nil, fmt.Errorf("failed to handle fork: %v", err)
+	}
+
+	// Migrations take too long to run in a simulation.
+	end := epoch
+	for _, m := range msgs {
+		end += m.AdvanceEpochs
+	}
+	for e := epoch; e < end; e++ {
+		if c.fork.HasExpensiveFork(ctx, e) {
+			return cid.Undef, nil, ErrExpensiveFork
+		}
+	}
+
+	rnd := SimulationRandomness{
+		HeadRandomness: HeadRandomness{
+			Chain: c.rnd,
+			Head:  ts.Key(),
+		},
+		Height: epoch,
+	}
+	v, err := vm.NewVM(vm.VmOption{
+		CircSupplyCalculator: func(ctx context.Context, epoch abi.ChainEpoch, tree state.Tree) (abi.TokenAmount, error) {
+			dertail, err := c.chainState.GetCirculatingSupplyDetailed(ctx, epoch, tree)
+			if err != nil {
+				return abi.TokenAmount{}, err
+			}
+			return dertail.FilCirculating, nil
+		},
+		NtwkVersionGetter: c.fork.GetNtwkVersion,
+		Rnd:               &rnd,
+		BaseFee:           ts.At(0).ParentBaseFee,
+		Epoch:             epoch,
+		GasPriceSchedule:  c.gasPirceSchedule,
+		Fork:              c.fork,
+		PRoot:             bstate,
+		Bsstore:           bs,
+		SysCallsImpl:      c.syscallsImpl,
+		StateCache:        c.stateCache,
+	})
+	if err != nil {
+		return cid.Undef, nil, err
+	}
+
+	rets := make([]*vm.Ret, 0, len(msgs))
+	for i, m := range msgs {
+		for j := abi.ChainEpoch(0); j < m.AdvanceEpochs; j++ {
+			if err := v.AdvanceEpoch(ts, m.Cron); err != nil {
+				return cid.Undef, nil, xerrors.Wrapf(err, "advancing epochs before message %d", i)
+			}
+		}
+
+		msg := *m.Message
+		if msg.GasLimit == 0 {
+			msg.GasLimit = constants.BlockGasLimit
+		}
+		fromActor, found, err := v.StateTree().GetActor(ctx, msg.From)
+		if err != nil {
+			return cid.Undef, nil, xerrors.Wrapf(err, "loading sender of message %d", i)
+		}
+		if !found {
+			return cid.Undef, nil, xerrors.Errorf("sender %s of message %d not found", msg.From, i)
+		}
+		msg.Nonce = fromActor.Nonce
+
+		ret, err := v.ApplyMessage(&msg)
+		if err != nil {
+			return cid.Undef, nil, xerrors.Wrapf(err, "applying message %d", i)
+		}
+		if ret.GasTracker != nil {
+			ret.GasTracker.ExecutionTrace.Msg = &msg
+		}
+		rets = append(rets, ret)
+	}
+
+	root, err := v.Flush()
+	if err != nil {
+		return cid.Undef, nil, xerrors.Wrap(err, "flushing simulated state")
+	}
+	return root, rets, nil
+}
+
+// SimulationRandomness is the randomness of a simulation on top of the tipset
+// at `Height`. The chain has no randomness for the epochs a simulation
+// advances past it, theirs is drawn from the one at `Height` mixed with the
+// epoch. It is deterministic but not the randomness the chain will have.
+type SimulationRandomness struct {
+	HeadRandomness
+	Height abi.ChainEpoch
+}
+
+func (r *SimulationRandomness) Randomness(ctx context.Context, tag acrypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) (abi.Randomness, error) {
+	if epoch <= r.Height {
+		return r.HeadRandomness.Randomness(ctx, tag, epoch, entropy)
+	}
+	return r.HeadRandomness.Randomness(ctx, tag, r.Height, futureEntropy(epoch, entropy))
+}
+
+func (r *SimulationRandomness) GetRandomnessFromBeacon(ctx context.Context, tag acrypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) (abi.Randomness, error) {
+	if epoch <= r.Height {
+		return r.HeadRandomness.GetRandomnessFromBeacon(ctx, tag, epoch, entropy)
+	}
+	return r.HeadRandomness.GetRandomnessFromBeacon(ctx, tag, r.Height, futureEntropy(epoch, entropy))
+}
+
+// futureEntropy prefixes `entropy` with `epoch`, the draws of different epochs
+// above the head differ.
+func futureEntropy(epoch abi.ChainEpoch, entropy []byte) []byte {
+	buf := make([]byte, 8, 8+len(entropy))
+	binary.BigEndian.PutUint64(buf, uint64(epoch))
+	return append(buf, entropy...)
+}
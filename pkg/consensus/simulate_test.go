@@ -0,0 +1,78 @@
+package consensus_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	acrypto "github.com/filecoin-project/go-state-types/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/consensus"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+// chainRandomness draws fake randomness from a chain ending at `height`.
+type chainRandomness struct {
+	height abi.ChainEpoch
+}
+
+func (r *chainRandomness) draw(epoch abi.ChainEpoch, entropy []byte) (abi.Randomness, error) {
+	if epoch > r.height {
+		return nil, errors.New("cannot draw randomness from the future")
+	}
+	return []byte(fmt.Sprintf("%d/%x", epoch, entropy)), nil
+}
+
+func (r *chainRandomness) SampleChainRandomness(_ context.Context, _ block.TipSetKey, _ acrypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) (abi.Randomness, error) {
+	return r.draw(epoch, entropy)
+}
+
+func (r *chainRandomness) ChainGetRandomnessFromBeacon(_ context.Context, _ block.TipSetKey, _ acrypto.DomainSeparationTag, epoch abi.ChainEpoch, entropy []byte) (abi.Randomness, error) {
+	return r.draw(epoch, entropy)
+}
+
+func TestSimulationRandomness(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	head := consensus.HeadRandomness{Chain: &chainRandomness{height: 10}}
+	rnd := consensus.SimulationRandomness{HeadRandomness: head, Height: 10}
+	entropy := []byte("entropy")
+
+	draws := map[string]func(context.Context, acrypto.DomainSeparationTag, abi.ChainEpoch, []byte) (abi.Randomness, error){
+		"chain":  rnd.Randomness,
+		"beacon": rnd.GetRandomnessFromBeacon,
+	}
+	heads := map[string]func(context.Context, acrypto.DomainSeparationTag, abi.ChainEpoch, []byte) (abi.Randomness, error){
+		"chain":  head.Randomness,
+		"beacon": head.GetRandomnessFromBeacon,
+	}
+	for name, draw := range draws {
+		t.Run(name, func(t *testing.T) {
+			// up to the head the chain is drawn
+			expected, err := heads[name](ctx, acrypto.DomainSeparationTag_TicketProduction, 10, entropy)
+			require.NoError(t, err)
+			got, err := draw(ctx, acrypto.DomainSeparationTag_TicketProduction, 10, entropy)
+			require.NoError(t, err)
+			assert.Equal(t, expected, got)
+
+			// above it the draws are deterministic and differ per epoch
+			_, err = heads[name](ctx, acrypto.DomainSeparationTag_TicketProduction, 11, entropy)
+			assert.Error(t, err)
+			first, err := draw(ctx, acrypto.DomainSeparationTag_TicketProduction, 11, entropy)
+			require.NoError(t, err)
+			again, err := draw(ctx, acrypto.DomainSeparationTag_TicketProduction, 11, entropy)
+			require.NoError(t, err)
+			assert.Equal(t, first, again)
+			next, err := draw(ctx, acrypto.DomainSeparationTag_TicketProduction, 12, entropy)
+			require.NoError(t, err)
+			assert.NotEqual(t, first, next)
+			assert.NotEqual(t, expected, first)
+		})
+	}
+}
@@ -25,6 +25,7 @@ type Bootstrapper struct {
 	// MinPeerThreshold is the number of connections it attempts to maintain.
 	MinPeerThreshold int
 	// Peers to connect to if we fall below the threshold.
+	peersLk        sync.Mutex
 	bootstrapPeers []peer.AddrInfo
 	// Period is the interval at which it periodically checks to see
 	// if the threshold is maintained.
@@ -93,6 +94,14 @@ func (b *Bootstrapper) Stop() {
 	}
 }
 
+// SetBootstrapPeers replaces the peers connected to when the threshold is not
+// met, from the next period on.
+func (b *Bootstrapper) SetBootstrapPeers(peers []peer.AddrInfo) {
+	b.peersLk.Lock()
+	defer b.peersLk.Unlock()
+	b.bootstrapPeers = peers
+}
+
 // bootstrap does the actual work. If the number of connected peers
 // has fallen below b.MinPeerThreshold it will attempt to connect to
 // a random subset of its bootstrap peers.
@@ -106,7 +115,11 @@ func (b *Bootstrapper) bootstrap(currentPeers []peer.ID) {
 		cancel()
 	}()
 
-	for _, bootstrappPeer := range b.bootstrapPeers {
+	b.peersLk.Lock()
+	bootstrapPeers := b.bootstrapPeers
+	b.peersLk.Unlock()
+
+	for _, bootstrappPeer := range bootstrapPeers {
 		pinfo := bootstrappPeer
 		// Don't try to connect to an already connected peer.
 		if hasPID(currentPeers, pinfo.ID) {
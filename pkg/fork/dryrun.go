@@ -0,0 +1,294 @@
+package fork
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/network"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	xerrors "github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/config"
+	"github.com/filecoin-project/venus/pkg/specactors/builtin"
+	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
+	vmstate "github.com/filecoin-project/venus/pkg/vm/state"
+)
+
+// MigrationDryRun reports the migrations to a network version run on a
+// scratch store.
+type MigrationDryRun struct {
+	Network network.Version
+	// Height is the upgrade epoch of the last migration run.
+	Height   abi.ChainEpoch
+	FromRoot cid.Cid
+	NewRoot  cid.Cid
+	Duration time.Duration
+	// BaseHeap and PeakHeap are the heap in use before and at most during the
+	// migrations, in bytes.
+	BaseHeap uint64
+	PeakHeap uint64
+	// Actors summarises the changes per actor code, named after the migration.
+	Actors []ActorChanges
+	// Checked is set when the new root was checked against the chain, Matched
+	// then tells whether executing the tipset above the upgrade on it gives
+	// the ParentStateRoot of the tipset after.
+	Checked bool
+	Matched bool
+	// ChainRoot is the ParentStateRoot the check expected and ExecutedRoot the
+	// root it computed.
+	ChainRoot    cid.Cid
+	ExecutedRoot cid.Cid
+}
+
+// ActorChanges counts the actors of one code and how a migration changed
+// them.
+type ActorChanges struct {
+	Code string
+	// Total is the number of actors with the code after the migration, or
+	// before it for removed actors.
+	Total          int
+	CodeChanged    int
+	HeadChanged    int
+	BalanceChanged int
+	Added          int
+	Removed        int
+}
+
+// StateTransition executes the messages of `ts` on the state `root`, already
+// migrated, and returns the state computed. It writes to `bs` only.
+type StateTransition func(ctx context.Context, bs blockstoreutil.Blockstore, ts *block.TipSet, root cid.Cid) (cid.Cid, error)
+
+// DryRunMigration runs the migrations of the upgrades to network version `nv`
+// on the state `root` and reports the result. Everything is written to a
+// scratch store, `bs` is only read. When `root` is undefined the state the
+// chain of `cr` had at the upgrade is migrated, the chain must be past it.
+//
+// When the migrated state is the one of the chain and `execute` is set, the
+// tipset above the upgrade is executed on the new root with `execute` and the
+// result is checked against the ParentStateRoot of the tipset after it.
+func DryRunMigration(ctx context.Context,
+	cr chainReader,
+	bs blockstoreutil.Blockstore,
+	forkUpgrade *config.ForkUpgradeConfig,
+	root cid.Cid,
+	nv network.Version,
+	execute StateTransition,
+) (*MigrationDryRun, error) {
+	return dryRunMigration(ctx, cr, bs, func(cf *ChainFork) UpgradeSchedule {
+		cf.forkUpgrade = forkUpgrade
+		return defaultUpgradeSchedule(cf, forkUpgrade)
+	}, root, nv, execute)
+}
+
+func dryRunMigration(ctx context.Context,
+	cr chainReader,
+	bs blockstoreutil.Blockstore,
+	schedule func(*ChainFork) UpgradeSchedule,
+	root cid.Cid,
+	nv network.Version,
+	execute StateTransition,
+) (*MigrationDryRun, error) {
+	scratchBs := blockstoreutil.NewTieredBstore(bs, blockstoreutil.NewTemporarySync())
+	scratch := &ChainFork{
+		cr:        cr,
+		bs:        scratchBs,
+		ipldstore: cbor.NewCborStore(scratchBs),
+	}
+
+	var upgrades []Upgrade
+	for _, u := range schedule(scratch) {
+		if u.Network == nv && u.Migration != nil {
+			upgrades = append(upgrades, u)
+		}
+	}
+	if len(upgrades) == 0 {
+		return nil, xerrors.Errorf("no scheduled upgrade to network version %d migrates the state", nv)
+	}
+	height := upgrades[len(upgrades)-1].Height
+
+	// The migration at height runs on the parent state of the first tipset
+	// above it when it has no null rounds before, before its messages. These
+	// give the ParentStateRoot of the tipset after.
+	head := cr.Head()
+	headHeight, err := head.Height()
+	if err != nil {
+		return nil, err
+	}
+	var ts, next, after *block.TipSet
+	if headHeight > height {
+		next, err = cr.GetTipSetByHeight(ctx, head, height+1, false)
+		if err != nil {
+			return nil, xerrors.Wrapf(err, "failed to load the tipset after the upgrade at %d", height)
+		}
+		parent, err := cr.GetTipSet(next.EnsureParents())
+		if err != nil {
+			return nil, err
+		}
+		if parent.EnsureHeight() == height {
+			ts = parent
+		} else {
+			next = nil
+		}
+	}
+	if next != nil && headHeight > next.EnsureHeight() {
+		if after, err = cr.GetTipSetByHeight(ctx, head, next.EnsureHeight()+1, false); err != nil {
+			return nil, xerrors.Wrapf(err, "failed to load the tipset above %d", next.EnsureHeight())
+		}
+	}
+	if ts == nil {
+		ts = head
+	}
+	fromChain := next != nil && (!root.Defined() || root == next.At(0).ParentStateRoot)
+	if !root.Defined() {
+		if !fromChain {
+			return nil, xerrors.Errorf("the chain has no state at the upgrade epoch %d, a root is needed", height)
+		}
+		root = next.At(0).ParentStateRoot
+	}
+
+	res := &MigrationDryRun{
+		Network:  nv,
+		Height:   height,
+		FromRoot: root,
+	}
+
+	sampler := startHeapSampler()
+	start := time.Now()
+	newRoot := root
+	for _, u := range upgrades {
+		newRoot, err = u.Migration(ctx, NewMigrationCache(), newRoot, u.Height, ts)
+		if err != nil {
+			sampler.stop()
+			return nil, xerrors.Wrapf(err, "migration at %d failed", u.Height)
+		}
+	}
+	res.Duration = time.Since(start)
+	res.BaseHeap, res.PeakHeap = sampler.stop()
+	res.NewRoot = newRoot
+
+	res.Actors, err = diffActors(ctx, scratch.ipldstore, root, newRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	if fromChain && after != nil && execute != nil {
+		res.ChainRoot = after.At(0).ParentStateRoot
+		if res.ExecutedRoot, err = execute(ctx, scratchBs, next, newRoot); err != nil {
+			return nil, xerrors.Wrapf(err, "failed to execute %s on the new root", next.Key())
+		}
+		res.Checked = true
+		res.Matched = res.ExecutedRoot == res.ChainRoot
+	}
+	return res, nil
+}
+
+// diffActors summarises per actor code how the actors of `from` changed in
+// `to`.
+func diffActors(ctx context.Context, store cbor.IpldStore, from, to cid.Cid) ([]ActorChanges, error) {
+	before, err := vmstate.LoadState(ctx, store, from)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to load the state before the migration")
+	}
+	after, err := vmstate.LoadState(ctx, store, to)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to load the state after the migration")
+	}
+
+	old := map[vmstate.ActorKey]*types.Actor{}
+	if err := before.ForEach(func(addr vmstate.ActorKey, act *types.Actor) error {
+		old[addr] = act
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	changes := map[string]*ActorChanges{}
+	entry := func(code cid.Cid) *ActorChanges {
+		name := builtin.ActorNameByCode(code)
+		if changes[name] == nil {
+			changes[name] = &ActorChanges{Code: name}
+		}
+		return changes[name]
+	}
+	if err := after.ForEach(func(addr vmstate.ActorKey, act *types.Actor) error {
+		e := entry(act.Code)
+		e.Total++
+		prev, ok := old[addr]
+		if !ok {
+			e.Added++
+			return nil
+		}
+		delete(old, addr)
+		if !prev.Code.Equals(act.Code) {
+			e.CodeChanged++
+		}
+		if !prev.Head.Equals(act.Head) {
+			e.HeadChanged++
+		}
+		if !prev.Balance.Equals(act.Balance) {
+			e.BalanceChanged++
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	for _, act := range old {
+		e := entry(act.Code)
+		e.Total++
+		e.Removed++
+	}
+
+	out := make([]ActorChanges, 0, len(changes))
+	for _, e := range changes {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out, nil
+}
+
+// heapSampler records the highest heap in use until stopped.
+type heapSampler struct {
+	done chan struct{}
+	wg   sync.WaitGroup
+	base uint64
+	peak uint64
+}
+
+func startHeapSampler() *heapSampler {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	s := &heapSampler{done: make(chan struct{}), base: ms.HeapInuse, peak: ms.HeapInuse}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				runtime.ReadMemStats(&ms)
+				if ms.HeapInuse > s.peak {
+					s.peak = ms.HeapInuse
+				}
+			case <-s.done:
+				return
+			}
+		}
+	}()
+	return s
+}
+
+// stop ends the sampling and returns the heap in use at the start and the
+// highest sampled.
+func (s *heapSampler) stop() (uint64, uint64) {
+	close(s.done)
+	s.wg.Wait()
+	return s.base, s.peak
+}
@@ -0,0 +1,201 @@
+package fork
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	fbig "github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/network"
+	builtin0 "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/chain"
+	"github.com/filecoin-project/venus/pkg/specactors/builtin"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
+	vmstate "github.com/filecoin-project/venus/pkg/vm/state"
+)
+
+// parentStateBuilder gives the blocks at a height the parent state root set
+// for it, `def` otherwise.
+type parentStateBuilder struct {
+	chain.FakeStateBuilder
+	def   cid.Cid
+	roots map[abi.ChainEpoch]cid.Cid
+}
+
+func (sb *parentStateBuilder) ComputeState(prev cid.Cid, blockmsg []block.BlockMessagesInfo) (cid.Cid, []types.MessageReceipt, error) {
+	if root, ok := sb.roots[blockmsg[0].Block.Height]; ok {
+		return root, []types.MessageReceipt{}, nil
+	}
+	if sb.def.Defined() {
+		return sb.def, []types.MessageReceipt{}, nil
+	}
+	return sb.FakeStateBuilder.ComputeState(prev, blockmsg)
+}
+
+// builderReader reads the chain of a builder up to `head`.
+type builderReader struct {
+	chainReader
+	builder *chain.Builder
+	head    *block.TipSet
+}
+
+func (r *builderReader) Head() *block.TipSet {
+	return r.head
+}
+
+func (r *builderReader) GetTipSet(key block.TipSetKey) (*block.TipSet, error) {
+	return r.builder.GetTipSet(key)
+}
+
+func (r *builderReader) GetTipSetByHeight(ctx context.Context, ts *block.TipSet, h abi.ChainEpoch, prev bool) (*block.TipSet, error) {
+	return r.builder.GetTipSetByHeight(ctx, ts, h, prev)
+}
+
+func TestDryRunMigration(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	sb := &parentStateBuilder{roots: map[abi.ChainEpoch]cid.Cid{}}
+	builder := chain.NewBuilderWithDeps(t, address.Undef, sb, &chain.ZeroTimestamper{})
+	bs := builder.BlockStore()
+
+	// The state has one account, the migration credits it and adds another.
+	account, err := address.NewIDAddress(100)
+	require.NoError(t, err)
+	added, err := address.NewIDAddress(101)
+	require.NoError(t, err)
+	state, err := vmstate.NewState(cbor.NewCborStore(bs), vmstate.StateTreeVersion0)
+	require.NoError(t, err)
+	require.NoError(t, state.SetActor(ctx, account, &types.Actor{Code: builtin0.AccountActorCodeID, Head: builtin0.AccountActorCodeID, Balance: abi.NewTokenAmount(10)}))
+	preRoot, err := state.Flush(ctx)
+	require.NoError(t, err)
+	require.NoError(t, state.SetActor(ctx, account, &types.Actor{Code: builtin0.AccountActorCodeID, Head: builtin0.AccountActorCodeID, Balance: abi.NewTokenAmount(20)}))
+	otherRoot, err := state.Flush(ctx)
+	require.NoError(t, err)
+
+	const upgradeHeight = abi.ChainEpoch(2)
+	migrate := func(store cbor.IpldStore) UpgradeFunc {
+		return func(ctx context.Context, _ *MigrationCache, root cid.Cid, _ abi.ChainEpoch, _ *block.TipSet) (cid.Cid, error) {
+			state, err := vmstate.LoadState(ctx, store, root)
+			if err != nil {
+				return cid.Undef, err
+			}
+			act, _, err := state.GetActor(ctx, account)
+			if err != nil {
+				return cid.Undef, err
+			}
+			act.Balance = fbig.Add(act.Balance, abi.NewTokenAmount(1))
+			if err := state.SetActor(ctx, account, act); err != nil {
+				return cid.Undef, err
+			}
+			if err := state.SetActor(ctx, added, &types.Actor{Code: builtin0.AccountActorCodeID, Head: builtin0.AccountActorCodeID, Balance: fbig.Zero()}); err != nil {
+				return cid.Undef, err
+			}
+			return state.Flush(ctx)
+		}
+	}
+	schedule := func(cf *ChainFork) UpgradeSchedule {
+		return UpgradeSchedule{
+			{Height: 1, Network: network.Version1},
+			{Height: upgradeHeight, Network: network.Version2, Migration: migrate(cf.ipldstore)},
+		}
+	}
+	// the root the migration computes, without writing it to the chain store
+	scratchBs := blockstoreutil.NewTieredBstore(bs, blockstoreutil.NewTemporarySync())
+	migratedRoot, err := migrate(cbor.NewCborStore(scratchBs))(ctx, nil, preRoot, upgradeHeight, nil)
+	require.NoError(t, err)
+
+	// The tipset at 3 is the first above the upgrade, its parent state is the
+	// state migrated and the tipset at 4 has the state after executing it.
+	sb.def = preRoot
+	sb.roots[4] = migratedRoot
+	tipsets := []*block.TipSet{builder.Genesis()}
+	for i := 0; i < 5; i++ {
+		tipsets = append(tipsets, builder.AppendOn(tipsets[len(tipsets)-1], 1))
+	}
+	cr := &builderReader{builder: builder, head: tipsets[5]}
+	require.Equal(t, preRoot, tipsets[3].At(0).ParentStateRoot)
+	require.Equal(t, migratedRoot, tipsets[4].At(0).ParentStateRoot)
+
+	var executed []*block.TipSet
+	noMessages := func(_ context.Context, _ blockstoreutil.Blockstore, ts *block.TipSet, root cid.Cid) (cid.Cid, error) {
+		executed = append(executed, ts)
+		return root, nil
+	}
+
+	t.Run("the chain state is migrated and checked", func(t *testing.T) {
+		executed = nil
+		res, err := dryRunMigration(ctx, cr, bs, schedule, cid.Undef, network.Version2, noMessages)
+		require.NoError(t, err)
+		assert.Equal(t, upgradeHeight, res.Height)
+		assert.Equal(t, preRoot, res.FromRoot)
+		assert.Equal(t, migratedRoot, res.NewRoot)
+		assert.True(t, res.Checked)
+		assert.True(t, res.Matched)
+		assert.Equal(t, migratedRoot, res.ChainRoot)
+		assert.Equal(t, migratedRoot, res.ExecutedRoot)
+		require.Len(t, executed, 1)
+		assert.Equal(t, tipsets[3].Key(), executed[0].Key())
+
+		require.Len(t, res.Actors, 1)
+		assert.Equal(t, ActorChanges{
+			Code:           builtin.ActorNameByCode(builtin0.AccountActorCodeID),
+			Total:          2,
+			BalanceChanged: 1,
+			Added:          1,
+		}, res.Actors[0])
+
+		// the migrated state is not written to the chain store
+		has, err := bs.Has(migratedRoot)
+		require.NoError(t, err)
+		assert.False(t, has)
+	})
+
+	t.Run("a different post-upgrade state is a mismatch", func(t *testing.T) {
+		other := types.CidFromString(t, "other")
+		res, err := dryRunMigration(ctx, cr, bs, schedule, preRoot, network.Version2, func(context.Context, blockstoreutil.Blockstore, *block.TipSet, cid.Cid) (cid.Cid, error) {
+			return other, nil
+		})
+		require.NoError(t, err)
+		assert.True(t, res.Checked)
+		assert.False(t, res.Matched)
+		assert.Equal(t, other, res.ExecutedRoot)
+		assert.Equal(t, migratedRoot, res.ChainRoot)
+	})
+
+	t.Run("another root is not checked", func(t *testing.T) {
+		executed = nil
+		res, err := dryRunMigration(ctx, cr, bs, schedule, otherRoot, network.Version2, noMessages)
+		require.NoError(t, err)
+		assert.Equal(t, otherRoot, res.FromRoot)
+		assert.False(t, res.Checked)
+		assert.False(t, res.Matched)
+		assert.Empty(t, executed)
+	})
+
+	t.Run("the chain must be past the upgrade", func(t *testing.T) {
+		// the tipset after the one above the upgrade is needed for the check
+		res, err := dryRunMigration(ctx, &builderReader{builder: builder, head: tipsets[3]}, bs, schedule, cid.Undef, network.Version2, noMessages)
+		require.NoError(t, err)
+		assert.False(t, res.Checked)
+
+		_, err = dryRunMigration(ctx, &builderReader{builder: builder, head: tipsets[2]}, bs, schedule, cid.Undef, network.Version2, noMessages)
+		assert.Error(t, err)
+	})
+
+	t.Run("the network version must migrate the state", func(t *testing.T) {
+		_, err := dryRunMigration(ctx, cr, bs, schedule, cid.Undef, network.Version1, noMessages)
+		assert.Error(t, err)
+		_, err = dryRunMigration(ctx, cr, bs, schedule, cid.Undef, network.Version3, noMessages)
+		assert.Error(t, err)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"github.com/filecoin-project/venus/pkg/constants"
+	"runtime"
 
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
@@ -34,6 +35,7 @@ import (
 	adt0 "github.com/filecoin-project/specs-actors/actors/util/adt"
 
 	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/chain"
 	"github.com/filecoin-project/venus/pkg/config"
 	"github.com/filecoin-project/venus/pkg/specactors/adt"
 	"github.com/filecoin-project/venus/pkg/specactors/builtin"
@@ -50,18 +52,43 @@ var ErrExpensiveFork = errors.New("refusing explicit call due to state fork at e
 
 // UpgradeFunc is a migration function run at every upgrade.
 //
+// - The cache holds the results of the pre-migrations run before the upgrade,
+//   it may be empty.
 // - The oldState is the state produced by the upgrade epoch.
 // - The returned newState is the new state that will be used by the next epoch.
 // - The height is the upgrade epoch height (already executed).
 // - The tipset is the tipset for the last non-null block before the upgrade. Do
 //   not assume that ts.Height() is the upgrade height.
-type UpgradeFunc func(ctx context.Context, oldState cid.Cid, height abi.ChainEpoch, ts *block.TipSet) (newState cid.Cid, err error)
+type UpgradeFunc func(ctx context.Context, cache *MigrationCache, oldState cid.Cid, height abi.ChainEpoch, ts *block.TipSet) (newState cid.Cid, err error)
+
+// PreMigrationFunc runs part of a migration ahead of the upgrade on the state
+// of a recent tipset, writing its results to the cache of the upgrade.
+//
+// - The oldState is the parent state of ts.
+// - The height is the height of ts, not of the upgrade.
+type PreMigrationFunc func(ctx context.Context, cache *MigrationCache, oldState cid.Cid, height abi.ChainEpoch, ts *block.TipSet) error
+
+// PreMigration is run in the background before an expensive upgrade so the
+// migration at the upgrade epoch finds most of its work cached. All the
+// epochs count down to the upgrade height.
+type PreMigration struct {
+	PreMigration PreMigrationFunc
+	// StartWithin starts the pre-migration this many epochs before the upgrade.
+	StartWithin abi.ChainEpoch
+	// DontStartWithin skips the pre-migration when the node only reaches the
+	// start window this many epochs before the upgrade.
+	DontStartWithin abi.ChainEpoch
+	// StopWithin cancels the pre-migration if it still runs this many epochs
+	// before the upgrade.
+	StopWithin abi.ChainEpoch
+}
 
 type Upgrade struct {
-	Height    abi.ChainEpoch
-	Network   network.Version
-	Expensive bool
-	Migration UpgradeFunc
+	Height        abi.ChainEpoch
+	Network       network.Version
+	Expensive     bool
+	Migration     UpgradeFunc
+	PreMigrations []PreMigration
 }
 
 type UpgradeSchedule []Upgrade
@@ -133,6 +160,17 @@ func defaultUpgradeSchedule(cf *ChainFork, upgradeHeight *config.ForkUpgradeConf
 		Height:    upgradeHeight.UpgradeActorsV3Height,
 		Network:   network.Version10,
 		Migration: cf.UpgradeActorsV3,
+		PreMigrations: []PreMigration{{
+			PreMigration:    cf.PreUpgradeActorsV3,
+			StartWithin:     120,
+			DontStartWithin: 60,
+			StopWithin:      35,
+		}, {
+			PreMigration:    cf.PreUpgradeActorsV3,
+			StartWithin:     30,
+			DontStartWithin: 15,
+			StopWithin:      5,
+		}},
 		Expensive: true,
 	}}
 
@@ -178,6 +216,18 @@ func (us UpgradeSchedule) Validate() error {
 		}
 	}
 
+	// Make sure the pre-migrations start before they stop.
+	for _, u := range us {
+		for _, m := range u.PreMigrations {
+			if m.StartWithin <= 0 || m.StopWithin < 0 {
+				return xerrors.Errorf("pre-migration of version %d must start and stop before the upgrade", u.Network)
+			}
+			if m.DontStartWithin > m.StartWithin || m.StopWithin > m.DontStartWithin {
+				return xerrors.Errorf("pre-migration of version %d must start within %d epochs, not start within %d and stop within %d", u.Network, m.StartWithin, m.DontStartWithin, m.StopWithin)
+			}
+		}
+	}
+
 	// Make sure all the upgrades make sense.
 	for i := 1; i < len(us); i++ {
 		prev := &us[i-1]
@@ -203,12 +253,16 @@ type chainReader interface {
 	GetTipSetByHeight(context.Context, *block.TipSet, abi.ChainEpoch, bool) (*block.TipSet, error)
 	GetTipSetState(context.Context, *block.TipSet) (vmstate.Tree, error)
 	GetGenesisBlock(context.Context) (*block.Block, error)
+	ChainNotify(context.Context) chan []*chain.HeadChange
 }
 type IFork interface {
 	HandleStateForks(ctx context.Context, root cid.Cid, height abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error)
 	GetNtwkVersion(ctx context.Context, height abi.ChainEpoch) network.Version
 	HasExpensiveFork(ctx context.Context, height abi.ChainEpoch) bool
+	HasMigration(ctx context.Context, height abi.ChainEpoch) bool
 	GetForkUpgrade() *config.ForkUpgradeConfig
+	Start(ctx context.Context) error
+	Stop(ctx context.Context)
 }
 
 var _ = IFork((*ChainFork)(nil))
@@ -223,7 +277,7 @@ type ChainFork struct {
 	latestVersion   network.Version
 
 	// Maps chain epochs to upgrade functions.
-	stateMigrations map[abi.ChainEpoch]UpgradeFunc
+	stateMigrations map[abi.ChainEpoch]*migration
 	// A set of potentially expensive/time consuming upgrades. Explicit
 	// calls for, e.g., gas estimation fail against this epoch with
 	// ErrExpensiveFork.
@@ -231,6 +285,18 @@ type ChainFork struct {
 
 	// upgrade param
 	forkUpgrade *config.ForkUpgradeConfig
+
+	// cancel and shutdown stop the pre-migration worker.
+	cancel   context.CancelFunc
+	shutdown chan struct{}
+}
+
+// migration is an upgrade function along with its pre-migrations and the
+// cache they fill.
+type migration struct {
+	upgrade       UpgradeFunc
+	preMigrations []PreMigration
+	cache         *MigrationCache
 }
 
 type versionSpec struct {
@@ -253,7 +319,7 @@ func NewChainFork(cr chainReader, ipldstore cbor.IpldStore, bs blockstore.Blocks
 		return nil, err
 	}
 
-	stateMigrations := make(map[abi.ChainEpoch]UpgradeFunc, len(us))
+	stateMigrations := make(map[abi.ChainEpoch]*migration, len(us))
 	expensiveUpgrades := make(map[abi.ChainEpoch]struct{}, len(us))
 	var networkVersions []versionSpec
 	lastVersion := network.Version0
@@ -261,7 +327,11 @@ func NewChainFork(cr chainReader, ipldstore cbor.IpldStore, bs blockstore.Blocks
 		// If we have any upgrades, process them and create a version schedule.
 		for _, upgrade := range us {
 			if upgrade.Migration != nil {
-				stateMigrations[upgrade.Height] = upgrade.Migration
+				stateMigrations[upgrade.Height] = &migration{
+					upgrade:       upgrade.Migration,
+					preMigrations: upgrade.PreMigrations,
+					cache:         NewMigrationCache(),
+				}
 			}
 			if upgrade.Expensive {
 				expensiveUpgrades[upgrade.Height] = struct{}{}
@@ -294,7 +364,7 @@ func (c *ChainFork) HandleStateForks(ctx context.Context, root cid.Cid, height a
 	var err error
 	migration, ok := c.stateMigrations[height]
 	if ok {
-		retCid, err = migration(ctx, root, height, ts)
+		retCid, err = migration.upgrade(ctx, migration.cache, root, height, ts)
 		if err != nil {
 			return cid.Undef, err
 		}
@@ -308,6 +378,12 @@ func (c *ChainFork) HasExpensiveFork(ctx context.Context, height abi.ChainEpoch)
 	return ok
 }
 
+// HasMigration returns whether the state is migrated at `height`.
+func (c *ChainFork) HasMigration(ctx context.Context, height abi.ChainEpoch) bool {
+	_, ok := c.stateMigrations[height]
+	return ok
+}
+
 func (c *ChainFork) GetNtwkVersion(ctx context.Context, height abi.ChainEpoch) network.Version {
 	// The epochs here are the _last_ epoch for every version, or -1 if the
 	// version is disabled.
@@ -362,7 +438,7 @@ func (c *ChainFork) ParentState(ts *block.TipSet) cid.Cid {
 	return ts.Blocks()[0].ParentStateRoot
 }
 
-func (c *ChainFork) UpgradeFaucetBurnRecovery(ctx context.Context, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
+func (c *ChainFork) UpgradeFaucetBurnRecovery(ctx context.Context, cache *MigrationCache, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
 	// Some initial parameters
 	FundsForMiners := types.NewAttoFILFromFIL(1_000_000)
 	LookbackEpoch := abi.ChainEpoch(32000)
@@ -873,7 +949,7 @@ func resetMultisigVesting0(ctx context.Context, store adt0.Store, tree *vmstate.
 	return nil
 }
 
-func (c *ChainFork) UpgradeIgnition(ctx context.Context, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
+func (c *ChainFork) UpgradeIgnition(ctx context.Context, cache *MigrationCache, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
 	store := adt.WrapStore(ctx, c.ipldstore)
 
 	if c.forkUpgrade.UpgradeLiftoffHeight <= epoch {
@@ -928,7 +1004,7 @@ func (c *ChainFork) UpgradeIgnition(ctx context.Context, root cid.Cid, epoch abi
 	return tree.Flush(ctx)
 }
 
-func (c *ChainFork) UpgradeRefuel(ctx context.Context, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
+func (c *ChainFork) UpgradeRefuel(ctx context.Context, cache *MigrationCache, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
 	store := adt.WrapStore(ctx, c.ipldstore)
 	tree, err := c.StateTree(ctx, root)
 	if err != nil {
@@ -1101,7 +1177,7 @@ func Copy(ctx context.Context, from, to blockstore.Blockstore, root cid.Cid) err
 	return nil
 }
 
-func (c *ChainFork) UpgradeActorsV2(ctx context.Context, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
+func (c *ChainFork) UpgradeActorsV2(ctx context.Context, cache *MigrationCache, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
 	buf := blockstoreutil.NewTieredBstore(c.bs, blockstoreutil.NewTemporarySync())
 	store := ActorStore(ctx, buf)
 
@@ -1147,7 +1223,7 @@ func (c *ChainFork) UpgradeActorsV2(ctx context.Context, root cid.Cid, epoch abi
 	return newRoot, nil
 }
 
-func (c *ChainFork) UpgradeLiftoff(ctx context.Context, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
+func (c *ChainFork) UpgradeLiftoff(ctx context.Context, cache *MigrationCache, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
 	tree, err := c.StateTree(ctx, root)
 	if err != nil {
 		return cid.Undef, xerrors.Errorf("getting state tree: %v", err)
@@ -1161,7 +1237,7 @@ func (c *ChainFork) UpgradeLiftoff(ctx context.Context, root cid.Cid, epoch abi.
 	return tree.Flush(ctx)
 }
 
-func (c *ChainFork) UpgradeCalico(ctx context.Context, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
+func (c *ChainFork) UpgradeCalico(ctx context.Context, cache *MigrationCache, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
 	store := ActorStore(ctx, c.bs)
 	var stateRoot vmstate.StateRoot
 	if err := store.Get(ctx, root, &stateRoot); err != nil {
@@ -1203,7 +1279,51 @@ func (c *ChainFork) UpgradeCalico(ctx context.Context, root cid.Cid, epoch abi.C
 	return newRoot, nil
 }
 
-func (c *ChainFork) UpgradeActorsV3(ctx context.Context, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
+func (c *ChainFork) UpgradeActorsV3(ctx context.Context, cache *MigrationCache, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) (cid.Cid, error) {
+	// Use all the CPUs except 3.
+	workerCount := runtime.NumCPU() - 3
+	if workerCount <= 0 {
+		workerCount = 1
+	}
+
+	config := nv10.Config{MaxWorkers: uint(workerCount)}
+	newRoot, err := c.upgradeActorsV3Common(ctx, cache, root, epoch, config)
+	if err != nil {
+		return cid.Undef, xerrors.Errorf("migrating actors v3 state: %v", err)
+	}
+
+	// perform some basic sanity checks to make sure everything still works.
+	store := ActorStore(ctx, c.bs)
+	if newSm, err := vmstate.LoadState(ctx, store, newRoot); err != nil {
+		return cid.Undef, xerrors.Errorf("state tree sanity load failed: %v", err)
+	} else if newRoot2, err := newSm.Flush(ctx); err != nil {
+		return cid.Undef, xerrors.Errorf("state tree sanity flush failed: %v", err)
+	} else if newRoot2 != newRoot {
+		return cid.Undef, xerrors.Errorf("state-root mismatch: %s != %s", newRoot, newRoot2)
+	} else if _, _, err := newSm.GetActor(ctx, init_.Address); err != nil {
+		return cid.Undef, xerrors.Errorf("failed to load init actor after upgrade: %v", err)
+	}
+
+	return newRoot, nil
+}
+
+// PreUpgradeActorsV3 runs the actors v3 migration on the state of a tipset
+// before the upgrade, caching the migrated actors. Its result is discarded.
+func (c *ChainFork) PreUpgradeActorsV3(ctx context.Context, cache *MigrationCache, root cid.Cid, epoch abi.ChainEpoch, ts *block.TipSet) error {
+	// Use half the CPUs, validating blocks goes first.
+	workerCount := runtime.NumCPU()
+	if workerCount <= 4 {
+		workerCount = 1
+	} else {
+		workerCount /= 2
+	}
+
+	config := nv10.Config{MaxWorkers: uint(workerCount)}
+	_, err := c.upgradeActorsV3Common(ctx, cache, root, epoch, config)
+	return err
+}
+
+func (c *ChainFork) upgradeActorsV3Common(ctx context.Context, cache *MigrationCache, root cid.Cid, epoch abi.ChainEpoch, config nv10.Config) (cid.Cid, error) {
 	buf := blockstoreutil.NewTieredBstore(c.bs, blockstoreutil.NewTemporarySync())
 	store := ActorStore(ctx, buf)
 
@@ -1223,10 +1343,6 @@ func (c *ChainFork) UpgradeActorsV3(ctx context.Context, root cid.Cid, epoch abi
 
 	// Perform the migration
 
-	// TODO: store this somewhere and pre-migrate
-	cache := nv10.NewMemMigrationCache()
-	// TODO: tune this.
-	config := nv10.Config{MaxWorkers: 1}
 	newHamtRoot, err := nv10.MigrateStateTree(ctx, store, stateRoot.Actors, epoch, config, migrationLogger{}, cache)
 	if err != nil {
 		return cid.Undef, xerrors.Errorf("upgrading to actors v2: %v", err)
@@ -1243,19 +1359,6 @@ func (c *ChainFork) UpgradeActorsV3(ctx context.Context, root cid.Cid, epoch abi
 		return cid.Undef, xerrors.Errorf("failed to persist new state root: %v", err)
 	}
 
-	// Check the result.
-
-	// perform some basic sanity checks to make sure everything still works.
-	if newSm, err := vmstate.LoadState(ctx, store, newRoot); err != nil {
-		return cid.Undef, xerrors.Errorf("state tree sanity load failed: %v", err)
-	} else if newRoot2, err := newSm.Flush(ctx); err != nil {
-		return cid.Undef, xerrors.Errorf("state tree sanity flush failed: %v", err)
-	} else if newRoot2 != newRoot {
-		return cid.Undef, xerrors.Errorf("state-root mismatch: %s != %s", newRoot, newRoot2)
-	} else if _, _, err := newSm.GetActor(ctx, init_.Address); err != nil {
-		return cid.Undef, xerrors.Errorf("failed to load init actor after upgrade: %v", err)
-	}
-
 	// Persist the new tree.
 
 	{
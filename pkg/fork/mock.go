@@ -31,6 +31,16 @@ func (mockFork *MockFork) HasExpensiveFork(ctx context.Context, height abi.Chain
 	return false
 }
 
+func (mockFork *MockFork) HasMigration(ctx context.Context, height abi.ChainEpoch) bool {
+	return false
+}
+
+func (mockFork *MockFork) Start(ctx context.Context) error {
+	return nil
+}
+
+func (mockFork *MockFork) Stop(ctx context.Context) {}
+
 func (mockFork *MockFork) GetForkUpgrade() *config.ForkUpgradeConfig {
 	return &config.ForkUpgradeConfig{
 		UpgradeSmokeHeight:       -1,
@@ -0,0 +1,255 @@
+package fork
+
+import (
+	"context"
+	"sync"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/specs-actors/v3/actors/migration/nv10"
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/chain"
+	"github.com/filecoin-project/venus/pkg/metrics"
+)
+
+var (
+	preMigrationStarted   = metrics.NewInt64Counter("fork/premigration_started", "Number of pre-migrations started")
+	preMigrationCompleted = metrics.NewInt64Counter("fork/premigration_completed", "Number of pre-migrations completed")
+	preMigrationFailed    = metrics.NewInt64Counter("fork/premigration_failed", "Number of pre-migrations that failed")
+	preMigrationCancelled = metrics.NewInt64Counter("fork/premigration_cancelled", "Number of pre-migrations cancelled by a reorg or by reaching their stop epoch")
+	preMigrationDuration  = metrics.NewTimerWithBuckets("fork/premigration_duration", "Duration of the completed pre-migrations in milliseconds",
+		"ms", []float64{1000, 10000, 30000, 60000, 300000, 600000, 1800000, 3600000})
+	migrationCacheEntries = metrics.NewInt64Gauge("fork/migration_cache_entries", "Number of entries cached for the next upgrade by its pre-migrations")
+)
+
+var _ nv10.MigrationCache = (*MigrationCache)(nil)
+
+// MigrationCache maps the keys of migrated objects to their migrated cid so
+// the migration at the upgrade epoch can skip what a pre-migration already
+// did. It is safe for concurrent use.
+type MigrationCache struct {
+	lk    sync.RWMutex
+	cache map[string]cid.Cid
+}
+
+// NewMigrationCache returns an empty cache.
+func NewMigrationCache() *MigrationCache {
+	return &MigrationCache{cache: map[string]cid.Cid{}}
+}
+
+func (m *MigrationCache) Write(key nv10.MigrationCacheKey, c cid.Cid) error {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.cache[string(key)] = c
+	return nil
+}
+
+func (m *MigrationCache) Read(key nv10.MigrationCacheKey) (bool, cid.Cid, error) {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+	c, ok := m.cache[string(key)]
+	return ok, c, nil
+}
+
+// Load returns the cached cid of `key`, calling `loadFunc` and caching its
+// result when there is none.
+func (m *MigrationCache) Load(key nv10.MigrationCacheKey, loadFunc func() (cid.Cid, error)) (cid.Cid, error) {
+	if found, c, _ := m.Read(key); found {
+		return c, nil
+	}
+	c, err := loadFunc()
+	if err != nil {
+		return cid.Undef, err
+	}
+	return c, m.Write(key, c)
+}
+
+// Clone returns a copy of the cache.
+func (m *MigrationCache) Clone() *MigrationCache {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+	clone := NewMigrationCache()
+	for k, v := range m.cache {
+		clone.cache[k] = v
+	}
+	return clone
+}
+
+// Update adds the entries of `other` to the cache.
+func (m *MigrationCache) Update(other *MigrationCache) {
+	other.lk.RLock()
+	defer other.lk.RUnlock()
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	for k, v := range other.cache {
+		m.cache[k] = v
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (m *MigrationCache) Len() int {
+	m.lk.RLock()
+	defer m.lk.RUnlock()
+	return len(m.cache)
+}
+
+// Start runs the pre-migrations of the upcoming upgrades in the background as
+// the chain head gets close to them.
+func (c *ChainFork) Start(ctx context.Context) error {
+	var workerCtx context.Context
+	workerCtx, c.cancel = context.WithCancel(context.Background())
+	c.shutdown = make(chan struct{})
+	go c.preMigrationWorker(workerCtx)
+	return nil
+}
+
+// Stop cancels the running pre-migrations and waits for them to exit.
+func (c *ChainFork) Stop(ctx context.Context) {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	<-c.shutdown
+}
+
+// preMigrationTask follows one pre-migration of an upgrade through the head
+// changes: it starts in its window, restarts when a reorg drops the tipset
+// it runs on, and is cancelled at its stop epoch.
+type preMigrationTask struct {
+	PreMigration
+	upgrade abi.ChainEpoch
+	index   int
+	cache   *MigrationCache
+
+	lk sync.Mutex
+	// done is set once the pre-migration completed, failed, was stopped or
+	// its window was missed.
+	done bool
+	// base and cancel are set while the pre-migration runs on base.
+	base   *block.TipSet
+	cancel context.CancelFunc
+}
+
+func (c *ChainFork) preMigrationWorker(ctx context.Context) {
+	defer close(c.shutdown)
+
+	var tasks []*preMigrationTask
+	for height, m := range c.stateMigrations {
+		for i, pm := range m.preMigrations {
+			tasks = append(tasks, &preMigrationTask{
+				PreMigration: pm,
+				upgrade:      height,
+				index:        i,
+				cache:        m.cache,
+			})
+		}
+	}
+	if len(tasks) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for changes := range c.cr.ChainNotify(ctx) {
+		for _, change := range changes {
+			if change.Type == chain.HCRevert {
+				for _, t := range tasks {
+					t.revert(ctx, change.Val)
+				}
+				continue
+			}
+			height, err := change.Val.Height()
+			if err != nil {
+				log.Errorf("failed to read the height of head %s: %s", change.Val.Key(), err)
+				continue
+			}
+			for _, t := range tasks {
+				t.advance(ctx, c, &wg, change.Val, height)
+			}
+		}
+	}
+}
+
+// advance starts, stops or skips the pre-migration for the new head `ts`.
+func (t *preMigrationTask) advance(ctx context.Context, c *ChainFork, wg *sync.WaitGroup, ts *block.TipSet, height abi.ChainEpoch) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	switch {
+	case t.done:
+	case t.cancel != nil:
+		if height >= t.upgrade-t.StopWithin {
+			log.Warnf("stopping pre-migration %d of the upgrade at %d, the head reached %d", t.index, t.upgrade, height)
+			preMigrationCancelled.Inc(ctx, 1)
+			t.cancel()
+			t.base, t.cancel, t.done = nil, nil, true
+		}
+	case height >= t.upgrade-t.DontStartWithin:
+		log.Warnf("skipping pre-migration %d of the upgrade at %d, the head reached %d", t.index, t.upgrade, height)
+		t.done = true
+	case height >= t.upgrade-t.StartWithin:
+		var runCtx context.Context
+		runCtx, t.cancel = context.WithCancel(ctx)
+		t.base = ts
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.runPreMigration(runCtx, t, ts, height)
+			t.finish(runCtx)
+		}()
+	}
+}
+
+// revert cancels the pre-migration if it runs on `ts`, it starts again on the
+// next head in its window.
+func (t *preMigrationTask) revert(ctx context.Context, ts *block.TipSet) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	if t.cancel == nil || !t.base.Equals(ts) {
+		return
+	}
+	log.Warnf("cancelling pre-migration %d of the upgrade at %d, tipset %s was reverted", t.index, t.upgrade, ts.Key())
+	preMigrationCancelled.Inc(ctx, 1)
+	t.cancel()
+	t.base, t.cancel = nil, nil
+}
+
+// finish records the end of a run that was not cancelled, cancelled runs were
+// already accounted for by whoever cancelled them.
+func (t *preMigrationTask) finish(runCtx context.Context) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+
+	if runCtx.Err() != nil {
+		return
+	}
+	t.cancel()
+	t.base, t.cancel, t.done = nil, nil, true
+}
+
+func (c *ChainFork) runPreMigration(ctx context.Context, t *preMigrationTask, ts *block.TipSet, height abi.ChainEpoch) {
+	log.Infof("starting pre-migration %d of the upgrade at %d on tipset %s at %d", t.index, t.upgrade, ts.Key(), height)
+	preMigrationStarted.Inc(ctx, 1)
+	sw := preMigrationDuration.Start(ctx)
+
+	// Work on a copy so a failed or cancelled run leaves the cache unchanged,
+	// the migration could otherwise find entries whose blocks were never
+	// written.
+	tmp := t.cache.Clone()
+	if err := t.PreMigration.PreMigration(ctx, tmp, c.ParentState(ts), height, ts); err != nil {
+		if ctx.Err() == nil {
+			preMigrationFailed.Inc(ctx, 1)
+			log.Errorf("pre-migration %d of the upgrade at %d failed: %s", t.index, t.upgrade, err)
+		}
+		return
+	}
+	t.cache.Update(tmp)
+
+	duration := sw.Stop(ctx)
+	preMigrationCompleted.Inc(ctx, 1)
+	migrationCacheEntries.Set(ctx, int64(t.cache.Len()))
+	log.Infof("completed pre-migration %d of the upgrade at %d in %s, %d entries cached", t.index, t.upgrade, duration, t.cache.Len())
+}
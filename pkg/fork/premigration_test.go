@@ -0,0 +1,169 @@
+package fork
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	fbig "github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/network"
+	"github.com/ipfs/go-cid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/chain"
+	emptycid "github.com/filecoin-project/venus/pkg/testhelpers/empty_cid"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+// fakeHeads feeds the pre-migration worker, only ChainNotify is used.
+type fakeHeads struct {
+	chainReader
+	ch chan []*chain.HeadChange
+}
+
+func (f *fakeHeads) ChainNotify(context.Context) chan []*chain.HeadChange {
+	return f.ch
+}
+
+func TestPreMigrations(t *testing.T) {
+	tf.UnitTest(t)
+
+	tipset := func(height abi.ChainEpoch, miner uint64) *block.TipSet {
+		minerAddr, err := address.NewIDAddress(miner)
+		require.NoError(t, err)
+		return block.RequireNewTipSet(t, &block.Block{
+			Miner:                 minerAddr,
+			Height:                height,
+			ParentWeight:          fbig.Zero(),
+			ParentStateRoot:       emptycid.EmptyMessagesCID,
+			Messages:              emptycid.EmptyMessagesCID,
+			ParentMessageReceipts: emptycid.EmptyReceiptsCID,
+		})
+	}
+	apply := func(ts *block.TipSet) *chain.HeadChange {
+		return &chain.HeadChange{Type: chain.HCApply, Val: ts}
+	}
+	revert := func(ts *block.TipSet) *chain.HeadChange {
+		return &chain.HeadChange{Type: chain.HCRevert, Val: ts}
+	}
+
+	type run struct {
+		height abi.ChainEpoch
+		ctx    context.Context
+	}
+	// newFork returns a fork upgrading at 100, whose pre-migration runs from
+	// 80 to 95 and caches its height once `release` is closed.
+	newFork := func() (*ChainFork, *fakeHeads, chan run, chan struct{}) {
+		runs := make(chan run, 10)
+		release := make(chan struct{})
+		heads := &fakeHeads{ch: make(chan []*chain.HeadChange, 10)}
+		cf := &ChainFork{
+			cr: heads,
+			stateMigrations: map[abi.ChainEpoch]*migration{
+				100: {
+					preMigrations: []PreMigration{{
+						PreMigration: func(ctx context.Context, cache *MigrationCache, _ cid.Cid, height abi.ChainEpoch, _ *block.TipSet) error {
+							runs <- run{height: height, ctx: ctx}
+							select {
+							case <-release:
+							case <-ctx.Done():
+								return ctx.Err()
+							}
+							return cache.Write("height", emptycid.EmptyMessagesCID)
+						},
+						StartWithin:     20,
+						DontStartWithin: 10,
+						StopWithin:      5,
+					}},
+					cache: NewMigrationCache(),
+				},
+			},
+		}
+		require.NoError(t, cf.Start(context.Background()))
+		return cf, heads, runs, release
+	}
+	nextRun := func(runs chan run) run {
+		select {
+		case r := <-runs:
+			return r
+		case <-time.After(5 * time.Second):
+			t.Fatal("pre-migration did not start")
+		}
+		return run{}
+	}
+
+	t.Run("restarts after a reorg and fills the cache", func(t *testing.T) {
+		cf, heads, runs, release := newFork()
+		cache := cf.stateMigrations[100].cache
+
+		heads.ch <- []*chain.HeadChange{apply(tipset(70, 1))}
+		a := tipset(80, 1)
+		heads.ch <- []*chain.HeadChange{apply(a)}
+		first := nextRun(runs)
+		assert.Equal(t, abi.ChainEpoch(80), first.height)
+
+		heads.ch <- []*chain.HeadChange{revert(a), apply(tipset(81, 2))}
+		second := nextRun(runs)
+		assert.Equal(t, abi.ChainEpoch(81), second.height)
+		<-first.ctx.Done()
+		assert.Equal(t, 0, cache.Len(), "a cancelled run caches nothing")
+
+		close(release)
+		require.Eventually(t, func() bool { return cache.Len() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+		// completed, it does not run again in its window
+		heads.ch <- []*chain.HeadChange{apply(tipset(85, 1))}
+		close(heads.ch)
+		cf.Stop(context.Background())
+		assert.Empty(t, runs)
+	})
+
+	t.Run("stops at its stop epoch", func(t *testing.T) {
+		cf, heads, runs, _ := newFork()
+
+		heads.ch <- []*chain.HeadChange{apply(tipset(80, 1))}
+		r := nextRun(runs)
+		heads.ch <- []*chain.HeadChange{apply(tipset(95, 1))}
+		<-r.ctx.Done()
+
+		heads.ch <- []*chain.HeadChange{apply(tipset(96, 1))}
+		close(heads.ch)
+		cf.Stop(context.Background())
+		assert.Empty(t, runs)
+		assert.Equal(t, 0, cf.stateMigrations[100].cache.Len())
+	})
+
+	t.Run("is skipped when the window is missed", func(t *testing.T) {
+		cf, heads, runs, _ := newFork()
+
+		heads.ch <- []*chain.HeadChange{apply(tipset(91, 1)), apply(tipset(85, 1))}
+		close(heads.ch)
+		cf.Stop(context.Background())
+		assert.Empty(t, runs)
+	})
+}
+
+func TestValidatePreMigrations(t *testing.T) {
+	tf.UnitTest(t)
+
+	us := UpgradeSchedule{{
+		Height:  100,
+		Network: network.Version10,
+		PreMigrations: []PreMigration{{
+			StartWithin:     20,
+			DontStartWithin: 10,
+			StopWithin:      5,
+		}},
+	}}
+	require.NoError(t, us.Validate())
+
+	us[0].PreMigrations[0].StopWithin = 15
+	assert.Error(t, us.Validate())
+
+	us[0].PreMigrations[0] = PreMigration{StartWithin: 0}
+	assert.Error(t, us.Validate())
+}
@@ -0,0 +1,168 @@
+package invariants
+
+import (
+	"context"
+	"sort"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/network"
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	states2 "github.com/filecoin-project/specs-actors/v2/actors/states"
+	adt2 "github.com/filecoin-project/specs-actors/v2/actors/util/adt"
+	builtin3 "github.com/filecoin-project/specs-actors/v3/actors/builtin"
+	states3 "github.com/filecoin-project/specs-actors/v3/actors/states"
+	adt3 "github.com/filecoin-project/specs-actors/v3/actors/util/adt"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	xerrors "github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/pkg/crypto"
+	"github.com/filecoin-project/venus/pkg/metrics"
+	"github.com/filecoin-project/venus/pkg/specactors"
+	"github.com/filecoin-project/venus/pkg/specactors/builtin"
+	vmstate "github.com/filecoin-project/venus/pkg/vm/state"
+)
+
+var (
+	checksRun     = metrics.NewInt64Counter("invariants/checks", "Number of state invariant checks run")
+	checksFailed  = metrics.NewInt64Counter("invariants/checks_failed", "Number of state invariant checks that found violations")
+	violations    = metrics.NewInt64Gauge("invariants/violations", "Number of state invariant violations found by the last check")
+	checkDuration = metrics.NewTimerWithBuckets("invariants/check_duration", "Duration of the state invariant checks in milliseconds",
+		"ms", []float64{1000, 10000, 30000, 60000, 300000, 600000})
+)
+
+// CrossActor groups the violations of checks spanning several actors: the
+// miners against the power table, the deals against the miner sectors and the
+// total FIL in the state.
+const CrossActor = "cross-actor"
+
+// Report is the result of an invariant check of a state tree.
+type Report struct {
+	Height       abi.ChainEpoch
+	StateRoot    cid.Cid
+	ActorVersion specactors.Version
+	// Violations are the messages of the failed checks, grouped by actor
+	// type or under CrossActor.
+	Violations []ActorViolations
+}
+
+// ActorViolations lists the failed checks of the actors of one type.
+type ActorViolations struct {
+	Actor    string
+	Messages []string
+}
+
+// Ok tells whether no invariant is violated.
+func (r *Report) Ok() bool {
+	return len(r.Violations) == 0
+}
+
+// Count returns the number of violations.
+func (r *Report) Count() int {
+	n := 0
+	for _, v := range r.Violations {
+		n += len(v.Messages)
+	}
+	return n
+}
+
+// Check runs the specs-actors invariant checks of the actor version of `nv`
+// on the state `root`, computed at `height`. The checks cover each builtin
+// actor, the miners against their power claims, the market deals and escrow
+// against the miner sectors and the conservation of the total FIL. The
+// violations are only counted in the metrics, logging them is left to the
+// caller.
+func Check(ctx context.Context, store cbor.IpldStore, root cid.Cid, height abi.ChainEpoch, nv network.Version) (*Report, error) {
+	checksRun.Inc(ctx, 1)
+	sw := checkDuration.Start(ctx)
+	defer sw.Stop(ctx)
+
+	tree, err := vmstate.LoadState(ctx, store, root)
+	if err != nil {
+		return nil, xerrors.Wrap(err, "failed to load the state tree")
+	}
+	actorsRoot, err := tree.ActorsRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	version := specactors.VersionForNetwork(nv)
+	var messages []string
+	switch version {
+	case specactors.Version2:
+		actors, err := states2.LoadTree(adt2.WrapStore(ctx, store), actorsRoot)
+		if err != nil {
+			return nil, xerrors.Wrap(err, "failed to load the actors v2 tree")
+		}
+		var acc *builtin2.MessageAccumulator
+		if acc, err = states2.CheckStateInvariants(actors, crypto.TotalFilecoinInt, height); err != nil {
+			return nil, err
+		}
+		messages = acc.Messages()
+	case specactors.Version3:
+		actors, err := states3.LoadTree(adt3.WrapStore(ctx, store), actorsRoot)
+		if err != nil {
+			return nil, xerrors.Wrap(err, "failed to load the actors v3 tree")
+		}
+		var acc *builtin3.MessageAccumulator
+		if acc, err = states3.CheckStateInvariants(actors, crypto.TotalFilecoinInt, height); err != nil {
+			return nil, err
+		}
+		messages = acc.Messages()
+	default:
+		return nil, xerrors.Errorf("no invariant checks for actors v%d", version)
+	}
+
+	report := &Report{
+		Height:       height,
+		StateRoot:    root,
+		ActorVersion: version,
+	}
+	report.Violations, err = groupByActor(ctx, tree, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	n := report.Count()
+	violations.Set(ctx, int64(n))
+	if n > 0 {
+		checksFailed.Inc(ctx, 1)
+	}
+	return report, nil
+}
+
+// groupByActor groups the messages by the type of the actor whose address
+// prefixes them, the others are cross-actor checks.
+func groupByActor(ctx context.Context, tree *vmstate.State, messages []string) ([]ActorViolations, error) {
+	groups := map[string][]string{}
+	for _, msg := range messages {
+		group := CrossActor
+		if addr, err := address.NewFromString(firstWord(msg)); err == nil {
+			act, found, err := tree.GetActor(ctx, addr)
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				group = builtin.ActorNameByCode(act.Code)
+			}
+		}
+		groups[group] = append(groups[group], msg)
+	}
+
+	out := make([]ActorViolations, 0, len(groups))
+	for actor, msgs := range groups {
+		out = append(out, ActorViolations{Actor: actor, Messages: msgs})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Actor < out[j].Actor })
+	return out, nil
+}
+
+func firstWord(s string) string {
+	for i, r := range s {
+		if r == ' ' || r == ':' {
+			return s[:i]
+		}
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+package invariants
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/network"
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/fixtures/fortest"
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/specactors"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
+	vmstate "github.com/filecoin-project/venus/pkg/vm/state"
+	gengen "github.com/filecoin-project/venus/tools/gengen/util"
+)
+
+// genesisState returns the store and the state root of the genesis of the
+// test gengen config, which runs the v2 actors.
+func genesisState(t *testing.T) (cbor.IpldStore, cid.Cid) {
+	ctx := context.Background()
+	bs := blockstoreutil.NewTemporarySync()
+	info, err := gengen.GenGen(ctx, &fortest.TestGenGenConfig, bs)
+	require.NoError(t, err)
+	store := cbor.NewCborStore(bs)
+	var gen block.Block
+	require.NoError(t, store.Get(ctx, info.GenesisCid, &gen))
+	return store, gen.ParentStateRoot
+}
+
+func TestFirstWord(t *testing.T) {
+	tf.UnitTest(t)
+
+	assert.Equal(t, "t04", firstWord("t04: claim mismatch"))
+	assert.Equal(t, "t04", firstWord("t04 miner power"))
+	assert.Equal(t, "total", firstWord("total token balance is 1"))
+	assert.Equal(t, "word", firstWord("word"))
+	assert.Equal(t, "", firstWord(""))
+}
+
+func TestGroupByActor(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	store, root := genesisState(t)
+	tree, err := vmstate.LoadState(ctx, store, root)
+	require.NoError(t, err)
+
+	power := builtin2.StoragePowerActorAddr.String()
+	market := builtin2.StorageMarketActorAddr.String()
+	missing, err := address.NewIDAddress(999999)
+	require.NoError(t, err)
+
+	groups, err := groupByActor(ctx, tree, []string{
+		power + " power: claim mismatch",
+		"total token balance is 1, expected 2",
+		market + ": escrow below locked",
+		missing.String() + " miner has no claim",
+		power + " power: total mismatch",
+	})
+	require.NoError(t, err)
+
+	// sorted by actor, in the order of the messages within a group
+	assert.Equal(t, []ActorViolations{
+		{Actor: CrossActor, Messages: []string{"total token balance is 1, expected 2", missing.String() + " miner has no claim"}},
+		{Actor: builtin2.ActorNameByCode(builtin2.StorageMarketActorCodeID), Messages: []string{market + ": escrow below locked"}},
+		{Actor: builtin2.ActorNameByCode(builtin2.StoragePowerActorCodeID), Messages: []string{power + " power: claim mismatch", power + " power: total mismatch"}},
+	}, groups)
+
+	groups, err = groupByActor(ctx, tree, nil)
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+}
+
+func TestCheck(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	store, root := genesisState(t)
+
+	t.Run("the actors v2 state is checked", func(t *testing.T) {
+		report, err := Check(ctx, store, root, 0, network.Version8)
+		require.NoError(t, err)
+		assert.Equal(t, root, report.StateRoot)
+		assert.Equal(t, specactors.Version2, report.ActorVersion)
+		for _, v := range report.Violations {
+			assert.NotEmpty(t, v.Messages)
+		}
+	})
+
+	t.Run("minted FIL is reported as a cross-actor violation", func(t *testing.T) {
+		tree, err := vmstate.LoadState(ctx, store, root)
+		require.NoError(t, err)
+		act, found, err := tree.GetActor(ctx, builtin2.BurntFundsActorAddr)
+		require.NoError(t, err)
+		require.True(t, found)
+		act.Balance = big.Add(act.Balance, big.NewInt(1))
+		require.NoError(t, tree.SetActor(ctx, builtin2.BurntFundsActorAddr, act))
+		minted, err := tree.Flush(ctx)
+		require.NoError(t, err)
+
+		report, err := Check(ctx, store, minted, 0, network.Version8)
+		require.NoError(t, err)
+		assert.False(t, report.Ok())
+		var cross []string
+		for _, v := range report.Violations {
+			if v.Actor == CrossActor {
+				cross = v.Messages
+			}
+		}
+		var balance bool
+		for _, msg := range cross {
+			balance = balance || strings.HasPrefix(msg, "total token balance")
+		}
+		assert.True(t, balance, "violations: %v", report.Violations)
+	})
+
+	t.Run("actor versions without checks are refused", func(t *testing.T) {
+		_, err := Check(ctx, store, root, 0, network.Version0)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no invariant checks for actors v0")
+	})
+}
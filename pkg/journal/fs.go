@@ -0,0 +1,305 @@
+package journal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/venus/pkg/constants"
+)
+
+const RFC3339nocolon = "2006-01-02T150405Z0700"
+
+const (
+	// DefaultSizeLimit is the size at which a journal file is rolled.
+	DefaultSizeLimit = 256 << 20
+
+	// DefaultMaxFiles is the number of journal files kept, the oldest files
+	// are deleted when the journal rolls.
+	DefaultMaxFiles = 8
+
+	// subscriberBuffer is the number of events buffered for a subscriber,
+	// events are dropped for subscribers that fall further behind.
+	subscriberBuffer = 256
+)
+
+// fsJournal is a basic journal backed by files on a filesystem.
+type fsJournal struct {
+	EventTypeRegistry
+
+	dir       string
+	sizeLimit int64
+	maxFiles  int
+
+	fi    *os.File
+	fSize int64
+
+	incoming chan *Event
+
+	subsLk sync.Mutex
+	subs   map[*subscriber]struct{}
+
+	closing chan struct{}
+	closed  chan struct{}
+}
+
+type subscriber struct {
+	filter EventFilter
+	out    chan *Event
+}
+
+var _ Reader = (*fsJournal)(nil)
+
+// OpenFSJournal constructs a rolling filesystem journal in `dir`, with a
+// per-file size limit of DefaultSizeLimit and DefaultMaxFiles files kept.
+func OpenFSJournal(dir string, disabled DisabledEvents) (Journal, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to mk directory %s for file journal: %w", dir, err)
+	}
+
+	f := &fsJournal{
+		EventTypeRegistry: NewEventTypeRegistry(disabled),
+		dir:               dir,
+		sizeLimit:         DefaultSizeLimit,
+		maxFiles:          DefaultMaxFiles,
+		incoming:          make(chan *Event, 32),
+		subs:              make(map[*subscriber]struct{}),
+		closing:           make(chan struct{}),
+		closed:            make(chan struct{}),
+	}
+
+	if err := f.rollJournalFile(); err != nil {
+		return nil, err
+	}
+
+	go f.runLoop()
+
+	return f, nil
+}
+
+func (f *fsJournal) RecordEvent(evtType EventType, supplier func() interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Warnf("recovered from panic while recording journal event; type=%s, err=%v", evtType, r)
+		}
+	}()
+
+	if !evtType.Enabled() {
+		return
+	}
+
+	je := &Event{
+		EventType: evtType,
+		Timestamp: constants.Clock.Now(),
+		Data:      supplier(),
+	}
+	select {
+	case f.incoming <- je:
+	case <-f.closing:
+		log.Warnw("journal closed but tried to log event", "event", je)
+	}
+}
+
+func (f *fsJournal) Close() error {
+	close(f.closing)
+	<-f.closed
+	return nil
+}
+
+// Query reads the events matching `filter` from the journal files.
+func (f *fsJournal) Query(ctx context.Context, filter EventFilter) ([]*Event, error) {
+	files, err := f.journalFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*Event
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		evts, err := readJournalFile(file, filter)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, evts...)
+		if filter.Limit > 0 && len(out) > filter.Limit {
+			out = out[len(out)-filter.Limit:]
+		}
+	}
+	return out, nil
+}
+
+// Subscribe returns the events matching `filter` recorded from now on.
+func (f *fsJournal) Subscribe(ctx context.Context, filter EventFilter) (<-chan *Event, error) {
+	sub := &subscriber{
+		filter: filter,
+		out:    make(chan *Event, subscriberBuffer),
+	}
+	f.subsLk.Lock()
+	f.subs[sub] = struct{}{}
+	f.subsLk.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-f.closing:
+		}
+		f.subsLk.Lock()
+		delete(f.subs, sub)
+		close(sub.out)
+		f.subsLk.Unlock()
+	}()
+	return sub.out, nil
+}
+
+func (f *fsJournal) putEvent(evt *Event) error {
+	b, err := json.Marshal(evt)
+	if err != nil {
+		return err
+	}
+	n, err := f.fi.Write(append(b, '\n'))
+	if err != nil {
+		return err
+	}
+
+	f.fSize += int64(n)
+
+	if f.fSize >= f.sizeLimit {
+		_ = f.rollJournalFile()
+	}
+
+	return nil
+}
+
+// publish hands `evt` to the matching subscribers without blocking the
+// journal on slow readers.
+func (f *fsJournal) publish(evt *Event) {
+	f.subsLk.Lock()
+	defer f.subsLk.Unlock()
+	for sub := range f.subs {
+		if !sub.filter.Match(evt) {
+			continue
+		}
+		select {
+		case sub.out <- evt:
+		default:
+			log.Warnw("journal subscriber is too slow, dropping event", "event", evt.EventType)
+		}
+	}
+}
+
+func (f *fsJournal) rollJournalFile() error {
+	if f.fi != nil {
+		_ = f.fi.Close()
+	}
+
+	// the files rolled within the same second are told apart by a sequence
+	// number, an existing file is never truncated
+	now := constants.Clock.Now().Format(RFC3339nocolon)
+	var nfi *os.File
+	for seq := 0; nfi == nil; seq++ {
+		name := filepath.Join(f.dir, fmt.Sprintf("venus-journal-%s-%04d.ndjson", now, seq))
+		fi, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return xerrors.Errorf("failed to open journal file: %w", err)
+		}
+		nfi = fi
+	}
+
+	f.fi = nfi
+	f.fSize = 0
+
+	files, err := f.journalFiles()
+	if err != nil {
+		return err
+	}
+	for len(files) > f.maxFiles {
+		if err := os.Remove(files[0]); err != nil {
+			log.Warnf("failed to remove old journal file %s: %s", files[0], err)
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// journalFiles returns the journal files from the oldest to the newest.
+func (f *fsJournal) journalFiles() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(f.dir, "*.ndjson"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool {
+		fi, erri := os.Stat(files[i])
+		fj, errj := os.Stat(files[j])
+		if erri != nil || errj != nil {
+			return files[i] < files[j]
+		}
+		if fi.ModTime().Equal(fj.ModTime()) {
+			return files[i] < files[j]
+		}
+		return fi.ModTime().Before(fj.ModTime())
+	})
+	return files, nil
+}
+
+func readJournalFile(path string, filter EventFilter) ([]*Event, error) {
+	fi, err := os.Open(path)
+	if os.IsNotExist(err) {
+		// rolled away in the meantime
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer fi.Close() // nolint: errcheck
+
+	var out []*Event
+	scanner := bufio.NewScanner(fi)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		var evt Event
+		if err := json.Unmarshal(scanner.Bytes(), &evt); err != nil {
+			// the last line may be partially written
+			continue
+		}
+		if !filter.Match(&evt) {
+			continue
+		}
+		out = append(out, &evt)
+		if filter.Limit > 0 && len(out) > filter.Limit {
+			out = out[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, xerrors.Errorf("failed to read journal file %s: %w", path, err)
+	}
+	return out, nil
+}
+
+func (f *fsJournal) runLoop() {
+	defer close(f.closed)
+
+	for {
+		select {
+		case je := <-f.incoming:
+			if err := f.putEvent(je); err != nil {
+				log.Errorw("failed to write out journal event", "event", je, "err", err)
+			}
+			f.publish(je)
+		case <-f.closing:
+			_ = f.fi.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package journal
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/raulk/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/constants"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+func TestFSJournal(t *testing.T) {
+	tf.UnitTest(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	j, err := OpenFSJournal(dir, nil)
+	require.NoError(t, err)
+	reader := j.(Reader)
+
+	tail, err := reader.Subscribe(ctx, EventFilter{System: "chain"})
+	require.NoError(t, err)
+
+	headChange := j.RegisterEventType("chain", "head_change")
+	peerConnected := j.RegisterEventType("net", "peer_connected")
+	for i := 0; i < 3; i++ {
+		height := i
+		j.RecordEvent(headChange, func() interface{} { return map[string]int{"Height": height} })
+		j.RecordEvent(peerConnected, func() interface{} { return "peer" })
+	}
+
+	for i := 0; i < 3; i++ {
+		select {
+		case evt := <-tail:
+			assert.Equal(t, "head_change", evt.Event)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for tailed event")
+		}
+	}
+	require.NoError(t, j.Close())
+
+	evts, err := reader.Query(ctx, EventFilter{})
+	require.NoError(t, err)
+	assert.Len(t, evts, 6)
+
+	evts, err = reader.Query(ctx, EventFilter{System: "chain", Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, evts, 2)
+	assert.Equal(t, "head_change", evts[0].Event)
+	assert.Equal(t, map[string]interface{}{"Height": float64(2)}, evts[1].Data)
+
+	evts, err = reader.Query(ctx, EventFilter{Since: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Empty(t, evts)
+}
+
+func TestFSJournalRollsWithinASecond(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	mock := clock.NewMock()
+	constants.Clock = mock
+	defer func() { constants.Clock = clock.New() }()
+
+	dir := t.TempDir()
+	// every event fills a file
+	f := &fsJournal{
+		EventTypeRegistry: NewEventTypeRegistry(nil),
+		dir:               dir,
+		sizeLimit:         1,
+		maxFiles:          DefaultMaxFiles,
+	}
+	require.NoError(t, f.rollJournalFile())
+	headChange := f.RegisterEventType("chain", "head_change")
+	for i := 0; i < 3; i++ {
+		require.NoError(t, f.putEvent(&Event{EventType: headChange, Timestamp: mock.Now(), Data: i}))
+	}
+	require.NoError(t, f.fi.Close())
+
+	files, err := f.journalFiles()
+	require.NoError(t, err)
+	assert.Len(t, files, 4)
+
+	evts, err := f.Query(ctx, EventFilter{})
+	require.NoError(t, err)
+	require.Len(t, evts, 3)
+	for i, evt := range evts {
+		assert.Equal(t, float64(i), evt.Data)
+	}
+
+	// a journal reopened within the same second keeps the files
+	j, err := OpenFSJournal(dir, nil)
+	require.NoError(t, err)
+	require.NoError(t, j.Close())
+	evts, err = j.(Reader).Query(ctx, EventFilter{})
+	require.NoError(t, err)
+	assert.Len(t, evts, 3)
+}
@@ -1,6 +1,7 @@
 package journal
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -12,11 +13,9 @@ var log = logging.Logger("journal")
 
 var (
 	// DefaultDisabledEvents lists the journal events disabled by
-	// default, usually because they are considered noisy.
-	DefaultDisabledEvents = DisabledEvents{
-		EventType{System: "mpool", Event: "add"},
-		EventType{System: "mpool", Event: "remove"},
-	}
+	// default, usually because they are considered noisy. Noisy events can
+	// be disabled through VENUS_JOURNAL_DISABLED_EVENTS.
+	DefaultDisabledEvents = DisabledEvents{}
 )
 
 // DisabledEvents is the set of event types whose journaling is suppressed.
@@ -100,3 +99,40 @@ type Event struct {
 	Timestamp time.Time
 	Data      interface{}
 }
+
+// EventFilter selects journal events, empty fields match every event.
+type EventFilter struct {
+	System string
+	Event  string
+	Since  time.Time
+	Until  time.Time
+	// Limit keeps the latest Limit events of a query when positive.
+	Limit int
+}
+
+// Match returns whether `evt` passes the filter.
+func (f EventFilter) Match(evt *Event) bool {
+	if f.System != "" && f.System != evt.System {
+		return false
+	}
+	if f.Event != "" && f.Event != evt.Event {
+		return false
+	}
+	if !f.Since.IsZero() && evt.Timestamp.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && evt.Timestamp.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Reader reads back the events recorded by a journal.
+type Reader interface {
+	// Query returns the recorded events matching `filter`, oldest first.
+	Query(ctx context.Context, filter EventFilter) ([]*Event, error)
+
+	// Subscribe returns the events matching `filter` as they are recorded,
+	// until `ctx` is done.
+	Subscribe(ctx context.Context, filter EventFilter) (<-chan *Event, error)
+}
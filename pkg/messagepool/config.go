@@ -63,6 +63,12 @@ func saveConfig(cfg *MpoolConfig, ds repo.Datastore) error {
 	return ds.Put(ConfigKey, cfgBytes)
 }
 
+// SizeLimits returns the limits of a pool pruned once it holds more than
+// `maxSize` messages, down to the share of `maxSize` the default limits keep.
+func SizeLimits(maxSize int) (high, low int) {
+	return maxSize, maxSize * MemPoolSizeLimitLoDefault / MemPoolSizeLimitHiDefault
+}
+
 func (mp *MessagePool) GetConfig() *MpoolConfig {
 	mp.cfgLk.Lock()
 	defer mp.cfgLk.Unlock()
@@ -74,6 +80,10 @@ func validateConfg(cfg *MpoolConfig) error {
 		return fmt.Errorf("'ReplaceByFeeRatio' is less than required %f < %f",
 			cfg.ReplaceByFeeRatio, ReplaceByFeeRatioDefault)
 	}
+	if cfg.SizeLimitLow > cfg.SizeLimitHigh {
+		return fmt.Errorf("'SizeLimitLow' is greater than 'SizeLimitHigh' %d > %d",
+			cfg.SizeLimitLow, cfg.SizeLimitHigh)
+	}
 	if cfg.GasLimitOverestimation < 1 {
 		return fmt.Errorf("'GasLimitOverestimation' cannot be less than 1")
 	}
@@ -97,6 +107,20 @@ func (mp *MessagePool) SetConfig(cfg *MpoolConfig) error {
 	return nil
 }
 
+// SetMaxNonceGap sets the maximum gap between the nonce of a trusted message
+// and the next nonce of its sender.
+func (mp *MessagePool) SetMaxNonceGap(gap uint64) {
+	mp.cfgLk.Lock()
+	defer mp.cfgLk.Unlock()
+	mp.maxNonceGap = gap
+}
+
+func (mp *MessagePool) getMaxNonceGap() uint64 {
+	mp.cfgLk.Lock()
+	defer mp.cfgLk.Unlock()
+	return mp.maxNonceGap
+}
+
 func DefaultConfig() *MpoolConfig {
 	return &MpoolConfig{
 		SizeLimitHigh:          MemPoolSizeLimitHiDefault,
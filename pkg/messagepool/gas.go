@@ -2,7 +2,6 @@ package messagepool
 
 import (
 	"context"
-	"math"
 	"math/rand"
 	"sort"
 
@@ -38,10 +37,7 @@ func (mp *MessagePool) GasEstimateFeeCap(
 	}
 
 	parentBaseFee := ts.Blocks()[0].ParentBaseFee
-	increaseFactor := math.Pow(1.+1./float64(constants.BaseFeeMaxChangeDenom), float64(maxqueueblks))
-
-	feeInFuture := big.Mul(parentBaseFee, big.NewInt(int64(increaseFactor*(1<<8))))
-	out := big.Div(feeInFuture, big.NewInt(1<<8))
+	out := baseFeeAfter(parentBaseFee, maxqueueblks)
 
 	if !msg.GasPremium.Nil() && big.Cmp(msg.GasPremium, big.NewInt(0)) != 0 {
 		out = big.Add(out, msg.GasPremium)
@@ -228,6 +224,9 @@ func (mp *MessagePool) GasEstimateGasLimit(ctx context.Context, msgIn *types.Uns
 }
 
 func (mp *MessagePool) GasEstimateMessageGas(ctx context.Context, msg *types.UnsignedMessage, spec *types.MessageSendSpec, _ block.TipSetKey) (*types.UnsignedMessage, error) {
+	sw := gasEstimateDuration.Start(ctx)
+	defer sw.Stop(ctx)
+
 	if msg.GasLimit == 0 {
 		gasLimit, err := mp.GasEstimateGasLimit(ctx, msg, block.TipSetKey{})
 		if err != nil {
@@ -0,0 +1,440 @@
+package messagepool
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	"golang.org/x/xerrors"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/constants"
+	"github.com/filecoin-project/venus/pkg/repo"
+	"github.com/filecoin-project/venus/pkg/types"
+)
+
+var (
+	// GasStatsPercentiles are the percentiles of the premiums in GasStats.
+	GasStatsPercentiles = []int{10, 25, 50, 75, 90}
+	// FeeHistoryEpochs is the number of past tipsets the fee recommendations
+	// are derived from.
+	FeeHistoryEpochs = 20
+	// GasStatsWindow is the number of tipsets below the head the gas stats are
+	// served and kept for.
+	GasStatsWindow = abi.ChainEpoch(2880)
+
+	GasStatsKey = datastore.NewKey("/mpool/gasstats")
+)
+
+// GasStats is the gas usage of the messages included by a tipset.
+type GasStats struct {
+	Height abi.ChainEpoch
+	TipSet block.TipSetKey
+	Blocks int
+	// BaseFee is the base fee paid by the messages of the tipset, the parent
+	// base fee of its blocks.
+	BaseFee  abi.TokenAmount
+	Messages int
+	// GasLimit is the sum of the gas limits of the messages, GasUsed the gas
+	// used in their receipts and BlockGasLimit the gas limit of the blocks.
+	GasLimit      int64
+	GasUsed       int64
+	BlockGasLimit int64
+	// Premiums are the premiums paid by the messages at GasStatsPercentiles,
+	// weighted by gas limit. A premium is capped by the fee cap of its message
+	// above the base fee.
+	Premiums []abi.TokenAmount
+}
+
+// FeeUrgency is how soon a message with a recommended fee should be included.
+type FeeUrgency string
+
+const (
+	FeeUrgencyNextBlock  FeeUrgency = "next-block"
+	FeeUrgencyTenBlocks  FeeUrgency = "ten-blocks"
+	FeeUrgencyEconomical FeeUrgency = "economical"
+)
+
+var feeUrgencies = map[FeeUrgency]struct {
+	// epochs is the number of tipsets the message can wait for.
+	epochs int
+	// percentile is the percentile of the premiums paid in the history.
+	percentile int
+	// economical caps the fee at the median base fee of the history, the
+	// message waits for the base fee to go back to it.
+	economical bool
+}{
+	FeeUrgencyNextBlock:  {epochs: 1, percentile: 90},
+	FeeUrgencyTenBlocks:  {epochs: 10, percentile: 50},
+	FeeUrgencyEconomical: {epochs: FeeHistoryEpochs, percentile: 25, economical: true},
+}
+
+// FeeRecommendation is the fee cap and premium to set on a message to be
+// included with an urgency.
+type FeeRecommendation struct {
+	Urgency FeeUrgency
+	// BaseFee is the base fee of the next tipset.
+	BaseFee    abi.TokenAmount
+	GasFeeCap  abi.TokenAmount
+	GasPremium abi.TokenAmount
+}
+
+// gasStatsIndex persists the gas stats of the tipsets by height. The stats of
+// a tipset are recorded when its child is applied, the child holds the root
+// of its receipts, and dropped once GasStatsWindow below the head.
+type gasStatsIndex struct {
+	lk  sync.Mutex
+	ds  datastore.Datastore
+	api Provider
+	// pruned is the height the stats below are dropped, -1 until the index
+	// is first pruned.
+	pruned abi.ChainEpoch
+}
+
+func newGasStatsIndex(api Provider, ds repo.Datastore) *gasStatsIndex {
+	return &gasStatsIndex{api: api, ds: namespace.Wrap(ds, GasStatsKey), pruned: -1}
+}
+
+func gasStatsKey(h abi.ChainEpoch) datastore.Key {
+	return datastore.NewKey(strconv.FormatInt(int64(h), 10))
+}
+
+func (idx *gasStatsIndex) headChange(ctx context.Context, revert, apply []*block.TipSet) error {
+	idx.lk.Lock()
+	for _, ts := range revert {
+		if err := idx.ds.Delete(gasStatsKey(ts.EnsureHeight())); err != nil && err != datastore.ErrNotFound {
+			idx.lk.Unlock()
+			return err
+		}
+	}
+	idx.lk.Unlock()
+
+	for _, ts := range apply {
+		if ts.EnsureHeight() == 0 {
+			continue
+		}
+		pts, err := idx.api.LoadTipSet(ts.EnsureParents())
+		if err != nil {
+			return xerrors.Errorf("loading parent of %s: %w", ts.Key(), err)
+		}
+		if _, err := idx.stats(ctx, pts, ts); err != nil {
+			return err
+		}
+	}
+	if len(apply) > 0 {
+		return idx.prune(apply[len(apply)-1].EnsureHeight())
+	}
+	return nil
+}
+
+// prune drops the stats of the tipsets more than GasStatsWindow below `head`.
+// The first prune scans the index for the stats left by a previous run.
+func (idx *gasStatsIndex) prune(head abi.ChainEpoch) error {
+	idx.lk.Lock()
+	defer idx.lk.Unlock()
+
+	cutoff := head - GasStatsWindow
+	if cutoff <= idx.pruned {
+		return nil
+	}
+	var heights []abi.ChainEpoch
+	if idx.pruned < 0 {
+		res, err := idx.ds.Query(query.Query{KeysOnly: true})
+		if err != nil {
+			return err
+		}
+		entries, err := res.Rest()
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			h, err := strconv.ParseInt(datastore.NewKey(e.Key).BaseNamespace(), 10, 64)
+			if err != nil {
+				return xerrors.Errorf("invalid gas stats key %s: %w", e.Key, err)
+			}
+			if abi.ChainEpoch(h) < cutoff {
+				heights = append(heights, abi.ChainEpoch(h))
+			}
+		}
+	} else {
+		for h := idx.pruned; h < cutoff; h++ {
+			heights = append(heights, h)
+		}
+	}
+	for _, h := range heights {
+		if err := idx.ds.Delete(gasStatsKey(h)); err != nil && err != datastore.ErrNotFound {
+			return err
+		}
+	}
+	idx.pruned = cutoff
+	return nil
+}
+
+// stats returns the stats of `ts` from the index, computing and recording
+// them when they are missing or recorded for another tipset at its height.
+func (idx *gasStatsIndex) stats(ctx context.Context, ts, child *block.TipSet) (*GasStats, error) {
+	idx.lk.Lock()
+	defer idx.lk.Unlock()
+
+	key := gasStatsKey(ts.EnsureHeight())
+	data, err := idx.ds.Get(key)
+	switch err {
+	case nil:
+		st := new(GasStats)
+		if err := json.Unmarshal(data, st); err != nil {
+			return nil, xerrors.Errorf("decoding gas stats at %d: %w", ts.EnsureHeight(), err)
+		}
+		if st.TipSet.Equals(ts.Key()) {
+			return st, nil
+		}
+	case datastore.ErrNotFound:
+	default:
+		return nil, err
+	}
+
+	st, err := idx.computeStats(ctx, ts, child)
+	if err != nil {
+		return nil, xerrors.Errorf("computing gas stats of %s: %w", ts.Key(), err)
+	}
+	if data, err = json.Marshal(st); err != nil {
+		return nil, err
+	}
+	if err := idx.ds.Put(key, data); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (idx *gasStatsIndex) computeStats(ctx context.Context, ts, child *block.TipSet) (*GasStats, error) {
+	msgs, err := idx.api.MessagesForTipset(ts)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := idx.api.LoadReceipts(ctx, child.At(0).ParentMessageReceipts)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &GasStats{
+		Height:        ts.EnsureHeight(),
+		TipSet:        ts.Key(),
+		Blocks:        ts.Len(),
+		BaseFee:       ts.At(0).ParentBaseFee,
+		BlockGasLimit: constants.BlockGasLimit * int64(ts.Len()),
+	}
+	// A message included by several blocks is only applied once.
+	seen := make(map[cid.Cid]struct{})
+	var prices []gasMeta
+	for _, m := range msgs {
+		c, err := m.Cid()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[c]; ok {
+			continue
+		}
+		seen[c] = struct{}{}
+
+		msg := m.VMMessage()
+		st.Messages++
+		st.GasLimit += msg.GasLimit
+		prices = append(prices, gasMeta{price: effectivePremium(msg, st.BaseFee), limit: msg.GasLimit})
+	}
+	for _, r := range receipts {
+		st.GasUsed += r.GasUsed
+	}
+	st.Premiums = premiumPercentiles(prices, GasStatsPercentiles)
+	return st, nil
+}
+
+// effectivePremium is the premium `msg` pays at `baseFee`.
+func effectivePremium(msg *types.UnsignedMessage, baseFee abi.TokenAmount) abi.TokenAmount {
+	room := big.Sub(msg.GasFeeCap, baseFee)
+	if room.LessThan(msg.GasPremium) {
+		return big.Max(room, big.Zero())
+	}
+	return msg.GasPremium
+}
+
+// premiumPercentiles returns the premiums of `prices` at `percentiles`,
+// weighted by gas limit, zero when there are none.
+func premiumPercentiles(prices []gasMeta, percentiles []int) []abi.TokenAmount {
+	out := make([]abi.TokenAmount, len(percentiles))
+	var total int64
+	for _, p := range prices {
+		total += p.limit
+	}
+	if total == 0 {
+		for i := range out {
+			out[i] = big.Zero()
+		}
+		return out
+	}
+
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].price.LessThan(prices[j].price)
+	})
+	for i, pct := range percentiles {
+		at := total * int64(pct) / 100
+		var acc int64
+		for _, p := range prices {
+			acc += p.limit
+			out[i] = p.price
+			if acc >= at {
+				break
+			}
+		}
+	}
+	return out
+}
+
+// ChainGasStats returns the gas stats of the tipsets with a height in
+// [from, to] on the chain of the pool, in ascending height. The current
+// tipset of the pool has no stats yet, its receipts are in its child, and
+// `from` can't be more than GasStatsWindow below it.
+func (mp *MessagePool) ChainGasStats(ctx context.Context, from, to abi.ChainEpoch) ([]*GasStats, error) {
+	mp.curTsLk.Lock()
+	head := mp.curTs
+	mp.curTsLk.Unlock()
+	return mp.chainGasStats(ctx, head, from, to)
+}
+
+func (mp *MessagePool) chainGasStats(ctx context.Context, head *block.TipSet, from, to abi.ChainEpoch) ([]*GasStats, error) {
+	if from < 0 || from > to {
+		return nil, xerrors.Errorf("invalid height range [%d, %d]", from, to)
+	}
+	if oldest := head.EnsureHeight() - GasStatsWindow; from < oldest {
+		return nil, xerrors.Errorf("gas stats are kept for the last %d epochs, from %d", GasStatsWindow, oldest)
+	}
+
+	child := head
+	var out []*GasStats
+	for child.EnsureHeight() > from {
+		ts, err := mp.api.LoadTipSet(child.EnsureParents())
+		if err != nil {
+			return nil, xerrors.Errorf("loading parent of %s: %w", child.Key(), err)
+		}
+		if ts.EnsureHeight() < from {
+			break
+		}
+		if ts.EnsureHeight() <= to {
+			st, err := mp.gasStats.stats(ctx, ts, child)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, st)
+		}
+		child = ts
+	}
+
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, nil
+}
+
+// GasFeeRecommendation returns the fee cap and premium for a message to be
+// included with `urgency`, from the fees paid in the last FeeHistoryEpochs
+// tipsets and the premiums of the pending messages.
+//
+// The premium is the highest of the median over the history of the premiums
+// paid at the percentile of the urgency, of the premium of the pending
+// messages that fill the blocks the message can wait for, and of
+// MinGasPremium. The fee cap covers the base fee rising in every tipset the
+// message can wait for, or for the economical urgency the median base fee of
+// the history when it is lower.
+func (mp *MessagePool) GasFeeRecommendation(ctx context.Context, urgency FeeUrgency) (*FeeRecommendation, error) {
+	params, ok := feeUrgencies[urgency]
+	if !ok {
+		return nil, xerrors.Errorf("unknown fee urgency %q", urgency)
+	}
+	pctIdx := -1
+	for i, pct := range GasStatsPercentiles {
+		if pct == params.percentile {
+			pctIdx = i
+		}
+	}
+	if pctIdx < 0 {
+		return nil, xerrors.Errorf("percentile %d is not in the gas stats", params.percentile)
+	}
+
+	pending, ts := mp.Pending()
+	baseFee, err := mp.api.ChainComputeBaseFee(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+	from := ts.EnsureHeight() - abi.ChainEpoch(FeeHistoryEpochs)
+	if from < 0 {
+		from = 0
+	}
+	history, err := mp.chainGasStats(ctx, ts, from, ts.EnsureHeight())
+	if err != nil {
+		return nil, err
+	}
+
+	var premiums, baseFees []abi.TokenAmount
+	for _, st := range history {
+		baseFees = append(baseFees, st.BaseFee)
+		if st.Messages > 0 && pctIdx < len(st.Premiums) {
+			premiums = append(premiums, st.Premiums[pctIdx])
+		}
+	}
+	premium := big.Max(medianAmount(premiums), big.NewInt(MinGasPremium))
+
+	// The premium of the last pending message that fits in the blocks of the
+	// tipsets the message can wait for, when the pending ones don't all fit.
+	prices := make([]gasMeta, 0, len(pending))
+	for _, m := range pending {
+		prices = append(prices, gasMeta{price: effectivePremium(&m.Message, baseFee), limit: m.Message.GasLimit})
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		return prices[i].price.GreaterThan(prices[j].price)
+	})
+	room := constants.BlockGasTarget * int64(constants.ExpectedLeadersPerEpoch) * int64(params.epochs)
+	for _, p := range prices {
+		if room -= p.limit; room < 0 {
+			premium = big.Max(premium, big.Add(p.price, big.NewInt(1)))
+			break
+		}
+	}
+
+	feeCap := baseFeeAfter(baseFee, int64(params.epochs))
+	if params.economical && len(baseFees) > 0 {
+		median := big.Max(medianAmount(baseFees), big.NewInt(constants.MinimumBaseFee))
+		feeCap = big.Min(feeCap, median)
+	}
+
+	return &FeeRecommendation{
+		Urgency:    urgency,
+		BaseFee:    baseFee,
+		GasFeeCap:  big.Add(feeCap, premium),
+		GasPremium: premium,
+	}, nil
+}
+
+// baseFeeAfter is `baseFee` after rising at the highest rate for `epochs`.
+func baseFeeAfter(baseFee abi.TokenAmount, epochs int64) abi.TokenAmount {
+	increaseFactor := math.Pow(1.+1./float64(constants.BaseFeeMaxChangeDenom), float64(epochs))
+	feeInFuture := big.Mul(baseFee, big.NewInt(int64(increaseFactor*(1<<8))))
+	return big.Div(feeInFuture, big.NewInt(1<<8))
+}
+
+func medianAmount(amounts []abi.TokenAmount) abi.TokenAmount {
+	if len(amounts) == 0 {
+		return big.Zero()
+	}
+	sorted := append([]abi.TokenAmount(nil), amounts...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].LessThan(sorted[j])
+	})
+	return sorted[len(sorted)/2]
+}
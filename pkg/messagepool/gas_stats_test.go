@@ -0,0 +1,184 @@
+package messagepool
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	tbig "github.com/filecoin-project/go-state-types/big"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+
+	"github.com/filecoin-project/venus/pkg/config"
+	"github.com/filecoin-project/venus/pkg/crypto"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/venus/pkg/types"
+)
+
+func mkPremiumMessage(from, to address.Address, nonce uint64, premium int64) *types.SignedMessage {
+	return &types.SignedMessage{
+		Message: types.UnsignedMessage{
+			To:         to,
+			From:       from,
+			Value:      tbig.NewInt(1),
+			Nonce:      nonce,
+			GasLimit:   1000000,
+			GasFeeCap:  tbig.NewInt(1000000000),
+			GasPremium: tbig.NewInt(premium),
+		},
+		Signature: crypto.Signature{Type: crypto.SigTypeSecp256k1, Data: []byte("not checked")},
+	}
+}
+
+func TestGasStatsAndFeeRecommendation(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	tma := newTestMpoolAPI()
+	ds := datastore.NewMapDatastore()
+	mp, err := New(tma, ds, config.DefaultForkUpgradeParam, "mptest", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Five messages of the same gas limit paying 100e3 to 500e3.
+	sender, target := mkAddress(1000), mkAddress(1001)
+	var msgs []*types.SignedMessage
+	for i := 0; i < 5; i++ {
+		msgs = append(msgs, mkPremiumMessage(sender, target, uint64(i), int64(i+1)*MinGasPremium))
+	}
+	a := tma.nextBlock()
+	tma.setBlockMessages(a, msgs...)
+	tma.applyBlock(t, a)
+
+	// The receipts of a are in its child.
+	b := tma.nextBlock()
+	tma.receipts[b.ParentMessageReceipts] = []types.MessageReceipt{{GasUsed: 10}, {GasUsed: 20}, {GasUsed: 30}, {GasUsed: 40}, {GasUsed: 50}}
+	tma.applyBlock(t, b)
+
+	stats, err := mp.ChainGasStats(ctx, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected the stats of the genesis and a, got %d", len(stats))
+	}
+	if stats[0].Height != 0 || stats[0].Messages != 0 {
+		t.Fatalf("unexpected genesis stats %+v", stats[0])
+	}
+	st := stats[1]
+	if st.Height != 1 || !st.TipSet.Equals(tma.tipsets[1].Key()) {
+		t.Fatalf("unexpected tipset %s at %d", st.TipSet, st.Height)
+	}
+	if st.Messages != 5 || st.GasLimit != 5000000 || st.GasUsed != 150 {
+		t.Fatalf("unexpected gas usage %+v", st)
+	}
+	expected := []int64{100e3, 200e3, 300e3, 400e3, 500e3}
+	for i, premium := range st.Premiums {
+		if !premium.Equals(tbig.NewInt(expected[i])) {
+			t.Fatalf("expected p%d to be %d, got %s", GasStatsPercentiles[i], expected[i], premium)
+		}
+	}
+
+	// The stats are persisted in the index.
+	if _, err := ds.Get(GasStatsKey.ChildString("1")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		urgency FeeUrgency
+		feeCap  int64
+		premium int64
+	}{
+		// The base fee of 100 rises to 112 in one epoch, to 324 in ten.
+		{FeeUrgencyNextBlock, 112 + 500e3, 500e3},
+		{FeeUrgencyTenBlocks, 324 + 300e3, 300e3},
+		// Capped at the median base fee of the history.
+		{FeeUrgencyEconomical, 100 + 200e3, 200e3},
+	} {
+		rec, err := mp.GasFeeRecommendation(ctx, tc.urgency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !rec.GasPremium.Equals(tbig.NewInt(tc.premium)) {
+			t.Fatalf("%s: expected a premium of %d, got %s", tc.urgency, tc.premium, rec.GasPremium)
+		}
+		if !rec.GasFeeCap.Equals(tbig.NewInt(tc.feeCap)) {
+			t.Fatalf("%s: expected a fee cap of %d, got %s", tc.urgency, tc.feeCap, rec.GasFeeCap)
+		}
+	}
+
+	if _, err := mp.GasFeeRecommendation(ctx, FeeUrgency("soon")); err == nil {
+		t.Fatal("expected an unknown urgency to fail")
+	}
+	if _, err := mp.ChainGasStats(ctx, 3, abi.ChainEpoch(2)); err == nil {
+		t.Fatal("expected an invalid range to fail")
+	}
+}
+
+func TestGasStatsWindow(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	defer func(window abi.ChainEpoch) { GasStatsWindow = window }(GasStatsWindow)
+	GasStatsWindow = 3
+
+	tma := newTestMpoolAPI()
+	ds := datastore.NewMapDatastore()
+	mp, err := New(tma, ds, config.DefaultForkUpgradeParam, "mptest", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		tma.applyBlock(t, tma.nextBlock())
+	}
+
+	// The head is at 5, the stats below 2 are dropped and not served.
+	for h, kept := range []bool{false, false, true, true, true} {
+		_, err := ds.Get(GasStatsKey.ChildString(strconv.Itoa(h)))
+		if kept && err != nil {
+			t.Fatalf("expected the stats at %d to be kept: %s", h, err)
+		}
+		if !kept && err != datastore.ErrNotFound {
+			t.Fatalf("expected the stats at %d to be dropped, got %v", h, err)
+		}
+	}
+	if _, err := mp.ChainGasStats(ctx, 0, 5); err == nil {
+		t.Fatal("expected a range beyond the window to fail")
+	}
+	stats, err := mp.ChainGasStats(ctx, 2, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 3 || stats[0].Height != 2 || stats[2].Height != 4 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	t.Run("the stats left by a previous run are dropped", func(t *testing.T) {
+		ds := datastore.NewMapDatastore()
+		idx := newGasStatsIndex(tma, ds)
+		for h := 0; h < 10; h++ {
+			if err := ds.Put(GasStatsKey.ChildString(strconv.Itoa(h)), []byte("{}")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, head := range []abi.ChainEpoch{10, 12} {
+			if err := idx.prune(head); err != nil {
+				t.Fatal(err)
+			}
+			res, err := ds.Query(query.Query{Prefix: GasStatsKey.String(), KeysOnly: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries, err := res.Rest()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := int(10 - head + GasStatsWindow); len(entries) != want {
+				t.Fatalf("expected %d stats kept at %d, got %d", want, head, len(entries))
+			}
+		}
+	})
+}
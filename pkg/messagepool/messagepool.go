@@ -27,6 +27,7 @@ import (
 	logging "github.com/ipfs/go-log/v2"
 	pubsub "github.com/libp2p/go-libp2p-pubsub"
 	lps "github.com/whyrusleeping/pubsub"
+	"go.opencensus.io/tag"
 	"golang.org/x/xerrors"
 
 	"github.com/filecoin-project/venus/pkg/block"
@@ -34,7 +35,8 @@ import (
 	"github.com/filecoin-project/venus/pkg/constants"
 	vcrypto "github.com/filecoin-project/venus/pkg/crypto"
 	"github.com/filecoin-project/venus/pkg/crypto/sigs"
-	"github.com/filecoin-project/venus/pkg/messagepool/journal"
+	"github.com/filecoin-project/venus/pkg/journal"
+	"github.com/filecoin-project/venus/pkg/metrics"
 	"github.com/filecoin-project/venus/pkg/net/msgsub"
 	"github.com/filecoin-project/venus/pkg/repo"
 	"github.com/filecoin-project/venus/pkg/types"
@@ -58,6 +60,12 @@ type MpoolUpdate struct {
 
 var log = logging.Logger("messagepool")
 
+var (
+	mpoolSizeGauge      = metrics.NewInt64Gauge("mpool/size", "Number of pending messages in the message pool", metrics.Origin)
+	selectDuration      = metrics.NewTimerMs("mpool/select_duration", "Duration of message selection for a block in milliseconds")
+	gasEstimateDuration = metrics.NewTimerMs("mpool/gas_estimate_duration", "Duration of message gas estimation in milliseconds")
+)
+
 var futureDebug = false
 
 var rbfNumBig = big.NewInt(int64((ReplaceByFeeRatioDefault - 1) * RbfDenom))
@@ -159,8 +167,9 @@ type MessagePool struct {
 	curTsLk sync.Mutex // DO NOT LOCK INSIDE lk
 	curTs   *block.TipSet
 
-	cfgLk sync.Mutex
-	cfg   *MpoolConfig
+	cfgLk       sync.Mutex
+	cfg         *MpoolConfig
+	maxNonceGap uint64
 
 	api Provider
 
@@ -189,6 +198,8 @@ type MessagePool struct {
 
 	gasPriceSchedule *gas.PricesSchedule
 
+	gasStats *gasStatsIndex
+
 	gp        gasPredictor
 	ap        actorProvider
 	GetMaxFee DefaultMaxFeeFunc
@@ -249,7 +260,7 @@ func (ms *msgSet) add(m *types.SignedMessage, mp *MessagePool, strict, untrusted
 	nextNonce := ms.nextNonce
 	nonceGap := false
 
-	maxNonceGap := MaxNonceGap
+	maxNonceGap := mp.getMaxNonceGap()
 	maxActorPendingMessages := MaxActorPendingMessages
 	if untrusted {
 		maxNonceGap = 0
@@ -409,6 +420,7 @@ func New(api Provider, ds repo.Datastore, forkParams *config.ForkUpgradeConfig,
 		gp:            gp,
 		ap:            ap,
 		cfg:           cfg,
+		maxNonceGap:   MaxNonceGap,
 		evtTypes: [...]journal.EventType{
 			evtTypeMpoolAdd:    j.RegisterEventType("mpool", "add"),
 			evtTypeMpoolRemove: j.RegisterEventType("mpool", "remove"),
@@ -417,6 +429,8 @@ func New(api Provider, ds repo.Datastore, forkParams *config.ForkUpgradeConfig,
 		journal: j,
 
 		gasPriceSchedule: gas.NewPricesSchedule(forkParams),
+
+		gasStats: newGasStatsIndex(api, ds),
 	}
 
 	// enable initial prunes
@@ -427,8 +441,12 @@ func New(api Provider, ds repo.Datastore, forkParams *config.ForkUpgradeConfig,
 		err := mp.HeadChange(rev, app)
 		if err != nil {
 			log.Errorf("mpool head notif handler error: %+v", err)
+			return err
 		}
-		return err
+		if err := mp.gasStats.headChange(context.TODO(), rev, app); err != nil {
+			log.Warnf("failed to index gas stats: %s", err)
+		}
+		return nil
 	})
 
 	mp.curTsLk.Lock()
@@ -504,9 +522,10 @@ func (mp *MessagePool) PublishMsgForWallet(addr address.Address) error {
 	return nil
 }
 
+// Close stops the message pool, the journal is owned and closed by the node.
 func (mp *MessagePool) Close() error {
 	close(mp.closer)
-	return mp.journal.Close()
+	return nil
 }
 
 func (mp *MessagePool) Prune() {
@@ -891,6 +910,7 @@ func (mp *MessagePool) addLocked(m *types.SignedMessage, strict, untrusted bool)
 
 	if incr {
 		mp.currentSize++
+		mp.recordSize()
 		if mp.currentSize > mp.cfg.SizeLimitHigh {
 			// send signal to prune messages if it hasnt already been sent
 			select {
@@ -1032,6 +1052,7 @@ func (mp *MessagePool) remove(from address.Address, nonce uint64, applied bool)
 		})
 
 		mp.currentSize--
+		mp.recordSize()
 	}
 
 	// NB: This deletes any message with the given nonce. This makes sense
@@ -1043,6 +1064,21 @@ func (mp *MessagePool) remove(from address.Address, nonce uint64, applied bool)
 	}
 }
 
+// recordSize reports the number of pending messages sent from local and
+// remote addresses. Callers must hold mp.lk.
+func (mp *MessagePool) recordSize() {
+	local := 0
+	for a := range mp.localAddrs {
+		if mset, ok := mp.pending[a]; ok {
+			local += len(mset.msgs)
+		}
+	}
+
+	ctx := context.TODO()
+	mpoolSizeGauge.Set(metrics.WithTags(ctx, tag.Upsert(metrics.Origin, metrics.OriginLocal)), int64(local))
+	mpoolSizeGauge.Set(metrics.WithTags(ctx, tag.Upsert(metrics.Origin, metrics.OriginRemote)), int64(mp.currentSize-local))
+}
+
 func (mp *MessagePool) Pending() ([]*types.SignedMessage, *block.TipSet) {
 	mp.curTsLk.Lock()
 	defer mp.curTsLk.Unlock()
@@ -38,6 +38,7 @@ type testMpoolAPI struct {
 	bmsgs      map[cid.Cid][]*types.SignedMessage
 	statenonce map[address.Address]uint64
 	balance    map[address.Address]tbig.Int
+	receipts   map[cid.Cid][]types.MessageReceipt
 
 	tipsets []*block.TipSet
 
@@ -141,6 +142,7 @@ func newTestMpoolAPI() *testMpoolAPI {
 		bmsgs:      make(map[cid.Cid][]*types.SignedMessage),
 		statenonce: make(map[address.Address]uint64),
 		balance:    make(map[address.Address]tbig.Int),
+		receipts:   make(map[cid.Cid][]types.MessageReceipt),
 		baseFee:    tbig.NewInt(100),
 	}
 	genesis := mkBlock(nil, 1, 1)
@@ -287,6 +289,10 @@ func (tma *testMpoolAPI) MessagesForTipset(ts *block.TipSet) ([]types.ChainMsg,
 	return out, nil
 }
 
+func (tma *testMpoolAPI) LoadReceipts(ctx context.Context, c cid.Cid) ([]types.MessageReceipt, error) {
+	return tma.receipts[c], nil
+}
+
 func (tma *testMpoolAPI) LoadTipSet(tsk block.TipSetKey) (*block.TipSet, error) {
 	for _, ts := range tma.tipsets {
 		if tsk.Equals(ts.Key()) {
@@ -543,6 +549,30 @@ func TestPruningSimple(t *testing.T) {
 	}
 }
 
+func TestSizeLimits(t *testing.T) {
+	tf.UnitTest(t)
+
+	mp, err := New(newTestMpoolAPI(), datastore.NewMapDatastore(), config.DefaultForkUpgradeParam, "mptest", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if high, low := SizeLimits(MemPoolSizeLimitHiDefault); high != MemPoolSizeLimitHiDefault || low != MemPoolSizeLimitLoDefault {
+		t.Fatalf("expected the default limits, got %d and %d", high, low)
+	}
+	// sizes below the default low limit are accepted by the pool too
+	for _, size := range []int{1, 10000, 1 << 32} {
+		cfg := mp.GetConfig()
+		cfg.SizeLimitHigh, cfg.SizeLimitLow = SizeLimits(size)
+		if err := mp.SetConfig(cfg); err != nil {
+			t.Fatalf("size %d: %s", size, err)
+		}
+		if cfg.SizeLimitLow > size {
+			t.Fatalf("size %d: low limit %d is above it", size, cfg.SizeLimitLow)
+		}
+	}
+}
+
 func TestLoadLocal(t *testing.T) {
 	tf.UnitTest(t)
 
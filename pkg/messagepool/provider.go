@@ -33,6 +33,7 @@ type Provider interface {
 	StateAccountKey(context.Context, address.Address, *block.TipSet) (address.Address, error)
 	MessagesForBlock(block2 *block.Block) ([]*types.UnsignedMessage, []*types.SignedMessage, error)
 	MessagesForTipset(*block.TipSet) ([]types.ChainMsg, error)
+	LoadReceipts(context.Context, cid.Cid) ([]types.MessageReceipt, error)
 	LoadTipSet(tsk block.TipSetKey) (*block.TipSet, error)
 	ChainComputeBaseFee(ctx context.Context, ts *block.TipSet) (tbig.Int, error)
 }
@@ -115,6 +116,10 @@ func (mpp *mpoolProvider) MessagesForTipset(ts *block.TipSet) ([]types.ChainMsg,
 	return mpp.cms.MessagesForTipset(ts)
 }
 
+func (mpp *mpoolProvider) LoadReceipts(ctx context.Context, c cid.Cid) ([]types.MessageReceipt, error) {
+	return mpp.cms.LoadReceipts(ctx, c)
+}
+
 func (mpp *mpoolProvider) LoadTipSet(tsk block.TipSetKey) (*block.TipSet, error) {
 	return mpp.sm.GetTipSet(tsk)
 }
@@ -45,6 +45,9 @@ type msgChain struct {
 }
 
 func (mp *MessagePool) SelectMessages(ts *block.TipSet, tq float64) (msgs []*types.SignedMessage, err error) {
+	sw := selectDuration.Start(context.TODO())
+	defer sw.Stop(context.TODO())
+
 	mp.curTsLk.Lock()
 	defer mp.curTsLk.Unlock()
 
@@ -1,7 +1,9 @@
 package metrics
 
 import (
+	"context"
 	"net/http"
+	"sync"
 	"time"
 
 	"contrib.go.opencensus.io/exporter/jaeger"
@@ -15,8 +17,28 @@ import (
 	"github.com/filecoin-project/venus/pkg/config"
 )
 
-// RegisterPrometheusEndpoint registers and serves prometheus metrics
+var (
+	exportLk   sync.Mutex
+	promServer *http.Server
+	promExp    *prometheus.Exporter
+	jaegerExp  *jaeger.Exporter
+)
+
+// RegisterPrometheusEndpoint registers and serves prometheus metrics. It may be
+// called again with a changed config, the previous endpoint is shut down.
 func RegisterPrometheusEndpoint(cfg *config.MetricsConfig) error {
+	exportLk.Lock()
+	defer exportLk.Unlock()
+
+	if promServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := promServer.Shutdown(ctx); err != nil {
+			log.Warnf("failed to shut down /metrics endpoint: %s", err)
+		}
+		view.UnregisterExporter(promExp)
+		promServer, promExp = nil, nil
+	}
 	if !cfg.PrometheusEnabled {
 		return nil
 	}
@@ -51,20 +73,31 @@ func RegisterPrometheusEndpoint(cfg *config.MetricsConfig) error {
 	view.RegisterExporter(pe)
 	view.SetReportingPeriod(interval)
 
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", pe)
+	server := &http.Server{Addr: promAddr, Handler: mux}
 	go func() {
-		mux := http.NewServeMux()
-		mux.Handle("/metrics", pe)
-		if err := http.ListenAndServe(promAddr, mux); err != nil {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Errorf("failed to serve /metrics endpoint on %v", err)
 		}
 	}()
+	promServer, promExp = server, pe
 
 	return nil
 }
 
 // RegisterJaeger registers the jaeger endpoint with opencensus and names the
-// tracer `name`.
+// tracer `name`. It may be called again with a changed config, the previous
+// exporter is unregistered.
 func RegisterJaeger(name string, cfg *config.TraceConfig) error {
+	exportLk.Lock()
+	defer exportLk.Unlock()
+
+	if jaegerExp != nil {
+		trace.UnregisterExporter(jaegerExp)
+		jaegerExp.Flush()
+		jaegerExp = nil
+	}
 	if !cfg.JaegerTracingEnabled {
 		return nil
 	}
@@ -79,6 +112,7 @@ func RegisterJaeger(name string, cfg *config.TraceConfig) error {
 	}
 
 	trace.RegisterExporter(je)
+	jaegerExp = je
 	trace.ApplyConfig(trace.Config{DefaultSampler: trace.ProbabilitySampler(cfg.ProbabilitySampler)})
 
 	return nil
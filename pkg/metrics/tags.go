@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"context"
+
+	"go.opencensus.io/tag"
+)
+
+// Tag keys shared by the metrics of several packages.
+var (
+	// Actor is the name of the actor a message is sent to.
+	Actor = tag.MustNewKey("actor")
+	// Method is the method number a message invokes.
+	Method = tag.MustNewKey("method")
+	// Origin tells whether a message was pushed by this node (`local`) or
+	// received from the network (`remote`).
+	Origin = tag.MustNewKey("origin")
+)
+
+// Origin tag values.
+const (
+	OriginLocal  = "local"
+	OriginRemote = "remote"
+)
+
+// WithTags returns `ctx` with the given tags set, metrics recorded with the
+// returned context are aggregated by these tags.
+func WithTags(ctx context.Context, mutators ...tag.Mutator) context.Context {
+	tagged, err := tag.New(ctx, mutators...)
+	if err != nil {
+		// only happens with invalid keys or values, which is a developer error.
+		log.Warnf("failed to tag metrics context: %s", err)
+		return ctx
+	}
+	return tagged
+}
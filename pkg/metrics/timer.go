@@ -72,3 +72,10 @@ func (sw *Stopwatch) Stop(ctx context.Context) time.Duration {
 	stats.Record(ctx, sw.recorder(float64(duration)/1e6))
 	return duration
 }
+
+// Record records a duration that was measured elsewhere, e.g. the delay
+// between a timestamp carried by a block and its arrival, without rounding
+// it to milliseconds.
+func (t *Float64Timer) Record(ctx context.Context, d time.Duration) {
+	stats.Record(ctx, t.measureMs.M(float64(d)/1e6))
+}
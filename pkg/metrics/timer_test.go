@@ -3,9 +3,11 @@ package metrics
 import (
 	"context"
 	"testing"
+	"time"
 
 	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opencensus.io/stats/view"
 )
 
@@ -69,3 +71,21 @@ func TestMultipleTimers(t *testing.T) {
 	assert.NotEqual(t, 0, sw2.start)
 
 }
+
+func TestTimerRecord(t *testing.T) {
+	tf.BadUnitTestWithSideEffects(t)
+
+	ctx := context.Background()
+
+	testTimer := NewTimerMs("testRecord", "testRecordDesc")
+	defer view.Unregister(testTimer.view)
+
+	testTimer.Record(ctx, 1500*time.Microsecond)
+
+	rows, err := view.RetrieveData("testRecord")
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	dist := rows[0].Data.(*view.DistributionData)
+	assert.Equal(t, int64(1), dist.Count)
+	assert.Equal(t, 1.5, dist.Mean)
+}
@@ -0,0 +1,219 @@
+package net
+
+import (
+	gonet "net"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/libp2p/go-libp2p-core/control"
+	"github.com/libp2p/go-libp2p-core/host"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p/p2p/net/conngater"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net" //nolint
+	"github.com/pkg/errors"
+)
+
+// NetBlockList is a set of peers, IP addresses and subnets (in CIDR
+// notation) that the connection gater refuses to talk to.
+type NetBlockList struct {
+	Peers     []peer.ID
+	IPAddrs   []string
+	IPSubnets []string
+}
+
+// PeerBanner is implemented by components able to refuse connections to a
+// misbehaving peer for a while.
+type PeerBanner interface {
+	BanPeer(p peer.ID, duration time.Duration, reason string)
+}
+
+// ConnGater is a libp2p connection gater backed by a datastore for the
+// permanent blocklist, plus an in memory set of temporary peer bans that
+// expire on their own.
+type ConnGater struct {
+	*conngater.BasicConnectionGater
+
+	lk       sync.Mutex
+	tempBans map[peer.ID]time.Time
+}
+
+// NewConnGater creates a connection gater persisting its blocklist in `ds`.
+func NewConnGater(ds datastore.Datastore) (*ConnGater, error) {
+	basic, err := conngater.NewBasicConnectionGater(ds)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create connection gater")
+	}
+	return &ConnGater{
+		BasicConnectionGater: basic,
+		tempBans:             make(map[peer.ID]time.Time),
+	}, nil
+}
+
+// BanPeerFor refuses connections to `p` until `duration` has elapsed.
+func (cg *ConnGater) BanPeerFor(p peer.ID, duration time.Duration) {
+	cg.lk.Lock()
+	defer cg.lk.Unlock()
+	until := time.Now().Add(duration)
+	if cur, ok := cg.tempBans[p]; ok && cur.After(until) {
+		return
+	}
+	cg.tempBans[p] = until
+}
+
+// TempBanned returns the peers currently banned temporarily and when their
+// ban expires.
+func (cg *ConnGater) TempBanned() map[peer.ID]time.Time {
+	cg.lk.Lock()
+	defer cg.lk.Unlock()
+	out := make(map[peer.ID]time.Time, len(cg.tempBans))
+	now := time.Now()
+	for p, until := range cg.tempBans {
+		if until.Before(now) {
+			delete(cg.tempBans, p)
+			continue
+		}
+		out[p] = until
+	}
+	return out
+}
+
+func (cg *ConnGater) isTempBanned(p peer.ID) bool {
+	cg.lk.Lock()
+	defer cg.lk.Unlock()
+	until, ok := cg.tempBans[p]
+	if !ok {
+		return false
+	}
+	if until.Before(time.Now()) {
+		delete(cg.tempBans, p)
+		return false
+	}
+	return true
+}
+
+// InterceptPeerDial implements connmgr.ConnectionGater.
+func (cg *ConnGater) InterceptPeerDial(p peer.ID) bool {
+	if cg.isTempBanned(p) {
+		return false
+	}
+	return cg.BasicConnectionGater.InterceptPeerDial(p)
+}
+
+// InterceptAddrDial implements connmgr.ConnectionGater.
+func (cg *ConnGater) InterceptAddrDial(p peer.ID, a ma.Multiaddr) bool {
+	if cg.isTempBanned(p) {
+		return false
+	}
+	return cg.BasicConnectionGater.InterceptAddrDial(p, a)
+}
+
+// InterceptSecured implements connmgr.ConnectionGater.
+func (cg *ConnGater) InterceptSecured(dir network.Direction, p peer.ID, cma network.ConnMultiaddrs) bool {
+	if cg.isTempBanned(p) {
+		return false
+	}
+	return cg.BasicConnectionGater.InterceptSecured(dir, p, cma)
+}
+
+// InterceptUpgraded implements connmgr.ConnectionGater.
+func (cg *ConnGater) InterceptUpgraded(conn network.Conn) (bool, control.DisconnectReason) {
+	return cg.BasicConnectionGater.InterceptUpgraded(conn)
+}
+
+// Block adds everything in `list` to the persistent blocklist and closes the
+// existing connections of `h` that are now blocked.
+func (cg *ConnGater) Block(h host.Host, list NetBlockList) error {
+	for _, p := range list.Peers {
+		if err := cg.BlockPeer(p); err != nil {
+			return errors.Wrapf(err, "blocking peer %s", p)
+		}
+		if err := h.Network().ClosePeer(p); err != nil {
+			log.Warnf("failed to close connection to blocked peer %s: %s", p, err)
+		}
+	}
+
+	for _, addr := range list.IPAddrs {
+		ip := gonet.ParseIP(addr)
+		if ip == nil {
+			return errors.Errorf("invalid IP address %s", addr)
+		}
+		if err := cg.BlockAddr(ip); err != nil {
+			return errors.Wrapf(err, "blocking IP address %s", addr)
+		}
+		closeConnsMatching(h, ip.Equal)
+	}
+
+	for _, subnet := range list.IPSubnets {
+		_, cidr, err := gonet.ParseCIDR(subnet)
+		if err != nil {
+			return errors.Wrapf(err, "invalid subnet %s", subnet)
+		}
+		if err := cg.BlockSubnet(cidr); err != nil {
+			return errors.Wrapf(err, "blocking subnet %s", subnet)
+		}
+		closeConnsMatching(h, cidr.Contains)
+	}
+
+	return nil
+}
+
+// Unblock removes everything in `list` from the persistent blocklist.
+func (cg *ConnGater) Unblock(list NetBlockList) error {
+	for _, p := range list.Peers {
+		if err := cg.UnblockPeer(p); err != nil {
+			return errors.Wrapf(err, "unblocking peer %s", p)
+		}
+	}
+
+	for _, addr := range list.IPAddrs {
+		ip := gonet.ParseIP(addr)
+		if ip == nil {
+			return errors.Errorf("invalid IP address %s", addr)
+		}
+		if err := cg.UnblockAddr(ip); err != nil {
+			return errors.Wrapf(err, "unblocking IP address %s", addr)
+		}
+	}
+
+	for _, subnet := range list.IPSubnets {
+		_, cidr, err := gonet.ParseCIDR(subnet)
+		if err != nil {
+			return errors.Wrapf(err, "invalid subnet %s", subnet)
+		}
+		if err := cg.UnblockSubnet(cidr); err != nil {
+			return errors.Wrapf(err, "unblocking subnet %s", subnet)
+		}
+	}
+
+	return nil
+}
+
+// List returns the persistent blocklist.
+func (cg *ConnGater) List() NetBlockList {
+	var list NetBlockList
+	list.Peers = cg.ListBlockedPeers()
+	for _, ip := range cg.ListBlockedAddrs() {
+		list.IPAddrs = append(list.IPAddrs, ip.String())
+	}
+	for _, cidr := range cg.ListBlockedSubnets() {
+		list.IPSubnets = append(list.IPSubnets, cidr.String())
+	}
+	return list
+}
+
+func closeConnsMatching(h host.Host, match func(gonet.IP) bool) {
+	for _, conn := range h.Network().Conns() {
+		ip, err := manet.ToIP(conn.RemoteMultiaddr())
+		if err != nil {
+			continue
+		}
+		if match(ip) {
+			if err := conn.Close(); err != nil {
+				log.Warnf("failed to close connection to blocked address %s: %s", conn.RemoteMultiaddr(), err)
+			}
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package net
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/libp2p/go-libp2p-core/network"
+	"github.com/libp2p/go-libp2p-core/peer"
+	mocknet "github.com/libp2p/go-libp2p/p2p/net/mock"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+type connAddrs struct {
+	local, remote ma.Multiaddr
+}
+
+func (c connAddrs) LocalMultiaddr() ma.Multiaddr  { return c.local }
+func (c connAddrs) RemoteMultiaddr() ma.Multiaddr { return c.remote }
+
+func TestConnGaterBlocklist(t *testing.T) {
+	tf.UnitTest(t)
+
+	ctx := context.Background()
+	mn := mocknet.New(ctx)
+	h, err := mn.GenPeer()
+	require.NoError(t, err)
+
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	cg, err := NewConnGater(ds)
+	require.NoError(t, err)
+
+	blockedPeer, otherPeer := peer.ID("blocked"), peer.ID("other")
+	require.NoError(t, cg.Block(h, NetBlockList{
+		Peers:     []peer.ID{blockedPeer},
+		IPAddrs:   []string{"1.2.3.4"},
+		IPSubnets: []string{"10.0.0.0/8"},
+	}))
+
+	assert.False(t, cg.InterceptPeerDial(blockedPeer))
+	assert.True(t, cg.InterceptPeerDial(otherPeer))
+
+	for addr, allowed := range map[string]bool{
+		"/ip4/1.2.3.4/tcp/1":   false,
+		"/ip4/1.2.3.5/tcp/1":   true,
+		"/ip4/10.1.2.3/tcp/1":  false,
+		"/ip4/11.1.2.3/tcp/1":  true,
+		"/ip4/127.0.0.1/tcp/1": true,
+	} {
+		remote := ma.StringCast(addr)
+		assert.Equal(t, allowed, cg.InterceptAddrDial(otherPeer, remote), addr)
+		assert.Equal(t, allowed, cg.InterceptAccept(connAddrs{local: ma.StringCast("/ip4/127.0.0.1/tcp/2"), remote: remote}), addr)
+	}
+	assert.False(t, cg.InterceptSecured(network.DirInbound, blockedPeer, connAddrs{local: ma.StringCast("/ip4/127.0.0.1/tcp/2"), remote: ma.StringCast("/ip4/127.0.0.1/tcp/1")}))
+
+	// the blocklist is persisted
+	reloaded, err := NewConnGater(ds)
+	require.NoError(t, err)
+	list := reloaded.List()
+	assert.Equal(t, []peer.ID{blockedPeer}, list.Peers)
+	assert.Equal(t, []string{"1.2.3.4"}, list.IPAddrs)
+	assert.Equal(t, []string{"10.0.0.0/8"}, list.IPSubnets)
+	assert.False(t, reloaded.InterceptAddrDial(otherPeer, ma.StringCast("/ip4/10.9.9.9/tcp/1")))
+
+	require.NoError(t, cg.Unblock(NetBlockList{
+		Peers:     []peer.ID{blockedPeer},
+		IPAddrs:   []string{"1.2.3.4"},
+		IPSubnets: []string{"10.0.0.0/8"},
+	}))
+	assert.True(t, cg.InterceptPeerDial(blockedPeer))
+	assert.True(t, cg.InterceptAddrDial(otherPeer, ma.StringCast("/ip4/1.2.3.4/tcp/1")))
+	assert.True(t, cg.InterceptAddrDial(otherPeer, ma.StringCast("/ip4/10.1.2.3/tcp/1")))
+	list = cg.List()
+	assert.Empty(t, list.Peers)
+	assert.Empty(t, list.IPAddrs)
+	assert.Empty(t, list.IPSubnets)
+
+	assert.Error(t, cg.Block(h, NetBlockList{IPAddrs: []string{"1.2.3"}}))
+	assert.Error(t, cg.Block(h, NetBlockList{IPSubnets: []string{"10.0.0.0/33"}}))
+}
+
+func TestConnGaterTempBanExpires(t *testing.T) {
+	tf.UnitTest(t)
+
+	cg, err := NewConnGater(datastore.NewMapDatastore())
+	require.NoError(t, err)
+
+	p := peer.ID("banned")
+	cg.BanPeerFor(p, 100*time.Millisecond)
+	// a shorter ban does not cut the current one
+	cg.BanPeerFor(p, time.Millisecond)
+
+	assert.False(t, cg.InterceptPeerDial(p))
+	assert.False(t, cg.InterceptAddrDial(p, ma.StringCast("/ip4/127.0.0.1/tcp/1")))
+	assert.Contains(t, cg.TempBanned(), p)
+	// temporary bans are not persisted
+	assert.Empty(t, cg.List().Peers)
+
+	time.Sleep(150 * time.Millisecond)
+	assert.True(t, cg.InterceptPeerDial(p))
+	assert.Empty(t, cg.TempBanned())
+}
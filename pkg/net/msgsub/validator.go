@@ -3,6 +3,7 @@ package msgsub
 import (
 	"bytes"
 	"context"
+	"time"
 
 	"github.com/ipfs/go-log/v2"
 	"github.com/libp2p/go-libp2p-core/peer"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/filecoin-project/venus/pkg/consensus"
 	"github.com/filecoin-project/venus/pkg/metrics"
+	"github.com/filecoin-project/venus/pkg/net"
 	"github.com/filecoin-project/venus/pkg/types"
 )
 
@@ -17,38 +19,59 @@ var messageTopicLogger = log.Logger("net/message_validator")
 var mDecodeMsgFail = metrics.NewInt64Counter("net/pubsub_message_decode_failure", "Number of messages that fail to decode seen on message pubsub channel")
 var mInvalidMsg = metrics.NewInt64Counter("net/pubsub_invalid_message", "Number of messages that fail syntax validation seen on message pubsub channel")
 
+// BanDuration is how long a peer sending undecodable messages is banned for.
+var BanDuration = 10 * time.Minute
+
 // MessageTopicValidator may be registered on go-libp3p-pubsub to validate msgsub payloads.
 type MessageTopicValidator struct {
 	validator pubsub.Validator
 	opts      []pubsub.ValidatorOpt
+	banner    net.PeerBanner
 }
 
 // NewMessageTopicValidator returns a MessageTopicValidator using the input
 // signature and syntax validators.
 func NewMessageTopicValidator(syntaxVal *consensus.DefaultMessageSyntaxValidator, sigVal *consensus.MessageSignatureValidator, opts ...pubsub.ValidatorOpt) *MessageTopicValidator {
-	return &MessageTopicValidator{
+	mtv := &MessageTopicValidator{
 		opts: opts,
-		validator: func(ctx context.Context, p peer.ID, msg *pubsub.Message) bool {
-			unmarshaled := &types.SignedMessage{}
-			if err := unmarshaled.UnmarshalCBOR(bytes.NewReader(msg.GetData())); err != nil {
-				messageTopicLogger.Debugf("message from peer: %s failed to decode: %s", p.String(), err.Error())
-				mDecodeMsgFail.Inc(ctx, 1)
-				return false
-			}
-			if err := syntaxVal.ValidateSignedMessageSyntax(ctx, unmarshaled); err != nil {
-				mCid, _ := unmarshaled.Cid()
-				messageTopicLogger.Debugf("message %s from peer: %s failed to syntax validate: %s", mCid.String(), p.String(), err.Error())
-				mInvalidMsg.Inc(ctx, 1)
-				return false
-			}
-			if err := sigVal.Validate(ctx, unmarshaled); err != nil {
-				mCid, _ := unmarshaled.Cid()
-				messageTopicLogger.Debugf("message %s from peer: %s failed to signature validate: %s", mCid.String(), p.String(), err.Error())
-				mInvalidMsg.Inc(ctx, 1)
-				return false
-			}
-			return true
-		},
+	}
+	mtv.validator = func(ctx context.Context, p peer.ID, msg *pubsub.Message) bool {
+		unmarshaled := &types.SignedMessage{}
+		if err := unmarshaled.UnmarshalCBOR(bytes.NewReader(msg.GetData())); err != nil {
+			messageTopicLogger.Debugf("message from peer: %s failed to decode: %s", p.String(), err.Error())
+			mDecodeMsgFail.Inc(ctx, 1)
+			mtv.ban(p, "undecodable message")
+			return false
+		}
+		if err := syntaxVal.ValidateSignedMessageSyntax(ctx, unmarshaled); err != nil {
+			mCid, _ := unmarshaled.Cid()
+			messageTopicLogger.Debugf("message %s from peer: %s failed to syntax validate: %s", mCid.String(), p.String(), err.Error())
+			mInvalidMsg.Inc(ctx, 1)
+			return false
+		}
+		// the sender key is resolved in the state of our head, a node behind
+		// the network fails on the messages of new accounts, so the peer
+		// relaying them is not banned
+		if err := sigVal.Validate(ctx, unmarshaled); err != nil {
+			mCid, _ := unmarshaled.Cid()
+			messageTopicLogger.Debugf("message %s from peer: %s failed to signature validate: %s", mCid.String(), p.String(), err.Error())
+			mInvalidMsg.Inc(ctx, 1)
+			return false
+		}
+		return true
+	}
+	return mtv
+}
+
+// WithPeerBanner makes the validator ban peers that send undecodable messages.
+func (mtv *MessageTopicValidator) WithPeerBanner(banner net.PeerBanner) *MessageTopicValidator {
+	mtv.banner = banner
+	return mtv
+}
+
+func (mtv *MessageTopicValidator) ban(p peer.ID, reason string) {
+	if mtv.banner != nil {
+		mtv.banner.BanPeer(p, BanDuration, reason)
 	}
 }
 
@@ -0,0 +1,95 @@
+package msgsub_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	pubsubpb "github.com/libp2p/go-libp2p-pubsub/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/consensus"
+	"github.com/filecoin-project/venus/pkg/net/msgsub"
+	"github.com/filecoin-project/venus/pkg/state"
+	th "github.com/filecoin-project/venus/pkg/testhelpers"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/venus/pkg/types"
+)
+
+func TestMessageTopicValidatorBans(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	keys := types.MustGenerateKeyInfo(2, 42)
+	from, err := keys[0].Address()
+	require.NoError(t, err)
+	to, err := keys[1].Address()
+	require.NoError(t, err)
+	msg := types.NewMeteredMessage(from, to, 0, types.ZeroFIL, 0, nil, types.NewGasFeeCap(1), types.NewGasPremium(1), 5000)
+	smsg, err := types.NewSignedMessage(ctx, *msg, types.NewMockSigner(keys))
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, smsg.MarshalCBOR(buf))
+
+	banner := &fakeBanner{}
+	api := &fakeStateAPI{}
+	sigVal := consensus.NewMessageSignatureValidator(api)
+	validator := msgsub.NewMessageTopicValidator(consensus.NewMessageSyntaxValidator(), sigVal).WithPeerBanner(banner).Validator()
+	pid := th.RequireIntPeerID(t, 1)
+
+	t.Run("a message the local state can not validate is rejected without a ban", func(t *testing.T) {
+		assert.True(t, validator(ctx, pid, pubsubMsg(buf.Bytes())))
+
+		api.err = errors.New("state not synced")
+		assert.False(t, validator(ctx, pid, pubsubMsg(buf.Bytes())))
+		assert.Empty(t, banner.banned)
+	})
+
+	t.Run("an undecodable message bans the peer", func(t *testing.T) {
+		assert.False(t, validator(ctx, pid, pubsubMsg([]byte("meow"))))
+		assert.Equal(t, []peer.ID{pid}, banner.banned)
+	})
+}
+
+type fakeBanner struct {
+	banned []peer.ID
+}
+
+func (b *fakeBanner) BanPeer(p peer.ID, _ time.Duration, _ string) {
+	b.banned = append(b.banned, p)
+}
+
+// fakeStateAPI fails to load the state of its head with `err`.
+type fakeStateAPI struct {
+	err error
+}
+
+func (api *fakeStateAPI) Head() *block.TipSet {
+	return nil
+}
+
+func (api *fakeStateAPI) GetTipSet(block.TipSetKey) (*block.TipSet, error) {
+	return nil, errors.New("no tipset")
+}
+
+func (api *fakeStateAPI) AccountStateView(*block.TipSet) (state.AccountStateView, error) {
+	if api.err != nil {
+		return nil, api.err
+	}
+	return &state.FakeStateView{}, nil
+}
+
+func pubsubMsg(data []byte) *pubsub.Message {
+	return &pubsub.Message{
+		Message: &pubsubpb.Message{
+			Data: data,
+		},
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/libp2p/go-libp2p-core/host"
 	"github.com/libp2p/go-libp2p-core/metrics"
 	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/libp2p/go-libp2p-core/protocol"
 	swarm "github.com/libp2p/go-libp2p-swarm"
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/pkg/errors"
@@ -92,6 +93,16 @@ func (network *Network) GetBandwidthStats() metrics.Stats {
 	return network.Reporter.GetBandwidthTotals()
 }
 
+// GetBandwidthStatsByPeer gets stats on the current bandwidth usage of the network by peer
+func (network *Network) GetBandwidthStatsByPeer() map[peer.ID]metrics.Stats {
+	return network.Reporter.GetBandwidthByPeer()
+}
+
+// GetBandwidthStatsByProtocol gets stats on the current bandwidth usage of the network by protocol
+func (network *Network) GetBandwidthStatsByProtocol() map[protocol.ID]metrics.Stats {
+	return network.Reporter.GetBandwidthByProtocol()
+}
+
 // ConnectionResult represents the result of an attempted connection from the
 // Connect method.
 type ConnectionResult struct {
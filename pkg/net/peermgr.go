@@ -14,10 +14,17 @@ import (
 	dht "github.com/libp2p/go-libp2p-kad-dht"
 
 	logging "github.com/ipfs/go-log/v2"
+
+	"github.com/filecoin-project/venus/pkg/metrics"
 )
 
 var log = logging.Logger("peermgr")
 
+var (
+	peerCountGauge    = metrics.NewInt64Gauge("net/peer_count", "Number of connected libp2p peers")
+	filPeerCountGauge = metrics.NewInt64Gauge("net/filecoin_peer_count", "Number of connected peers speaking the filecoin protocols")
+)
+
 const (
 	MaxFilPeers = 320
 	MinFilPeers = 128
@@ -140,6 +147,8 @@ func (pmgr *PeerMgr) Run(ctx context.Context) {
 		select {
 		case <-tick.C:
 			pcount := pmgr.getPeerCount()
+			peerCountGauge.Set(ctx, int64(len(pmgr.h.Network().Peers())))
+			filPeerCountGauge.Set(ctx, int64(pcount))
 			if pcount < pmgr.minFilPeers {
 				pmgr.expandPeers()
 			} else if pcount > pmgr.maxFilPeers {
@@ -175,16 +184,26 @@ func (pmgr *PeerMgr) expandPeers() {
 	}()
 }
 
+// SetBootstrappers replaces the peers connected to when no peer is left.
+func (pmgr *PeerMgr) SetBootstrappers(bootstrappers []peer.AddrInfo) {
+	pmgr.peersLk.Lock()
+	defer pmgr.peersLk.Unlock()
+	pmgr.bootstrappers = bootstrappers
+}
+
 func (pmgr *PeerMgr) doExpand(ctx context.Context) {
 	pcount := pmgr.getPeerCount()
 	if pcount == 0 {
-		if len(pmgr.bootstrappers) == 0 {
+		pmgr.peersLk.Lock()
+		bootstrappers := pmgr.bootstrappers
+		pmgr.peersLk.Unlock()
+		if len(bootstrappers) == 0 {
 			log.Warn("no peers connected, and no bootstrappers configured")
 			return
 		}
 
 		log.Info("connecting to bootstrap peers")
-		for _, bsp := range pmgr.bootstrappers {
+		for _, bsp := range bootstrappers {
 			if err := pmgr.h.Connect(ctx, bsp); err != nil {
 				log.Warnf("failed to connect to bootstrap peer: %s", err)
 			}
@@ -0,0 +1,364 @@
+package repo
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	ci "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/pkg/config"
+)
+
+// BackupVersion is the version of the backup archive format.
+const BackupVersion = 1
+
+const (
+	backupManifestName = "manifest.json"
+	backupConfigName   = "config.json"
+	backupTokenName    = "token"
+	backupKeystoreDir  = "keystore"
+	backupDatastoreDir = "datastores"
+)
+
+// backupDatastores are the datastore ranges holding state that cannot be
+// recovered from the network. The bulk blockstore is left out, it is restored
+// from a chain snapshot.
+var backupDatastores = []struct {
+	name   string
+	prefix string
+	ds     func(Repo) Datastore
+}{
+	{name: "wallet", ds: func(r Repo) Datastore { return r.WalletDatastore() }},
+	// local messages of the message pool
+	{name: "mpool-local", prefix: "/mpool/local", ds: func(r Repo) Datastore { return r.MetaDatastore() }},
+	// blocks seen by the slash filter
+	{name: "slashfilter", prefix: "/slashfilter", ds: func(r Repo) Datastore { return r.ChainDatastore() }},
+}
+
+// BackupManifest describes the files of a backup archive.
+type BackupManifest struct {
+	Version     int
+	RepoVersion uint
+	Created     time.Time
+	// Files maps the archive files to their sha256 checksums.
+	Files map[string]string
+}
+
+// backupEntry is a line of a datastore file of a backup archive.
+type backupEntry struct {
+	Key   string
+	Value []byte
+}
+
+// Backup writes the config, the keystore, the api token and the datastores
+// holding the wallet, the local messages and the slash filter records of `r`
+// to `w` as a gzipped tar archive. The datastores are read in transactions
+// opened together, so the node may keep running.
+func Backup(r Repo, w io.Writer) error {
+	// open the snapshots first, before reading anything
+	snapshots := make([]datastore.Read, len(backupDatastores))
+	for i, b := range backupDatastores {
+		ds := b.ds(r)
+		if tds, ok := ds.(datastore.TxnDatastore); ok {
+			txn, err := tds.NewTransaction(true)
+			if err != nil {
+				return errors.Wrapf(err, "failed to open %s transaction", b.name)
+			}
+			defer txn.Discard()
+			snapshots[i] = txn
+		} else {
+			snapshots[i] = ds
+		}
+	}
+
+	gzw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gzw)
+	manifest := &BackupManifest{
+		Version:     BackupVersion,
+		RepoVersion: r.Version(),
+		Created:     time.Now(),
+		Files:       map[string]string{},
+	}
+	add := func(name string, data []byte) error {
+		sum := sha256.Sum256(data)
+		manifest.Files[name] = hex.EncodeToString(sum[:])
+		return writeTarFile(tw, name, data)
+	}
+
+	cfg, err := json.MarshalIndent(r.Config(), "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := add(backupConfigName, cfg); err != nil {
+		return err
+	}
+
+	if repoPath, err := r.Path(); err == nil {
+		token, err := ioutil.ReadFile(filepath.Join(repoPath, backupTokenName))
+		if err == nil {
+			if err := add(backupTokenName, token); err != nil {
+				return err
+			}
+		} else if !os.IsNotExist(err) {
+			return errors.Wrap(err, "failed to read api token")
+		}
+	}
+
+	names, err := r.Keystore().List()
+	if err != nil {
+		return errors.Wrap(err, "failed to list keystore")
+	}
+	for _, name := range names {
+		k, err := r.Keystore().Get(name)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read key %s", name)
+		}
+		data, err := ci.MarshalPrivateKey(k)
+		if err != nil {
+			return err
+		}
+		if err := add(path.Join(backupKeystoreDir, name), data); err != nil {
+			return err
+		}
+	}
+
+	for i, b := range backupDatastores {
+		data, err := dumpDatastore(snapshots[i], b.prefix)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s datastore", b.name)
+		}
+		if err := add(path.Join(backupDatastoreDir, b.name+".ndjson"), data); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := writeTarFile(tw, backupManifestName, data); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gzw.Close()
+}
+
+// BackupToFile writes the backup of `r` to the file `dst`, which is only
+// created once the backup is complete.
+func BackupToFile(r Repo, dst string) error {
+	tmp := dst + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return errors.Wrap(err, "failed to create backup file")
+	}
+	if err := Backup(r, f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// RestoreBackup verifies the backup archive read from `rd` and restores it
+// into a new repo at `repoPath`, which must not exist or be empty. The chain
+// has to be imported from a snapshot afterwards.
+func RestoreBackup(rd io.Reader, repoPath string) (*BackupManifest, error) {
+	manifest, files, err := readBackup(rd)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.RepoVersion > Version {
+		return nil, fmt.Errorf("binary needs update to handle backup repo version, got %d expected %d. Update binary to latest release", manifest.RepoVersion, Version)
+	}
+
+	cfg := config.NewDefaultConfig()
+	if err := json.Unmarshal(files[backupConfigName], cfg); err != nil {
+		return nil, errors.Wrap(err, "failed to decode config")
+	}
+	if err := InitFSRepoDirect(repoPath, manifest.RepoVersion, cfg); err != nil {
+		return nil, err
+	}
+	// a half restored repo would be refused by the next restore
+	if err := restoreFiles(repoPath, manifest.RepoVersion, files); err != nil {
+		_ = os.RemoveAll(repoPath)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// restoreFiles restores the token, the keys and the datastores of the backup
+// `files` into the repo initialized at `repoPath`.
+func restoreFiles(repoPath string, version uint, files map[string][]byte) error {
+	r, err := OpenFSRepo(repoPath, version)
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint: errcheck
+
+	if token, ok := files[backupTokenName]; ok {
+		if err := r.SetAPIToken(token); err != nil {
+			return err
+		}
+	}
+
+	for name, data := range files {
+		if !strings.HasPrefix(name, backupKeystoreDir+"/") {
+			continue
+		}
+		k, err := ci.UnmarshalPrivateKey(data)
+		if err != nil {
+			return errors.Wrapf(err, "failed to decode key %s", name)
+		}
+		if err := r.Keystore().Put(strings.TrimPrefix(name, backupKeystoreDir+"/"), k); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range backupDatastores {
+		data, ok := files[path.Join(backupDatastoreDir, b.name+".ndjson")]
+		if !ok {
+			continue
+		}
+		if err := loadDatastore(b.ds(r), data); err != nil {
+			return errors.Wrapf(err, "failed to restore %s datastore", b.name)
+		}
+	}
+	return nil
+}
+
+// readBackup reads the files of a backup archive and checks them against the
+// manifest.
+func readBackup(rd io.Reader) (*BackupManifest, map[string][]byte, error) {
+	gzr, err := gzip.NewReader(rd)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "not a backup archive")
+	}
+	tr := tar.NewReader(gzr)
+	files := map[string][]byte{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read backup archive")
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[hdr.Name] = data
+	}
+
+	data, ok := files[backupManifestName]
+	if !ok {
+		return nil, nil, errors.New("backup archive has no manifest")
+	}
+	delete(files, backupManifestName)
+	var manifest BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to decode backup manifest")
+	}
+	if manifest.Version != BackupVersion {
+		return nil, nil, fmt.Errorf("unsupported backup version %d, expected %d", manifest.Version, BackupVersion)
+	}
+
+	for name, data := range files {
+		want, ok := manifest.Files[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("backup file %s is not in the manifest", name)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != want {
+			return nil, nil, fmt.Errorf("checksum mismatch for backup file %s", name)
+		}
+	}
+	for name := range manifest.Files {
+		if _, ok := files[name]; !ok {
+			return nil, nil, fmt.Errorf("backup file %s is missing", name)
+		}
+	}
+	if _, ok := files[backupConfigName]; !ok {
+		return nil, nil, errors.New("backup archive has no config")
+	}
+	return &manifest, files, nil
+}
+
+func dumpDatastore(ds datastore.Read, prefix string) ([]byte, error) {
+	res, err := ds.Query(query.Query{Prefix: prefix})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := res.Rest()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	for _, e := range entries {
+		if err := enc.Encode(backupEntry{Key: e.Key, Value: e.Value}); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+func loadDatastore(ds Datastore, data []byte) error {
+	batch, err := ds.Batch()
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64<<10), 64<<20)
+	for scanner.Scan() {
+		var e backupEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return err
+		}
+		if err := batch.Put(datastore.NewKey(e.Key), e.Value); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return batch.Commit()
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
@@ -0,0 +1,151 @@
+package repo
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	ci "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+func TestBackupRestore(t *testing.T) {
+	tf.UnitTest(t)
+
+	src := NewInMemoryRepo()
+	src.Config().API.APIAddress = "/ip4/127.0.0.1/tcp/1234"
+	k, _, err := ci.GenerateKeyPair(ci.Ed25519, 0)
+	require.NoError(t, err)
+	require.NoError(t, src.Keystore().Put("self", k))
+	require.NoError(t, src.WalletDatastore().Put(ds.NewKey("/wallet/key"), []byte("wallet")))
+	require.NoError(t, src.MetaDatastore().Put(ds.NewKey("/mpool/local/msg"), []byte("msg")))
+	require.NoError(t, src.MetaDatastore().Put(ds.NewKey("/mpool/other"), []byte("other")))
+	require.NoError(t, src.ChainDatastore().Put(ds.NewKey("/slashfilter/epoch/f01/1"), []byte("block")))
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, Backup(src, buf))
+
+	container, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer RequireRemoveAll(t, container)
+	repoPath := path.Join(container, "repo")
+
+	manifest, err := RestoreBackup(bytes.NewReader(buf.Bytes()), repoPath)
+	require.NoError(t, err)
+	assert.Equal(t, Version, manifest.RepoVersion)
+
+	r, err := OpenFSRepo(repoPath, Version)
+	require.NoError(t, err)
+	defer r.Close() // nolint: errcheck
+
+	assert.Equal(t, "/ip4/127.0.0.1/tcp/1234", r.Config().API.APIAddress)
+	restored, err := r.Keystore().Get("self")
+	require.NoError(t, err)
+	assert.True(t, k.Equals(restored))
+
+	value, err := r.WalletDatastore().Get(ds.NewKey("/wallet/key"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("wallet"), value)
+	value, err = r.MetaDatastore().Get(ds.NewKey("/mpool/local/msg"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("msg"), value)
+	value, err = r.ChainDatastore().Get(ds.NewKey("/slashfilter/epoch/f01/1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("block"), value)
+
+	// only the local messages of the metadata datastore are backed up
+	has, err := r.MetaDatastore().Has(ds.NewKey("/mpool/other"))
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	t.Run("refuses a non-empty repo directory", func(t *testing.T) {
+		_, err := RestoreBackup(bytes.NewReader(buf.Bytes()), repoPath)
+		assert.Error(t, err)
+	})
+}
+
+func TestRestoreBackupChecksum(t *testing.T) {
+	tf.UnitTest(t)
+
+	buf := new(bytes.Buffer)
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+	manifest, err := json.Marshal(&BackupManifest{
+		Version:     BackupVersion,
+		RepoVersion: Version,
+		Files:       map[string]string{backupConfigName: "00"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, writeTarFile(tw, backupConfigName, []byte("{}")))
+	require.NoError(t, writeTarFile(tw, backupManifestName, manifest))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+
+	container, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer RequireRemoveAll(t, container)
+
+	_, err = RestoreBackup(buf, path.Join(container, "repo"))
+	assert.EqualError(t, err, "checksum mismatch for backup file config.json")
+}
+
+func TestRestoreBackupRemovesPartialRepo(t *testing.T) {
+	tf.UnitTest(t)
+
+	// the archive is consistent but its key cannot be decoded, which only
+	// fails once the repo is initialized
+	files := map[string][]byte{
+		backupConfigName:                     []byte("{}"),
+		path.Join(backupKeystoreDir, "self"): []byte("not a key"),
+	}
+	sums := map[string]string{}
+	for name, data := range files {
+		sum := sha256.Sum256(data)
+		sums[name] = hex.EncodeToString(sum[:])
+	}
+	manifest, err := json.Marshal(&BackupManifest{
+		Version:     BackupVersion,
+		RepoVersion: Version,
+		Files:       sums,
+	})
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+	gzw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gzw)
+	for name, data := range files {
+		require.NoError(t, writeTarFile(tw, name, data))
+	}
+	require.NoError(t, writeTarFile(tw, backupManifestName, manifest))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+
+	container, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer RequireRemoveAll(t, container)
+	repoPath := path.Join(container, "repo")
+
+	_, err = RestoreBackup(buf, repoPath)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to decode key")
+	_, err = os.Stat(repoPath)
+	assert.True(t, os.IsNotExist(err))
+
+	// the directory is free for another restore
+	src := NewInMemoryRepo()
+	good := new(bytes.Buffer)
+	require.NoError(t, Backup(src, good))
+	_, err = RestoreBackup(good, repoPath)
+	require.NoError(t, err)
+}
@@ -0,0 +1,327 @@
+package repo
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/dgraph-io/badger/v2"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/query"
+	lockfile "github.com/ipfs/go-fs-lock"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/pkg/config"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
+)
+
+// BlocksDatastore names the block datastore in conversions, its directory is
+// the datastore path of the config.
+const BlocksDatastore = "blocks"
+
+// ConvertibleDatastores are the datastores ConvertFSRepo can convert.
+var ConvertibleDatastores = []string{BlocksDatastore, chainDatastorePrefix, metaDatastorePrefix, walletDatastorePrefix}
+
+// convertBatchSize is the number of entries written per batch.
+const convertBatchSize = 1024
+
+// ConvertResult reports a conversion of the repo datastores.
+type ConvertResult struct {
+	To string
+	// Entries is the number of entries copied per converted datastore.
+	Entries map[string]int
+	// Previous is the directory keeping the data of each converted datastore
+	// in its previous backend, it is not deleted.
+	Previous map[string]string
+}
+
+// ConvertFSRepo copies the datastores `names` of the repo, all of them when
+// empty, to the backend `to` and selects it in the config. Datastores already
+// using `to` are skipped. The daemon must not be running.
+func ConvertFSRepo(repoPath string, to string, names []string) (*ConvertResult, error) {
+	if _, ok := datastoreBackends[to]; !ok {
+		return nil, errors.Errorf("unknown datastore type %s", to)
+	}
+	if to == "memds" {
+		return nil, errors.New("memds keeps nothing on disk, set it in the datastore config instead")
+	}
+	if len(names) == 0 {
+		names = ConvertibleDatastores
+	}
+	for _, name := range names {
+		if !isConvertible(name) {
+			return nil, errors.Errorf("unknown datastore %s, expected one of %v", name, ConvertibleDatastores)
+		}
+	}
+
+	repoPath, err := resolveRepoPath(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := lockfile.Lock(repoPath, lockFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to take repo lock, is the daemon running?")
+	}
+	defer lock.Close() // nolint: errcheck
+
+	configFile := filepath.Join(repoPath, configFilename)
+	cfg, err := config.ReadFile(configFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config")
+	}
+
+	res := &ConvertResult{
+		To:       to,
+		Entries:  map[string]int{},
+		Previous: map[string]string{},
+	}
+	from := map[string]string{}
+	for _, name := range names {
+		typ := datastoreType(cfg.Datastore, name)
+		if typ == to {
+			continue
+		}
+		dir := filepath.Join(repoPath, datastoreDir(cfg.Datastore, name))
+		previous := dir + "." + typ
+		if _, err := os.Stat(previous); err == nil {
+			return nil, errors.Errorf("%s already exists, remove it to convert %s", previous, name)
+		}
+		from[name] = typ
+		res.Previous[name] = previous
+	}
+
+	// copy and check everything before touching the repo
+	for name, typ := range from {
+		dir := filepath.Join(repoPath, datastoreDir(cfg.Datastore, name))
+		if err := os.RemoveAll(dir + ".convert"); err != nil {
+			return nil, err
+		}
+		var n int
+		if name == BlocksDatastore {
+			n, err = convertBlockstore(typ, to, dir, cfg.Datastore)
+		} else {
+			n, err = convertDatastore(typ, to, dir, cfg.Datastore)
+		}
+		if err != nil {
+			_ = os.RemoveAll(dir + ".convert")
+			for copied := range res.Entries {
+				_ = os.RemoveAll(filepath.Join(repoPath, datastoreDir(cfg.Datastore, copied)) + ".convert")
+			}
+			return nil, errors.Wrapf(err, "failed to convert %s datastore", name)
+		}
+		res.Entries[name] = n
+	}
+
+	for name, typ := range from {
+		dir := filepath.Join(repoPath, datastoreDir(cfg.Datastore, name))
+		if typ == "memds" {
+			// nothing on disk, keep an empty directory as the previous data
+			if err := os.MkdirAll(res.Previous[name], 0755); err != nil {
+				return nil, err
+			}
+		} else if err := os.Rename(dir, res.Previous[name]); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(dir+".convert", dir); err != nil {
+			return nil, err
+		}
+		setDatastoreType(cfg.Datastore, name, to)
+	}
+
+	tmp := filepath.Join(repoPath, tempConfigFilename)
+	if err := cfg.WriteFile(tmp); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, configFile); err != nil {
+		return nil, err
+	}
+	log.Infof("converted datastores %v to %s", names, to)
+	return res, nil
+}
+
+func isConvertible(name string) bool {
+	for _, n := range ConvertibleDatastores {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func datastoreType(cfg *config.DatastoreConfig, name string) string {
+	if name == BlocksDatastore {
+		return cfg.Type
+	}
+	return cfg.TypeOf(name)
+}
+
+func datastoreDir(cfg *config.DatastoreConfig, name string) string {
+	if name == BlocksDatastore {
+		return cfg.Path
+	}
+	return name
+}
+
+// setDatastoreType selects the backend `typ` for the datastore `name` without
+// changing the backend of the others.
+func setDatastoreType(cfg *config.DatastoreConfig, name string, typ string) {
+	overrides := map[string]*string{
+		chainDatastorePrefix:  &cfg.Chain,
+		metaDatastorePrefix:   &cfg.Meta,
+		walletDatastorePrefix: &cfg.Wallet,
+	}
+	if name == BlocksDatastore {
+		// the others default to the block datastore backend, pin them first
+		for _, override := range overrides {
+			if *override == "" {
+				*override = cfg.Type
+			}
+		}
+		cfg.Type = typ
+	} else {
+		*overrides[name] = typ
+	}
+	for _, override := range overrides {
+		if *override == cfg.Type {
+			*override = ""
+		}
+	}
+}
+
+// convertDatastore copies the datastore at `dir` to a `to` datastore at
+// `dir`.convert and returns the number of entries copied.
+func convertDatastore(from, to, dir string, cfg *config.DatastoreConfig) (int, error) {
+	src, err := OpenDatastore(from, dir, cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close() // nolint: errcheck
+	dst, err := OpenDatastore(to, dir+".convert", cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close() // nolint: errcheck
+
+	res, err := src.Query(query.Query{})
+	if err != nil {
+		return 0, err
+	}
+	defer res.Close() // nolint: errcheck
+
+	var batch datastore.Batch
+	n := 0
+	for r := range res.Next() {
+		if r.Error != nil {
+			return n, r.Error
+		}
+		if batch == nil {
+			if batch, err = dst.Batch(); err != nil {
+				return n, err
+			}
+		}
+		if err := batch.Put(datastore.NewKey(r.Key), r.Value); err != nil {
+			return n, err
+		}
+		n++
+		if n%convertBatchSize == 0 {
+			if err := batch.Commit(); err != nil {
+				return n, err
+			}
+			batch = nil
+		}
+	}
+	if batch != nil {
+		if err := batch.Commit(); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// convertBlockstore copies the block datastore at `dir` to a `to` block
+// datastore at `dir`.convert and returns the number of blocks copied.
+func convertBlockstore(from, to, dir string, cfg *config.DatastoreConfig) (int, error) {
+	src, err := openBlockstore(from, dir, cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close() // nolint: errcheck
+	dst, err := openBlockstore(to, dir+".convert", cfg)
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close() // nolint: errcheck
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	keys, err := src.AllKeysChan(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := make([]blocks.Block, 0, convertBatchSize)
+	n := 0
+	for c := range keys {
+		blk, err := src.Get(c)
+		if err != nil {
+			return n, errors.Wrapf(err, "failed to read block %s", c)
+		}
+		pending = append(pending, blk)
+		if len(pending) == convertBatchSize {
+			if err := dst.PutMany(pending); err != nil {
+				return n, err
+			}
+			n += len(pending)
+			pending = pending[:0]
+		}
+	}
+	if err := dst.PutMany(pending); err != nil {
+		return n, err
+	}
+	n += len(pending)
+
+	// a key the blockstore fails to parse is skipped by AllKeysChan, make sure
+	// nothing was left behind before the repo switches to the copy
+	total, err := countBlocks(src)
+	if err != nil {
+		return n, err
+	}
+	if n != total {
+		return n, errors.Errorf("copied %d of the %d entries of the block datastore", n, total)
+	}
+	return n, nil
+}
+
+// countBlocks returns the number of entries stored in the block datastore.
+func countBlocks(bs blockstoreCloser) (int, error) {
+	switch bs := bs.(type) {
+	case *blockstoreutil.BadgerBlockstore:
+		n := 0
+		err := bs.DB.View(func(txn *badger.Txn) error {
+			iter := txn.NewIterator(badger.IteratorOptions{})
+			defer iter.Close()
+			for iter.Rewind(); iter.Valid(); iter.Next() {
+				n++
+			}
+			return nil
+		})
+		return n, err
+	case *closingBlockstore:
+		res, err := bs.ds.Query(query.Query{KeysOnly: true})
+		if err != nil {
+			return 0, err
+		}
+		defer res.Close() // nolint: errcheck
+		n := 0
+		for r := range res.Next() {
+			if r.Error != nil {
+				return n, r.Error
+			}
+			n++
+		}
+		return n, nil
+	default:
+		return 0, errors.Errorf("unexpected block datastore %T", bs)
+	}
+}
@@ -0,0 +1,77 @@
+package repo
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	blocks "github.com/ipfs/go-block-format"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/config"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+func TestConvertFSRepo(t *testing.T) {
+	tf.UnitTest(t)
+
+	container, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer RequireRemoveAll(t, container)
+	repoPath := path.Join(container, "repo")
+
+	key := ds.NewKey("/key")
+	blk := blocks.NewBlock([]byte("block"))
+	require.NoError(t, InitFSRepo(repoPath, Version, config.NewDefaultConfig()))
+	r, err := OpenFSRepo(repoPath, Version)
+	require.NoError(t, err)
+	require.NoError(t, r.ChainDatastore().Put(key, []byte("chain")))
+	require.NoError(t, r.MetaDatastore().Put(key, []byte("meta")))
+	require.NoError(t, r.Datastore().Put(blk))
+	require.NoError(t, r.Close())
+
+	res, err := ConvertFSRepo(repoPath, "levelds", []string{BlocksDatastore, chainDatastorePrefix})
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Entries[BlocksDatastore])
+	assert.Equal(t, 1, res.Entries[chainDatastorePrefix])
+	assert.NotContains(t, res.Entries, metaDatastorePrefix)
+	_, err = os.Stat(res.Previous[chainDatastorePrefix])
+	require.NoError(t, err)
+
+	r, err = OpenFSRepo(repoPath, Version)
+	require.NoError(t, err)
+	dsCfg := r.Config().Datastore
+	assert.Equal(t, "levelds", dsCfg.Type)
+	assert.Equal(t, "levelds", dsCfg.TypeOf(chainDatastorePrefix))
+	// the datastores left out keep their backend
+	assert.Equal(t, "badgerds", dsCfg.TypeOf(metaDatastorePrefix))
+	assert.Equal(t, "badgerds", dsCfg.TypeOf(walletDatastorePrefix))
+
+	value, err := r.ChainDatastore().Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("chain"), value)
+	value, err = r.MetaDatastore().Get(key)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("meta"), value)
+	got, err := r.Datastore().Get(blk.Cid())
+	require.NoError(t, err)
+	assert.Equal(t, blk.RawData(), got.RawData())
+	require.NoError(t, r.Close())
+
+	// converting back keeps no override
+	_, err = ConvertFSRepo(repoPath, "badgerds", []string{BlocksDatastore, chainDatastorePrefix})
+	require.NoError(t, err)
+	cfg, err := config.ReadFile(path.Join(repoPath, configFilename))
+	require.NoError(t, err)
+	assert.Equal(t, config.NewDefaultConfig().Datastore, cfg.Datastore)
+
+	// the previous data of a datastore is never overwritten
+	_, err = ConvertFSRepo(repoPath, "levelds", []string{chainDatastorePrefix})
+	assert.Error(t, err)
+
+	_, err = ConvertFSRepo(repoPath, "memds", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"fmt"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	badgerds "github.com/ipfs/go-ds-badger2"
+	levelds "github.com/ipfs/go-ds-leveldb"
+	bstore "github.com/ipfs/go-ipfs-blockstore"
+
+	"github.com/filecoin-project/venus/pkg/config"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
+)
+
+// DatastoreBackend opens the datastore of one backend type at `path`.
+type DatastoreBackend func(path string, cfg *config.DatastoreConfig) (Datastore, error)
+
+var datastoreBackends = map[string]DatastoreBackend{
+	"badgerds": openBadgerDatastore,
+	"levelds":  openLevelDatastore,
+	"memds":    openMemDatastore,
+}
+
+// RegisterDatastoreBackend makes the backend selectable by `name` in the
+// datastore config.
+func RegisterDatastoreBackend(name string, backend DatastoreBackend) {
+	datastoreBackends[name] = backend
+	for _, typ := range config.DatastoreTypes {
+		if typ == name {
+			return
+		}
+	}
+	config.DatastoreTypes = append(config.DatastoreTypes, name)
+}
+
+// OpenDatastore opens the datastore of backend `typ` at `path`.
+func OpenDatastore(typ, path string, cfg *config.DatastoreConfig) (Datastore, error) {
+	backend, ok := datastoreBackends[typ]
+	if !ok {
+		return nil, fmt.Errorf("unknown datastore type in config: %s", typ)
+	}
+	return backend(path, cfg)
+}
+
+// closingBlockstore is a blockstore over a datastore that closes the
+// datastore with it.
+type closingBlockstore struct {
+	blockstoreutil.Blockstore
+	ds Datastore
+}
+
+func (b *closingBlockstore) Close() error {
+	return b.ds.Close()
+}
+
+// blockstoreCloser is the block datastore of a repo.
+type blockstoreCloser interface {
+	blockstoreutil.Blockstore
+	Close() error
+}
+
+// openBlockstore opens the block datastore of backend `typ` at `path`. Badger
+// is opened directly to keep zero-copy reads, other backends are wrapped.
+func openBlockstore(typ, path string, cfg *config.DatastoreConfig) (blockstoreCloser, error) {
+	if typ == "badgerds" {
+		opts, err := blockstoreutil.BadgerBlockstoreOptions(path, false)
+		if err != nil {
+			return nil, err
+		}
+		opts.Prefix = bstore.BlockPrefix.String()
+		applyBadgerConfig(&opts.Options, cfg.Badger)
+		return blockstoreutil.Open(opts)
+	}
+
+	ds, err := OpenDatastore(typ, path, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &closingBlockstore{Blockstore: blockstoreutil.NewBlockstore(ds), ds: ds}, nil
+}
+
+func openBadgerDatastore(path string, cfg *config.DatastoreConfig) (Datastore, error) {
+	opts := badgerOptions()
+	applyBadgerConfig(&opts.Options, cfg.Badger)
+	return badgerds.NewDatastore(path, opts)
+}
+
+func openLevelDatastore(path string, _ *config.DatastoreConfig) (Datastore, error) {
+	return levelds.NewDatastore(path, nil)
+}
+
+func openMemDatastore(path string, _ *config.DatastoreConfig) (Datastore, error) {
+	log.Warnf("datastore %s is kept in memory, its content is lost on exit", path)
+	return dssync.MutexWrap(datastore.NewMapDatastore()), nil
+}
+
+func badgerOptions() *badgerds.Options {
+	result := badgerds.DefaultOptions
+	result.Truncate = true
+	result.MaxTableSize = 64 << 21
+	return &result
+}
+
+// applyBadgerConfig overrides the badger options set in the config.
+func applyBadgerConfig(opts *badger.Options, cfg *config.BadgerConfig) {
+	if cfg == nil {
+		return
+	}
+	opts.Truncate = cfg.Truncate
+	opts.SyncWrites = cfg.SyncWrites
+	if cfg.MaxTableSize > 0 {
+		opts.MaxTableSize = cfg.MaxTableSize
+	}
+	if cfg.ValueLogFileSize > 0 {
+		opts.ValueLogFileSize = cfg.ValueLogFileSize
+	}
+	if cfg.NumCompactors > 0 {
+		opts.NumCompactors = cfg.NumCompactors
+	}
+}
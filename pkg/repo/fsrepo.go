@@ -3,7 +3,6 @@ package repo
 import (
 	"fmt"
 	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
-	bstore "github.com/ipfs/go-ipfs-blockstore"
 	"io"
 	"io/ioutil"
 	"os"
@@ -52,7 +51,7 @@ type FSRepo struct {
 	lk  sync.RWMutex
 	cfg *config.Config
 
-	ds        *blockstoreutil.BadgerBlockstore
+	ds        blockstoreCloser
 	stagingDs Datastore
 	mds       *multistore.MultiStore
 	keystore  keystore.Keystore
@@ -218,6 +217,9 @@ func (r *FSRepo) loadFromDisk() error {
 	if localVersion > r.version {
 		return fmt.Errorf("binary needs update to handle repo version, got %d expected %d. Update binary to latest release", localVersion, Version)
 	}
+	if localVersion < r.version {
+		return &ErrMigrationNeeded{Have: localVersion, Want: r.version}
+	}
 
 	if err := r.loadConfig(); err != nil {
 		return errors.Wrap(err, "failed to load config file")
@@ -396,42 +398,21 @@ func (r *FSRepo) loadConfig() error {
 
 // readVersion reads the repo's version file (but does not change r.version).
 func (r *FSRepo) readVersion() (uint, error) {
-	content, err := ReadVersion(r.path)
-	if err != nil {
-		return 0, err
-	}
-
-	version, err := strconv.Atoi(content)
-	if err != nil {
-		return 0, errors.New("corrupt version file: version is not an integer")
-	}
-
-	return uint(version), nil
+	return readVersionAt(r.path)
 }
 
 func (r *FSRepo) openDatastore() error {
-	switch r.cfg.Datastore.Type {
-	case "badgerds":
-		path := filepath.Join(r.path, r.cfg.Datastore.Path)
-		opts, err := blockstoreutil.BadgerBlockstoreOptions(path, false)
-		if err != nil {
-			return err
-		}
-		opts.Prefix = bstore.BlockPrefix.String()
-		ds, err := blockstoreutil.Open(opts)
-		if err != nil {
-			return err
-		}
-		r.ds = ds
-	default:
-		return fmt.Errorf("unknown datastore type in config: %s", r.cfg.Datastore.Type)
+	ds, err := openBlockstore(r.cfg.Datastore.Type, filepath.Join(r.path, r.cfg.Datastore.Path), r.cfg.Datastore)
+	if err != nil {
+		return err
 	}
+	r.ds = ds
 
 	return nil
 }
 
 func (r *FSRepo) openKeystore() error {
-	ksp := filepath.Join(r.path, "keystore")
+	ksp := filepath.Join(r.path, keystorePrefix)
 
 	ks, err := keystore.NewFSKeystore(ksp)
 	if err != nil {
@@ -444,7 +425,7 @@ func (r *FSRepo) openKeystore() error {
 }
 
 func (r *FSRepo) openChainDatastore() error {
-	ds, err := badgerds.NewDatastore(filepath.Join(r.path, chainDatastorePrefix), badgerOptions())
+	ds, err := r.openDatastoreAt(chainDatastorePrefix)
 	if err != nil {
 		return err
 	}
@@ -455,7 +436,7 @@ func (r *FSRepo) openChainDatastore() error {
 }
 
 func (r *FSRepo) openMetaDatastore() error {
-	ds, err := badgerds.NewDatastore(filepath.Join(r.path, metaDatastorePrefix), badgerOptions())
+	ds, err := r.openDatastoreAt(metaDatastorePrefix)
 	if err != nil {
 		return err
 	}
@@ -466,8 +447,7 @@ func (r *FSRepo) openMetaDatastore() error {
 }
 
 func (r *FSRepo) openWalletDatastore() error {
-	// TODO: read wallet datastore info from config, use that to open it up
-	ds, err := badgerds.NewDatastore(filepath.Join(r.path, walletDatastorePrefix), badgerOptions())
+	ds, err := r.openDatastoreAt(walletDatastorePrefix)
 	if err != nil {
 		return err
 	}
@@ -477,6 +457,12 @@ func (r *FSRepo) openWalletDatastore() error {
 	return nil
 }
 
+// openDatastoreAt opens the datastore in the repo directory `name` with the
+// backend the config selects for it.
+func (r *FSRepo) openDatastoreAt(name string) (Datastore, error) {
+	return OpenDatastore(r.cfg.Datastore.TypeOf(name), filepath.Join(r.path, name), r.cfg.Datastore)
+}
+
 func (r *FSRepo) openMultiStore() error {
 	var err error
 	r.stagingDs, err = badgerds.NewDatastore(filepath.Join(r.path, "/staging"), badgerOptions())
@@ -621,9 +607,9 @@ func (r *FSRepo) Path() (string, error) {
 	return r.path, nil
 }
 
-// JournalPath returns the path the journal is at.
+// JournalPath returns the directory of the rotating journal files.
 func (r *FSRepo) JournalPath() string {
-	return fmt.Sprintf("%s/journal.json", r.path)
+	return filepath.Join(r.path, "journal")
 }
 
 // APIAddrFromRepoPath returns the api addr from the filecoin repo
@@ -659,13 +645,6 @@ func (r *FSRepo) SetAPIToken(token []byte) error {
 	return ioutil.WriteFile(filepath.Join(r.path, "token"), token, 0600)
 }
 
-func badgerOptions() *badgerds.Options {
-	result := &badgerds.DefaultOptions
-	result.Truncate = true
-	result.MaxTableSize = 64 << 21
-	return result
-}
-
 func (r *FSRepo) Repo() Repo {
 	return r
 }
@@ -0,0 +1,530 @@
+package repo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/ipfs/go-datastore"
+	lockfile "github.com/ipfs/go-fs-lock"
+	keystore "github.com/ipfs/go-ipfs-keystore"
+	ci "github.com/libp2p/go-libp2p-core/crypto"
+	"github.com/mitchellh/go-homedir"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/pkg/config"
+)
+
+const (
+	keystorePrefix = "keystore"
+	backupsPrefix  = "backups"
+)
+
+// ErrMigrationNeeded is returned when opening a repo older than the binary,
+// the repo has to be migrated with MigrateFSRepo first.
+type ErrMigrationNeeded struct {
+	Have uint
+	Want uint
+}
+
+func (e *ErrMigrationNeeded) Error() string {
+	return fmt.Sprintf("repo version %d is older than version %d of the binary, run 'venus repo migrate'", e.Have, e.Want)
+}
+
+// DatastoreMigration migrates a datastore. Writes go to `batch`, which is
+// only committed when every step of the migration succeeds.
+type DatastoreMigration func(ds datastore.Datastore, batch datastore.Batch) error
+
+// Migration upgrades a repo from Version to Version+1. Steps that are nil
+// leave their store untouched.
+type Migration struct {
+	Version     uint
+	Description string
+
+	Config          func(cfg *config.Config) error
+	ChainDatastore  DatastoreMigration
+	MetaDatastore   DatastoreMigration
+	WalletDatastore DatastoreMigration
+	// Keystore migrates the keystore, its writes are only applied when every
+	// step of the migration succeeds.
+	Keystore func(ks keystore.Keystore) error
+}
+
+// datastores returns the datastore steps of the migration by directory.
+func (m Migration) datastores() map[string]DatastoreMigration {
+	steps := map[string]DatastoreMigration{}
+	if m.ChainDatastore != nil {
+		steps[chainDatastorePrefix] = m.ChainDatastore
+	}
+	if m.MetaDatastore != nil {
+		steps[metaDatastorePrefix] = m.MetaDatastore
+	}
+	if m.WalletDatastore != nil {
+		steps[walletDatastorePrefix] = m.WalletDatastore
+	}
+	return steps
+}
+
+// Migrations is a migration registry keyed by the version a migration
+// upgrades from.
+type Migrations map[uint]Migration
+
+// DefaultMigrations are the migrations up to the repo Version of this binary.
+var DefaultMigrations = Migrations{
+	2: mpoolDefaultsMigration,
+}
+
+// Register adds `m` to the registry, there can only be one migration from a
+// version.
+func (ms Migrations) Register(m Migration) {
+	if _, ok := ms[m.Version]; ok {
+		panic(fmt.Sprintf("duplicate repo migration from version %d", m.Version))
+	}
+	ms[m.Version] = m
+}
+
+// plan returns the migrations from version `from` to version `to`.
+func (ms Migrations) plan(from, to uint) ([]Migration, error) {
+	var out []Migration
+	for v := from; v < to; v++ {
+		m, ok := ms[v]
+		if !ok {
+			return nil, errors.Errorf("no migration from repo version %d", v)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// MigrationReport describes a migration run over a repo.
+type MigrationReport struct {
+	From        uint
+	To          uint
+	Description string
+	// Changes counts the writes and deletes by store.
+	Changes       map[string]int
+	ConfigChanged bool
+}
+
+// MigrationResult is the outcome of MigrateFSRepo.
+type MigrationResult struct {
+	From    uint
+	To      uint
+	DryRun  bool
+	Backup  string
+	Reports []*MigrationReport
+}
+
+// NeedsMigration returns the version of the repo at `repoPath` and whether it
+// is older than `version`.
+func NeedsMigration(repoPath string, version uint) (uint, bool, error) {
+	repoPath, err := resolveRepoPath(repoPath)
+	if err != nil {
+		return 0, false, err
+	}
+	have, err := readVersionAt(repoPath)
+	if err != nil {
+		return 0, false, err
+	}
+	return have, have < version, nil
+}
+
+// MigrateFSRepo upgrades the closed repo at `repoPath` to `version`.
+//
+// The config, the keystore and the datastores touched by the migrations are
+// copied to the backups directory of the repo first. Each migration runs all
+// its steps before committing any of them, and the backup is restored when a
+// commit fails. With `dryRun` the migrations run but nothing is written; note
+// that the steps of a migration then see the data as it was before the
+// previous migrations.
+func MigrateFSRepo(repoPath string, version uint, migrations Migrations, dryRun bool) (*MigrationResult, error) {
+	repoPath, err := resolveRepoPath(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := lockfile.Lock(repoPath, lockFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to take repo lock, is the daemon running?")
+	}
+	defer lock.Close() // nolint: errcheck
+
+	have, err := readVersionAt(repoPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read version")
+	}
+	if have > version {
+		return nil, fmt.Errorf("binary needs update to handle repo version, got %d expected %d. Update binary to latest release", have, version)
+	}
+
+	res := &MigrationResult{From: have, To: version, DryRun: dryRun}
+	if have == version {
+		return res, nil
+	}
+	plan, err := migrations.plan(have, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dryRun {
+		if res.Backup, err = backupRepo(repoPath, have, plan); err != nil {
+			return nil, errors.Wrap(err, "failed to back up repo")
+		}
+		log.Infof("backed up repo version %d to %s", have, res.Backup)
+	}
+
+	for _, m := range plan {
+		report, err := runMigration(repoPath, m, dryRun)
+		if err != nil {
+			if !dryRun {
+				if rerr := restoreRepo(repoPath, res.Backup); rerr != nil {
+					return nil, errors.Wrapf(err, "migration from version %d failed and the backup %s could not be restored: %s", m.Version, res.Backup, rerr)
+				}
+			}
+			return nil, errors.Wrapf(err, "migration from version %d failed", m.Version)
+		}
+		res.Reports = append(res.Reports, report)
+	}
+	return res, nil
+}
+
+// runMigration runs the steps of `m` and commits them unless `dryRun` is set.
+func runMigration(repoPath string, m Migration, dryRun bool) (*MigrationReport, error) {
+	report := &MigrationReport{
+		From:        m.Version,
+		To:          m.Version + 1,
+		Description: m.Description,
+		Changes:     map[string]int{},
+	}
+
+	// config
+	configFile := filepath.Join(repoPath, configFilename)
+	cfg, err := config.ReadFile(configFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config")
+	}
+	if m.Config != nil {
+		before, err := json.Marshal(cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.Config(cfg); err != nil {
+			return nil, errors.Wrap(err, "failed to migrate config")
+		}
+		after, err := json.Marshal(cfg)
+		if err != nil {
+			return nil, err
+		}
+		report.ConfigChanged = !bytes.Equal(before, after)
+	}
+
+	// datastores
+	type stagedDatastore struct {
+		ds    Datastore
+		batch *countingBatch
+	}
+	staged := map[string]*stagedDatastore{}
+	defer func() {
+		for _, s := range staged {
+			_ = s.ds.Close()
+		}
+	}()
+	steps := m.datastores()
+	names := make([]string, 0, len(steps))
+	for name := range steps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ds, err := OpenDatastore(cfg.Datastore.TypeOf(name), filepath.Join(repoPath, name), cfg.Datastore)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open %s datastore", name)
+		}
+		batch, err := ds.Batch()
+		if err != nil {
+			_ = ds.Close()
+			return nil, err
+		}
+		s := &stagedDatastore{ds: ds, batch: &countingBatch{Batch: batch}}
+		staged[name] = s
+		if err := steps[name](ds, s.batch); err != nil {
+			return nil, errors.Wrapf(err, "failed to migrate %s datastore", name)
+		}
+		report.Changes[name] = s.batch.changes
+	}
+
+	// keystore
+	var ks *stagedKeystore
+	if m.Keystore != nil {
+		base, err := keystore.NewFSKeystore(filepath.Join(repoPath, keystorePrefix))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open keystore")
+		}
+		ks = newStagedKeystore(base)
+		if err := m.Keystore(ks); err != nil {
+			return nil, errors.Wrap(err, "failed to migrate keystore")
+		}
+		report.Changes[keystorePrefix] = ks.changes()
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	// every step succeeded, commit
+	for _, name := range names {
+		if err := staged[name].batch.Commit(); err != nil {
+			return nil, errors.Wrapf(err, "failed to commit %s datastore", name)
+		}
+	}
+	if ks != nil {
+		if err := ks.commit(); err != nil {
+			return nil, errors.Wrap(err, "failed to commit keystore")
+		}
+	}
+	if report.ConfigChanged {
+		tmp := filepath.Join(repoPath, tempConfigFilename)
+		if err := cfg.WriteFile(tmp); err != nil {
+			return nil, err
+		}
+		if err := os.Rename(tmp, configFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := WriteVersion(repoPath, report.To); err != nil {
+		return nil, err
+	}
+	log.Infof("migrated repo from version %d to %d: %s", report.From, report.To, report.Description)
+	return report, nil
+}
+
+// backupRepo copies the version, the config, the keystore and the datastores
+// touched by `plan` to a new directory under the backups directory.
+func backupRepo(repoPath string, version uint, plan []Migration) (string, error) {
+	entries := map[string]struct{}{
+		versionFilename: {},
+		configFilename:  {},
+		keystorePrefix:  {},
+	}
+	for _, m := range plan {
+		for name := range m.datastores() {
+			entries[name] = struct{}{}
+		}
+	}
+
+	backup := filepath.Join(repoPath, backupsPrefix, MakeRepoDirName("migration", time.Now(), version, 0))
+	if err := os.MkdirAll(backup, 0755); err != nil {
+		return "", err
+	}
+	for name := range entries {
+		src := filepath.Join(repoPath, name)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := copyPath(src, filepath.Join(backup, name)); err != nil {
+			return "", errors.Wrapf(err, "failed to back up %s", name)
+		}
+	}
+	return backup, nil
+}
+
+// restoreRepo replaces the entries of the repo saved in `backup`.
+func restoreRepo(repoPath, backup string) error {
+	infos, err := ioutil.ReadDir(backup)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		dst := filepath.Join(repoPath, info.Name())
+		if err := os.RemoveAll(dst); err != nil {
+			return err
+		}
+		if err := copyPath(filepath.Join(backup, info.Name()), dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyPath copies the file or directory `src` to `dst`.
+func copyPath(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, info.Mode())
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close() // nolint: errcheck
+		out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			_ = out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+func resolveRepoPath(repoPath string) (string, error) {
+	repoPath, err := homedir.Expand(repoPath)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Lstat(repoPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to stat repo %s", repoPath)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return os.Readlink(repoPath)
+	}
+	return repoPath, nil
+}
+
+func readVersionAt(repoPath string) (uint, error) {
+	content, err := ReadVersion(repoPath)
+	if err != nil {
+		return 0, err
+	}
+	version, err := strconv.Atoi(content)
+	if err != nil {
+		return 0, errors.New("corrupt version file: version is not an integer")
+	}
+	return uint(version), nil
+}
+
+// countingBatch counts the writes and deletes of a batch.
+type countingBatch struct {
+	datastore.Batch
+	changes int
+}
+
+func (b *countingBatch) Put(key datastore.Key, value []byte) error {
+	b.changes++
+	return b.Batch.Put(key, value)
+}
+
+func (b *countingBatch) Delete(key datastore.Key) error {
+	b.changes++
+	return b.Batch.Delete(key)
+}
+
+// stagedKeystore records the writes to a keystore so that they are applied
+// only on commit, reads see the staged writes.
+type stagedKeystore struct {
+	base    keystore.Keystore
+	puts    map[string]ci.PrivKey
+	deletes map[string]struct{}
+}
+
+var _ keystore.Keystore = (*stagedKeystore)(nil)
+
+func newStagedKeystore(base keystore.Keystore) *stagedKeystore {
+	return &stagedKeystore{
+		base:    base,
+		puts:    map[string]ci.PrivKey{},
+		deletes: map[string]struct{}{},
+	}
+}
+
+func (ks *stagedKeystore) Has(name string) (bool, error) {
+	if _, ok := ks.puts[name]; ok {
+		return true, nil
+	}
+	if _, ok := ks.deletes[name]; ok {
+		return false, nil
+	}
+	return ks.base.Has(name)
+}
+
+func (ks *stagedKeystore) Put(name string, k ci.PrivKey) error {
+	delete(ks.deletes, name)
+	ks.puts[name] = k
+	return nil
+}
+
+func (ks *stagedKeystore) Get(name string) (ci.PrivKey, error) {
+	if k, ok := ks.puts[name]; ok {
+		return k, nil
+	}
+	if _, ok := ks.deletes[name]; ok {
+		return nil, keystore.ErrNoSuchKey
+	}
+	return ks.base.Get(name)
+}
+
+func (ks *stagedKeystore) Delete(name string) error {
+	delete(ks.puts, name)
+	ks.deletes[name] = struct{}{}
+	return nil
+}
+
+func (ks *stagedKeystore) List() ([]string, error) {
+	names, err := ks.base.List()
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, name := range names {
+		if _, ok := ks.deletes[name]; ok {
+			continue
+		}
+		if _, ok := ks.puts[name]; ok {
+			continue
+		}
+		out = append(out, name)
+	}
+	for name := range ks.puts {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (ks *stagedKeystore) changes() int {
+	return len(ks.puts) + len(ks.deletes)
+}
+
+func (ks *stagedKeystore) commit() error {
+	for name := range ks.deletes {
+		if err := ks.base.Delete(name); err != nil && err != keystore.ErrNoSuchKey {
+			return err
+		}
+	}
+	for name, k := range ks.puts {
+		// the fs keystore refuses to overwrite keys
+		if has, err := ks.base.Has(name); err != nil {
+			return err
+		} else if has {
+			if err := ks.base.Delete(name); err != nil {
+				return err
+			}
+		}
+		if err := ks.base.Put(name, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package repo
+
+import (
+	"errors"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"testing"
+
+	ds "github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/config"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+)
+
+func TestMigrateFSRepo(t *testing.T) {
+	tf.UnitTest(t)
+
+	oldKey := ds.NewKey("/old")
+	newKey := ds.NewKey("/new")
+	migrations := Migrations{}
+	migrations.Register(Migration{
+		Version:     1,
+		Description: "rename /old to /new",
+		Config: func(cfg *config.Config) error {
+			cfg.API.APIAddress = "/ip4/127.0.0.1/tcp/1234"
+			return nil
+		},
+		MetaDatastore: func(d ds.Datastore, batch ds.Batch) error {
+			value, err := d.Get(oldKey)
+			if err != nil {
+				return err
+			}
+			if err := batch.Put(newKey, value); err != nil {
+				return err
+			}
+			return batch.Delete(oldKey)
+		},
+	})
+
+	// initRepo creates a repo at version 1 holding /old in the meta datastore.
+	initRepo := func(t *testing.T) string {
+		container, err := ioutil.TempDir("", "")
+		require.NoError(t, err)
+		repoPath := path.Join(container, "repo")
+
+		require.NoError(t, InitFSRepo(repoPath, 1, config.NewDefaultConfig()))
+		r, err := OpenFSRepo(repoPath, 1)
+		require.NoError(t, err)
+		require.NoError(t, r.MetaDatastore().Put(oldKey, []byte("value")))
+		require.NoError(t, r.Close())
+		return container
+	}
+
+	t.Run("outdated repo does not open", func(t *testing.T) {
+		container := initRepo(t)
+		defer RequireRemoveAll(t, container)
+
+		_, err := OpenFSRepo(path.Join(container, "repo"), 2)
+		var needed *ErrMigrationNeeded
+		require.True(t, errors.As(err, &needed))
+		assert.Equal(t, uint(1), needed.Have)
+		assert.Equal(t, uint(2), needed.Want)
+	})
+
+	t.Run("dry run reports without writing", func(t *testing.T) {
+		container := initRepo(t)
+		defer RequireRemoveAll(t, container)
+		repoPath := path.Join(container, "repo")
+
+		res, err := MigrateFSRepo(repoPath, 2, migrations, true)
+		require.NoError(t, err)
+		assert.Empty(t, res.Backup)
+		require.Len(t, res.Reports, 1)
+		assert.True(t, res.Reports[0].ConfigChanged)
+		assert.Equal(t, 2, res.Reports[0].Changes[metaDatastorePrefix])
+
+		r, err := OpenFSRepo(repoPath, 1)
+		require.NoError(t, err)
+		defer r.Close() // nolint: errcheck
+		has, err := r.MetaDatastore().Has(oldKey)
+		require.NoError(t, err)
+		assert.True(t, has)
+		assert.Equal(t, config.NewDefaultConfig().API.APIAddress, r.Config().API.APIAddress)
+	})
+
+	t.Run("migrates config and datastores", func(t *testing.T) {
+		container := initRepo(t)
+		defer RequireRemoveAll(t, container)
+		repoPath := path.Join(container, "repo")
+
+		res, err := MigrateFSRepo(repoPath, 2, migrations, false)
+		require.NoError(t, err)
+		assert.FileExists(t, filepath.Join(res.Backup, configFilename))
+		assert.DirExists(t, filepath.Join(res.Backup, metaDatastorePrefix))
+
+		r, err := OpenFSRepo(repoPath, 2)
+		require.NoError(t, err)
+		defer r.Close() // nolint: errcheck
+		value, err := r.MetaDatastore().Get(newKey)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("value"), value)
+		has, err := r.MetaDatastore().Has(oldKey)
+		require.NoError(t, err)
+		assert.False(t, has)
+		assert.Equal(t, "/ip4/127.0.0.1/tcp/1234", r.Config().API.APIAddress)
+	})
+
+	t.Run("failed migration leaves the repo untouched", func(t *testing.T) {
+		container := initRepo(t)
+		defer RequireRemoveAll(t, container)
+		repoPath := path.Join(container, "repo")
+
+		failing := Migrations{}
+		failing.Register(Migration{
+			Version: 1,
+			MetaDatastore: func(d ds.Datastore, batch ds.Batch) error {
+				if err := batch.Delete(oldKey); err != nil {
+					return err
+				}
+				return errors.New("boom")
+			},
+		})
+		_, err := MigrateFSRepo(repoPath, 2, failing, false)
+		assert.Error(t, err)
+
+		r, err := OpenFSRepo(repoPath, 1)
+		require.NoError(t, err)
+		defer r.Close() // nolint: errcheck
+		has, err := r.MetaDatastore().Has(oldKey)
+		require.NoError(t, err)
+		assert.True(t, has)
+	})
+
+	t.Run("missing migration", func(t *testing.T) {
+		container := initRepo(t)
+		defer RequireRemoveAll(t, container)
+
+		_, err := MigrateFSRepo(path.Join(container, "repo"), 3, migrations, true)
+		assert.EqualError(t, err, "no migration from repo version 2")
+	})
+}
+
+func TestMpoolDefaultsMigration(t *testing.T) {
+	tf.UnitTest(t)
+
+	container, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer RequireRemoveAll(t, container)
+	repoPath := path.Join(container, "repo")
+
+	cfg := config.NewDefaultConfig()
+	cfg.Mpool.MaxPoolSize = oldMaxPoolSize
+	cfg.Mpool.MaxNonceGap = oldMaxNonceGap
+	require.NoError(t, InitFSRepo(repoPath, 2, cfg))
+
+	res, err := MigrateFSRepo(repoPath, Version, DefaultMigrations, false)
+	require.NoError(t, err)
+	require.Len(t, res.Reports, 1)
+	assert.True(t, res.Reports[0].ConfigChanged)
+
+	r, err := OpenFSRepo(repoPath, Version)
+	require.NoError(t, err)
+	assert.Equal(t, config.NewDefaultConfig().Mpool, r.Config().Mpool)
+	require.NoError(t, r.Close())
+
+	// values set by the user are kept
+	require.NoError(t, mpoolDefaultsMigration.Config(cfg))
+	cfg.Mpool.MaxPoolSize = 50000
+	require.NoError(t, mpoolDefaultsMigration.Config(cfg))
+	assert.Equal(t, uint(50000), cfg.Mpool.MaxPoolSize)
+}
@@ -0,0 +1,33 @@
+package repo
+
+import (
+	"github.com/filecoin-project/venus/pkg/config"
+)
+
+// The mpool defaults written to the configs of version 2, when the pool
+// ignored them.
+const (
+	oldMaxPoolSize = 1000000
+	oldMaxNonceGap = 100
+)
+
+// mpoolDefaultsMigration replaces the old mpool defaults of the config by the
+// current ones, now that the pool applies them. Values the user changed are
+// kept.
+var mpoolDefaultsMigration = Migration{
+	Version:     2,
+	Description: "replace the mpool defaults the pool ignored by its own",
+	Config: func(cfg *config.Config) error {
+		if cfg.Mpool == nil {
+			return nil
+		}
+		defaults := config.NewDefaultConfig().Mpool
+		if cfg.Mpool.MaxPoolSize == oldMaxPoolSize {
+			cfg.Mpool.MaxPoolSize = defaults.MaxPoolSize
+		}
+		if cfg.Mpool.MaxNonceGap == oldMaxNonceGap {
+			cfg.Mpool.MaxNonceGap = defaults.MaxNonceGap
+		}
+		return nil
+	},
+}
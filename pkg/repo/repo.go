@@ -9,7 +9,7 @@ import (
 )
 
 // Version is the version of repo schema that this code understands.
-const Version uint = 2
+const Version uint = 3
 
 // Datastore is the datastore interface provided by the repo
 type Datastore interface {
@@ -54,7 +54,7 @@ type Repo interface {
 	// Path returns the repo path.
 	Path() (string, error)
 
-	// JournalPath returns the journal path.
+	// JournalPath returns the directory the journal files are kept in.
 	JournalPath() string
 
 	// Close shuts down the repo.
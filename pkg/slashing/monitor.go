@@ -23,6 +23,10 @@ type ConsensusFault struct {
 	// Block1 and Block2 are two distinct blocks from an overlapping interval
 	// signed by the same miner
 	Block1, Block2 *block.Block
+	// Extra is the witness of a parent-grinding fault, a block of the parent
+	// tipset of Block2 that has the same parents as Block1. It is nil for the
+	// other faults.
+	Extra *block.Block
 }
 
 // NewConsensusFaultDetector returns a fault detector given a fault channel
@@ -53,6 +57,17 @@ func (detector *ConsensusFaultDetector) CheckBlock(b *block.Block, p *block.TipS
 		detector.minerIndex[b.Miner] = blockByEpoch
 	}
 
+	// Parent grinding: the miner produced a block at the parent epoch that
+	// could have been included in the parent tipset but was left out
+	if own, tracked := blockByEpoch[parentHeight]; tracked && own.Height == parentHeight && !p.Key().Has(own.Cid()) {
+		for _, sibling := range p.Blocks() {
+			if sibling.Parents.Equals(own.Parents) {
+				detector.faultCh <- ConsensusFault{Block1: own, Block2: b, Extra: sibling}
+				break
+			}
+		}
+	}
+
 	// Add this epoch to the miner's index, emitting any detected faults
 	for e := earliest; e <= latest; e++ {
 		collision, tracked := blockByEpoch[e]
@@ -62,7 +77,7 @@ func (detector *ConsensusFaultDetector) CheckBlock(b *block.Block, p *block.TipS
 				continue
 			}
 			// Emit all faults, any special handling of duplicates belongs downstream
-			detector.faultCh <- ConsensusFault{b, collision}
+			detector.faultCh <- ConsensusFault{Block1: b, Block2: collision}
 		}
 		// In case of collision overwrite with most recent
 		blockByEpoch[e] = b
@@ -140,3 +140,44 @@ func TestFaultNullBlocks(t *testing.T) {
 		}
 	})
 }
+
+func TestFaultParentGrinding(t *testing.T) {
+	tf.UnitTest(t)
+	addrGetter := types.NewForTestGetter()
+	minerAddr1 := addrGetter()
+	minerAddr2 := addrGetter()
+
+	mockCid := types.CidFromString(t, "mock")
+
+	grandParentBlock := &block.Block{Height: 41, Miner: minerAddr2, Messages: mockCid, ParentMessageReceipts: mockCid, ParentStateRoot: mockCid}
+	grandParentTipSet := block.RequireNewTipSet(t, grandParentBlock)
+
+	ownBlock := &block.Block{Miner: minerAddr1, Height: 42, Parents: grandParentTipSet.Key(), Messages: mockCid, ParentMessageReceipts: mockCid, ParentStateRoot: mockCid}
+	siblingBlock := &block.Block{Miner: minerAddr2, Height: 42, Parents: grandParentTipSet.Key(), Messages: mockCid, ParentMessageReceipts: mockCid, ParentStateRoot: mockCid}
+
+	t.Run("leaving out an own block of the parent epoch slashes", func(t *testing.T) {
+		parentTipSet := block.RequireNewTipSet(t, siblingBlock)
+		grindingBlock := &block.Block{Miner: minerAddr1, Height: 43, Parents: parentTipSet.Key(), Messages: mockCid, ParentMessageReceipts: mockCid, ParentStateRoot: mockCid}
+
+		faultCh := make(chan ConsensusFault, 1)
+		cfd := NewConsensusFaultDetector(faultCh)
+		assert.NoError(t, cfd.CheckBlock(ownBlock, grandParentTipSet))
+		assertEmptyCh(t, faultCh)
+		assert.NoError(t, cfd.CheckBlock(grindingBlock, parentTipSet))
+		fault := <-faultCh
+		assert.Equal(t, ownBlock, fault.Block1)
+		assert.Equal(t, grindingBlock, fault.Block2)
+		assert.Equal(t, siblingBlock, fault.Extra)
+	})
+
+	t.Run("including the own block of the parent epoch doesn't slash", func(t *testing.T) {
+		parentTipSet := block.RequireNewTipSet(t, ownBlock, siblingBlock)
+		nextBlock := &block.Block{Miner: minerAddr1, Height: 43, Parents: parentTipSet.Key(), Messages: mockCid, ParentMessageReceipts: mockCid, ParentStateRoot: mockCid}
+
+		faultCh := make(chan ConsensusFault, 1)
+		cfd := NewConsensusFaultDetector(faultCh)
+		assert.NoError(t, cfd.CheckBlock(ownBlock, grandParentTipSet))
+		assert.NoError(t, cfd.CheckBlock(nextBlock, parentTipSet))
+		assertEmptyCh(t, faultCh)
+	})
+}
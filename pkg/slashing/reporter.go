@@ -0,0 +1,226 @@
+package slashing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	runtime2 "github.com/filecoin-project/specs-actors/v2/actors/runtime"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/pkg/errors"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/specactors"
+	"github.com/filecoin-project/venus/pkg/specactors/builtin/miner"
+	"github.com/filecoin-project/venus/pkg/types"
+)
+
+var log = logging.Logger("slashing")
+
+// ReportedFault is a consensus fault that has been reported on chain.
+type ReportedFault struct {
+	Miner address.Address
+	Epoch abi.ChainEpoch
+	Type  runtime2.ConsensusFaultType
+	// Block1, Block2 and Extra are the headers passed to ReportConsensusFault,
+	// Extra is undefined unless the fault is a parent-grinding fault.
+	Block1 cid.Cid
+	Block2 cid.Cid
+	Extra  cid.Cid
+	// Message is the cid of the ReportConsensusFault message.
+	Message    cid.Cid
+	ReportedAt time.Time
+}
+
+// FaultVerifier checks that serialized headers prove a consensus fault.
+type FaultVerifier func(ctx context.Context, h1, h2, extra []byte) (*runtime2.ConsensusFault, error)
+
+type messagePusher interface {
+	MpoolPushMessage(ctx context.Context, msg *types.UnsignedMessage, spec *types.MessageSendSpec) (*types.SignedMessage, error)
+}
+
+// ConsensusFaultReporter reports the faults found by a ConsensusFaultDetector
+// to the offending miner actor. Reported faults are recorded in the datastore
+// so that each fault is reported once.
+type ConsensusFaultReporter struct {
+	from   address.Address
+	pusher messagePusher
+	verify FaultVerifier
+	// reported tracks reported faults by miner and block cids
+	reported ds.Datastore
+	lk       sync.Mutex
+}
+
+// NewConsensusFaultReporter returns a reporter sending ReportConsensusFault
+// messages from `from`.
+func NewConsensusFaultReporter(from address.Address, pusher messagePusher, verify FaultVerifier, dstore ds.Datastore) *ConsensusFaultReporter {
+	return &ConsensusFaultReporter{
+		from:     from,
+		pusher:   pusher,
+		verify:   verify,
+		reported: namespace.Wrap(dstore, ds.NewKey("/slashing/reported")),
+	}
+}
+
+// HandleFault reports `fault` in the background so that block validation is
+// not held up by message signing and pushing, failures are logged.
+func (reporter *ConsensusFaultReporter) HandleFault(ctx context.Context, fault ConsensusFault) {
+	go func() {
+		if _, err := reporter.Report(ctx, fault); err != nil {
+			log.Errorf("failed to report consensus fault of %s: %s", fault.Block1.Miner, err)
+		}
+	}()
+}
+
+// Report submits a ReportConsensusFault message for `fault`. It returns nil
+// when the fault has already been reported.
+func (reporter *ConsensusFaultReporter) Report(ctx context.Context, fault ConsensusFault) (*ReportedFault, error) {
+	// the fault checker expects the lower block first
+	b1, b2 := fault.Block1, fault.Block2
+	if b1.Height > b2.Height || (b1.Height == b2.Height && fault.Extra == nil && b1.Cid().String() > b2.Cid().String()) {
+		b1, b2 = b2, b1
+	}
+
+	reporter.lk.Lock()
+	defer reporter.lk.Unlock()
+
+	key := reportedKey(b1, b2)
+	has, err := reporter.reported.Has(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to look up reported faults")
+	}
+	if has {
+		return nil, nil
+	}
+
+	h1, err := marshalHeader(b1)
+	if err != nil {
+		return nil, err
+	}
+	h2, err := marshalHeader(b2)
+	if err != nil {
+		return nil, err
+	}
+	var extra []byte
+	extraCid := cid.Undef
+	if fault.Extra != nil {
+		if extra, err = marshalHeader(fault.Extra); err != nil {
+			return nil, err
+		}
+		extraCid = fault.Extra.Cid()
+	}
+
+	// an invalid report would only burn the gas of the reporter
+	verified, err := reporter.verify(ctx, h1, h2, extra)
+	if err != nil {
+		return nil, errors.Wrap(err, "fault does not verify")
+	}
+
+	params, aerr := specactors.SerializeParams(&miner.ReportConsensusFaultParams{
+		BlockHeader1:     h1,
+		BlockHeader2:     h2,
+		BlockHeaderExtra: extra,
+	})
+	if aerr != nil {
+		return nil, errors.Wrap(aerr, "failed to serialize params")
+	}
+
+	msg := &types.UnsignedMessage{
+		To:     b1.Miner,
+		From:   reporter.from,
+		Value:  big.Zero(),
+		Method: miner.Methods.ReportConsensusFault,
+		Params: params,
+	}
+	smsg, err := reporter.pusher.MpoolPushMessage(ctx, msg, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to push ReportConsensusFault message")
+	}
+	msgCid, err := smsg.Cid()
+	if err != nil {
+		return nil, err
+	}
+
+	reported := &ReportedFault{
+		Miner:      b1.Miner,
+		Epoch:      verified.Epoch,
+		Type:       verified.Type,
+		Block1:     b1.Cid(),
+		Block2:     b2.Cid(),
+		Extra:      extraCid,
+		Message:    msgCid,
+		ReportedAt: time.Now(),
+	}
+	data, err := json.Marshal(reported)
+	if err != nil {
+		return nil, err
+	}
+	if err := reporter.reported.Put(key, data); err != nil {
+		return nil, errors.Wrap(err, "failed to record reported fault")
+	}
+
+	log.Infof("reported consensus fault of %s at epoch %d in message %s", reported.Miner, reported.Epoch, msgCid)
+	return reported, nil
+}
+
+// List returns the reported faults ordered by epoch.
+func (reporter *ConsensusFaultReporter) List(ctx context.Context) ([]*ReportedFault, error) {
+	res, err := reporter.reported.Query(query.Query{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query reported faults")
+	}
+	defer res.Close() // nolint: errcheck
+
+	var out []*ReportedFault
+	for entry := range res.Next() {
+		if entry.Error != nil {
+			return nil, entry.Error
+		}
+		var reported ReportedFault
+		if err := json.Unmarshal(entry.Value, &reported); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode reported fault %s", entry.Key)
+		}
+		out = append(out, &reported)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Epoch < out[j].Epoch
+	})
+	return out, nil
+}
+
+// ConsensusFaultTypeName returns a readable name of a consensus fault type.
+func ConsensusFaultTypeName(faultType runtime2.ConsensusFaultType) string {
+	switch faultType {
+	case runtime2.ConsensusFaultDoubleForkMining:
+		return "double-fork mining"
+	case runtime2.ConsensusFaultParentGrinding:
+		return "parent grinding"
+	case runtime2.ConsensusFaultTimeOffsetMining:
+		return "time-offset mining"
+	default:
+		return "unknown"
+	}
+}
+
+func reportedKey(b1, b2 *block.Block) ds.Key {
+	return ds.NewKey(b1.Miner.String()).ChildString(b1.Cid().String()).ChildString(b2.Cid().String())
+}
+
+func marshalHeader(b *block.Block) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := b.MarshalCBOR(buf); err != nil {
+		return nil, errors.Wrapf(err, "failed to marshal block %s", b.Cid())
+	}
+	return buf.Bytes(), nil
+}
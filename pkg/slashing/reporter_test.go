@@ -0,0 +1,92 @@
+package slashing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	runtime2 "github.com/filecoin-project/specs-actors/v2/actors/runtime"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/crypto"
+	. "github.com/filecoin-project/venus/pkg/slashing"
+	"github.com/filecoin-project/venus/pkg/specactors/builtin/miner"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/venus/pkg/types"
+)
+
+type fakePusher struct {
+	pushed []*types.UnsignedMessage
+}
+
+func (p *fakePusher) MpoolPushMessage(_ context.Context, msg *types.UnsignedMessage, _ *types.MessageSendSpec) (*types.SignedMessage, error) {
+	msg.GasFeeCap = big.Zero()
+	msg.GasPremium = big.Zero()
+	p.pushed = append(p.pushed, msg)
+	return &types.SignedMessage{Message: *msg, Signature: crypto.Signature{Type: crypto.SigTypeBLS}}, nil
+}
+
+func TestReportConsensusFault(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+	addrGetter := types.NewForTestGetter()
+	minerAddr := addrGetter()
+	reporterAddr := addrGetter()
+
+	mockCid := types.CidFromString(t, "mock")
+	block1 := &block.Block{Miner: minerAddr, Height: 43, ParentStateRoot: types.CidFromString(t, "some-state"), Messages: mockCid, ParentMessageReceipts: mockCid}
+	block2 := &block.Block{Miner: minerAddr, Height: 43, ParentStateRoot: types.CidFromString(t, "some-other-state"), Messages: mockCid, ParentMessageReceipts: mockCid}
+
+	doubleFork := func(context.Context, []byte, []byte, []byte) (*runtime2.ConsensusFault, error) {
+		return &runtime2.ConsensusFault{Target: minerAddr, Epoch: 43, Type: runtime2.ConsensusFaultDoubleForkMining}, nil
+	}
+
+	t.Run("faults are reported once", func(t *testing.T) {
+		pusher := &fakePusher{}
+		reporter := NewConsensusFaultReporter(reporterAddr, pusher, doubleFork, ds.NewMapDatastore())
+
+		reported, err := reporter.Report(ctx, ConsensusFault{Block1: block1, Block2: block2})
+		require.NoError(t, err)
+		require.NotNil(t, reported)
+		assert.Equal(t, minerAddr, reported.Miner)
+		assert.Equal(t, runtime2.ConsensusFaultDoubleForkMining, reported.Type)
+		assert.False(t, reported.Extra.Defined())
+
+		// the detector emits the same fault with the blocks swapped
+		reported, err = reporter.Report(ctx, ConsensusFault{Block1: block2, Block2: block1})
+		require.NoError(t, err)
+		assert.Nil(t, reported)
+
+		require.Len(t, pusher.pushed, 1)
+		msg := pusher.pushed[0]
+		assert.Equal(t, minerAddr, msg.To)
+		assert.Equal(t, reporterAddr, msg.From)
+		assert.Equal(t, miner.Methods.ReportConsensusFault, msg.Method)
+
+		faults, err := reporter.List(ctx)
+		require.NoError(t, err)
+		require.Len(t, faults, 1)
+		assert.Equal(t, abi.ChainEpoch(43), faults[0].Epoch)
+	})
+
+	t.Run("faults that do not verify are not reported", func(t *testing.T) {
+		pusher := &fakePusher{}
+		invalid := func(context.Context, []byte, []byte, []byte) (*runtime2.ConsensusFault, error) {
+			return nil, errors.New("no consensus fault: blocks are ok")
+		}
+		reporter := NewConsensusFaultReporter(reporterAddr, pusher, invalid, ds.NewMapDatastore())
+
+		_, err := reporter.Report(ctx, ConsensusFault{Block1: block1, Block2: block2})
+		assert.Error(t, err)
+		assert.Empty(t, pusher.pushed)
+
+		faults, err := reporter.List(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, faults)
+	})
+}
@@ -892,6 +892,10 @@ func (v *View) LoadMarketState(ctx context.Context) (market.State, error) {
 	return v.loadMarketState(ctx)
 }
 
+func (v *View) LoadPowerState(ctx context.Context) (power.State, error) {
+	return v.loadPowerActor(ctx)
+}
+
 func (v *View) loadMinerState(ctx context.Context, maddr addr.Address) (miner.State, error) {
 	resolvedAddr, err := v.InitResolveAddress(ctx, maddr)
 	if err != nil {
@@ -0,0 +1,15 @@
+package types
+
+import (
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// SimulatedMessage is a step of a simulation, the epochs are advanced before
+// the message is applied.
+type SimulatedMessage struct {
+	Message *UnsignedMessage
+	// AdvanceEpochs is the number of fake null rounds before the message.
+	AdvanceEpochs abi.ChainEpoch
+	// Cron runs the cron tick at the end of each advanced epoch.
+	Cron bool
+}
@@ -0,0 +1,24 @@
+package types
+
+import (
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+)
+
+// StateOverrides are changes applied to a copy of a tipset's state before a
+// message is called against it, nil fields keep the values of the tipset.
+type StateOverrides struct {
+	Actors map[address.Address]ActorOverride
+	// Epoch is the epoch the message is executed at.
+	Epoch *abi.ChainEpoch
+	// BaseFee is the base fee the message is executed with.
+	BaseFee *abi.TokenAmount
+}
+
+// ActorOverride replaces fields of an existing actor.
+type ActorOverride struct {
+	Balance *abi.TokenAmount
+	Nonce   *uint64
+	// Head is the CBOR encoded state replacing the head of the actor.
+	Head []byte
+}
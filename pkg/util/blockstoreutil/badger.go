@@ -397,9 +397,12 @@ func (b *BadgerBlockstore) AllKeysChan(ctx context.Context) (<-chan cid.Cid, err
 				// open iterators will run even after the database is closed...
 				return // closing, yield.
 			}
-			k := iter.Item().Key()
+			k := ds.RawKey(string(iter.Item().Key()))
+			if b.keyTransform.Prefix.String() != "/" && !b.keyTransform.Prefix.IsAncestorOf(k) {
+				continue // not a block key.
+			}
 			// need to convert to key.Key using key.KeyFromDsKey.
-			bk, err := dshelp.BinaryFromDsKey(ds.RawKey(string(k)))
+			bk, err := dshelp.BinaryFromDsKey(b.keyTransform.InvertKey(k))
 			if err != nil {
 				log.Warnf("error parsing key from binary: %s", err)
 				continue
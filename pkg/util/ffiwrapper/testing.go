@@ -25,6 +25,11 @@ func (f *FakeVerifier) VerifyWindowPoSt(context.Context, proof.WindowPoStVerifyI
 	return true, nil
 }
 
-func (f *FakeVerifier) GenerateWinningPoStSectorChallenge(context.Context, abi.RegisteredPoStProof, abi.ActorID, abi.PoStRandomness, uint64) ([]uint64, error) {
-	return []uint64{}, nil
+// GenerateWinningPoStSectorChallenge challenges the first proving sector so
+// that miners with sectors can produce (mock) winning PoSts.
+func (f *FakeVerifier) GenerateWinningPoStSectorChallenge(_ context.Context, _ abi.RegisteredPoStProof, _ abi.ActorID, _ abi.PoStRandomness, eligibleSectorCount uint64) ([]uint64, error) {
+	if eligibleSectorCount == 0 {
+		return []uint64{}, nil
+	}
+	return []uint64{0}, nil
 }
@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/ipfs/go-cid"
 
 	types2 "github.com/filecoin-project/venus/pkg/types"
 	"github.com/filecoin-project/venus/pkg/vm/runtime"
@@ -21,6 +23,11 @@ type GasTracker struct { //nolint
 	CallerValidated   bool      //nolint
 	LastGasChargeTime time.Time //nolint
 	LastGasCharge     *types2.GasTrace
+
+	// Profiler aggregates the charges when set.
+	Profiler *Profiler
+	frames   []ProfileKey
+	lastKey  ProfileKey
 }
 
 // NewGasTracker initializes a new empty gas tracker
@@ -77,6 +84,17 @@ func (t *GasTracker) TryCharge(gasCharge GasCharge) bool {
 		gasTrace.VirtualComputeGas = gasTrace.ComputeGas
 	}
 
+	if t.Profiler != nil {
+		if t.LastGasCharge != nil {
+			t.Profiler.addTime(t.lastKey, t.LastGasCharge.TimeTaken)
+		}
+		t.lastKey = ProfileKey{Charge: gasCharge.Name}
+		if n := len(t.frames); n > 0 {
+			t.lastKey.Code, t.lastKey.Method = t.frames[n-1].Code, t.frames[n-1].Method
+		}
+		t.Profiler.charge(t.lastKey, &gasTrace)
+	}
+
 	t.ExecutionTrace.GasCharges = append(t.ExecutionTrace.GasCharges, &gasTrace)
 	t.LastGasChargeTime = now
 	t.LastGasCharge = &gasTrace
@@ -90,3 +108,18 @@ func (t *GasTracker) TryCharge(gasCharge GasCharge) bool {
 	t.GasUsed += toUse
 	return true
 }
+
+// EnterActor attributes the next charges to the method `method` of the actor
+// with code `code` until the matching ExitActor, when profiling.
+func (t *GasTracker) EnterActor(code cid.Cid, method abi.MethodNum) {
+	if t.Profiler != nil {
+		t.frames = append(t.frames, ProfileKey{Code: code, Method: method})
+	}
+}
+
+// ExitActor returns from the actor of the last EnterActor.
+func (t *GasTracker) ExitActor() {
+	if t.Profiler != nil && len(t.frames) > 0 {
+		t.frames = t.frames[:len(t.frames)-1]
+	}
+}
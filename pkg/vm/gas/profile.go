@@ -0,0 +1,131 @@
+package gas
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/google/pprof/profile"
+	"github.com/ipfs/go-cid"
+
+	types2 "github.com/filecoin-project/venus/pkg/types"
+)
+
+// ProfileKey identifies the gas charges aggregated together by a Profiler.
+type ProfileKey struct {
+	// Code is the code of the actor executing when the gas was charged,
+	// undefined for the charges of the vm outside of any actor, like the
+	// inclusion of the message.
+	Code   cid.Cid
+	Method abi.MethodNum
+	Charge string
+}
+
+// ProfileEntry is the aggregate of the gas charges of a key.
+type ProfileEntry struct {
+	ProfileKey
+	Count      int64
+	TotalGas   int64
+	ComputeGas int64
+	StorageGas int64
+	// Time is the wall-clock time measured from each charge to the next one
+	// in the same message, the last charge of a message is not timed.
+	Time time.Duration
+}
+
+// Profiler aggregates the gas charges of the messages applied with it by
+// actor code, method number and charge name. It is safe for concurrent use.
+type Profiler struct {
+	lk      sync.Mutex
+	entries map[ProfileKey]*ProfileEntry
+}
+
+// NewProfiler returns an empty profiler.
+func NewProfiler() *Profiler {
+	return &Profiler{entries: make(map[ProfileKey]*ProfileEntry)}
+}
+
+func (p *Profiler) entry(key ProfileKey) *ProfileEntry {
+	e, ok := p.entries[key]
+	if !ok {
+		e = &ProfileEntry{ProfileKey: key}
+		p.entries[key] = e
+	}
+	return e
+}
+
+func (p *Profiler) charge(key ProfileKey, trace *types2.GasTrace) {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	e := p.entry(key)
+	e.Count++
+	e.TotalGas += trace.TotalGas
+	e.ComputeGas += trace.ComputeGas
+	e.StorageGas += trace.StorageGas
+}
+
+func (p *Profiler) addTime(key ProfileKey, d time.Duration) {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	p.entry(key).Time += d
+}
+
+// Entries returns the aggregated charges, the most expensive first.
+func (p *Profiler) Entries() []ProfileEntry {
+	p.lk.Lock()
+	defer p.lk.Unlock()
+	out := make([]ProfileEntry, 0, len(p.entries))
+	for _, e := range p.entries {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].TotalGas != out[j].TotalGas {
+			return out[i].TotalGas > out[j].TotalGas
+		}
+		return out[i].Time > out[j].Time
+	})
+	return out
+}
+
+// WritePprof writes `entries` as a gzipped pprof profile, each charge is a
+// sample under its method under its actor, named by `actorName`.
+func WritePprof(w io.Writer, entries []ProfileEntry, actorName func(cid.Cid) string) error {
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "gas", Unit: "count"},
+			{Type: "compute_gas", Unit: "count"},
+			{Type: "storage_gas", Unit: "count"},
+			{Type: "time", Unit: "nanoseconds"},
+			{Type: "charges", Unit: "count"},
+		},
+	}
+	locations := make(map[string]*profile.Location)
+	location := func(name string) *profile.Location {
+		if loc, ok := locations[name]; ok {
+			return loc
+		}
+		fn := &profile.Function{ID: uint64(len(p.Function) + 1), Name: name, SystemName: name}
+		loc := &profile.Location{ID: uint64(len(p.Location) + 1), Line: []profile.Line{{Function: fn}}}
+		p.Function = append(p.Function, fn)
+		p.Location = append(p.Location, loc)
+		locations[name] = loc
+		return loc
+	}
+
+	for _, e := range entries {
+		actor := "vm"
+		if e.Code.Defined() {
+			actor = actorName(e.Code)
+		}
+		method := fmt.Sprintf("%s.%d", actor, e.Method)
+		p.Sample = append(p.Sample, &profile.Sample{
+			// Leaf first.
+			Location: []*profile.Location{location(method + "." + e.Charge), location(method), location(actor)},
+			Value:    []int64{e.TotalGas, e.ComputeGas, e.StorageGas, int64(e.Time), e.Count},
+		})
+	}
+	return p.Write(w)
+}
@@ -0,0 +1,178 @@
+package state
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	lru "github.com/hashicorp/golang-lru"
+	block "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/venus/pkg/metrics"
+	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
+)
+
+var (
+	sharedActorCacheHit  = metrics.NewInt64Counter("state/shared_actor_cache_hit", "Number of actor lookups served by the cache shared by the state trees")
+	sharedActorCacheMiss = metrics.NewInt64Counter("state/shared_actor_cache_miss", "Number of actor lookups missed by the cache shared by the state trees")
+	blockCacheHit        = metrics.NewInt64Counter("state/block_cache_hit", "Number of state blocks served by the cache shared by the state trees")
+	blockCacheMiss       = metrics.NewInt64Counter("state/block_cache_miss", "Number of state blocks missed by the cache shared by the state trees")
+)
+
+const (
+	// DefaultCacheActors is the number of actor headers kept by a Cache.
+	DefaultCacheActors = 1 << 16
+	// DefaultCacheBlocks is the number of blocks kept by a Cache.
+	DefaultCacheBlocks = 1 << 16
+	// DefaultCacheRetention is the number of epochs an unused entry is kept.
+	DefaultCacheRetention = abi.ChainEpoch(20)
+)
+
+// Cache is a bounded cache of state shared by the state trees using it, it is
+// safe for concurrent use. It keeps the actor headers decoded from an actors
+// HAMT, keyed by the root of the HAMT and the ID address of the actor, and the
+// blocks read and written by the trees, HAMT and AMT nodes and actor states,
+// keyed by CID. The nodes are kept encoded, the HAMT mutates the nodes it
+// loads in place so decoded nodes can't be shared between trees.
+//
+// The entries are stamped with the epoch they were last used at, set with
+// SetEpoch, and dropped when they have not been used for the retention.
+type Cache struct {
+	epoch     int64 // accessed atomically, first for the alignment
+	actors    *lru.ARCCache
+	blocks    *lru.ARCCache
+	retention abi.ChainEpoch
+}
+
+type actorCacheKey struct {
+	root cid.Cid
+	addr address.Address
+}
+
+type cacheEntry struct {
+	epoch abi.ChainEpoch
+	value interface{}
+}
+
+// NewCache returns a cache of `actors` actor headers and `blocks` blocks.
+func NewCache(actors, blocks int, retention abi.ChainEpoch) (*Cache, error) {
+	actorCache, err := lru.NewARC(actors)
+	if err != nil {
+		return nil, err
+	}
+	blockCache, err := lru.NewARC(blocks)
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{actors: actorCache, blocks: blockCache, retention: retention}, nil
+}
+
+// SetEpoch sets the epoch the state is used at, it only moves forward.
+func (c *Cache) SetEpoch(epoch abi.ChainEpoch) {
+	for {
+		cur := atomic.LoadInt64(&c.epoch)
+		if int64(epoch) <= cur || atomic.CompareAndSwapInt64(&c.epoch, cur, int64(epoch)) {
+			return
+		}
+	}
+}
+
+func (c *Cache) get(cache *lru.ARCCache, key interface{}) (interface{}, bool) {
+	v, ok := cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	entry := v.(cacheEntry)
+	epoch := abi.ChainEpoch(atomic.LoadInt64(&c.epoch))
+	if entry.epoch+c.retention < epoch {
+		cache.Remove(key)
+		return nil, false
+	}
+	if entry.epoch != epoch {
+		cache.Add(key, cacheEntry{epoch: epoch, value: entry.value})
+	}
+	return entry.value, true
+}
+
+func (c *Cache) add(cache *lru.ARCCache, key, value interface{}) {
+	cache.Add(key, cacheEntry{epoch: abi.ChainEpoch(atomic.LoadInt64(&c.epoch)), value: value})
+}
+
+func (c *Cache) getActor(ctx context.Context, root cid.Cid, addr address.Address) (*types.Actor, bool) {
+	v, ok := c.get(c.actors, actorCacheKey{root: root, addr: addr})
+	if !ok {
+		sharedActorCacheMiss.Inc(ctx, 1)
+		return nil, false
+	}
+	sharedActorCacheHit.Inc(ctx, 1)
+	act := v.(types.Actor)
+	return &act, true
+}
+
+func (c *Cache) addActor(root cid.Cid, addr address.Address, act *types.Actor) {
+	c.add(c.actors, actorCacheKey{root: root, addr: addr}, *act)
+}
+
+// Blockstore returns `bs` with its reads and writes going through the cache.
+func (c *Cache) Blockstore(bs blockstoreutil.Blockstore) blockstoreutil.Blockstore {
+	return &cachedBlockstore{Blockstore: bs, cache: c}
+}
+
+// cachedBlockstore serves the blocks from the cache before `Blockstore`.
+// Has always asks the underlying store, a block cached from another store
+// may be missing from it.
+type cachedBlockstore struct {
+	blockstoreutil.Blockstore
+	cache *Cache
+}
+
+var _ blockstoreutil.Blockstore = (*cachedBlockstore)(nil)
+var _ blockstoreutil.Viewer = (*cachedBlockstore)(nil)
+
+func (bs *cachedBlockstore) Get(c cid.Cid) (block.Block, error) {
+	if v, ok := bs.cache.get(bs.cache.blocks, c); ok {
+		blockCacheHit.Inc(context.TODO(), 1)
+		return v.(block.Block), nil
+	}
+	blockCacheMiss.Inc(context.TODO(), 1)
+	blk, err := bs.Blockstore.Get(c)
+	if err != nil {
+		return nil, err
+	}
+	bs.cache.add(bs.cache.blocks, c, blk)
+	return blk, nil
+}
+
+func (bs *cachedBlockstore) View(c cid.Cid, callback func([]byte) error) error {
+	blk, err := bs.Get(c)
+	if err != nil {
+		return err
+	}
+	return callback(blk.RawData())
+}
+
+func (bs *cachedBlockstore) Put(blk block.Block) error {
+	if err := bs.Blockstore.Put(blk); err != nil {
+		return err
+	}
+	bs.cache.add(bs.cache.blocks, blk.Cid(), blk)
+	return nil
+}
+
+func (bs *cachedBlockstore) PutMany(blks []block.Block) error {
+	if err := bs.Blockstore.PutMany(blks); err != nil {
+		return err
+	}
+	for _, blk := range blks {
+		bs.cache.add(bs.cache.blocks, blk.Cid(), blk)
+	}
+	return nil
+}
+
+func (bs *cachedBlockstore) DeleteBlock(c cid.Cid) error {
+	bs.cache.blocks.Remove(c)
+	return bs.Blockstore.DeleteBlock(c)
+}
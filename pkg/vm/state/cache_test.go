@@ -0,0 +1,135 @@
+package state
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	builtin0 "github.com/filecoin-project/specs-actors/actors/builtin"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/pkg/repo"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
+)
+
+// recordRange writes a state of `actors` accounts then `epochs` states, each
+// bumping the nonce of the `busy` first accounts, and returns their roots.
+func recordRange(tb testing.TB, bs blockstoreutil.Blockstore, actors, busy, epochs int) []cid.Cid {
+	ctx := context.Background()
+	cst := cbor.NewCborStore(bs)
+	tree, err := NewState(cst, StateTreeVersion1)
+	require.NoError(tb, err)
+	head, err := cst.Put(ctx, []struct{}{})
+	require.NoError(tb, err)
+	for i := 0; i < actors; i++ {
+		require.NoError(tb, tree.SetActor(ctx, idAddr(tb, i), &types.Actor{Code: builtin0.AccountActorCodeID, Head: head, Balance: abi.NewTokenAmount(0)}))
+	}
+	root, err := tree.Flush(ctx)
+	require.NoError(tb, err)
+
+	roots := []cid.Cid{root}
+	for e := 0; e < epochs; e++ {
+		tree, err := LoadState(ctx, cst, root)
+		require.NoError(tb, err)
+		for i := 0; i < busy; i++ {
+			require.NoError(tb, tree.MutateActor(idAddr(tb, i), func(act *types.Actor) error {
+				act.IncrementSeqNum()
+				return nil
+			}))
+		}
+		root, err = tree.Flush(ctx)
+		require.NoError(tb, err)
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+func idAddr(tb testing.TB, i int) address.Address {
+	addr, err := address.NewIDAddress(uint64(100 + i))
+	require.NoError(tb, err)
+	return addr
+}
+
+func loadCached(tb testing.TB, bs blockstoreutil.Blockstore, cache *Cache, root cid.Cid) *State {
+	if cache != nil {
+		bs = cache.Blockstore(bs)
+	}
+	tree, err := LoadState(context.Background(), cbor.NewCborStore(bs), root)
+	require.NoError(tb, err)
+	if cache != nil {
+		tree.UseCache(cache)
+	}
+	return tree
+}
+
+func TestCacheFollowsStateRoot(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	bs := repo.NewInMemoryRepo().Datastore()
+	roots := recordRange(t, bs, 10, 2, 3)
+	cache, err := NewCache(DefaultCacheActors, DefaultCacheBlocks, DefaultCacheRetention)
+	require.NoError(t, err)
+
+	// The nonce of a busy actor is the epoch of the root, the same actor is
+	// cached once per root.
+	for epoch, root := range roots {
+		for i := 0; i < 2; i++ {
+			act, found, err := loadCached(t, bs, cache, root).GetActor(ctx, idAddr(t, 0))
+			require.NoError(t, err)
+			require.True(t, found)
+			assert.Equal(t, uint64(epoch), act.Nonce)
+		}
+	}
+
+	// A tree reloaded at the root flushed with the cache reads the new actors,
+	// not the ones cached for the root the change was made at.
+	last := roots[len(roots)-1]
+	tree := loadCached(t, bs, cache, last)
+	UpdateAccount(t, tree, idAddr(t, 0), func(act *types.Actor) { act.Nonce = 42 })
+	flushed, err := tree.Flush(ctx)
+	require.NoError(t, err)
+	for root, nonce := range map[cid.Cid]uint64{flushed: 42, last: uint64(len(roots) - 1)} {
+		act, found, err := loadCached(t, bs, cache, root).GetActor(ctx, idAddr(t, 0))
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, nonce, act.Nonce)
+	}
+}
+
+func TestCacheRetention(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	bs := repo.NewInMemoryRepo().Datastore()
+	roots := recordRange(t, bs, 1, 1, 0)
+	cache, err := NewCache(16, 16, 5)
+	require.NoError(t, err)
+
+	cache.SetEpoch(10)
+	act, found, err := loadCached(t, bs, cache, roots[0]).GetActor(ctx, idAddr(t, 0))
+	require.NoError(t, err)
+	require.True(t, found)
+	actorsRoot, err := loadCached(t, bs, cache, roots[0]).ActorsRoot()
+	require.NoError(t, err)
+
+	cache.SetEpoch(15)
+	cached, ok := cache.getActor(ctx, actorsRoot, idAddr(t, 0))
+	require.True(t, ok)
+	assert.Equal(t, act, cached)
+
+	// Used at 15, expired after 20.
+	cache.SetEpoch(21)
+	_, ok = cache.getActor(ctx, actorsRoot, idAddr(t, 0))
+	assert.False(t, ok)
+
+	// The epoch never moves back.
+	cache.SetEpoch(3)
+	assert.Equal(t, int64(21), cache.epoch)
+}
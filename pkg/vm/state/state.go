@@ -15,6 +15,7 @@ import (
 	"go.opencensus.io/trace"
 	"golang.org/x/xerrors"
 
+	"github.com/filecoin-project/venus/pkg/metrics"
 	"github.com/filecoin-project/venus/pkg/specactors/adt"
 	init_ "github.com/filecoin-project/venus/pkg/specactors/builtin/init"
 	"github.com/filecoin-project/venus/pkg/types"
@@ -26,6 +27,11 @@ import (
 
 type StateTreeVersion uint64 //nolint
 
+var (
+	actorCacheHit  = metrics.NewInt64Counter("state/actor_cache_hit", "Number of actor lookups served by the state tree cache")
+	actorCacheMiss = metrics.NewInt64Counter("state/actor_cache_miss", "Number of actor lookups loaded from the state tree HAMT")
+)
+
 type ActorKey = address.Address
 
 type Root = cid.Cid
@@ -116,6 +122,11 @@ type State struct {
 	lookupIDFun func(address.Address) (address.Address, error)
 
 	snaps *stateSnaps
+
+	// cache is shared with other trees, the actors are cached for the actors
+	// HAMT at cacheRoot, undefined until the tree is loaded or flushed.
+	cache     *Cache
+	cacheRoot cid.Cid
 }
 
 func NewState(cst cbor.IpldStore, ver StateTreeVersion) (*State, error) {
@@ -212,17 +223,24 @@ func LoadState(ctx context.Context, cst cbor.IpldStore, c cid.Cid) (*State, erro
 	}
 
 	s := &State{
-		root:    hamt,
-		info:    root.Info,
-		version: root.Version,
-		Store:   cst,
-		snaps:   newStateSnaps(),
+		root:      hamt,
+		info:      root.Info,
+		version:   root.Version,
+		Store:     cst,
+		snaps:     newStateSnaps(),
+		cacheRoot: root.Actors,
 	}
 	s.lookupIDFun = s.lookupIDinternal
 
 	return s, nil
 }
 
+// UseCache makes the tree look the actors up in `cache` before its HAMT, the
+// store of the tree should read through the same cache, see Cache.Blockstore.
+func (st *State) UseCache(cache *Cache) {
+	st.cache = cache
+}
+
 func (st *State) SetActor(ctx context.Context, addr ActorKey, act *types.Actor) error {
 	stateLog.Debugf("set actor addr:", addr.String(), " Balance:", act.Balance.String(), " Head:", act.Head, " Nonce:", act.Nonce)
 	iaddr, err := st.LookupID(addr)
@@ -302,9 +320,20 @@ func (st *State) GetActor(ctx context.Context, addr ActorKey) (*types.Actor, boo
 	}
 
 	if snapAct != nil {
+		actorCacheHit.Inc(ctx, 1)
 		return snapAct, true, nil
 	}
 
+	useCache := st.cache != nil && st.cacheRoot.Defined()
+	if useCache {
+		if act, ok := st.cache.getActor(ctx, st.cacheRoot, addr); ok {
+			st.snaps.setActor(addr, act)
+			return act, true, nil
+		}
+	}
+
+	actorCacheMiss.Inc(ctx, 1)
+
 	var act types.Actor
 	if found, err := st.root.Get(abi.AddrKey(addr), &act); err != nil {
 		return nil, false, xerrors.Errorf("hamt find failed: %v", err)
@@ -312,6 +341,9 @@ func (st *State) GetActor(ctx context.Context, addr ActorKey) (*types.Actor, boo
 		return nil, false, nil
 	}
 
+	if useCache {
+		st.cache.addActor(st.cacheRoot, addr, &act)
+	}
 	st.snaps.setActor(addr, &act)
 
 	return &act, true, nil
@@ -369,6 +401,7 @@ func (st *State) Flush(ctx context.Context) (cid.Cid, error) {
 	if err != nil {
 		return cid.Undef, xerrors.Errorf("failed to flush state-tree hamt: %v", err)
 	}
+	st.cacheRoot = root
 	// If we're version 0, return a raw tree.
 	if st.version == StateTreeVersion0 {
 		return root, nil
@@ -465,6 +498,12 @@ func (st *State) Version() StateTreeVersion {
 	return st.version
 }
 
+// ActorsRoot returns the root of the actors HAMT, without the version wrapper
+// of the state root.
+func (st *State) ActorsRoot() (cid.Cid, error) {
+	return st.root.Root()
+}
+
 func (st *State) GetStore() cbor.IpldStore {
 	return st.Store
 }
@@ -478,6 +517,7 @@ func (st *State) At(root Root) error {
 	st.root = newState.root
 	st.version = newState.version
 	st.info = newState.info
+	st.cacheRoot = newState.cacheRoot
 	return nil
 }
 func Diff(oldTree, newTree *State) (map[string]types.Actor, error) {
@@ -0,0 +1,243 @@
+package vm_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	builtin2 "github.com/filecoin-project/specs-actors/v2/actors/builtin"
+	"github.com/google/pprof/profile"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/fixtures/fortest"
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/config"
+	"github.com/filecoin-project/venus/pkg/fork"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
+	"github.com/filecoin-project/venus/pkg/vm"
+	"github.com/filecoin-project/venus/pkg/vm/gas"
+	"github.com/filecoin-project/venus/pkg/vm/state"
+	gengen "github.com/filecoin-project/venus/tools/gengen/util"
+)
+
+// fixture is the genesis state of the test gengen config.
+type fixture struct {
+	bs      blockstoreutil.Blockstore
+	root    cid.Cid
+	baseFee abi.TokenAmount
+	keys    []address.Address
+}
+
+func newFixture(tb testing.TB) *fixture {
+	ctx := context.Background()
+	bs := blockstoreutil.NewTemporarySync()
+	info, err := gengen.GenGen(ctx, &fortest.TestGenGenConfig, bs)
+	require.NoError(tb, err)
+	var gen block.Block
+	require.NoError(tb, cbor.NewCborStore(bs).Get(ctx, info.GenesisCid, &gen))
+
+	f := &fixture{bs: bs, root: gen.ParentStateRoot, baseFee: gen.ParentBaseFee}
+	for _, k := range info.Keys {
+		addr, err := k.Address()
+		require.NoError(tb, err)
+		f.keys = append(f.keys, addr)
+	}
+	return f
+}
+
+// newVM returns a vm at `epoch` on the state `root`, `opt` sets the other
+// options.
+func (f *fixture) newVM(tb testing.TB, root cid.Cid, epoch abi.ChainEpoch, opt func(*vm.VmOption)) vm.Interpreter {
+	// without upgrades the network runs the v2 actors of the fixture genesis
+	forkUpgrade := config.NoForkUpgrades()
+	chainFork, err := fork.NewChainFork(nil, cbor.NewCborStore(f.bs), f.bs, forkUpgrade)
+	require.NoError(tb, err)
+
+	option := vm.VmOption{
+		CircSupplyCalculator: func(context.Context, abi.ChainEpoch, state.Tree) (abi.TokenAmount, error) {
+			return abi.NewTokenAmount(0), nil
+		},
+		NtwkVersionGetter: chainFork.GetNtwkVersion,
+		BaseFee:           f.baseFee,
+		Fork:              chainFork,
+		Epoch:             epoch,
+		GasPriceSchedule:  gas.NewPricesSchedule(forkUpgrade),
+		PRoot:             root,
+		Bsstore:           f.bs,
+	}
+	if opt != nil {
+		opt(&option)
+	}
+	lvm, err := vm.NewVM(option)
+	require.NoError(tb, err)
+	return lvm
+}
+
+func send(from, to address.Address, nonce uint64, value int64) *types.UnsignedMessage {
+	return &types.UnsignedMessage{
+		From:       from,
+		To:         to,
+		Nonce:      nonce,
+		Value:      abi.NewTokenAmount(value),
+		GasLimit:   10_000_000,
+		GasFeeCap:  big.Zero(),
+		GasPremium: big.Zero(),
+	}
+}
+
+func TestGasProfilerNestedSend(t *testing.T) {
+	tf.UnitTest(t)
+
+	f := newFixture(t)
+	profiler := gas.NewProfiler()
+	lvm := f.newVM(t, f.root, 1, func(option *vm.VmOption) {
+		option.GasProfiler = profiler
+	})
+
+	// sending to a new key address constructs its account actor in a nested
+	// invocation
+	to, err := address.NewSecp256k1Address(bytes.Repeat([]byte{1}, 65))
+	require.NoError(t, err)
+	ret, err := lvm.ApplyMessage(send(f.keys[0], to, 0, 100))
+	require.NoError(t, err)
+	require.Equal(t, exitcode.Ok, ret.Receipt.ExitCode)
+
+	entries := profiler.Entries()
+	counts := make(map[gas.ProfileKey]int64)
+	var total int64
+	for _, e := range entries {
+		counts[e.ProfileKey] = e.Count
+		total += e.TotalGas
+	}
+	assert.Equal(t, ret.Receipt.GasUsed, total)
+
+	t.Run("the charges are attributed to the frame executing", func(t *testing.T) {
+		vmKey := func(charge string) gas.ProfileKey { return gas.ProfileKey{Charge: charge} }
+		constructor := func(charge string) gas.ProfileKey {
+			return gas.ProfileKey{Code: builtin2.AccountActorCodeID, Method: builtin2.MethodConstructor, Charge: charge}
+		}
+		// the invocations, the transfer and the creation of the account are
+		// charged to the caller, the top-level send runs no actor
+		assert.Equal(t, int64(1), counts[vmKey("OnChainMessage")])
+		assert.Equal(t, int64(2), counts[vmKey("OnMethodInvocation")])
+		assert.Equal(t, int64(1), counts[vmKey("OnCreateActor")])
+		// the state of the account is written by its constructor
+		assert.Equal(t, int64(1), counts[constructor("OnIpldPut")])
+
+		for key := range counts {
+			if key.Code.Defined() {
+				assert.Equal(t, builtin2.AccountActorCodeID, key.Code, key)
+				assert.Equal(t, builtin2.MethodConstructor, key.Method, key)
+			}
+		}
+	})
+
+	t.Run("the pprof profile nests the charges under their method and actor", func(t *testing.T) {
+		buf := new(bytes.Buffer)
+		require.NoError(t, gas.WritePprof(buf, entries, func(code cid.Cid) string {
+			require.Equal(t, builtin2.AccountActorCodeID, code)
+			return "account"
+		}))
+		p, err := profile.Parse(buf)
+		require.NoError(t, err)
+		require.Len(t, p.Sample, len(entries))
+
+		stacks := make(map[string]int64)
+		var gasTotal int64
+		for _, s := range p.Sample {
+			var names []string
+			for _, loc := range s.Location {
+				names = append(names, loc.Line[0].Function.Name)
+			}
+			require.Len(t, names, 3)
+			stacks[names[2]+" "+names[1]+" "+names[0]] = s.Value[4]
+			gasTotal += s.Value[0]
+		}
+		assert.Equal(t, total, gasTotal)
+		assert.Equal(t, int64(2), stacks["vm vm.0 vm.0.OnMethodInvocation"])
+		assert.Equal(t, int64(1), stacks["account account.1 account.1.OnIpldPut"])
+	})
+}
+
+// BenchmarkReplayRange re-executes a recorded range of epochs through the vm
+// like a node replaying the chain, with the states read cold or through a
+// cache shared by the vms of the range.
+func BenchmarkReplayRange(b *testing.B) {
+	const epochs, perEpoch = 20, 20
+	f := newFixture(b)
+
+	// the senders pay the fixture keys and new accounts in turn
+	senders := f.keys[:2]
+	nonces := make(map[address.Address]uint64)
+	roots := []cid.Cid{f.root}
+	msgs := make([][]*types.UnsignedMessage, epochs)
+	for e := 0; e < epochs; e++ {
+		for i := 0; i < perEpoch; i++ {
+			from := senders[i%len(senders)]
+			to := f.keys[2+i%(len(f.keys)-2)]
+			if i%5 == 0 {
+				var err error
+				to, err = address.NewSecp256k1Address([]byte{byte(e), byte(i)})
+				require.NoError(b, err)
+			}
+			msgs[e] = append(msgs[e], send(from, to, nonces[from], 1))
+			nonces[from]++
+		}
+		roots = append(roots, replayEpoch(b, f, roots[e], abi.ChainEpoch(e+1), msgs[e], nil))
+	}
+
+	replay := func(b *testing.B, cache *state.Cache) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			for e := range msgs {
+				epoch := abi.ChainEpoch(e + 1)
+				if cache != nil {
+					cache.SetEpoch(epoch)
+				}
+				if root := replayEpoch(b, f, roots[e], epoch, msgs[e], cache); root != roots[e+1] {
+					b.Fatalf("epoch %d replayed to %s, recorded %s", epoch, root, roots[e+1])
+				}
+			}
+		}
+	}
+
+	b.Run("cold", func(b *testing.B) {
+		replay(b, nil)
+	})
+	b.Run("cached", func(b *testing.B) {
+		cache, err := state.NewCache(state.DefaultCacheActors, state.DefaultCacheBlocks, state.DefaultCacheRetention)
+		require.NoError(b, err)
+		replay(b, cache)
+	})
+}
+
+// replayEpoch applies `msgs` on `root` at `epoch` and returns the new root.
+func replayEpoch(b *testing.B, f *fixture, root cid.Cid, epoch abi.ChainEpoch, msgs []*types.UnsignedMessage, cache *state.Cache) cid.Cid {
+	lvm := f.newVM(b, root, epoch, func(option *vm.VmOption) {
+		option.StateCache = cache
+	})
+	for _, msg := range msgs {
+		ret, err := lvm.ApplyMessage(msg)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if ret.Receipt.ExitCode != exitcode.Ok {
+			b.Fatalf("message failed with %s", ret.Receipt.ExitCode)
+		}
+	}
+	newRoot, err := lvm.Flush()
+	if err != nil {
+		b.Fatal(err)
+	}
+	return newRoot
+}
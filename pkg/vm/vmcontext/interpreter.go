@@ -20,6 +20,8 @@ type VMInterpreter interface {
 	ApplyGenesisMessage(from address.Address, to address.Address, method abi.MethodNum, value abi.TokenAmount, params interface{}) (*Ret, error)
 	ApplyMessage(msg types.ChainMsg) (*Ret, error)
 	ApplyImplicitMessage(msg types.ChainMsg) (*Ret, error)
+	// AdvanceEpoch ends the current epoch like a null round and moves to the next one.
+	AdvanceEpoch(ts *block.TipSet, cron bool) error
 
 	StateTree() state.Tree
 	Flush() (state.Root, error)
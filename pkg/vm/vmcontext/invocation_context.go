@@ -50,6 +50,8 @@ type invocationContext struct {
 	allowSideEffects  bool
 	stateHandle       internalActorStateHandle
 	gasIpld           ipfscbor.IpldStore
+	// toCode is the code of the target actor once resolved.
+	toCode cid.Cid
 }
 
 type internalActorStateHandle interface {
@@ -226,7 +228,10 @@ func (ctx *invocationContext) invoke() (ret []byte, errcode exitcode.ExitCode) {
 
 	// 1. load target actor
 	// Note: we replace the "To" address with the normalized version
-	_, toIDAddr := ctx.resolveTarget(ctx.originMsg.To)
+	target, toIDAddr := ctx.resolveTarget(ctx.originMsg.To)
+	if target != nil {
+		ctx.toCode = target.Code
+	}
 	if ctx.vm.NtwkVersion() > network.Version3 {
 		ctx.msg.To = toIDAddr
 	}
@@ -251,6 +256,8 @@ func (ctx *invocationContext) invoke() (ret []byte, errcode exitcode.ExitCode) {
 	if err != nil || !found {
 		panic(xerrors.Errorf("cannt find to actor %v", err))
 	}
+	ctx.gasTank.EnterActor(toActor.Code, ctx.originMsg.Method)
+	defer ctx.gasTank.ExitActor()
 	actorImpl := ctx.vm.getActorImpl(toActor.Code, ctx.Runtime())
 	// 6. create target stateView handle
 	stateHandle := newActorStateHandle((*stateHandleContext)(ctx))
@@ -32,6 +32,10 @@ type VmOption struct { //nolint
 	PRoot                cid.Cid
 	Bsstore              blockstoreutil.Blockstore
 	SysCallsImpl         SyscallsImpl
+	// GasProfiler aggregates the gas charges of the messages when set.
+	GasProfiler *gas.Profiler
+	// StateCache is shared by the vms for the state they read when set.
+	StateCache *state.Cache
 }
 
 type Ret struct {
@@ -9,6 +9,7 @@ import (
 	"github.com/filecoin-project/venus/pkg/specactors/builtin/miner"
 	cbor "github.com/ipfs/go-ipld-cbor"
 	"reflect"
+	"strconv"
 	"time"
 
 	"github.com/filecoin-project/go-address"
@@ -19,10 +20,13 @@ import (
 	"github.com/ipfs/go-cid"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/pkg/errors"
+	"go.opencensus.io/stats"
+	"go.opencensus.io/tag"
 	"golang.org/x/xerrors"
 
 	specsruntime "github.com/filecoin-project/specs-actors/actors/runtime"
 	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/metrics"
 	"github.com/filecoin-project/venus/pkg/specactors/adt"
 	"github.com/filecoin-project/venus/pkg/specactors/builtin"
 	"github.com/filecoin-project/venus/pkg/specactors/builtin/cron"
@@ -40,6 +44,12 @@ const MaxCallDepth = 4096
 
 var vmlog = logging.Logger("vm.context")
 
+var applyMessageDuration = metrics.NewTimerWithBuckets("vm/apply_message_duration",
+	"Duration of applying a message in milliseconds, by receiving actor and method",
+	stats.UnitMilliseconds,
+	[]float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000},
+	metrics.Actor, metrics.Method)
+
 // VM holds the stateView and executes messages over the stateView.
 type VM struct {
 	context    context.Context
@@ -92,21 +102,29 @@ var _ VMInterpreter = (*VM)(nil)
 // NewVM creates a new runtime for executing messages.
 // Dragons: change To take a root and the store, build the tree internally
 func NewVM(actorImpls ActorImplLookup, vmOption VmOption) (*VM, error) {
-	buf := blockstoreutil.NewBufferedBstore(vmOption.Bsstore)
+	bs := vmOption.Bsstore
+	if vmOption.StateCache != nil {
+		bs = vmOption.StateCache.Blockstore(bs)
+	}
+	buf := blockstoreutil.NewBufferedBstore(bs)
 	cst := cbor.NewCborStore(buf)
 	var st state.Tree
-	var err error
 	if vmOption.PRoot == cid.Undef {
 		//just for chain gen
+		var err error
 		st, err = state.NewState(cst, state.StateTreeVersion1)
 		if err != nil {
 			panic(xerrors.Errorf("create state error, should never come here"))
 		}
 	} else {
-		st, err = state.LoadState(context.Background(), cst, vmOption.PRoot)
+		loaded, err := state.LoadState(context.Background(), cst, vmOption.PRoot)
 		if err != nil {
 			return nil, err
 		}
+		if vmOption.StateCache != nil {
+			loaded.UseCache(vmOption.StateCache)
+		}
+		st = loaded
 	}
 
 	return &VM{
@@ -353,6 +371,7 @@ func (vm *VM) ApplyTipSetMessages(blocks []block.BlockMessagesInfo, ts *block.Ti
 func (vm *VM) applyImplicitMessage(imsg VmMessage) (*Ret, error) {
 	// implicit messages gas is tracked separatly and not paid by the miner
 	gasTank := gas.NewGasTracker(constants.BlockGasLimit * 10000)
+	gasTank.Profiler = vm.vmOption.GasProfiler
 
 	// the execution of the implicit messages is simpler than full external/actor-actor messages
 	// execution:
@@ -456,6 +475,13 @@ func (vm *VM) MutateState(ctx context.Context, addr address.Address, fn interfac
 
 // applyMessage applies the message To the current stateView.
 func (vm *VM) applyMessage(msg *types.UnsignedMessage, onChainMsgSize int) (*Ret, error) {
+	// the code of the receiver is known once the message is invoked
+	toCode := cid.Undef
+	start := time.Now()
+	defer func() {
+		vm.recordApplyDuration(msg, toCode, start)
+	}()
+
 	vm.SetCurrentEpoch(vm.vmOption.Epoch)
 	// This Method does not actually execute the message itself,
 	// but rather deals with the pre/post processing of a message.
@@ -463,6 +489,7 @@ func (vm *VM) applyMessage(msg *types.UnsignedMessage, onChainMsgSize int) (*Ret
 
 	// initiate gas tracking
 	gasTank := gas.NewGasTracker(msg.GasLimit)
+	gasTank.Profiler = vm.vmOption.GasProfiler
 
 	// pre-send
 	// 1. charge for message existence
@@ -598,6 +625,7 @@ func (vm *VM) applyMessage(msg *types.UnsignedMessage, onChainMsgSize int) (*Ret
 
 	// 3. invoke
 	ret, code := ctx.invoke()
+	toCode = ctx.toCode
 	// post-send
 	// 1. charge gas for putting the return Value on the chain
 	// 2. settle gas money around (unused_gas -> sender)
@@ -669,6 +697,23 @@ func (vm *VM) applyMessage(msg *types.UnsignedMessage, onChainMsgSize int) (*Ret
 	}, nil
 }
 
+// recordApplyDuration reports the time spent applying `msg` since `start`,
+// tagged with the name of the receiving actor of code `toCode`, unknown when
+// undefined, and the method number.
+func (vm *VM) recordApplyDuration(msg *types.UnsignedMessage, toCode cid.Cid, start time.Time) {
+	elapsed := time.Since(start)
+
+	actorName := "<unknown>"
+	if toCode.Defined() {
+		actorName = builtin.ActorNameByCode(toCode)
+	}
+
+	ctx := metrics.WithTags(vm.context,
+		tag.Upsert(metrics.Actor, actorName),
+		tag.Upsert(metrics.Method, strconv.FormatUint(uint64(msg.Method), 10)))
+	applyMessageDuration.Record(ctx, elapsed)
+}
+
 func (vm *VM) shouldBurn(msg *types.UnsignedMessage, errcode exitcode.ExitCode) (bool, error) {
 	// Check to see if we should burn funds. We avoid burning on successful
 	// window post. This won't catch _indirect_ window post calls, but this
@@ -755,6 +800,36 @@ func (vm *VM) getActorImpl(code cid.Cid, runtime2 runtime.Runtime) dispatch.Disp
 
 var _ runtime.Runtime = (*VM)(nil)
 
+// AdvanceEpoch ends the epoch the messages are applied at like a null round,
+// running the cron tick when `cron` is set and the state forks scheduled at
+// the epoch, then moves the vm to the next epoch. `ts` is the tipset the vm
+// started from, passed to the forks.
+func (vm *VM) AdvanceEpoch(ts *block.TipSet, cron bool) error {
+	epoch := vm.vmOption.Epoch
+	vm.SetCurrentEpoch(epoch)
+	if cron {
+		if _, err := vm.applyImplicitMessage(makeCronTickMessage()); err != nil {
+			return xerrors.Errorf("running cron at %d: %w", epoch, err)
+		}
+	}
+	root, err := vm.Flush()
+	if err != nil {
+		return xerrors.Errorf("flushing state at %d: %w", epoch, err)
+	}
+	forkedCid, err := vm.vmOption.Fork.HandleStateForks(vm.context, root, epoch, ts)
+	if err != nil {
+		return xerrors.Errorf("handling forks at %d: %w", epoch, err)
+	}
+	if forkedCid != root {
+		if err := vm.State.At(forkedCid); err != nil {
+			return xerrors.Errorf("loading forked state %s: %w", forkedCid, err)
+		}
+	}
+	vm.vmOption.Epoch = epoch + 1
+	vm.SetCurrentEpoch(epoch + 1)
+	return nil
+}
+
 // CurrentEpoch implements runtime.Runtime.
 func (vm *VM) CurrentEpoch() abi.ChainEpoch {
 	return vm.currentEpoch
@@ -12,11 +12,6 @@ import (
 	"github.com/filecoin-project/test-vectors/schema"
 )
 
-var invokees = map[schema.Class]func(Reporter, string, *schema.TestVector, *schema.Variant){
-	schema.ClassMessage: ExecuteMessageVector,
-	schema.ClassTipset:  ExecuteTipsetVector,
-}
-
 const (
 	// EnvSkipConformance, if 1, skips the conformance test suite.
 	EnvSkipConformance = "SKIP_CONFORMANCE"
@@ -35,12 +30,6 @@ const (
 	defaultCorpusRoot = "../../vendors/test-vectors/corpus"
 )
 
-// ignore is a set of paths relative to root to skip.
-var ignore = map[string]struct{}{
-	".git":        {},
-	"schema.json": {},
-}
-
 // TestConformance is the entrypoint test that runs all test vectors found
 // in the corpus root directory.
 //
@@ -79,22 +79,34 @@ type ExecuteTipsetResult struct {
 	AppliedResults []*vm.Ret
 }
 
+type ExecuteTipsetParams struct {
+	Preroot cid.Cid
+	// ParentEpoch is the last epoch in which an actual tipset was processed. This
+	// is used by Lotus for null block counting and cron firing.
+	ParentEpoch abi.ChainEpoch
+	Tipset      *schema.Tipset
+	ExecEpoch   abi.ChainEpoch
+
+	Rand chain.RandomnessSource
+}
+
 // ExecuteTipset executes the supplied tipset on top of the state represented
 // by the preroot CID.
 //
-// parentEpoch is the last epoch in which an actual tipset was processed. This
-// is used by Lotus for null block counting and cron firing.
-//
 // This method returns the the receipts root, the poststate root, and the VM
 // message results. The latter _include_ implicit messages, such as cron ticks
 // and reward withdrawal per miner.
-func (d *Driver) ExecuteTipset(bs blockstore.Blockstore, chainDs ds.Batching, preroot cid.Cid, parentEpoch abi.ChainEpoch, tipset *schema.Tipset, execEpoch abi.ChainEpoch) (*ExecuteTipsetResult, error) {
+func (d *Driver) ExecuteTipset(bs blockstore.Blockstore, chainDs ds.Batching, params ExecuteTipsetParams) (*ExecuteTipsetResult, error) {
+	if params.Rand == nil {
+		params.Rand = NewFixedRand()
+	}
+	tipset := params.Tipset
 	ipldStore := cbor.NewCborStore(bs)
 	chainStatusReporter := chain.NewStatusReporter()
 	mainNetParams := networks.Mainnet()
 	node.SetNetParams(&mainNetParams.Network)
 	//chainstore
-	chainStore := chain.NewStore(chainDs, ipldStore, bs, chainStatusReporter, mainNetParams.Network.ForkUpgradeParam, cid.Undef) //load genesis from car
+	chainStore := chain.NewStore(chainDs, ipldStore, bs, chainStatusReporter, mainNetParams.Network.ForkUpgradeParam, cid.Undef, nil) //load genesis from car
 
 	//drand
 	/*genBlk, err := chainStore.GetGenesisBlock(context.TODO())
@@ -128,12 +140,12 @@ func (d *Driver) ExecuteTipset(bs blockstore.Blockstore, chainDs ds.Batching, pr
 				return dertail.FilCirculating, nil
 			},
 			NtwkVersionGetter: chainFork.GetNtwkVersion,
-			Rnd:               NewFixedRand(),
+			Rnd:               params.Rand,
 			BaseFee:           big.NewFromGo(&tipset.BaseFee),
 			Fork:              chainFork,
-			Epoch:             execEpoch,
+			Epoch:             params.ExecEpoch,
 			GasPriceSchedule:  gas.NewPricesSchedule(mainNetParams.Network.ForkUpgradeParam),
-			PRoot:             preroot,
+			PRoot:             params.Preroot,
 			Bsstore:           bs,
 			SysCallsImpl:      syscalls,
 		}
@@ -194,7 +206,7 @@ func (d *Driver) ExecuteTipset(bs blockstore.Blockstore, chainDs ds.Batching, pr
 		results  []*vm.Ret
 	)
 
-	postcid, receipt, err := lvm.ApplyTipSetMessages(blocks, nil, parentEpoch, execEpoch, func(_ cid.Cid, msg vm.VmMessage, ret *vm.Ret) error {
+	postcid, receipt, err := lvm.ApplyTipSetMessages(blocks, nil, params.ParentEpoch, params.ExecEpoch, func(_ cid.Cid, msg vm.VmMessage, ret *vm.Ret) error {
 		messages = append(messages, &msg)
 		results = append(results, ret)
 		return nil
@@ -257,7 +269,7 @@ func (d *Driver) ExecuteMessage(bs blockstore.Blockstore, params ExecuteMessageP
 	chainStatusReporter := chain.NewStatusReporter()
 	chainDs := ds.NewMapDatastore() //just mock one
 	//chainstore
-	chainStore := chain.NewStore(chainDs, ipldStore, bs, chainStatusReporter, mainNetParams.Network.ForkUpgradeParam, cid.Undef) //load genesis from car
+	chainStore := chain.NewStore(chainDs, ipldStore, bs, chainStatusReporter, mainNetParams.Network.ForkUpgradeParam, cid.Undef, nil) //load genesis from car
 
 	//drand
 	/*	genBlk, err := chainStore.GetGenesisBlock(context.TODO())
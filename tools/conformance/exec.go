@@ -0,0 +1,208 @@
+package conformance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/filecoin-project/test-vectors/schema"
+)
+
+// ignore is a set of paths relative to root to skip.
+var ignore = map[string]struct{}{
+	".git":        {},
+	"schema.json": {},
+}
+
+var invokees = map[schema.Class]func(Reporter, string, *schema.TestVector, *schema.Variant){
+	schema.ClassMessage: ExecuteMessageVector,
+	schema.ClassTipset:  ExecuteTipsetVector,
+}
+
+// Statuses of a variant in a Report.
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Report is the machine-readable outcome of a run of test vectors.
+type Report struct {
+	Passed  int             `json:"passed"`
+	Failed  int             `json:"failed"`
+	Skipped int             `json:"skipped"`
+	Results []VariantResult `json:"results"`
+}
+
+// VariantResult is the outcome of one variant of a test vector.
+type VariantResult struct {
+	File    string `json:"file"`
+	ID      string `json:"id"`
+	Class   string `json:"class"`
+	Variant string `json:"variant"`
+	Status  string `json:"status"`
+	// Errors are the failed assertions, or the reason of a skip.
+	Errors   []string      `json:"errors,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+func (r *Report) add(res VariantResult) {
+	switch res.Status {
+	case StatusPass:
+		r.Passed++
+	case StatusFail:
+		r.Failed++
+	default:
+		r.Skipped++
+	}
+	r.Results = append(r.Results, res)
+}
+
+// ExecuteVectors runs the test vectors at `paths`, files or directories
+// walked for .json files not starting with _, and reports each variant.
+func ExecuteVectors(paths []string) (*Report, error) {
+	var files []string
+	for _, p := range paths {
+		err := filepath.Walk(p, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			name := filepath.Base(path)
+			if info.IsDir() {
+				if _, ok := ignore[name]; ok {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if _, ok := ignore[name]; ok || filepath.Ext(path) != ".json" || strings.HasPrefix(name, "_") {
+				return nil
+			}
+			files = append(files, path)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no test vectors found in %v", paths)
+	}
+
+	report := &Report{Results: []VariantResult{}}
+	for _, file := range files {
+		raw, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var vector schema.TestVector
+		if err := json.Unmarshal(raw, &vector); err != nil {
+			report.add(VariantResult{File: file, Status: StatusFail, Errors: []string{fmt.Sprintf("failed to parse test vector: %s", err)}})
+			continue
+		}
+		for _, res := range executeVector(file, &vector) {
+			report.add(res)
+		}
+	}
+	return report, nil
+}
+
+func executeVector(file string, vector *schema.TestVector) []VariantResult {
+	var id string
+	if vector.Meta != nil {
+		id = vector.Meta.ID
+	}
+	result := func(variant string) VariantResult {
+		return VariantResult{File: file, ID: id, Class: string(vector.Class), Variant: variant}
+	}
+
+	skip := ""
+	for _, h := range vector.Hints {
+		if h == schema.HintIncorrect {
+			skip = "vector marked as incorrect"
+		}
+	}
+	invokee, ok := invokees[vector.Class]
+	if !ok {
+		skip = fmt.Sprintf("unsupported test vector class %s", vector.Class)
+	}
+
+	var out []VariantResult
+	for _, variant := range vector.Pre.Variants {
+		variant := variant
+		res := result(variant.ID)
+		if skip != "" {
+			res.Status, res.Errors = StatusSkip, []string{skip}
+			out = append(out, res)
+			continue
+		}
+
+		r := &collectingReporter{}
+		start := time.Now()
+		r.run(func() { invokee(r, file, vector, &variant) })
+		res.Duration = time.Since(start)
+		res.Status, res.Errors = StatusPass, r.errors
+		if r.Failed() {
+			res.Status = StatusFail
+		}
+		out = append(out, res)
+	}
+	return out
+}
+
+// collectingReporter records the failed assertions of a vector instead of
+// exiting like LogReporter, a fatal failure ends the vector only.
+type collectingReporter struct {
+	errors []string
+	failed bool
+}
+
+var _ Reporter = (*collectingReporter)(nil)
+
+// errFailNow unwinds the execution of a vector after a fatal failure.
+type errFailNow struct{}
+
+func (r *collectingReporter) run(f func()) {
+	defer func() {
+		if v := recover(); v != nil {
+			if _, ok := v.(errFailNow); !ok {
+				r.failed = true
+				r.errors = append(r.errors, fmt.Sprintf("panic: %v", v))
+			}
+		}
+	}()
+	f()
+}
+
+func (*collectingReporter) Helper() {}
+
+func (*collectingReporter) Log(args ...interface{}) {
+	log.Println(args...)
+}
+
+func (*collectingReporter) Logf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+}
+
+func (r *collectingReporter) Errorf(format string, args ...interface{}) {
+	r.failed = true
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *collectingReporter) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	r.FailNow()
+}
+
+func (r *collectingReporter) FailNow() {
+	r.failed = true
+	panic(errFailNow{})
+}
+
+func (r *collectingReporter) Failed() bool {
+	return r.failed
+}
@@ -0,0 +1,457 @@
+package conformance
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/go-state-types/network"
+	"github.com/filecoin-project/test-vectors/schema"
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+
+	"github.com/filecoin-project/venus/app/submodule/chain/cst"
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/chain"
+	"github.com/filecoin-project/venus/pkg/config"
+	"github.com/filecoin-project/venus/pkg/constants"
+	"github.com/filecoin-project/venus/pkg/fork"
+	"github.com/filecoin-project/venus/pkg/repo"
+	"github.com/filecoin-project/venus/pkg/types"
+	"github.com/filecoin-project/venus/pkg/util/blockstoreutil"
+	"github.com/filecoin-project/venus/pkg/vm/register"
+	vmstate "github.com/filecoin-project/venus/pkg/vm/state"
+)
+
+// ChainSource reads the chain of a synced repo to extract test vectors from
+// it. The repo is never written to.
+type ChainSource struct {
+	bs         blockstoreutil.Blockstore
+	store      *chain.Store
+	messages   *chain.MessageStore
+	state      *cst.ChainStateReadWriter
+	fork       fork.IFork
+	forkParams *config.ForkUpgradeConfig
+}
+
+// NewChainSource loads the chain of the repo `r`, the daemon must not be
+// running on it.
+func NewChainSource(ctx context.Context, r repo.Repo) (*ChainSource, error) {
+	raw, err := r.ChainDatastore().Get(chain.GenesisKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the genesis cid, is the repo initialized? %w", err)
+	}
+	var genesis cid.Cid
+	if err := json.Unmarshal(raw, &genesis); err != nil {
+		return nil, fmt.Errorf("failed to decode the genesis cid: %w", err)
+	}
+
+	bs := r.Datastore()
+	ipldStore := cbor.NewCborStore(bs)
+	forkParams := r.Config().NetworkParams.ForkUpgradeParam
+	store := chain.NewStore(r.ChainDatastore(), ipldStore, bs, chain.NewStatusReporter(), forkParams, genesis, nil)
+	if err := store.Load(ctx); err != nil {
+		return nil, fmt.Errorf("failed to load the chain: %w", err)
+	}
+	messages := chain.NewMessageStore(bs)
+	state := cst.NewChainStateReadWriter(store, messages, bs, register.DefaultActors, nil)
+	chainFork, err := fork.NewChainFork(state, ipldStore, bs, forkParams)
+	if err != nil {
+		return nil, err
+	}
+	return &ChainSource{
+		bs:         bs,
+		store:      store,
+		messages:   messages,
+		state:      state,
+		fork:       chainFork,
+		forkParams: forkParams,
+	}, nil
+}
+
+// ExtractMessage writes a message-class vector applying the message `msgCid`,
+// found in the last `lookback` epochs of the chain, on the state it was
+// executed on. The messages preceding it in the tipset are applied first,
+// outside of the vector. The pre-state of the vector is minimized to the
+// blocks read while applying the message. The vector expects the receipt
+// computed, the extraction fails when the chain recorded another one.
+func (s *ChainSource) ExtractMessage(ctx context.Context, msgCid cid.Cid, lookback abi.ChainEpoch) (*schema.TestVector, error) {
+	ts, index, msgs, err := s.findMessage(ctx, msgCid, lookback)
+	if err != nil {
+		return nil, err
+	}
+	height := ts.EnsureHeight()
+	target := msgs[index].VMMessage()
+	reporter := new(LogReporter)
+	driver := NewDriver(ctx, schema.Selector{}, DriverOpts{DisableVMFlush: true})
+
+	// writes of the precursors and of the message go to memory
+	preBs := blockstoreutil.NewTieredBstore(s.bs, blockstoreutil.NewTemporarySync())
+	root := ts.At(0).ParentStateRoot
+	baseFee := ts.At(0).ParentBaseFee
+	circSupply, err := s.circulatingSupply(ctx, root, height)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range msgs[:index] {
+		_, root, err = driver.ExecuteMessage(preBs, ExecuteMessageParams{
+			Preroot:    root,
+			Epoch:      height,
+			Message:    m.VMMessage(),
+			BaseFee:    baseFee,
+			CircSupply: circSupply,
+			Rand:       NewRecordingRand(reporter, s.randAt(ts)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply precursor message: %w", err)
+		}
+	}
+
+	tracing := newTracingBlockstore(preBs)
+	rand := NewRecordingRand(reporter, s.randAt(ts))
+	ret, postRoot, err := driver.ExecuteMessage(blockstoreutil.NewTieredBstore(tracing, blockstoreutil.NewTemporarySync()), ExecuteMessageParams{
+		Preroot:    root,
+		Epoch:      height,
+		Message:    target,
+		BaseFee:    baseFee,
+		CircSupply: circSupply,
+		Rand:       rand,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply message: %w", err)
+	}
+
+	receipt := ret.Receipt
+	if onChain, err := s.chainReceipts(ctx, ts); err != nil {
+		return nil, err
+	} else if onChain != nil && !receiptEquals(onChain[index], receipt) {
+		return nil, fmt.Errorf("the receipt on chain %+v differs from the computed one %+v", onChain[index], receipt)
+	}
+
+	msgBlk, err := target.ToStorageBlock()
+	if err != nil {
+		return nil, err
+	}
+	carBytes, err := writeVectorCAR(preBs, tracing.accessed(), root)
+	if err != nil {
+		return nil, err
+	}
+	nv := s.fork.GetNtwkVersion(ctx, height)
+	return &schema.TestVector{
+		Class: schema.ClassMessage,
+		Meta:  vectorMeta(fmt.Sprintf("msg-%s", msgCid)),
+		CAR:   carBytes,
+		Pre: &schema.Preconditions{
+			Variants:   []schema.Variant{variant(height, nv)},
+			BaseFee:    baseFee.Int,
+			CircSupply: circSupply.Int,
+			StateTree:  &schema.StateTree{RootCID: root},
+		},
+		ApplyMessages: []schema.Message{{Bytes: msgBlk.RawData()}},
+		Post: &schema.Postconditions{
+			StateTree: &schema.StateTree{RootCID: postRoot},
+			Receipts:  []*schema.Receipt{vectorReceipt(receipt)},
+		},
+		Randomness: rand.Recorded(),
+	}, nil
+}
+
+// ExtractTipset writes a tipset-class vector applying the messages of the
+// tipset `tsk` on the state of its parent. The vector expects the state and
+// receipts roots computed, the extraction fails when the chain holds others.
+func (s *ChainSource) ExtractTipset(ctx context.Context, tsk block.TipSetKey) (*schema.TestVector, error) {
+	ts, err := s.store.GetTipSet(tsk)
+	if err != nil {
+		return nil, err
+	}
+	parent, err := s.store.GetTipSet(ts.EnsureParents())
+	if err != nil {
+		return nil, err
+	}
+	height, parentHeight := ts.EnsureHeight(), parent.EnsureHeight()
+	reporter := new(LogReporter)
+
+	bms, err := s.messages.LoadTipSetMessage(ctx, ts)
+	if err != nil {
+		return nil, err
+	}
+	tipset := schema.Tipset{
+		EpochOffset: int64(height - parentHeight),
+		BaseFee:     *ts.At(0).ParentBaseFee.Int,
+	}
+	for _, bm := range bms {
+		blk := schema.Block{
+			MinerAddr: bm.Block.Miner,
+			WinCount:  bm.Block.ElectionProof.WinCount,
+		}
+		for _, m := range append(bm.BlsMessages, bm.SecpkMessages...) {
+			msgBlk, err := m.VMMessage().ToStorageBlock()
+			if err != nil {
+				return nil, err
+			}
+			blk.Messages = append(blk.Messages, msgBlk.RawData())
+		}
+		tipset.Blocks = append(tipset.Blocks, blk)
+	}
+
+	root := ts.At(0).ParentStateRoot
+	tracing := newTracingBlockstore(s.bs)
+	rand := NewRecordingRand(reporter, s.randAt(ts))
+	driver := NewDriver(ctx, schema.Selector{}, DriverOpts{})
+	ret, err := driver.ExecuteTipset(blockstoreutil.NewTieredBstore(tracing, blockstoreutil.NewTemporarySync()), ds.NewMapDatastore(), ExecuteTipsetParams{
+		Preroot:     root,
+		ParentEpoch: parentHeight,
+		Tipset:      &tipset,
+		ExecEpoch:   height,
+		Rand:        rand,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply tipset: %w", err)
+	}
+
+	// the implicit messages have no receipt on chain, the receipts are the
+	// computed ones
+	var receipts []*schema.Receipt
+	for _, r := range ret.AppliedResults {
+		receipts = append(receipts, vectorReceipt(r.Receipt))
+	}
+	postRoot, receiptsRoot := ret.PostStateRoot, ret.ReceiptsRoot
+	if child, err := s.child(ctx, ts); err != nil {
+		return nil, err
+	} else if child != nil {
+		if onChain := child.At(0).ParentStateRoot; onChain != postRoot {
+			return nil, fmt.Errorf("the state root on chain %s differs from the computed one %s", onChain, postRoot)
+		}
+		if onChain := child.At(0).ParentMessageReceipts; onChain != receiptsRoot {
+			return nil, fmt.Errorf("the receipts root on chain %s differs from the computed one %s", onChain, receiptsRoot)
+		}
+	}
+
+	carBytes, err := writeVectorCAR(s.bs, tracing.accessed(), root)
+	if err != nil {
+		return nil, err
+	}
+	nv := s.fork.GetNtwkVersion(ctx, parentHeight)
+	return &schema.TestVector{
+		Class: schema.ClassTipset,
+		Meta:  vectorMeta(fmt.Sprintf("tipset-%d", height)),
+		CAR:   carBytes,
+		Pre: &schema.Preconditions{
+			Variants:  []schema.Variant{variant(parentHeight, nv)},
+			StateTree: &schema.StateTree{RootCID: root},
+		},
+		ApplyTipsets: []schema.Tipset{tipset},
+		Post: &schema.Postconditions{
+			StateTree:     &schema.StateTree{RootCID: postRoot},
+			Receipts:      receipts,
+			ReceiptsRoots: []cid.Cid{receiptsRoot},
+		},
+		Randomness: rand.Recorded(),
+	}, nil
+}
+
+// findMessage walks back at most `lookback` epochs from the head to the
+// tipset including `msgCid`, it returns the messages of the tipset in their
+// execution order and the index of the message.
+func (s *ChainSource) findMessage(ctx context.Context, msgCid cid.Cid, lookback abi.ChainEpoch) (*block.TipSet, int, []types.ChainMsg, error) {
+	head := s.store.GetHead()
+	stop := head.EnsureHeight() - lookback
+	for ts := head; ts.EnsureHeight() > 0 && ts.EnsureHeight() >= stop; {
+		msgs, err := s.messages.MessagesForTipset(ts)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		for i, m := range msgs {
+			c, err := m.Cid()
+			if err != nil {
+				return nil, 0, nil, err
+			}
+			unsigned, err := m.VMMessage().Cid()
+			if err != nil {
+				return nil, 0, nil, err
+			}
+			if c == msgCid || unsigned == msgCid {
+				return ts, i, msgs, nil
+			}
+		}
+		if ts, err = s.store.GetTipSet(ts.EnsureParents()); err != nil {
+			return nil, 0, nil, err
+		}
+	}
+	return nil, 0, nil, fmt.Errorf("message %s not found in the last %d epochs", msgCid, lookback)
+}
+
+// child returns the tipset of the chain whose parent is `ts`, nil when `ts`
+// is the head.
+func (s *ChainSource) child(ctx context.Context, ts *block.TipSet) (*block.TipSet, error) {
+	height := ts.EnsureHeight()
+	for cur := s.store.GetHead(); cur.EnsureHeight() > height; {
+		parents := cur.EnsureParents()
+		if parents.Equals(ts.Key()) {
+			return cur, nil
+		}
+		var err error
+		if cur, err = s.store.GetTipSet(parents); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// chainReceipts returns the receipts of the messages of `ts` recorded by its
+// child, nil when `ts` is the head.
+func (s *ChainSource) chainReceipts(ctx context.Context, ts *block.TipSet) ([]types.MessageReceipt, error) {
+	child, err := s.child(ctx, ts)
+	if err != nil || child == nil {
+		return nil, err
+	}
+	return s.messages.LoadReceipts(ctx, child.At(0).ParentMessageReceipts)
+}
+
+func (s *ChainSource) circulatingSupply(ctx context.Context, root cid.Cid, height abi.ChainEpoch) (abi.TokenAmount, error) {
+	tree, err := vmstate.LoadState(ctx, cbor.NewCborStore(s.bs), root)
+	if err != nil {
+		return abi.TokenAmount{}, err
+	}
+	supply, err := chain.NewCirculatingSupplyCalculator(s.bs, s.store, s.forkParams).GetCirculatingSupplyDetailed(ctx, height, tree)
+	if err != nil {
+		return abi.TokenAmount{}, err
+	}
+	return supply.FilCirculating, nil
+}
+
+// randAt draws the randomness the node used to execute the messages of `ts`.
+func (s *ChainSource) randAt(ts *block.TipSet) chainReader {
+	return &tipSetRand{state: s.state, ts: ts}
+}
+
+type tipSetRand struct {
+	state *cst.ChainStateReadWriter
+	ts    *block.TipSet
+}
+
+func (r *tipSetRand) GetHead() (*block.TipSet, error) {
+	return r.ts, nil
+}
+
+func (r *tipSetRand) ChainGetRandomnessFromTickets(ctx context.Context, tsk block.TipSetKey, personalization crypto.DomainSeparationTag, randEpoch abi.ChainEpoch, entropy []byte) (abi.Randomness, error) {
+	return r.state.SampleChainRandomness(ctx, tsk, personalization, randEpoch, entropy)
+}
+
+func (r *tipSetRand) ChainGetRandomnessFromBeacon(ctx context.Context, tsk block.TipSetKey, personalization crypto.DomainSeparationTag, randEpoch abi.ChainEpoch, entropy []byte) (abi.Randomness, error) {
+	return r.state.ChainGetRandomnessFromBeacon(ctx, tsk, personalization, randEpoch, entropy)
+}
+
+// tracingBlockstore records the blocks read from it.
+type tracingBlockstore struct {
+	blockstoreutil.Blockstore
+
+	lk   sync.Mutex
+	seen map[cid.Cid]struct{}
+}
+
+func newTracingBlockstore(bs blockstoreutil.Blockstore) *tracingBlockstore {
+	return &tracingBlockstore{Blockstore: bs, seen: map[cid.Cid]struct{}{}}
+}
+
+func (t *tracingBlockstore) record(c cid.Cid) {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	t.seen[c] = struct{}{}
+}
+
+func (t *tracingBlockstore) Get(c cid.Cid) (blocks.Block, error) {
+	t.record(c)
+	return t.Blockstore.Get(c)
+}
+
+func (t *tracingBlockstore) View(c cid.Cid, callback func([]byte) error) error {
+	blk, err := t.Get(c)
+	if err != nil {
+		return err
+	}
+	return callback(blk.RawData())
+}
+
+func (t *tracingBlockstore) GetSize(c cid.Cid) (int, error) {
+	t.record(c)
+	return t.Blockstore.GetSize(c)
+}
+
+func (t *tracingBlockstore) Has(c cid.Cid) (bool, error) {
+	t.record(c)
+	return t.Blockstore.Has(c)
+}
+
+func (t *tracingBlockstore) accessed() []cid.Cid {
+	t.lk.Lock()
+	defer t.lk.Unlock()
+	out := make([]cid.Cid, 0, len(t.seen))
+	for c := range t.seen {
+		out = append(out, c)
+	}
+	return out
+}
+
+// writeVectorCAR returns the gzipped CAR of the blocks `cids` of `bs` rooted
+// at `root`, the blocks `bs` does not have were written by the execution and
+// are left out.
+func writeVectorCAR(bs blockstoreutil.Blockstore, cids []cid.Cid, root cid.Cid) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	if err := car.WriteHeader(&car.CarHeader{Roots: []cid.Cid{root}, Version: 1}, gz); err != nil {
+		return nil, err
+	}
+	for _, c := range cids {
+		blk, err := bs.Get(c)
+		if err == blockstoreutil.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := carutil.LdWrite(gz, c.Bytes(), blk.RawData()); err != nil {
+			return nil, err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func vectorMeta(id string) *schema.Metadata {
+	return &schema.Metadata{
+		ID:  id,
+		Gen: []schema.GenerationData{{Source: "venus-conformance", Version: constants.UserVersion()}},
+	}
+}
+
+func variant(epoch abi.ChainEpoch, nv network.Version) schema.Variant {
+	return schema.Variant{
+		ID:             fmt.Sprintf("nv%d", nv),
+		Epoch:          int64(epoch),
+		NetworkVersion: uint(nv),
+	}
+}
+
+func vectorReceipt(r types.MessageReceipt) *schema.Receipt {
+	return &schema.Receipt{
+		ExitCode:    int64(r.ExitCode),
+		ReturnValue: r.ReturnValue,
+		GasUsed:     r.GasUsed,
+	}
+}
+
+func receiptEquals(a, b types.MessageReceipt) bool {
+	return a.ExitCode == b.ExitCode && a.GasUsed == b.GasUsed && bytes.Equal(a.ReturnValue, b.ReturnValue)
+}
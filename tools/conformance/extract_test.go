@@ -0,0 +1,260 @@
+package conformance
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/test-vectors/schema"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/filecoin-project/venus/app/node/test"
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/chain"
+	"github.com/filecoin-project/venus/pkg/genesis"
+	"github.com/filecoin-project/venus/pkg/repo"
+	tf "github.com/filecoin-project/venus/pkg/testhelpers/testflags"
+	"github.com/filecoin-project/venus/pkg/types"
+)
+
+// extractChain is a repo whose chain has a tipset at 1 with `msgs`, and its
+// child at 2 holding the state and receipts computed for them.
+type extractChain struct {
+	repo repo.Repo
+	// roots are the states before each message and after the last one.
+	roots    []cid.Cid
+	msgs     []*types.UnsignedMessage
+	receipts []types.MessageReceipt
+	ts       *block.TipSet
+}
+
+// newExtractChain builds the chain, `tamper` changes the receipts recorded on
+// chain from the computed ones.
+func newExtractChain(t *testing.T, tamper func([]types.MessageReceipt)) *extractChain {
+	ctx := context.Background()
+	cs := test.FixtureChainSeed(t)
+	r := repo.NewInMemoryRepo()
+	store, err := genesis.Init(ctx, r, r.Datastore(), cbor.NewCborStore(r.Datastore()), cs.GenesisInitFunc)
+	require.NoError(t, err)
+	gen := store.GetHead()
+
+	// two senders, the message extracted is preceded by one of each
+	alice, bob := cs.Addr(t, 0), cs.Addr(t, 1)
+	send := func(from address.Address, nonce uint64, value int64) *types.UnsignedMessage {
+		return &types.UnsignedMessage{
+			From:       from,
+			To:         cs.Addr(t, 2),
+			Nonce:      nonce,
+			Value:      abi.NewTokenAmount(value),
+			GasLimit:   10_000_000,
+			GasFeeCap:  big.Zero(),
+			GasPremium: big.Zero(),
+		}
+	}
+	c := &extractChain{
+		repo:  r,
+		roots: []cid.Cid{gen.At(0).ParentStateRoot},
+		msgs:  []*types.UnsignedMessage{send(alice, 0, 10), send(bob, 0, 20), send(alice, 1, 30)},
+	}
+
+	// the chain holds the states and receipts of the driver
+	baseFee := gen.At(0).ParentBaseFee
+	driver := NewDriver(ctx, schema.Selector{}, DriverOpts{})
+	for _, m := range c.msgs {
+		ret, root, err := driver.ExecuteMessage(r.Datastore(), ExecuteMessageParams{
+			Preroot:    c.roots[len(c.roots)-1],
+			Epoch:      1,
+			Message:    m,
+			BaseFee:    baseFee,
+			CircSupply: abi.NewTokenAmount(0),
+		})
+		require.NoError(t, err)
+		require.Equal(t, 0, int(ret.Receipt.ExitCode))
+		c.roots = append(c.roots, root)
+		c.receipts = append(c.receipts, ret.Receipt)
+	}
+	onChain := append([]types.MessageReceipt{}, c.receipts...)
+	if tamper != nil {
+		tamper(onChain)
+	}
+
+	messages := chain.NewMessageStore(r.Datastore())
+	txMeta, err := messages.StoreMessages(ctx, nil, c.msgs)
+	require.NoError(t, err)
+	receiptsRoot, err := messages.StoreReceipts(ctx, onChain)
+	require.NoError(t, err)
+	emptyMeta, err := messages.StoreMessages(ctx, nil, nil)
+	require.NoError(t, err)
+
+	newTipSet := func(parent *block.TipSet, root, receipts, msgs cid.Cid) *block.TipSet {
+		blk := &block.Block{
+			Miner:                 gen.At(0).Miner,
+			Ticket:                block.Ticket{VRFProof: []byte{byte(parent.EnsureHeight())}},
+			ElectionProof:         &block.ElectionProof{VRFProof: []byte{0x0c}, WinCount: 1},
+			Parents:               parent.Key(),
+			ParentWeight:          big.Zero(),
+			Height:                parent.EnsureHeight() + 1,
+			ParentStateRoot:       root,
+			ParentMessageReceipts: receipts,
+			Messages:              msgs,
+			ParentBaseFee:         baseFee,
+			BLSAggregate:          &crypto.Signature{Type: crypto.SigTypeBLS, Data: []byte{}},
+			BlockSig:              &crypto.Signature{Type: crypto.SigTypeSecp256k1, Data: []byte{}},
+		}
+		_, err := cbor.NewCborStore(r.Datastore()).Put(ctx, blk)
+		require.NoError(t, err)
+		return block.RequireNewTipSet(t, blk)
+	}
+	c.ts = newTipSet(gen, gen.At(0).ParentStateRoot, gen.At(0).ParentMessageReceipts, txMeta)
+	child := newTipSet(c.ts, c.roots[len(c.roots)-1], receiptsRoot, emptyMeta)
+	for _, tsm := range []*chain.TipSetMetadata{
+		{TipSet: c.ts, TipSetStateRoot: child.At(0).ParentStateRoot, TipSetReceipts: receiptsRoot},
+		{TipSet: child, TipSetStateRoot: child.At(0).ParentStateRoot, TipSetReceipts: emptyMeta},
+	} {
+		require.NoError(t, store.PutTipSetMetadata(ctx, tsm))
+	}
+	require.NoError(t, store.SetHead(ctx, child))
+	return c
+}
+
+func TestExtractMessage(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	t.Run("the vector applies the message on the state left by all its precursors", func(t *testing.T) {
+		c := newExtractChain(t, nil)
+		source, err := NewChainSource(ctx, c.repo)
+		require.NoError(t, err)
+
+		target, err := c.msgs[2].Cid()
+		require.NoError(t, err)
+		vector, err := source.ExtractMessage(ctx, target, 10)
+		require.NoError(t, err)
+
+		assert.Equal(t, schema.ClassMessage, vector.Class)
+		assert.Equal(t, c.roots[2], vector.Pre.StateTree.RootCID)
+		assert.Equal(t, c.roots[3], vector.Post.StateTree.RootCID)
+		require.Len(t, vector.Post.Receipts, 1)
+		assert.Equal(t, vectorReceipt(c.receipts[2]), vector.Post.Receipts[0])
+		require.Len(t, vector.Pre.Variants, 1)
+		assert.Equal(t, int64(1), vector.Pre.Variants[0].Epoch)
+
+		// the minimized pre-state is enough to run the vector
+		for _, res := range executeVector("extracted", vector) {
+			assert.Equal(t, StatusPass, res.Status, res.Errors)
+		}
+	})
+
+	t.Run("a receipt not reproduced fails the extraction", func(t *testing.T) {
+		c := newExtractChain(t, func(receipts []types.MessageReceipt) {
+			receipts[2].GasUsed++
+		})
+		source, err := NewChainSource(ctx, c.repo)
+		require.NoError(t, err)
+
+		target, err := c.msgs[2].Cid()
+		require.NoError(t, err)
+		_, err = source.ExtractMessage(ctx, target, 10)
+		assert.Error(t, err)
+
+		// the other messages are still extracted
+		first, err := c.msgs[0].Cid()
+		require.NoError(t, err)
+		vector, err := source.ExtractMessage(ctx, first, 10)
+		require.NoError(t, err)
+		assert.Equal(t, c.roots[0], vector.Pre.StateTree.RootCID)
+	})
+
+	t.Run("the message must be in the lookback", func(t *testing.T) {
+		c := newExtractChain(t, nil)
+		source, err := NewChainSource(ctx, c.repo)
+		require.NoError(t, err)
+
+		target, err := c.msgs[0].Cid()
+		require.NoError(t, err)
+		_, err = source.ExtractMessage(ctx, target, 0)
+		assert.Error(t, err)
+		_, err = source.ExtractMessage(ctx, types.CidFromString(t, "unknown"), 10)
+		assert.Error(t, err)
+	})
+}
+
+func TestExecuteVectors(t *testing.T) {
+	tf.UnitTest(t)
+	ctx := context.Background()
+
+	c := newExtractChain(t, nil)
+	source, err := NewChainSource(ctx, c.repo)
+	require.NoError(t, err)
+	target, err := c.msgs[2].Cid()
+	require.NoError(t, err)
+	vector, err := source.ExtractMessage(ctx, target, 10)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	write := func(name string, v interface{}) {
+		raw, err := json.Marshal(v)
+		require.NoError(t, err)
+		require.NoError(t, writeFile(dir, name, raw))
+	}
+	write("pass.json", vector)
+
+	wrong := *vector
+	wrong.Post = &schema.Postconditions{
+		StateTree: &schema.StateTree{RootCID: c.roots[2]},
+		Receipts:  vector.Post.Receipts,
+	}
+	write("nested/wrong-root.json", &wrong)
+
+	incorrect := *vector
+	incorrect.Hints = []string{schema.HintIncorrect}
+	write("incorrect.json", &incorrect)
+
+	// ignored files
+	write("_draft.json", vector)
+	require.NoError(t, writeFile(dir, "notes.txt", []byte("not a vector")))
+	require.NoError(t, writeFile(dir, "broken.json", []byte("{")))
+
+	report, err := ExecuteVectors([]string{dir})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Passed)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, 1, report.Skipped)
+
+	statuses := map[string]string{}
+	for _, res := range report.Results {
+		rel, err := filepath.Rel(dir, res.File)
+		require.NoError(t, err)
+		statuses[rel] = res.Status
+		if res.Status == StatusFail {
+			assert.NotEmpty(t, res.Errors)
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"pass.json":              StatusPass,
+		"nested/wrong-root.json": StatusFail,
+		"incorrect.json":         StatusSkip,
+		"broken.json":            StatusFail,
+	}, statuses)
+
+	_, err = ExecuteVectors([]string{t.TempDir()})
+	assert.Error(t, err)
+}
+
+func writeFile(dir, name string, raw []byte) error {
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, raw, 0644)
+}
@@ -72,7 +72,7 @@ func ExecuteMessageVector(r Reporter, v string, vector *schema.TestVector, varia
 
 		for _, xx := range ret.GasTracker.ExecutionTrace.GasCharges {
 			if xx.TotalGas > 0 {
-				fmt.Println(xx)
+				r.Log(xx)
 			}
 		}
 		// Assert that the receipt matches what the test vector expects.
@@ -112,7 +112,13 @@ func ExecuteTipsetVector(r Reporter, v string, vector *schema.TestVector, varian
 	for i, ts := range vector.ApplyTipsets {
 		ts := ts // capture
 		execEpoch := baseEpoch + abi.ChainEpoch(ts.EpochOffset)
-		ret, err := driver.ExecuteTipset(bs, tmpds, root, prevEpoch, &ts, execEpoch)
+		ret, err := driver.ExecuteTipset(bs, tmpds, ExecuteTipsetParams{
+			Preroot:     root,
+			ParentEpoch: prevEpoch,
+			Tipset:      &ts,
+			ExecEpoch:   execEpoch,
+			Rand:        NewReplayingRand(r, vector.Randomness),
+		})
 		if err != nil {
 			r.Fatalf("failed to apply tipset %d message: %s", i, err)
 		}
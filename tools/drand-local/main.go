@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/filecoin-project/venus/pkg/beacon/localdrand"
+)
+
+// drand-local serves a local drand chain over the drand HTTP API. Nodes use it
+// by setting `parameters.localDrand` to the printed config and scheduling
+// DrandLocalnet (4) in `parameters.drandSchedule`.
+func main() {
+	port := flag.Int("port", 8080, "port over which to serve the drand HTTP API")
+	keyFile := flag.String("key-file", "drand-local.key", "file holding the key of the chain, generated when missing")
+	roundsFile := flag.String("rounds-file", "drand-local.rounds", "file holding the rounds of the chain produced so far")
+	period := flag.Duration("period", 30*time.Second, "duration of a round of a new chain")
+	genesis := flag.Int64("genesis-time", 0, "unix time of the first round of a new chain, defaults to now")
+	shares := flag.Int("shares", 3, "number of shares of a new chain")
+	threshold := flag.Int("threshold", 2, "number of shares signing each round of a new chain")
+	configOut := flag.String("config-out", "", "file to write the drand config to, printed when empty")
+	flag.Parse()
+
+	key, err := loadOrCreateKey(*keyFile, *shares, *threshold, *period, *genesis)
+	if err != nil {
+		exit(err)
+	}
+	ld, err := localdrand.New(key, *roundsFile)
+	if err != nil {
+		exit(err)
+	}
+
+	drandConf, err := ld.DrandConf(fmt.Sprintf("http://127.0.0.1:%d", *port))
+	if err != nil {
+		exit(err)
+	}
+	data, err := json.MarshalIndent(drandConf, "", "\t")
+	if err != nil {
+		exit(err)
+	}
+	if *configOut == "" {
+		fmt.Println(string(data))
+	} else if err := ioutil.WriteFile(*configOut, data, 0644); err != nil {
+		exit(err)
+	}
+
+	fmt.Printf("serving drand chain %s on port %d\n", hex.EncodeToString(ld.Info().Hash()), *port)
+	exit(http.ListenAndServe(fmt.Sprintf(":%d", *port), ld))
+}
+
+func loadOrCreateKey(path string, shares, threshold int, period time.Duration, genesis int64) (*localdrand.Key, error) {
+	if _, err := os.Stat(path); err == nil {
+		return localdrand.LoadKey(path)
+	}
+
+	genesisTime := time.Now()
+	if genesis != 0 {
+		genesisTime = time.Unix(genesis, 0)
+	}
+	key, err := localdrand.NewKey(shares, threshold, period, genesisTime)
+	if err != nil {
+		return nil, err
+	}
+	if err := key.WriteFile(path); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err) // nolint: errcheck
+	os.Exit(1)
+}
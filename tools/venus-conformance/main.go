@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/ipfs/go-cid"
+
+	"github.com/filecoin-project/venus/app/paths"
+	"github.com/filecoin-project/venus/pkg/block"
+	"github.com/filecoin-project/venus/pkg/repo"
+	"github.com/filecoin-project/venus/tools/conformance"
+)
+
+const usage = `venus-conformance runs and extracts conformance test vectors.
+
+Usage:
+  venus-conformance exec [-out report.json] <vector file or directory>...
+  venus-conformance extract [-repo dir] (-msg cid | -tipset cid,...) [-out vector.json]
+
+exec runs the vectors through the conformance driver and writes a JSON report
+of each variant, it exits with 1 when one fails.
+
+extract reads a synced repo, the daemon must be stopped, and writes a test
+vector reproducing the execution of a message or of a tipset, with its
+minimized pre-state, the randomness drawn and the receipts computed. It fails
+when the execution does not reproduce the receipts or roots on chain.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage) // nolint: errcheck
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "exec":
+		err = execCmd(os.Args[2:])
+	case "extract":
+		err = extractCmd(os.Args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage) // nolint: errcheck
+		os.Exit(2)
+	}
+	if err != nil {
+		exit(err)
+	}
+}
+
+func execCmd(args []string) error {
+	flags := flag.NewFlagSet("exec", flag.ExitOnError)
+	out := flags.String("out", "", "file to write the report to, printed when empty")
+	_ = flags.Parse(args)
+	if flags.NArg() == 0 {
+		return fmt.Errorf("exec needs vector files or directories")
+	}
+
+	report, err := conformance.ExecuteVectors(flags.Args())
+	if err != nil {
+		return err
+	}
+	if err := writeJSON(*out, report); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+	return nil
+}
+
+func extractCmd(args []string) error {
+	flags := flag.NewFlagSet("extract", flag.ExitOnError)
+	repoDir := flags.String("repo", "", "repo to read the chain from, defaults to the venus repo")
+	msg := flags.String("msg", "", "cid of the message to extract a message-class vector of")
+	tipset := flags.String("tipset", "", "comma separated block cids of the tipset to extract a tipset-class vector of")
+	lookback := flags.Int64("lookback", 2880, "number of epochs back from the head to search the message in")
+	out := flags.String("out", "", "file to write the vector to, printed when empty")
+	_ = flags.Parse(args)
+	if (*msg == "") == (*tipset == "") {
+		return fmt.Errorf("extract needs one of -msg or -tipset")
+	}
+
+	dir, err := paths.GetRepoPath(*repoDir)
+	if err != nil {
+		return err
+	}
+	r, err := repo.OpenFSRepo(dir, repo.Version)
+	if err != nil {
+		return err
+	}
+	defer r.Close() // nolint: errcheck
+
+	ctx := context.Background()
+	source, err := conformance.NewChainSource(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	var vector interface{}
+	if *msg != "" {
+		c, err := cid.Decode(*msg)
+		if err != nil {
+			return fmt.Errorf("invalid -msg: %w", err)
+		}
+		if vector, err = source.ExtractMessage(ctx, c, abi.ChainEpoch(*lookback)); err != nil {
+			return err
+		}
+	} else {
+		var cids []cid.Cid
+		for _, s := range strings.Split(*tipset, ",") {
+			c, err := cid.Decode(strings.TrimSpace(s))
+			if err != nil {
+				return fmt.Errorf("invalid -tipset: %w", err)
+			}
+			cids = append(cids, c)
+		}
+		if vector, err = source.ExtractTipset(ctx, block.NewTipSetKey(cids...)); err != nil {
+			return err
+		}
+	}
+	return writeJSON(*out, vector)
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		fmt.Println(string(data))
+		return nil
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err) // nolint: errcheck
+	os.Exit(1)
+}